@@ -13,6 +13,8 @@ import (
 	"github.com/dayuer/nanobot-go/internal/agent"
 	"github.com/dayuer/nanobot-go/internal/bus"
 	"github.com/dayuer/nanobot-go/internal/config"
+	"github.com/dayuer/nanobot-go/internal/mcp"
+	"github.com/dayuer/nanobot-go/internal/tools"
 	"github.com/spf13/cobra"
 )
 
@@ -40,15 +42,29 @@ func runAgent(cmd *cobra.Command, args []string) error {
 	}
 
 	msgBus := bus.NewMessageBus()
+	defer msgBus.Close()
 	provider := makeProvider(cfg)
 
-	loop := agent.NewAgentLoop(msgBus, provider, agent.AgentConfig{
+	loop := agent.NewAgentLoop(msgBus, provider, withToolLimits(agent.AgentConfig{
 		Workspace:     cfg.Agent.Workspace,
 		Model:         cfg.Agent.Model,
 		Temperature:   cfg.Agent.Temperature,
 		MaxTokens:     cfg.Agent.MaxTokens,
 		MaxIterations: cfg.Agent.MaxIterations,
-	})
+		ToolWhitelist: tools.AllTools,
+		ToolFactory:   defaultToolFactory(cfg, msgBus, nil),
+		Knowledge:     knowledgeSink(cfg),
+		AlwaysSkills:  cfg.Agent.AlwaysSkills,
+
+		ReasoningEffort: cfg.Agent.ReasoningEffort,
+		ReasoningBudget: cfg.Agent.ReasoningBudget,
+	}, cfg.Tools))
+
+	// Memory consolidation runs in the background; let it land before exit
+	defer loop.WaitConsolidations()
+
+	mcpServers := mcp.ConnectServers(context.Background(), cfg.Agent.MCPServers, loop.Tools, loop.ToolWhitelist)
+	defer mcpServers.Close()
 
 	if agentMessage != "" {
 		// Single message mode
@@ -73,6 +89,7 @@ func runAgent(cmd *cobra.Command, args []string) error {
 		<-sigCh
 		fmt.Println("\nGoodbye!")
 		cancel()
+		loop.WaitConsolidations()
 		os.Exit(0)
 	}()
 
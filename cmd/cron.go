@@ -0,0 +1,329 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dayuer/nanobot-go/internal/config"
+	"github.com/dayuer/nanobot-go/internal/cron"
+)
+
+var cronCmd = &cobra.Command{
+	Use:   "cron",
+	Short: "Manage scheduled jobs",
+	Long: `Inspect and edit the scheduled jobs in workspace/cron/jobs.json, the
+store the agent's cron tool uses. Jobs run in the gateway, which picks up
+changes made here within 30 seconds.`,
+}
+
+var cronListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List scheduled jobs",
+	Args:  cobra.NoArgs,
+	RunE:  runCronList,
+}
+
+var cronAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Schedule a job",
+	Long: `Schedule a message for the agent. Give exactly one of --every, --cron
+or --at. The agent's reply is delivered to --channel/--to when both are set.
+
+Examples:
+  nanobot cron add -n water -m "Remind me to drink water" --every 2h --channel telegram --to 12345
+  nanobot cron add -m "Summarize today's news" --cron "0 9 * * 1-5" --tz Asia/Shanghai
+  nanobot cron add -m "Call mom" --at 2025-01-31T18:30`,
+	Args: cobra.NoArgs,
+	RunE: runCronAdd,
+}
+
+var cronRemoveCmd = &cobra.Command{
+	Use:   "remove <id>",
+	Short: "Remove a job",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runCronRemove,
+}
+
+var cronEnableCmd = &cobra.Command{
+	Use:   "enable <id>",
+	Short: "Enable a job, scheduling it from now",
+	Args:  cobra.ExactArgs(1),
+	RunE:  func(cmd *cobra.Command, args []string) error { return setCronEnabled(args[0], true) },
+}
+
+var cronDisableCmd = &cobra.Command{
+	Use:   "disable <id>",
+	Short: "Disable a job",
+	Args:  cobra.ExactArgs(1),
+	RunE:  func(cmd *cobra.Command, args []string) error { return setCronEnabled(args[0], false) },
+}
+
+var cronRunNowCmd = &cobra.Command{
+	Use:   "run-now <id>",
+	Short: "Run a job once, out of schedule",
+	Long: `Ask the scheduler to run a job once as soon as possible, even if it is
+disabled. The run happens in the gateway; see it with 'nanobot cron history'.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCronRunNow,
+}
+
+var cronHistoryCmd = &cobra.Command{
+	Use:   "history <id>",
+	Short: "Show a job's recent runs",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runCronHistory,
+}
+
+var (
+	cronJSON bool
+
+	cronAddName    string
+	cronAddMessage string
+	cronAddEvery   time.Duration
+	cronAddExpr    string
+	cronAddTZ      string
+	cronAddAt      string
+	cronAddChannel string
+	cronAddTo      string
+	cronAddCatchUp string
+	cronAddKeep    bool
+)
+
+func init() {
+	for _, c := range []*cobra.Command{cronListCmd, cronAddCmd, cronHistoryCmd} {
+		c.Flags().BoolVar(&cronJSON, "json", false, "Print JSON instead of a table")
+	}
+
+	f := cronAddCmd.Flags()
+	f.StringVarP(&cronAddName, "name", "n", "", "Job name (default: the job ID)")
+	f.StringVarP(&cronAddMessage, "message", "m", "", "Message sent to the agent when the job runs")
+	f.DurationVar(&cronAddEvery, "every", 0, "Run at this interval, e.g. 30m or 2h")
+	f.StringVar(&cronAddExpr, "cron", "", "Five-field cron expression, e.g. \"0 9 * * 1-5\"")
+	f.StringVar(&cronAddTZ, "tz", "", "IANA time zone for --cron (default: config cron.timezone)")
+	f.StringVar(&cronAddAt, "at", "", "Run once at this ISO date-time")
+	f.StringVar(&cronAddChannel, "channel", "", "Channel to deliver the reply to, e.g. telegram")
+	f.StringVar(&cronAddTo, "to", "", "Chat ID to deliver the reply to")
+	f.StringVar(&cronAddCatchUp, "catch-up", "", "Missed-run policy: skip, once or all (default: config cron.catchUp)")
+	f.BoolVar(&cronAddKeep, "keep", false, "Keep an --at job (disabled) after it runs instead of deleting it")
+	cronAddCmd.MarkFlagRequired("message")
+
+	cronCmd.AddCommand(cronListCmd, cronAddCmd, cronRemoveCmd, cronEnableCmd, cronDisableCmd, cronRunNowCmd, cronHistoryCmd)
+	rootCmd.AddCommand(cronCmd)
+}
+
+// cronStore opens the job store for the configured workspace.
+func cronStore() (*cron.Service, error) {
+	cfg, err := config.Load("")
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+	return newCronService(cfg)
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// cronTime formats an optional time for tables.
+func cronTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// cronCell flattens s to one line of at most n runes.
+func cronCell(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > n {
+		s = string(r[:n-1]) + "…"
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func runCronList(cmd *cobra.Command, args []string) error {
+	svc, err := cronStore()
+	if err != nil {
+		return err
+	}
+	jobs, err := svc.Jobs()
+	if err != nil {
+		return err
+	}
+	if cronJSON {
+		if jobs == nil {
+			jobs = []cron.Job{}
+		}
+		return printJSON(jobs)
+	}
+	if len(jobs) == 0 {
+		fmt.Printf("No scheduled jobs in %s\n", svc.Path)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSCHEDULE\tENABLED\tNEXT RUN\tLAST RUN\tLAST RESULT\tFAILURES")
+	for _, j := range jobs {
+		enabled := "yes"
+		if !j.Enabled {
+			enabled = "no"
+		}
+		result := j.State.LastResult
+		if j.State.LastStatus == "error" {
+			result = "error: " + j.State.LastError
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d/%d\n",
+			j.ID, cronCell(j.Name, 24), j.Schedule, enabled,
+			cronTime(j.State.NextRun), cronTime(j.State.LastRun), cronCell(result, 40),
+			j.State.Failures, j.State.Runs)
+	}
+	return w.Flush()
+}
+
+func runCronAdd(cmd *cobra.Command, args []string) error {
+	svc, err := cronStore()
+	if err != nil {
+		return err
+	}
+
+	var scheds []cron.Schedule
+	if cronAddEvery != 0 {
+		if cronAddEvery < time.Second {
+			return fmt.Errorf("--every must be at least 1s")
+		}
+		scheds = append(scheds, cron.Schedule{Kind: cron.KindEvery, EverySeconds: int(cronAddEvery / time.Second)})
+	}
+	if cronAddExpr != "" {
+		tz, expr := cron.SplitTZ(cronAddExpr)
+		if cronAddTZ != "" {
+			tz = cronAddTZ
+		}
+		scheds = append(scheds, cron.Schedule{Kind: cron.KindCron, Expr: expr, TZ: tz})
+	}
+	if cronAddAt != "" {
+		loc := svc.Location
+		if loc == nil {
+			loc = time.Local
+		}
+		at, err := cron.ParseTime(cronAddAt, loc)
+		if err != nil {
+			return err
+		}
+		scheds = append(scheds, cron.Schedule{Kind: cron.KindAt, At: &at})
+	}
+	if len(scheds) != 1 {
+		return fmt.Errorf("give exactly one of --every, --cron or --at")
+	}
+	if (cronAddChannel == "") != (cronAddTo == "") {
+		return fmt.Errorf("--channel and --to go together")
+	}
+
+	job, err := svc.Add(cron.Job{
+		Name:           cronAddName,
+		Schedule:       scheds[0],
+		Payload:        cron.Payload{Message: cronAddMessage, Channel: cronAddChannel, ChatID: cronAddTo},
+		CatchUp:        cronAddCatchUp,
+		DeleteAfterRun: scheds[0].Kind == cron.KindAt && !cronAddKeep,
+	})
+	if err != nil {
+		return err
+	}
+	if cronJSON {
+		return printJSON(job)
+	}
+	fmt.Printf("✓ Added job %s (%s), next run %s\n", job.ID, job.Schedule, cronTime(job.State.NextRun))
+	if job.Payload.Channel == "" {
+		fmt.Println("  ⚠️ No --channel/--to: the agent's reply will not be delivered")
+	}
+	return nil
+}
+
+func runCronRemove(cmd *cobra.Command, args []string) error {
+	svc, err := cronStore()
+	if err != nil {
+		return err
+	}
+	if err := svc.Remove(args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Removed job %s\n", args[0])
+	return nil
+}
+
+func setCronEnabled(id string, enabled bool) error {
+	svc, err := cronStore()
+	if err != nil {
+		return err
+	}
+	job, err := svc.SetEnabled(id, enabled)
+	if err != nil {
+		return err
+	}
+	if enabled {
+		fmt.Printf("✓ Enabled job %s, next run %s\n", job.ID, cronTime(job.State.NextRun))
+	} else {
+		fmt.Printf("✓ Disabled job %s\n", job.ID)
+	}
+	return nil
+}
+
+func runCronRunNow(cmd *cobra.Command, args []string) error {
+	svc, err := cronStore()
+	if err != nil {
+		return err
+	}
+	job, err := svc.RequestRun(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✓ Requested a run of job %s; a running gateway picks it up within 30s\n", job.ID)
+	return nil
+}
+
+func runCronHistory(cmd *cobra.Command, args []string) error {
+	svc, err := cronStore()
+	if err != nil {
+		return err
+	}
+	job, err := svc.Get(args[0])
+	if err != nil {
+		return err
+	}
+	if cronJSON {
+		history := job.History
+		if history == nil {
+			history = []cron.Run{}
+		}
+		return printJSON(history)
+	}
+	if len(job.History) == 0 {
+		fmt.Printf("Job %s has not run yet\n", job.ID)
+		return nil
+	}
+
+	fmt.Printf("%s (%s) — %d runs, %d failed\n", job.Name, job.Schedule, job.State.Runs, job.State.Failures)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STARTED\tTRIGGER\tSTATUS\tDURATION\tRESULT")
+	for i := len(job.History) - 1; i >= 0; i-- {
+		r := job.History[i]
+		result := r.Result
+		if r.Status == "error" {
+			result = r.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			r.Start.Local().Format("2006-01-02 15:04:05"), r.Trigger, r.Status,
+			r.End.Sub(r.Start).Round(time.Millisecond), cronCell(result, 60))
+	}
+	return w.Flush()
+}
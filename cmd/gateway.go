@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -12,6 +13,11 @@ import (
 	"github.com/dayuer/nanobot-go/internal/bus"
 	"github.com/dayuer/nanobot-go/internal/channels"
 	"github.com/dayuer/nanobot-go/internal/config"
+	"github.com/dayuer/nanobot-go/internal/cron"
+	"github.com/dayuer/nanobot-go/internal/heartbeat"
+	"github.com/dayuer/nanobot-go/internal/lane"
+	"github.com/dayuer/nanobot-go/internal/mcp"
+	"github.com/dayuer/nanobot-go/internal/tools"
 	"github.com/spf13/cobra"
 )
 
@@ -36,16 +42,46 @@ func runGateway(cmd *cobra.Command, args []string) error {
 
 	fmt.Printf("🤖 Starting nanobot gateway on port %d...\n", gatewayPort)
 
-	msgBus := bus.NewMessageBus()
+	msgBus, err := newMessageBus(cfg)
+	if err != nil {
+		return err
+	}
+	defer msgBus.Close()
 	provider := makeProvider(cfg)
+	cronSvc, err := newCronService(cfg)
+	if err != nil {
+		return err
+	}
 
-	loop := agent.NewAgentLoop(msgBus, provider, agent.AgentConfig{
+	loop := agent.NewAgentLoop(msgBus, provider, withToolLimits(agent.AgentConfig{
 		Workspace:     cfg.Agent.Workspace,
 		Model:         cfg.Agent.Model,
 		Temperature:   cfg.Agent.Temperature,
 		MaxTokens:     cfg.Agent.MaxTokens,
 		MaxIterations: cfg.Agent.MaxIterations,
-	})
+		ToolWhitelist: tools.AllTools,
+		ToolFactory:   defaultToolFactory(cfg, msgBus, cronSvc),
+		Knowledge:     knowledgeSink(cfg),
+		AlwaysSkills:  cfg.Agent.AlwaysSkills,
+
+		ReasoningEffort: cfg.Agent.ReasoningEffort,
+		ReasoningBudget: cfg.Agent.ReasoningBudget,
+	}, cfg.Tools))
+
+	// Memory consolidation runs in the background; let it land on shutdown
+	defer loop.WaitConsolidations()
+
+	// Scheduled jobs run as agent turns and reply to the chat that set them
+	cronSvc.Handler = cron.AgentHandler(loop, msgBus)
+	var hb *heartbeat.Service
+	if cfg.Heartbeat.Enabled {
+		if hb, err = newHeartbeat(cfg, loop, msgBus); err != nil {
+			return err
+		}
+	}
+
+	mcpServers := mcp.ConnectServers(context.Background(), cfg.Agent.MCPServers, loop.Tools, loop.ToolWhitelist)
+	defer mcpServers.Close()
 
 	// Create channel manager
 	chMgr := channels.NewManager(msgBus)
@@ -76,30 +112,77 @@ func runGateway(cmd *cobra.Command, args []string) error {
 		cancel()
 	}()
 
+	// Each session's messages run in order on its own lane; lanes run in
+	// parallel up to the concurrency cap
+	lanes, err := newLaneManager(cfg, func(laneCtx context.Context, req lane.ChatRequest) lane.ChatResult {
+		resp, err := loop.ProcessDirect(laneCtx, req.Content, req.SessionKey, req.Channel, req.ChatID)
+		if err != nil {
+			log.Printf("Agent error: %v", err)
+			return lane.ChatResult{Error: err.Error()}
+		}
+		if err := msgBus.PublishOutbound(bus.OutboundMessage{
+			Channel: req.Channel,
+			ChatID:  req.ChatID,
+			Content: resp,
+		}); err != nil {
+			log.Printf("Dropped reply to %s:%s: %v", req.Channel, req.ChatID, err)
+		}
+		return lane.ChatResult{Content: resp}
+	})
+	if err != nil {
+		return err
+	}
+	defer lanes.Stop()
+
 	// Run agent loop and channels concurrently
 	errCh := make(chan error, 2)
 	go func() {
-		// Agent loop listens for inbound messages from bus
+		// Dispatch inbound messages from the bus to their session's lane
 		for {
 			select {
 			case <-ctx.Done():
 				errCh <- nil
 				return
 			case msg := <-msgBus.Inbound:
-				resp, err := loop.ProcessDirect(ctx, msg.Content, msg.SessionKey(), msg.Channel, msg.ChatID)
+				done, err := lanes.Enqueue(ctx, lane.ChatRequest{
+					Content:    msg.Content,
+					SessionKey: msg.SessionKey(),
+					Channel:    msg.Channel,
+					ChatID:     msg.ChatID,
+					Metadata:   msg.Metadata,
+					Timestamp:  msg.Timestamp,
+				}, "")
 				if err != nil {
-					log.Printf("Agent error: %v", err)
-					continue
+					errCh <- nil
+					return
 				}
-				msgBus.PublishOutbound(bus.OutboundMessage{
-					Channel: msg.Channel,
-					ChatID:  msg.ChatID,
-					Content: resp,
-				})
+				go settleInbound(ctx, msgBus, msg.ID, done)
 			}
 		}
 	}()
 	go func() { errCh <- chMgr.StartAll(ctx) }()
+	go cronSvc.Run(ctx)
+	if hb != nil {
+		go hb.Run(ctx)
+	}
 
 	return <-errCh
 }
+
+// settleInbound acks an inbound message once its lane has processed it, or
+// nacks it when the turn failed, so the bus redelivers it with backoff (the
+// provider layer has already retried transient errors). Messages
+// interrupted by a newer one are acked; on shutdown they are left unacked,
+// so a durable bus redelivers them.
+func settleInbound(ctx context.Context, msgBus *bus.MessageBus, id string, done <-chan lane.ChatResult) {
+	select {
+	case r := <-done:
+		switch {
+		case r.Error == "" || r.Error == lane.Interrupted:
+			msgBus.AckInbound(id)
+		case ctx.Err() == nil:
+			msgBus.NackInbound(id, errors.New(r.Error))
+		}
+	case <-ctx.Done():
+	}
+}
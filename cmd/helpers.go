@@ -1,16 +1,24 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/dayuer/nanobot-go/internal/agent"
+	"github.com/dayuer/nanobot-go/internal/bus"
 	"github.com/dayuer/nanobot-go/internal/config"
+	"github.com/dayuer/nanobot-go/internal/cron"
+	"github.com/dayuer/nanobot-go/internal/heartbeat"
+	"github.com/dayuer/nanobot-go/internal/lane"
 	"github.com/dayuer/nanobot-go/internal/providers"
 )
 
 // makeProvider creates a Provider from the loaded config.
 // It tries to detect the correct provider and API key from environment variables.
-func makeProvider(cfg config.Config) *providers.Provider {
+func makeProvider(cfg config.Config) providers.LLMProvider {
 	model := cfg.Agent.Model
 	if model == "" {
 		model = "anthropic/claude-sonnet-4-5"
@@ -46,5 +54,113 @@ func makeProvider(cfg config.Config) *providers.Provider {
 		providerName = "openrouter"
 	}
 
-	return providers.NewProvider(apiKey, apiBase, model, providerName)
+	return providers.NewLLMProvider(apiKey, apiBase, model, providerName)
+}
+
+// withToolLimits copies the tool runtime limits from config into ac.
+func withToolLimits(ac agent.AgentConfig, tc config.ToolsConfig) agent.AgentConfig {
+	if tc.Timeout > 0 {
+		ac.ToolTimeout = time.Duration(tc.Timeout) * time.Second
+	}
+	if len(tc.Timeouts) > 0 {
+		ac.ToolTimeouts = make(map[string]time.Duration, len(tc.Timeouts))
+		for name, secs := range tc.Timeouts {
+			ac.ToolTimeouts[name] = time.Duration(secs) * time.Second
+		}
+	}
+	ac.MaxToolOutput = tc.MaxOutputBytes
+	return ac
+}
+
+// newCronService opens the workspace job store with the configured zone and
+// catch-up policy. The caller sets Handler before running it.
+func newCronService(cfg config.Config) (*cron.Service, error) {
+	svc := cron.NewService(cfg.Agent.Workspace, nil)
+	if tz := cfg.Cron.Timezone; tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("cron timezone: %w", err)
+		}
+		svc.Location = loc
+	}
+	if p := cfg.Cron.CatchUp; p != "" {
+		if !cron.ValidCatchUp(p) {
+			return nil, fmt.Errorf("cron catchUp: unknown policy %q", p)
+		}
+		svc.CatchUp = p
+	}
+	return svc, nil
+}
+
+// newMessageBus opens the gateway's message bus on the configured backend.
+func newMessageBus(cfg config.Config) (*bus.MessageBus, error) {
+	bc := cfg.Bus
+	var backend bus.Backend
+	switch bc.Backend {
+	case "", "memory":
+		backend = bus.NewMemoryBackend()
+	case "log":
+		dir := bc.Dir
+		if dir == "" {
+			dir = filepath.Join(cfg.Agent.Workspace, "bus")
+		}
+		lb, err := bus.OpenLogBackend(dir)
+		if err != nil {
+			return nil, fmt.Errorf("opening bus log: %w", err)
+		}
+		backend = lb
+	default:
+		return nil, fmt.Errorf("bus backend: unknown backend %q", bc.Backend)
+	}
+	return bus.Open(backend, bus.Options{
+		Capacity:        bc.Capacity,
+		MaxDeliveries:   bc.MaxDeliveries,
+		RedeliveryDelay: time.Duration(bc.RedeliveryDelay) * time.Millisecond,
+	})
+}
+
+// newLaneManager builds the gateway's per-session lanes around handler.
+func newLaneManager(cfg config.Config, handler lane.ChatHandler) (*lane.Manager, error) {
+	lc := cfg.Lanes
+	mode := lane.Mode(lc.Mode)
+	switch mode {
+	case "":
+		mode = lane.ModeFollowup
+	case lane.ModeFollowup, lane.ModeCollect, lane.ModeInterrupt:
+	default:
+		return nil, fmt.Errorf("lanes mode: unknown mode %q", lc.Mode)
+	}
+	maxConcurrent := lc.MaxConcurrent
+	if maxConcurrent <= 0 {
+		maxConcurrent = 4
+	}
+	return lane.NewManager(lane.ManagerConfig{
+		Handler:       handler,
+		DefaultMode:   mode,
+		CollectWindow: time.Duration(lc.CollectWindow) * time.Millisecond,
+		MaxConcurrent: maxConcurrent,
+	}), nil
+}
+
+// newHeartbeat builds the heartbeat for agent from config.
+func newHeartbeat(cfg config.Config, agent heartbeat.Agent, msgBus *bus.MessageBus) (*heartbeat.Service, error) {
+	hc := cfg.Heartbeat
+	hb := heartbeat.NewService(cfg.Agent.Workspace, agent, msgBus)
+	hb.Interval = time.Duration(hc.Interval) * time.Second
+	hb.Channel, hb.ChatID = hc.Channel, hc.ChatID
+	if hc.QuietHours != "" {
+		q, err := heartbeat.ParseQuietHours(hc.QuietHours)
+		if err != nil {
+			return nil, err
+		}
+		hb.QuietHours = q
+	}
+	if hc.Timezone != "" {
+		loc, err := time.LoadLocation(hc.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("heartbeat timezone: %w", err)
+		}
+		hb.Location = loc
+	}
+	return hb, nil
 }
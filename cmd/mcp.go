@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dayuer/nanobot-go/internal/agent"
+	"github.com/dayuer/nanobot-go/internal/config"
+	"github.com/dayuer/nanobot-go/internal/mcp"
+	"github.com/dayuer/nanobot-go/internal/registry"
+	"github.com/dayuer/nanobot-go/internal/tools"
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Model Context Protocol integration",
+}
+
+var mcpServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve nanobot's tools to MCP clients (stdio by default, or --http)",
+	Long: `Expose nanobot's tool set (exec, file tools, web, knowledge_search,
+survival tools) as an MCP server.
+
+With --agent, only the tools whitelisted in that agent's agents.yaml entry
+are served. Without --http the server speaks stdio, so it can be launched
+directly by an IDE or another agent.
+
+Over HTTP, clients must send "Authorization: Bearer <mcpServe.token>". An
+Origin header, which browsers send, must be loopback or listed in
+mcpServe.origins. An address without a host binds 127.0.0.1 only.`,
+	RunE: runMCPServe,
+}
+
+var (
+	mcpServeHTTP   string
+	mcpServePath   string
+	mcpServeAgent  string
+	mcpServeAgents string
+)
+
+func init() {
+	mcpServeCmd.Flags().StringVar(&mcpServeHTTP, "http", "", "Serve streamable HTTP on this address (e.g. 127.0.0.1:18791) instead of stdio")
+	mcpServeCmd.Flags().StringVar(&mcpServePath, "path", "/mcp", "HTTP endpoint path")
+	mcpServeCmd.Flags().StringVar(&mcpServeAgent, "agent", "", "Only serve the tools whitelisted for this agent ID")
+	mcpServeCmd.Flags().StringVar(&mcpServeAgents, "agents", "", "Path to agents.yaml (default: workspace/agents.yaml)")
+	mcpCmd.AddCommand(mcpServeCmd)
+	rootCmd.AddCommand(mcpCmd)
+}
+
+func runMCPServe(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load("")
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	allow, err := agentToolWhitelist(cfg, mcpServeAgent, mcpServeAgents)
+	if err != nil {
+		return err
+	}
+
+	server := mcp.NewServer(serveToolRegistry(cfg), allow)
+	limits := withToolLimits(agent.AgentConfig{}, cfg.Tools)
+	if limits.ToolTimeout > 0 {
+		server.Executor.Timeout = limits.ToolTimeout
+	}
+	server.Executor.Timeouts = limits.ToolTimeouts
+	if limits.MaxToolOutput > 0 {
+		server.Executor.MaxOutput = limits.MaxToolOutput
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	// stdout is the protocol channel in stdio mode; logs go to stderr
+	log.SetOutput(os.Stderr)
+	log.Printf("[MCP] Serving %d tools", len(server.Tools()))
+
+	if mcpServeHTTP == "" {
+		return server.ServeStdio(ctx, os.Stdin, os.Stdout)
+	}
+
+	if cfg.MCPServe.Token == "" {
+		return fmt.Errorf("mcp serve --http requires mcpServe.token in config")
+	}
+	addr, err := loopbackDefault(mcpServeHTTP)
+	if err != nil {
+		return err
+	}
+	server.Token = cfg.MCPServe.Token
+	server.Origins = append(mcp.LocalOrigins(addr), cfg.MCPServe.Origins...)
+
+	mux := http.NewServeMux()
+	mux.Handle(mcpServePath, server)
+	httpServer := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, done := context.WithTimeout(context.Background(), 5*time.Second)
+		defer done()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+	log.Printf("[MCP] Listening on http://%s%s", addr, mcpServePath)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// loopbackDefault binds an address given without a host (":18791") to
+// 127.0.0.1; exposing the tools on other interfaces must be explicit.
+func loopbackDefault(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("--http %q: %w", addr, err)
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// agentToolWhitelist returns AgentSpec.Tools for agentID, or every tool when
+// no agent is given. An agent without a tools list gets none.
+func agentToolWhitelist(cfg config.Config, agentID, agentsPath string) ([]string, error) {
+	if agentID == "" {
+		return tools.AllTools, nil
+	}
+	if agentsPath == "" {
+		agentsPath = filepath.Join(cfg.Agent.Workspace, "agents.yaml")
+	}
+	specs, err := registry.LoadAgentSpecs(agentsPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, spec := range specs {
+		if spec.ID == agentID {
+			return spec.Tools, nil
+		}
+	}
+	return nil, fmt.Errorf("agent %q not found in %s", agentID, agentsPath)
+}
+
+// serveToolRegistry builds the tool set offered over MCP. Tools that need a
+// live chat session (message) are not available without a bus.
+func serveToolRegistry(cfg config.Config) *tools.Registry {
+	reg, _ := defaultToolFactory(cfg, nil, nil).Build(tools.AllTools)
+	return reg
+}
@@ -49,9 +49,10 @@ func runOnboard(cmd *cobra.Command, args []string) error {
 
 	// Create default bootstrap files
 	templates := map[string]string{
-		"AGENTS.md": "# Agent Instructions\n\nYou are a helpful AI assistant. Be concise, accurate, and friendly.\n\n## Guidelines\n\n- Always explain what you're doing before taking actions\n- Ask for clarification when the request is ambiguous\n- Use tools to help accomplish tasks\n- Remember important information in memory/MEMORY.md\n",
-		"SOUL.md":   "# Soul\n\nI am nanobot, a lightweight AI assistant.\n\n## Personality\n\n- Helpful and friendly\n- Concise and to the point\n- Curious and eager to learn\n",
-		"USER.md":   "# User\n\nInformation about the user goes here.\n\n## Preferences\n\n- Communication style: (casual/formal)\n- Timezone: (your timezone)\n- Language: (your preferred language)\n",
+		"AGENTS.md":    "# Agent Instructions\n\nYou are a helpful AI assistant. Be concise, accurate, and friendly.\n\n## Guidelines\n\n- Always explain what you're doing before taking actions\n- Ask for clarification when the request is ambiguous\n- Use tools to help accomplish tasks\n- Remember important information in memory/MEMORY.md\n",
+		"SOUL.md":      "# Soul\n\nI am nanobot, a lightweight AI assistant.\n\n## Personality\n\n- Helpful and friendly\n- Concise and to the point\n- Curious and eager to learn\n",
+		"USER.md":      "# User\n\nInformation about the user goes here.\n\n## Preferences\n\n- Communication style: (casual/formal)\n- Timezone: (your timezone)\n- Language: (your preferred language)\n",
+		"HEARTBEAT.md": "# Heartbeat Tasks\n\n<!-- With heartbeat enabled, the agent reads this file periodically and acts on it.\nOnly headings, comments and [x] items: the check is skipped. -->\n\n## Active Tasks\n\n\n## Completed\n\n",
 	}
 
 	for filename, content := range templates {
@@ -13,6 +13,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/dayuer/nanobot-go/internal/agent"
 	"github.com/dayuer/nanobot-go/internal/bus"
 	"github.com/dayuer/nanobot-go/internal/channels"
 	"github.com/dayuer/nanobot-go/internal/cluster"
@@ -25,10 +26,10 @@ import (
 )
 
 var (
-	serverPort    int
-	serverAPIKey  string
-	registryURL   string
-	agentsFile    string
+	serverPort   int
+	serverAPIKey string
+	registryURL  string
+	agentsFile   string
 )
 
 var serverCmd = &cobra.Command{
@@ -178,13 +179,37 @@ func runServer(cmd *cobra.Command, args []string) error {
 
 	// 5. Create message bus
 	msgBus := bus.NewMessageBus()
+	defer msgBus.Close()
 
-	// 6. Register agents
+	// 6. Init Redis (optional, graceful fallback)
+	if cfg.Redis.URL != "" {
+		if nanoredis.Init(nanoredis.Config{
+			URL:      cfg.Redis.URL,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		}) {
+			fmt.Println("   ✅ Redis connected")
+		} else {
+			fmt.Println("   ⚠️ Redis unavailable (cache disabled, memory kept in workspace files)")
+		}
+	}
+
+	// Per-person memory lives in Redis when it is up, else in workspace files
+	var memoryBackend agent.MemoryBackend
+	if nanoredis.IsAvailable() {
+		memoryBackend = agent.RedisMemory{}
+	}
+
+	// 7. Register agents
 	reg := registry.NewRegistry(registry.RegistryConfig{
 		DefaultProvider: dynProvider,
 		Bus:             msgBus,
 		Workspace:       cfg.Agent.Workspace,
 		DefaultModel:    llmCfg.Model,
+		ToolFactory:     defaultToolFactory(cfg, msgBus, nil),
+		Knowledge:       knowledgeSink(cfg),
+		MemoryBackend:   memoryBackend,
+		AlwaysSkills:    cfg.Agent.AlwaysSkills,
 	})
 
 	// Load agents.yaml
@@ -214,19 +239,6 @@ func runServer(cmd *cobra.Command, args []string) error {
 		fmt.Println("   📋 Single-agent mode (no agents.yaml)")
 	}
 
-	// 7. Init Redis (optional, graceful fallback)
-	if cfg.Redis.URL != "" {
-		if nanoredis.Init(nanoredis.Config{
-			URL:      cfg.Redis.URL,
-			Password: cfg.Redis.Password,
-			DB:       cfg.Redis.DB,
-		}) {
-			fmt.Println("   ✅ Redis connected")
-		} else {
-			fmt.Println("   ⚠️ Redis unavailable (memory/cache features disabled)")
-		}
-	}
-
 	// 8. Create LLM Router (if router model configured and agents > 1)
 	var llmRouter *router.LLMRouter
 	if cfg.RouterModel.Model != "" && reg.Len() > 1 {
@@ -241,7 +253,7 @@ func runServer(cmd *cobra.Command, args []string) error {
 			}
 		}
 		if len(roles) > 0 {
-			routerProvider := providers.NewProvider("", "", cfg.RouterModel.Model, "")
+			routerProvider := providers.NewLLMProvider("", "", cfg.RouterModel.Model, "")
 			llmRouter = router.NewLLMRouter(roles, cfg.RouterModel.Model, routerProvider)
 			fmt.Printf("   ✅ LLM Router enabled (model=%s, %d roles)\n", cfg.RouterModel.Model, len(roles))
 		}
@@ -326,7 +338,7 @@ func runServer(cmd *cobra.Command, args []string) error {
 }
 
 // makeProviderFromLLMConfig creates a Provider from ConfigHub's LLMConfig.
-func makeProviderFromLLMConfig(cfg *confighub.LLMConfig) *providers.Provider {
+func makeProviderFromLLMConfig(cfg *confighub.LLMConfig) providers.LLMProvider {
 	apiKey := cfg.APIKey
 	if apiKey == "" {
 		// Fallback to env
@@ -343,7 +355,7 @@ func makeProviderFromLLMConfig(cfg *confighub.LLMConfig) *providers.Provider {
 			}
 		}
 	}
-	return providers.NewProvider(apiKey, cfg.APIBase, cfg.Model, cfg.Provider)
+	return providers.NewLLMProvider(apiKey, cfg.APIBase, cfg.Model, cfg.Provider)
 }
 
 // generateFingerprint creates a random 16-char hex string.
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dayuer/nanobot-go/internal/agent"
+	"github.com/dayuer/nanobot-go/internal/config"
+)
+
+var skillsCmd = &cobra.Command{
+	Use:   "skills",
+	Short: "Manage workspace skills",
+}
+
+var skillsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List installed skills and whether their requirements are met",
+	Args:  cobra.NoArgs,
+	RunE:  runSkillsList,
+}
+
+var skillsShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show a skill's metadata and instructions",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSkillsShow,
+}
+
+var skillsInstallCmd = &cobra.Command{
+	Use:   "install <path|git-url>",
+	Short: "Install a skill from a directory or a git repository",
+	Long: `Copy a skill into workspace/skills. The source is a skill directory
+(containing SKILL.md) or a git URL, including a path to a local bare
+mirror; repositories are shallow-cloned with git. The skill is validated
+before it is installed.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSkillsInstall,
+}
+
+var skillsValidateCmd = &cobra.Command{
+	Use:   "validate [name|path...]",
+	Short: "Check skills for problems (all installed skills by default)",
+	RunE:  runSkillsValidate,
+}
+
+var skillsRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a workspace skill",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSkillsRemove,
+}
+
+var skillsInstallForce bool
+
+func init() {
+	skillsInstallCmd.Flags().BoolVarP(&skillsInstallForce, "force", "f", false, "Replace an installed skill of the same name")
+	skillsCmd.AddCommand(skillsListCmd, skillsShowCmd, skillsInstallCmd, skillsValidateCmd, skillsRemoveCmd)
+	rootCmd.AddCommand(skillsCmd)
+}
+
+// skillsLoader returns the loader for the configured workspace.
+func skillsLoader() (*agent.SkillsLoader, error) {
+	cfg, err := config.Load("")
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+	return agent.NewSkillsLoader(cfg.Agent.Workspace, ""), nil
+}
+
+func runSkillsList(cmd *cobra.Command, args []string) error {
+	loader, err := skillsLoader()
+	if err != nil {
+		return err
+	}
+	skills := loader.ListSkills()
+	if len(skills) == 0 {
+		fmt.Printf("No skills in %s\n", loader.WorkspaceSkills)
+		return nil
+	}
+	for _, sk := range skills {
+		mark := "✓"
+		if !sk.Available {
+			mark = "✗"
+		}
+		line := fmt.Sprintf("%s %s (%s) — %s", mark, sk.Name, sk.Source, sk.Description)
+		if sk.Always {
+			line += " [always]"
+		}
+		if len(sk.Missing) > 0 {
+			line += "\n    missing: " + strings.Join(sk.Missing, ", ")
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+func runSkillsShow(cmd *cobra.Command, args []string) error {
+	loader, err := skillsLoader()
+	if err != nil {
+		return err
+	}
+	info, ok := loader.GetSkill(args[0])
+	if !ok {
+		return fmt.Errorf("skill not found: %s", args[0])
+	}
+
+	fmt.Printf("Name: %s\n", info.Name)
+	fmt.Printf("Path: %s (%s)\n", info.Path, info.Source)
+	meta := loader.GetSkillMetadata(info.Name)
+	keys := make([]string, 0, len(meta))
+	for k := range meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Printf("%s: %s\n", k, meta[k])
+	}
+	if info.Available {
+		fmt.Println("Available: yes")
+	} else {
+		fmt.Printf("Available: no (missing %s)\n", strings.Join(info.Missing, ", "))
+	}
+	if len(info.Scripts) > 0 {
+		fmt.Printf("Scripts: %s\n", strings.Join(info.Scripts, ", "))
+	}
+	fmt.Println()
+	fmt.Println(loader.LoadSkill(info.Name))
+	return nil
+}
+
+func runSkillsInstall(cmd *cobra.Command, args []string) error {
+	loader, err := skillsLoader()
+	if err != nil {
+		return err
+	}
+	info, err := loader.InstallSkill(args[0], skillsInstallForce)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✓ Installed %s → %s\n", info.Name, info.Path)
+	if !info.Available {
+		fmt.Printf("  ⚠️ Unavailable until installed: %s\n", strings.Join(info.Missing, ", "))
+	}
+	return nil
+}
+
+func runSkillsValidate(cmd *cobra.Command, args []string) error {
+	loader, err := skillsLoader()
+	if err != nil {
+		return err
+	}
+
+	// Names resolve to installed skills; anything else is a directory
+	dirs := map[string]string{}
+	var order []string
+	if len(args) == 0 {
+		for _, sk := range loader.ListSkills() {
+			dir, _ := loader.SkillDir(sk.Name)
+			dirs[sk.Name] = dir
+			order = append(order, sk.Name)
+		}
+	}
+	for _, arg := range args {
+		dir, ok := loader.SkillDir(arg)
+		if !ok {
+			if _, err := os.Stat(arg); err != nil {
+				return fmt.Errorf("skill not found: %s", arg)
+			}
+			dir = arg
+		}
+		dirs[arg] = dir
+		order = append(order, arg)
+	}
+
+	failed := 0
+	for _, name := range order {
+		problems := agent.ValidateSkill(dirs[name])
+		if len(problems) == 0 {
+			fmt.Printf("✓ %s\n", name)
+			continue
+		}
+		failed++
+		fmt.Printf("✗ %s\n", name)
+		for _, p := range problems {
+			fmt.Printf("    %s\n", p)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d skills have problems", failed, len(order))
+	}
+	return nil
+}
+
+func runSkillsRemove(cmd *cobra.Command, args []string) error {
+	loader, err := skillsLoader()
+	if err != nil {
+		return err
+	}
+	if err := loader.RemoveSkill(args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Removed %s\n", args[0])
+	return nil
+}
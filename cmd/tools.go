@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/dayuer/nanobot-go/internal/agent"
+	"github.com/dayuer/nanobot-go/internal/bus"
+	"github.com/dayuer/nanobot-go/internal/config"
+	"github.com/dayuer/nanobot-go/internal/contextguard"
+	"github.com/dayuer/nanobot-go/internal/cron"
+	"github.com/dayuer/nanobot-go/internal/rag"
+	"github.com/dayuer/nanobot-go/internal/survivaltools"
+	"github.com/dayuer/nanobot-go/internal/tools"
+)
+
+// defaultToolFactory registers a constructor for every tool nanobot ships.
+// Agents pick from it by name via AgentSpec.Tools. msgBus and cronSvc may
+// be nil, in which case the message and cron tools are unavailable.
+func defaultToolFactory(cfg config.Config, msgBus *bus.MessageBus, cronSvc *cron.Service) *tools.Factory {
+	f := tools.NewFactory()
+	workspace := cfg.Agent.Workspace
+	allowedDir := ""
+	if cfg.Tools.RestrictToWorkspace {
+		allowedDir = workspace
+	}
+
+	f.Register("exec", func() tools.Tool {
+		t := tools.NewExecTool()
+		t.WorkingDir = workspace
+		t.Skills = agent.NewSkillsLoader(workspace, "")
+		t.RestrictToWorkspace = cfg.Tools.RestrictToWorkspace
+		if len(cfg.Tools.Exec.DenyPatterns) > 0 {
+			t.DenyPatterns = cfg.Tools.Exec.DenyPatterns
+		}
+		t.AllowPatterns = cfg.Tools.Exec.AllowPatterns
+		if cfg.Tools.Exec.Timeout > 0 {
+			t.Timeout = time.Duration(cfg.Tools.Exec.Timeout) * time.Second
+		}
+		return t
+	})
+	f.Register("read_file", func() tools.Tool { return &tools.ReadFileTool{AllowedDir: allowedDir} })
+	f.Register("write_file", func() tools.Tool { return &tools.WriteFileTool{AllowedDir: allowedDir} })
+	f.Register("edit_file", func() tools.Tool { return &tools.EditFileTool{AllowedDir: allowedDir} })
+	f.Register("list_dir", func() tools.Tool { return &tools.ListDirTool{AllowedDir: allowedDir} })
+	f.Register("web_search", func() tools.Tool { return &tools.WebSearchTool{APIKey: cfg.WebSearch.APIKey} })
+	f.Register("web_fetch", func() tools.Tool { return &tools.WebFetchTool{} })
+	f.Register("message", func() tools.Tool {
+		if msgBus == nil {
+			return nil
+		}
+		return &tools.MessageTool{SendCallback: msgBus.PublishOutbound}
+	})
+	f.Register("cron", func() tools.Tool {
+		if cronSvc == nil {
+			return nil
+		}
+		return &tools.CronTool{Cron: cronSvc}
+	})
+
+	// RAG knowledge base (needs an embedding key)
+	store := knowledgeStore(cfg)
+	f.Register("knowledge_search", func() tools.Tool {
+		if store == nil {
+			return nil
+		}
+		return survivaltools.NewKnowledgeSearchTool(survivaltools.RAGStore{Store: store})
+	})
+
+	// Survival backend bridges (need the backend URL)
+	url, key := cfg.Survival.APIURL, cfg.Survival.APIKey
+	survival := func(build func(url, key string) tools.Tool) tools.Constructor {
+		return func() tools.Tool {
+			if url == "" {
+				return nil
+			}
+			return build(url, key)
+		}
+	}
+	f.Register("survival_data", survival(func(u, k string) tools.Tool { return survivaltools.NewDataTool(u, k) }))
+	f.Register("survival_stock", survival(func(u, k string) tools.Tool { return survivaltools.NewStockTool(u, k) }))
+	f.Register("user_memory", survival(func(u, k string) tools.Tool { return survivaltools.NewMemoryTool(u, k) }))
+	f.Register("survival_notify", survival(func(u, k string) tools.Tool { return survivaltools.NewNotifyTool(u, k) }))
+	f.Register("survival_tools", survival(func(u, k string) tools.Tool { return survivaltools.NewToolsBridge(u, k) }))
+	return f
+}
+
+// knowledgeStore returns the RAG store, or nil when no embedding key is configured.
+func knowledgeStore(cfg config.Config) *rag.Store {
+	if cfg.Embedding.APIKey == "" {
+		return nil
+	}
+	ragCfg := rag.DefaultConfig()
+	ragCfg.Workspace = cfg.Agent.Workspace
+	ragCfg.EmbeddingAPIKey = cfg.Embedding.APIKey
+	if cfg.Embedding.BaseURL != "" {
+		ragCfg.EmbeddingBaseURL = cfg.Embedding.BaseURL
+	}
+	return rag.NewStore(ragCfg)
+}
+
+// knowledgeSink is knowledgeStore as a contextguard.KnowledgeStore, keeping
+// the interface nil when there is no store.
+func knowledgeSink(cfg config.Config) contextguard.KnowledgeStore {
+	if store := knowledgeStore(cfg); store != nil {
+		return store
+	}
+	return nil
+}
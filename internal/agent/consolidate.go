@@ -0,0 +1,181 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/dayuer/nanobot-go/internal/providers"
+	"github.com/dayuer/nanobot-go/internal/session"
+)
+
+// consolidateTimeout bounds one background consolidation call.
+const consolidateTimeout = 2 * time.Minute
+
+const consolidateSystemPrompt = "You are a memory consolidation agent. Respond only with valid JSON."
+
+const consolidatePrompt = `You are a memory consolidation agent. Process this conversation and return a JSON object with exactly two keys:
+
+1. "history_entry": A paragraph (2-5 sentences) summarizing the key events/decisions/topics. Start with a timestamp like [YYYY-MM-DD HH:MM]. Include enough detail to be useful when found by grep search later.
+
+2. "memory_update": The updated long-term memory content. Add any new facts: user location, preferences, personal info, habits, project context, technical decisions, tools/services used. If nothing new, return the existing content unchanged.
+
+## Current Long-term Memory
+%s
+
+## Conversation to Process
+%s
+
+Respond with ONLY valid JSON, no markdown fences.`
+
+// consolidation is the model's answer to consolidatePrompt.
+type consolidation struct {
+	HistoryEntry string `json:"history_entry"`
+	MemoryUpdate string `json:"memory_update"`
+}
+
+// maybeConsolidate starts a background consolidation once a session holds
+// more than MemoryWindow unconsolidated messages. Everything but the newest
+// MemoryWindow/2 messages is folded into the history and long-term memory
+// of target, so messages are consolidated before GetHistory's window drops
+// them. The caller must hold a.sessMu.
+func (a *AgentLoop) maybeConsolidate(sess *session.Session, target MemoryScope) {
+	start := sess.LastConsolidated
+	end := len(sess.Messages) - a.MemoryWindow/2
+	if len(sess.Messages)-start <= a.MemoryWindow || end <= start || a.consolidating[sess.Key] {
+		return
+	}
+	if a.consolidating == nil {
+		a.consolidating = make(map[string]bool)
+	}
+	a.consolidating[sess.Key] = true
+	batch := append([]session.Message(nil), sess.Messages[start:end]...)
+
+	a.consolidations.Add(1)
+	go func() {
+		defer a.consolidations.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), consolidateTimeout)
+		defer cancel()
+		err := a.consolidate(ctx, target, batch)
+
+		a.sessMu.Lock()
+		defer a.sessMu.Unlock()
+		delete(a.consolidating, sess.Key)
+		if err != nil {
+			log.Printf("[Memory] ⚠️ Consolidation of %s failed: %v", sess.Key, err)
+			return
+		}
+		// A reset or compression may have rewritten the session meanwhile;
+		// only advance past messages that are still where we left them.
+		if sess.LastConsolidated == start && end <= len(sess.Messages) && sameMessage(sess.Messages[end-1], batch[len(batch)-1]) {
+			sess.LastConsolidated = end
+			a.Sessions.Save(sess)
+		}
+	}()
+}
+
+func sameMessage(a, b session.Message) bool {
+	return a.Role == b.Role && a.Content == b.Content && a.Timestamp == b.Timestamp
+}
+
+// WaitConsolidations blocks until background consolidations have finished.
+func (a *AgentLoop) WaitConsolidations() {
+	a.consolidations.Wait()
+}
+
+// consolidate asks the model for a history entry and an updated long-term
+// memory covering messages, then appends and rewrites target's memory.
+func (a *AgentLoop) consolidate(ctx context.Context, target MemoryScope, messages []session.Message) error {
+	// Memory is read, merged by the model and rewritten: one at a time.
+	a.memoryMu.Lock()
+	defer a.memoryMu.Unlock()
+
+	mem := a.Context.Memory.Backend
+	current, err := mem.ReadLongTerm(ctx, target)
+	if err != nil {
+		return fmt.Errorf("read long-term memory: %w", err)
+	}
+	shown := current
+	if strings.TrimSpace(shown) == "" {
+		shown = "(empty)"
+	}
+
+	resp, err := a.Provider.Chat(ctx, providers.ChatRequest{
+		Messages: []providers.Message{
+			{Role: "system", Content: consolidateSystemPrompt},
+			{Role: "user", Content: fmt.Sprintf(consolidatePrompt, shown, formatForConsolidation(messages))},
+		},
+		Model:       a.Model,
+		MaxTokens:   a.MaxTokens,
+		Temperature: 0.2,
+	})
+	if err != nil {
+		return err
+	}
+	if resp.FinishReason == "error" {
+		return fmt.Errorf("%s", strings.TrimSpace(derefString(resp.Content)))
+	}
+	result, err := parseConsolidation(derefString(resp.Content))
+	if err != nil {
+		return err
+	}
+
+	if entry := strings.TrimSpace(result.HistoryEntry); entry != "" {
+		if err := mem.AppendHistory(ctx, target, entry); err != nil {
+			return fmt.Errorf("append history: %w", err)
+		}
+	}
+	if update := strings.TrimSpace(result.MemoryUpdate); update != "" && update != strings.TrimSpace(current) {
+		if err := mem.WriteLongTerm(ctx, target, update+"\n"); err != nil {
+			return fmt.Errorf("write long-term memory: %w", err)
+		}
+	}
+	log.Printf("[Memory] 🧠 Consolidated %d messages", len(messages))
+	return nil
+}
+
+// formatForConsolidation renders messages as "[timestamp] ROLE: content".
+func formatForConsolidation(messages []session.Message) string {
+	var b strings.Builder
+	for _, m := range messages {
+		if strings.TrimSpace(m.Content) == "" {
+			continue
+		}
+		ts := m.Timestamp
+		if len(ts) > 16 {
+			ts = ts[:16]
+		}
+		if ts == "" {
+			ts = "?"
+		}
+		fmt.Fprintf(&b, "[%s] %s: %s\n", ts, strings.ToUpper(m.Role), m.Content)
+	}
+	return b.String()
+}
+
+// parseConsolidation decodes the model's JSON, tolerating markdown fences
+// and prose around the object.
+func parseConsolidation(text string) (consolidation, error) {
+	var result consolidation
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "```") {
+		if i := strings.Index(text, "\n"); i >= 0 {
+			text = text[i+1:]
+		}
+		text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+	}
+	if err := json.Unmarshal([]byte(text), &result); err == nil {
+		return result, nil
+	}
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end <= start {
+		return result, fmt.Errorf("no JSON object in consolidation response")
+	}
+	if err := json.Unmarshal([]byte(text[start:end+1]), &result); err != nil {
+		return result, fmt.Errorf("decode consolidation response: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,172 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dayuer/nanobot-go/internal/bus"
+	"github.com/dayuer/nanobot-go/internal/providers"
+)
+
+// memoryProvider answers consolidation requests with reply and every other
+// request with "ok", recording that request's system prompt.
+type memoryProvider struct {
+	mu      sync.Mutex
+	reply   string
+	prompts []string
+	systems []string
+}
+
+func (p *memoryProvider) Chat(_ context.Context, req providers.ChatRequest) (*providers.LLMResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if req.Messages[0].Content == consolidateSystemPrompt {
+		p.prompts = append(p.prompts, req.Messages[1].Content)
+		return &providers.LLMResponse{Content: strP(p.reply), FinishReason: "stop"}, nil
+	}
+	p.systems = append(p.systems, req.Messages[0].Content)
+	return &providers.LLMResponse{Content: strP("ok"), FinishReason: "stop"}, nil
+}
+
+func (p *memoryProvider) DefaultModel() string { return "mock-model" }
+
+func TestProcessDirect_ConsolidatesPastThreshold(t *testing.T) {
+	p := &memoryProvider{reply: "```json\n" + `{"history_entry": "[2026-01-01 10:00] User said they live in Beijing.", "memory_update": "User lives in Beijing."}` + "\n```"}
+	loop := NewAgentLoop(bus.NewMessageBus(), p, AgentConfig{Workspace: t.TempDir(), MemoryWindow: 4})
+	require.NoError(t, loop.Context.Memory.WriteLongTerm("User likes tea."))
+
+	// Two turns = 4 messages: at the threshold, nothing happens yet
+	for i := 0; i < 2; i++ {
+		_, err := loop.ProcessDirect(context.Background(), fmt.Sprintf("msg %d", i), "", "", "")
+		require.NoError(t, err)
+	}
+	loop.WaitConsolidations()
+	assert.Empty(t, p.prompts)
+
+	_, err := loop.ProcessDirect(context.Background(), "I live in Beijing", "", "", "")
+	require.NoError(t, err)
+	loop.WaitConsolidations()
+
+	require.Len(t, p.prompts, 1)
+	assert.Contains(t, p.prompts[0], "User likes tea.")
+	assert.Contains(t, p.prompts[0], "USER: msg 0")
+	assert.NotContains(t, p.prompts[0], "I live in Beijing", "the newest MemoryWindow/2 messages stay unconsolidated")
+
+	assert.Equal(t, "User lives in Beijing.\n", loop.Context.Memory.ReadLongTerm())
+	history, err := os.ReadFile(loop.Context.Memory.HistoryFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(history), "User said they live in Beijing.")
+
+	sess := loop.Sessions.GetOrCreate("cli:direct")
+	assert.Equal(t, 4, sess.LastConsolidated)
+
+	// Reloading from disk keeps the advanced marker
+	loop.Sessions.Invalidate("cli:direct")
+	assert.Equal(t, 4, loop.Sessions.GetOrCreate("cli:direct").LastConsolidated)
+}
+
+func TestProcessDirect_PersonScopedMemory(t *testing.T) {
+	p := &memoryProvider{reply: `{"history_entry": "[2026-01-01 10:00] Asked about a contract.", "memory_update": "Alice has a contract dispute."}`}
+	loop := NewAgentLoop(bus.NewMessageBus(), p, AgentConfig{AgentID: "legal", Workspace: t.TempDir(), MemoryWindow: 2})
+	mem := loop.Context.Memory
+	require.NoError(t, mem.WriteLongTerm("workspace-wide notes"))
+	require.NoError(t, mem.Backend.WriteLongTerm(context.Background(), MemoryScope{PersonID: "alice"}, "Alice lives in Beijing."))
+
+	ctx := WithMemoryScope(context.Background(), MemoryScope{PersonID: "alice"})
+	for i := 0; i < 2; i++ {
+		_, err := loop.ProcessDirect(ctx, "my contract", "api:alice", "api", "alice")
+		require.NoError(t, err)
+		loop.WaitConsolidations()
+	}
+
+	assert.Contains(t, p.systems[0], "Alice lives in Beijing.")
+	assert.NotContains(t, p.systems[0], "workspace-wide notes")
+
+	// Consolidation lands in Alice's memory for this agent, not the workspace
+	require.Len(t, p.prompts, 1)
+	got, err := mem.Backend.ReadLongTerm(context.Background(), MemoryScope{PersonID: "alice", AgentID: "legal"})
+	require.NoError(t, err)
+	assert.Equal(t, "Alice has a contract dispute.\n", got)
+	assert.Equal(t, "workspace-wide notes", mem.ReadLongTerm())
+}
+
+func TestProcessDirect_FailedConsolidationKeepsMarker(t *testing.T) {
+	p := &memoryProvider{reply: "I cannot do that"}
+	loop := NewAgentLoop(bus.NewMessageBus(), p, AgentConfig{Workspace: t.TempDir(), MemoryWindow: 2})
+
+	for i := 0; i < 2; i++ {
+		_, err := loop.ProcessDirect(context.Background(), "hello", "", "", "")
+		require.NoError(t, err)
+		loop.WaitConsolidations()
+	}
+	assert.NotEmpty(t, p.prompts)
+	assert.Equal(t, 0, loop.Sessions.GetOrCreate("cli:direct").LastConsolidated)
+	assert.Empty(t, loop.Context.Memory.ReadLongTerm())
+}
+
+func TestMaybeConsolidate_SkipsRewrittenSession(t *testing.T) {
+	block := make(chan struct{})
+	p := &blockingProvider{release: block, reply: `{"history_entry": "x", "memory_update": "y"}`}
+	loop := NewAgentLoop(bus.NewMessageBus(), p, AgentConfig{Workspace: t.TempDir(), MemoryWindow: 2})
+	sess := loop.Sessions.GetOrCreate("cli:direct")
+	for i := 0; i < 4; i++ {
+		sess.AddMessage("user", fmt.Sprintf("m%d", i))
+	}
+
+	loop.sessMu.Lock()
+	loop.maybeConsolidate(sess, MemoryScope{})
+	loop.maybeConsolidate(sess, MemoryScope{}) // already in flight
+	sess.Clear()
+	sess.AddMessage("user", "fresh start")
+	loop.sessMu.Unlock()
+
+	close(block)
+	loop.WaitConsolidations()
+	assert.Equal(t, 1, p.calls)
+	assert.Equal(t, 0, sess.LastConsolidated)
+}
+
+type blockingProvider struct {
+	release <-chan struct{}
+	reply   string
+	calls   int
+}
+
+func (p *blockingProvider) Chat(_ context.Context, _ providers.ChatRequest) (*providers.LLMResponse, error) {
+	<-p.release
+	p.calls++
+	return &providers.LLMResponse{Content: strP(p.reply), FinishReason: "stop"}, nil
+}
+
+func (p *blockingProvider) DefaultModel() string { return "mock-model" }
+
+func TestParseConsolidation(t *testing.T) {
+	for _, text := range []string{
+		`{"history_entry": "h", "memory_update": "m"}`,
+		"```json\n{\"history_entry\": \"h\", \"memory_update\": \"m\"}\n```",
+		"Here you go:\n{\"history_entry\": \"h\", \"memory_update\": \"m\"}\nDone.",
+	} {
+		got, err := parseConsolidation(text)
+		require.NoError(t, err, text)
+		assert.Equal(t, consolidation{HistoryEntry: "h", MemoryUpdate: "m"}, got)
+	}
+	_, err := parseConsolidation("no json here")
+	assert.Error(t, err)
+}
+
+func TestFormatForConsolidation(t *testing.T) {
+	loop := NewAgentLoop(bus.NewMessageBus(), &memoryProvider{}, AgentConfig{Workspace: t.TempDir()})
+	sess := loop.Sessions.GetOrCreate("k")
+	sess.AddMessage("user", "hi")
+	sess.AddMessage("assistant", "")
+	out := formatForConsolidation(sess.Messages)
+	assert.True(t, strings.HasSuffix(out, "] USER: hi\n"), out)
+	assert.Equal(t, 1, strings.Count(out, "\n"))
+}
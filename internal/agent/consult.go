@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Consultation is another agent's answer to a focused sub-question of the
+// current request.
+type Consultation struct {
+	AgentID  string `json:"agentId"`
+	Question string `json:"question"`
+	Answer   string `json:"answer,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type consultationsKey struct{}
+
+// WithConsultations returns a context whose turn folds the given expert
+// answers into the system prompt.
+func WithConsultations(ctx context.Context, cs []Consultation) context.Context {
+	return context.WithValue(ctx, consultationsKey{}, cs)
+}
+
+// consultationsFrom returns the consultations attached to ctx.
+func consultationsFrom(ctx context.Context) []Consultation {
+	cs, _ := ctx.Value(consultationsKey{}).([]Consultation)
+	return cs
+}
+
+// formatConsultations renders the answered consultations for the system
+// prompt; failed ones are left out.
+func formatConsultations(cs []Consultation) string {
+	var parts []string
+	for _, c := range cs {
+		if c.Error != "" || strings.TrimSpace(c.Answer) == "" {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("## %s\nQuestion: %s\n\n%s", c.AgentID, c.Question, strings.TrimSpace(c.Answer)))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// Consult answers a one-off question from another agent. Memory comes from
+// the scope in ctx, as for a normal turn, but no session is read or saved
+// and nothing is streamed to the caller's handler.
+func (a *AgentLoop) Consult(ctx context.Context, question string) (string, error) {
+	scope := memoryScopeFrom(ctx)
+	scope.AgentID = a.AgentID
+	ctx = WithMemoryScope(ctx, scope)
+	ctx = WithStreamHandler(ctx, nil)
+	ctx = WithConsultations(ctx, nil)
+
+	messages := a.Context.BuildMessages(ctx, nil, question, "", "")
+	content, _, err := a.runLoop(ctx, messages, &turnState{})
+	if err != nil {
+		return "", err
+	}
+	return content, nil
+}
@@ -1,12 +1,16 @@
 package agent
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"time"
+
+	"github.com/dayuer/nanobot-go/internal/providers"
 )
 
 // BootstrapFiles are loaded into the system prompt when present.
@@ -17,6 +21,11 @@ type ContextBuilder struct {
 	Workspace string
 	Memory    *MemoryStore
 	Skills    *SkillsLoader
+
+	// AgentPrompt is the agent's own instructions (AgentSpec.SystemPromptFile)
+	AgentPrompt string
+	// ActiveSkills are loaded in full into every system prompt (AgentSpec.Skills)
+	ActiveSkills []string
 }
 
 // NewContextBuilder creates a ContextBuilder for a workspace.
@@ -28,24 +37,48 @@ func NewContextBuilder(workspace string) *ContextBuilder {
 	}
 }
 
-// BuildSystemPrompt builds the full system prompt from identity, bootstrap, memory, and skills.
-func (c *ContextBuilder) BuildSystemPrompt(skillNames []string) string {
+// BuildSystemPrompt builds the full system prompt from identity, agent
+// instructions, bootstrap, memory, consultations and skills. Memory comes
+// from the scope attached to ctx (WithMemoryScope), i.e. the person the
+// request carries; consultations from WithConsultations.
+// skillNames are loaded in full; every other skill is only listed in the summary.
+func (c *ContextBuilder) BuildSystemPrompt(ctx context.Context, skillNames []string) string {
 	var parts []string
 
-	parts = append(parts, c.getIdentity())
+	scope := memoryScopeFrom(ctx)
+	parts = append(parts, c.getIdentity(scope))
+
+	if prompt := strings.TrimSpace(c.AgentPrompt); prompt != "" {
+		parts = append(parts, fmt.Sprintf("# Agent Instructions\n\n%s", prompt))
+	}
 
 	if bs := c.loadBootstrapFiles(); bs != "" {
 		parts = append(parts, bs)
 	}
 
-	if mem := c.Memory.GetMemoryContext(); mem != "" {
+	if mem := c.Memory.ContextFor(ctx, scope); mem != "" {
 		parts = append(parts, fmt.Sprintf("# Memory\n\n%s", mem))
 	}
 
+	if cs := formatConsultations(consultationsFrom(ctx)); cs != "" {
+		parts = append(parts, fmt.Sprintf(`# Expert Consultations
+
+Other agents were consulted on parts of this request. Use their answers in your reply.
+
+%s`, cs))
+	}
+
+	// Always-on skills come first, then the agent's own
+	skillNames = append(c.Skills.AlwaysSkills(), skillNames...)
+	if active := c.Skills.LoadSkillsForContext(skillNames); active != "" {
+		parts = append(parts, fmt.Sprintf("# Active Skills\n\n%s", active))
+	}
+
 	if summary := c.Skills.BuildSkillsSummary(); summary != "" {
 		parts = append(parts, fmt.Sprintf(`# Skills
 
 The following skills extend your capabilities. To use a skill, read its SKILL.md file using the read_file tool.
+Skills with available="false" need the host requirements listed in <requires> first. A skill's bundled <scripts> run via the exec tool with its skill argument set to the skill name, e.g. command "sh scripts/run.sh".
 
 %s`, summary))
 	}
@@ -53,7 +86,7 @@ The following skills extend your capabilities. To use a skill, read its SKILL.md
 	return strings.Join(parts, "\n\n---\n\n")
 }
 
-func (c *ContextBuilder) getIdentity() string {
+func (c *ContextBuilder) getIdentity(scope MemoryScope) string {
 	now := time.Now().Format("2006-01-02 15:04 (Monday)")
 	tz, _ := time.Now().Zone()
 	sys := runtime.GOOS
@@ -80,11 +113,21 @@ You are nanobot, a helpful AI assistant. You have access to tools that allow you
 
 ## Workspace
 Your workspace is at: %s
-- Long-term memory: %s/memory/MEMORY.md
-- History log: %s/memory/HISTORY.md (grep-searchable)
+%s
 - Custom skills: %s/skills/{skill-name}/SKILL.md
 
-Always be helpful, accurate, and concise.`, now, tz, rt, ws, ws, ws, ws)
+Always be helpful, accurate, and concise.`, now, tz, rt, ws, c.memoryLocation(scope, ws), ws)
+}
+
+// memoryLocation tells the model where scope's memory lives: the workspace
+// files for the workspace scope kept on disk, else the # Memory section,
+// which is kept per user and not in the workspace.
+func (c *ContextBuilder) memoryLocation(scope MemoryScope, ws string) string {
+	if _, onDisk := c.Memory.Backend.(*FileMemory); onDisk && scope == (MemoryScope{}) {
+		return fmt.Sprintf(`- Long-term memory: %s/memory/MEMORY.md
+- History log: %s/memory/HISTORY.md (grep-searchable)`, ws, ws)
+	}
+	return "- Long-term memory: kept per user and shown under # Memory; it is updated from your conversations automatically"
 }
 
 func (c *ContextBuilder) loadBootstrapFiles() string {
@@ -103,8 +146,8 @@ func (c *ContextBuilder) loadBootstrapFiles() string {
 }
 
 // BuildMessages constructs the full message list for an LLM call.
-func (c *ContextBuilder) BuildMessages(history []map[string]any, userMsg string, channel, chatID string) []map[string]any {
-	systemPrompt := c.BuildSystemPrompt(nil)
+func (c *ContextBuilder) BuildMessages(ctx context.Context, history []map[string]any, userMsg string, channel, chatID string) []map[string]any {
+	systemPrompt := c.BuildSystemPrompt(ctx, c.ActiveSkills)
 	if channel != "" && chatID != "" {
 		systemPrompt += fmt.Sprintf("\n\n## Current Session\nChannel: %s\nChat ID: %s", channel, chatID)
 	}
@@ -138,3 +181,92 @@ func (c *ContextBuilder) AddAssistantMessage(messages []map[string]any, content
 	}
 	return append(messages, msg)
 }
+
+// appendAssistantResponse appends resp as an assistant message, keeping its
+// tool calls and reasoning (content and signed blocks) for the next call.
+func appendAssistantResponse(messages []map[string]any, resp *providers.LLMResponse) []map[string]any {
+	msg := map[string]any{"role": "assistant", "content": derefString(resp.Content)}
+	if len(resp.ToolCalls) > 0 {
+		msg["tool_calls"] = ToolCallDicts(resp.ToolCalls)
+	}
+	if rc := derefString(resp.ReasoningContent); rc != "" {
+		msg["reasoning_content"] = rc
+		if len(resp.ReasoningBlocks) > 0 {
+			msg["reasoning_blocks"] = resp.ReasoningBlocks
+		}
+	}
+	return append(messages, msg)
+}
+
+// ToolCallDicts converts LLM tool call requests into the OpenAI tool_calls
+// format stored on assistant messages.
+func ToolCallDicts(calls []providers.ToolCallRequest) []map[string]any {
+	var dicts []map[string]any
+	for _, tc := range calls {
+		args := tc.Arguments
+		if args == nil {
+			args = map[string]any{}
+		}
+		argsJSON, _ := json.Marshal(args)
+		dicts = append(dicts, map[string]any{
+			"id":   tc.ID,
+			"type": "function",
+			"function": map[string]any{
+				"name":      tc.Name,
+				"arguments": string(argsJSON),
+			},
+		})
+	}
+	return dicts
+}
+
+// ToProviderMessages converts context messages into provider messages,
+// preserving tool_calls, tool_call_id, name and reasoning (content and
+// signed blocks); providers decide whether the model gets reasoning back.
+func ToProviderMessages(messages []map[string]any) []providers.Message {
+	result := make([]providers.Message, 0, len(messages))
+	for _, m := range messages {
+		msg := providers.Message{}
+		msg.Role, _ = m["role"].(string)
+		msg.Content, _ = m["content"].(string)
+		msg.ToolCallID, _ = m["tool_call_id"].(string)
+		msg.Name, _ = m["name"].(string)
+		msg.ReasoningContent, _ = m["reasoning_content"].(string)
+		msg.ReasoningBlocks, _ = m["reasoning_blocks"].([]providers.ReasoningBlock)
+
+		switch calls := m["tool_calls"].(type) {
+		case []map[string]any:
+			for _, c := range calls {
+				msg.ToolCalls = append(msg.ToolCalls, toProviderToolCall(c))
+			}
+		case []any:
+			for _, c := range calls {
+				if cm, ok := c.(map[string]any); ok {
+					msg.ToolCalls = append(msg.ToolCalls, toProviderToolCall(cm))
+				}
+			}
+		}
+		result = append(result, msg)
+	}
+	return result
+}
+
+func toProviderToolCall(m map[string]any) providers.ToolCall {
+	tc := providers.ToolCall{Type: "function"}
+	tc.ID, _ = m["id"].(string)
+	if t, ok := m["type"].(string); ok && t != "" {
+		tc.Type = t
+	}
+	if fn, ok := m["function"].(map[string]any); ok {
+		tc.Function.Name, _ = fn["name"].(string)
+		switch args := fn["arguments"].(type) {
+		case string:
+			tc.Function.Arguments = args
+		case nil:
+		default:
+			b, _ := json.Marshal(args)
+			tc.Function.Arguments = string(b)
+		}
+	}
+	return tc
+}
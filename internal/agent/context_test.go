@@ -1,8 +1,10 @@
 package agent
 
 import (
+	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -12,7 +14,7 @@ import (
 func TestContextBuilder_BuildSystemPrompt_Basic(t *testing.T) {
 	ws := t.TempDir()
 	cb := NewContextBuilder(ws)
-	prompt := cb.BuildSystemPrompt(nil)
+	prompt := cb.BuildSystemPrompt(context.Background(), nil)
 	assert.Contains(t, prompt, "# nanobot")
 	assert.Contains(t, prompt, "workspace")
 }
@@ -21,7 +23,7 @@ func TestContextBuilder_BuildSystemPrompt_WithMemory(t *testing.T) {
 	ws := t.TempDir()
 	cb := NewContextBuilder(ws)
 	cb.Memory.WriteLongTerm("User is a Go developer")
-	prompt := cb.BuildSystemPrompt(nil)
+	prompt := cb.BuildSystemPrompt(context.Background(), nil)
 	assert.Contains(t, prompt, "# Memory")
 	assert.Contains(t, prompt, "User is a Go developer")
 }
@@ -30,7 +32,7 @@ func TestContextBuilder_BuildSystemPrompt_WithBootstrap(t *testing.T) {
 	ws := t.TempDir()
 	os.WriteFile(filepath.Join(ws, "AGENTS.md"), []byte("I am an agent"), 0o644)
 	cb := NewContextBuilder(ws)
-	prompt := cb.BuildSystemPrompt(nil)
+	prompt := cb.BuildSystemPrompt(context.Background(), nil)
 	assert.Contains(t, prompt, "## AGENTS.md")
 	assert.Contains(t, prompt, "I am an agent")
 }
@@ -42,7 +44,7 @@ func TestContextBuilder_BuildMessages(t *testing.T) {
 		{"role": "user", "content": "Hello"},
 		{"role": "assistant", "content": "Hi there!"},
 	}
-	msgs := cb.BuildMessages(history, "What's 2+2?", "telegram", "123")
+	msgs := cb.BuildMessages(context.Background(), history, "What's 2+2?", "telegram", "123")
 
 	require.Len(t, msgs, 4) // system + 2 history + user
 	assert.Equal(t, "system", msgs[0]["role"])
@@ -55,7 +57,7 @@ func TestContextBuilder_BuildMessages(t *testing.T) {
 func TestContextBuilder_BuildMessages_NoChannel(t *testing.T) {
 	ws := t.TempDir()
 	cb := NewContextBuilder(ws)
-	msgs := cb.BuildMessages(nil, "Hi", "", "")
+	msgs := cb.BuildMessages(context.Background(), nil, "Hi", "", "")
 	require.Len(t, msgs, 2) // system + user
 	assert.NotContains(t, msgs[0]["content"], "Channel:")
 }
@@ -89,3 +91,57 @@ func TestContextBuilder_AddAssistantMessage_NoToolCalls(t *testing.T) {
 	_, hasRC := msgs[0]["reasoning_content"]
 	assert.False(t, hasRC) // no reasoning_content key when empty
 }
+
+func TestContextBuilder_BuildSystemPrompt_AgentPromptAndSkills(t *testing.T) {
+	ws := t.TempDir()
+	os.MkdirAll(filepath.Join(ws, "skills", "triage"), 0o755)
+	os.WriteFile(filepath.Join(ws, "skills", "triage", "SKILL.md"),
+		[]byte("---\ndescription: triage\n---\nAsk for the error message first."), 0o644)
+
+	cb := NewContextBuilder(ws)
+	cb.AgentPrompt = "You are the support agent."
+	cb.ActiveSkills = []string{"triage"}
+
+	prompt := cb.BuildMessages(context.Background(), nil, "hi", "", "")[0]["content"].(string)
+	assert.Contains(t, prompt, "# Agent Instructions\n\nYou are the support agent.")
+	assert.Contains(t, prompt, "### Skill: triage")
+	assert.Contains(t, prompt, "Ask for the error message first.")
+	assert.NotContains(t, prompt, "description: triage", "frontmatter is stripped")
+
+	// without ActiveSkills the skill is only summarized
+	cb.ActiveSkills = nil
+	assert.NotContains(t, cb.BuildSystemPrompt(context.Background(), nil), "Ask for the error message first.")
+}
+
+func TestContextBuilder_BuildSystemPrompt_AlwaysSkills(t *testing.T) {
+	ws := t.TempDir()
+	os.MkdirAll(filepath.Join(ws, "skills", "style"), 0o755)
+	os.WriteFile(filepath.Join(ws, "skills", "style", "SKILL.md"),
+		[]byte("---\ndescription: style\nalways: true\n---\nAnswer in short sentences."), 0o644)
+	os.MkdirAll(filepath.Join(ws, "skills", "triage"), 0o755)
+	os.WriteFile(filepath.Join(ws, "skills", "triage", "SKILL.md"),
+		[]byte("---\ndescription: triage\n---\nAsk for the error message first."), 0o644)
+
+	cb := NewContextBuilder(ws)
+	prompt := cb.BuildSystemPrompt(context.Background(), nil)
+	assert.Contains(t, prompt, "Answer in short sentences.")
+	assert.NotContains(t, prompt, "Ask for the error message first.")
+
+	cb.Skills.Always = []string{"triage"}
+	prompt = cb.BuildSystemPrompt(context.Background(), []string{"style"})
+	assert.Contains(t, prompt, "Ask for the error message first.")
+	assert.Equal(t, 1, strings.Count(prompt, "### Skill: style"), "loaded once")
+}
+
+func TestContextBuilder_BuildSystemPrompt_Consultations(t *testing.T) {
+	cb := NewContextBuilder(t.TempDir())
+	ctx := WithConsultations(context.Background(), []Consultation{
+		{AgentID: "legal", Question: "Who pays?", Answer: "The other driver."},
+		{AgentID: "mechanic", Question: "Cost?", Error: "timeout"},
+	})
+	prompt := cb.BuildSystemPrompt(ctx, nil)
+	assert.Contains(t, prompt, "# Expert Consultations")
+	assert.Contains(t, prompt, "## legal\nQuestion: Who pays?\n\nThe other driver.")
+	assert.NotContains(t, prompt, "mechanic", "failed consultations are left out")
+	assert.NotContains(t, cb.BuildSystemPrompt(context.Background(), nil), "# Expert Consultations")
+}
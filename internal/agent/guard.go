@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/dayuer/nanobot-go/internal/contextguard"
+	"github.com/dayuer/nanobot-go/internal/providers"
+	"github.com/dayuer/nanobot-go/internal/session"
+)
+
+// turnState carries per-turn state the context guard needs: the session the
+// turn belongs to (nil for bare RunAgentLoop calls) and any notice for the user.
+type turnState struct {
+	sess   *session.Session
+	notice string
+}
+
+// guardContext runs the context guard before a provider call. Near the limit
+// older turns are summarized by the model; at the critical ratio the session
+// is flushed to the knowledge base and cleared. Tool schemas count against the
+// window alongside the messages. It returns the messages to send.
+func (a *AgentLoop) guardContext(ctx context.Context, messages []map[string]any, toolSchemas []map[string]any, turn *turnState) []map[string]any {
+	if a.Guard == nil {
+		return messages
+	}
+	model := a.Model
+	if model == "" {
+		model = a.Provider.DefaultModel()
+	}
+	result := a.Guard.CheckRequest(providers.ChatRequest{
+		Messages: ToProviderMessages(messages),
+		Tools:    toolSchemas,
+		Model:    model,
+	})
+	switch result.Action {
+	case contextguard.ActionCompressed:
+		return a.compressContext(ctx, messages, turn)
+	case contextguard.ActionReset:
+		return a.resetContext(ctx, messages, turn, result)
+	default:
+		return messages
+	}
+}
+
+func (a *AgentLoop) compressContext(ctx context.Context, messages []map[string]any, turn *turnState) []map[string]any {
+	c := &contextguard.Compressor{Provider: a.Provider, Model: a.Model}
+	compressed, summary, replaced, err := c.Compress(ctx, messages)
+	if err != nil {
+		log.Printf("[ContextGuard] ⚠️ Compression failed, sending full context: %v", err)
+		return messages
+	}
+	if replaced == 0 {
+		return messages
+	}
+	log.Printf("[ContextGuard] 🗜️ Compressed %d messages into a summary", replaced)
+
+	// Persist the summary in place of the history it covers so the next turn
+	// starts from the compressed form. The summary call above ran unlocked;
+	// the lock only covers the rewrite.
+	if sess := turn.sess; sess != nil {
+		a.sessMu.Lock()
+		if replaced <= len(sess.Messages) {
+			keep := len(sess.Messages) - replaced
+			sess.Messages = append(sess.Messages[:keep:keep], session.Message{
+				Role:      "system",
+				Content:   contextguard.SummaryMessage(summary)["content"].(string),
+				Timestamp: time.Now().Format(time.RFC3339),
+			})
+			if sess.LastConsolidated > len(sess.Messages) {
+				sess.LastConsolidated = len(sess.Messages)
+			}
+			a.Sessions.Save(sess)
+		}
+		a.sessMu.Unlock()
+	}
+	return compressed
+}
+
+func (a *AgentLoop) resetContext(ctx context.Context, messages []map[string]any, turn *turnState, result contextguard.PreCheckResult) []map[string]any {
+	start, end := contextguard.OlderTurns(messages)
+
+	// Flush everything the session holds, not just the window in context.
+	// The flush embeds and uploads the transcript, so it runs on a snapshot
+	// without holding sessMu, which every session shares.
+	older := messages[start:end]
+	source := "conversation"
+	if sess := turn.sess; sess != nil {
+		a.sessMu.Lock()
+		older = make([]map[string]any, len(sess.Messages))
+		for i, m := range sess.Messages {
+			older[i] = map[string]any{"role": m.Role, "content": m.Content}
+		}
+		a.sessMu.Unlock()
+		source = "session:" + sess.Key
+	}
+
+	if a.Knowledge != nil {
+		if err := contextguard.Flush(ctx, a.Knowledge, source, older); err != nil {
+			log.Printf("[ContextGuard] ⚠️ %v", err)
+		} else {
+			result.MemoryFlushed = true
+		}
+	}
+	if sess := turn.sess; sess != nil {
+		a.sessMu.Lock()
+		sess.Clear()
+		a.Sessions.Save(sess)
+		a.sessMu.Unlock()
+	}
+	turn.notice = result.NotificationMessage()
+
+	out := make([]map[string]any, 0, len(messages)-(end-start))
+	out = append(out, messages[:start]...)
+	return append(out, messages[end:]...)
+}
@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dayuer/nanobot-go/internal/bus"
+	"github.com/dayuer/nanobot-go/internal/contextguard"
+	"github.com/dayuer/nanobot-go/internal/providers"
+)
+
+// guardProvider answers summary requests with a fixed summary and records
+// every other request.
+type guardProvider struct {
+	mu        sync.Mutex
+	summaries int
+	chats     []providers.ChatRequest
+}
+
+func (p *guardProvider) Chat(_ context.Context, req providers.ChatRequest) (*providers.LLMResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(req.Messages) > 0 && strings.Contains(req.Messages[0].Content, "compress conversation history") {
+		p.summaries++
+		return &providers.LLMResponse{Content: strP("Ada is the user."), FinishReason: "stop"}, nil
+	}
+	p.chats = append(p.chats, req)
+	return &providers.LLMResponse{Content: strP("Your name is Ada."), FinishReason: "stop"}, nil
+}
+
+func (p *guardProvider) DefaultModel() string { return "mock-model" }
+
+type recordingStore struct {
+	texts   []string
+	sources []string
+}
+
+func (s *recordingStore) IngestText(_ context.Context, text, source string) (int, error) {
+	s.texts = append(s.texts, text)
+	s.sources = append(s.sources, source)
+	return 1, nil
+}
+
+func newGuardedLoop(t *testing.T, p providers.LLMProvider, cfg contextguard.Config, store contextguard.KnowledgeStore) *AgentLoop {
+	loop := NewAgentLoop(bus.NewMessageBus(), p, AgentConfig{
+		Workspace:    t.TempDir(),
+		ContextGuard: &cfg,
+		Knowledge:    store,
+	})
+	sess := loop.Sessions.GetOrCreate("cli:direct")
+	sess.AddMessage("user", "my name is Ada")
+	sess.AddMessage("assistant", "nice to meet you, Ada")
+	return loop
+}
+
+func TestAgentLoop_ContextGuard_Compresses(t *testing.T) {
+	p := &guardProvider{}
+	// any non-trivial context triggers compression, never reset
+	loop := newGuardedLoop(t, p, contextguard.Config{WarnRatio: 0, CompressRatio: 1e-6, CriticalRatio: 100}, nil)
+
+	resp, err := loop.ProcessDirect(context.Background(), "what's my name?", "cli:direct", "cli", "direct")
+	require.NoError(t, err)
+	assert.Equal(t, "Your name is Ada.", resp)
+	assert.Equal(t, 1, p.summaries)
+
+	require.Len(t, p.chats, 1)
+	msgs := p.chats[0].Messages
+	require.Len(t, msgs, 3, "system, summary, current user message")
+	assert.Equal(t, contextguard.SummaryPrefix+"\nAda is the user.", msgs[1].Content)
+	assert.Equal(t, "what's my name?", msgs[2].Content)
+
+	// the session keeps the summary instead of the raw turns
+	sess := loop.Sessions.GetOrCreate("cli:direct")
+	require.Len(t, sess.Messages, 3)
+	assert.Equal(t, "system", sess.Messages[0].Role)
+	assert.Equal(t, "what's my name?", sess.Messages[1].Content)
+}
+
+func TestAgentLoop_ContextGuard_ResetFlushesSession(t *testing.T) {
+	p := &guardProvider{}
+	store := &recordingStore{}
+	loop := newGuardedLoop(t, p, contextguard.Config{WarnRatio: 0, CompressRatio: 1e-6, CriticalRatio: 1e-6}, store)
+
+	resp, err := loop.ProcessDirect(context.Background(), "what's my name?", "cli:direct", "cli", "direct")
+	require.NoError(t, err)
+	assert.Contains(t, resp, "知识库", "user is told the memory was saved")
+	assert.True(t, strings.HasSuffix(resp, "Your name is Ada."))
+	assert.Zero(t, p.summaries)
+
+	require.Len(t, store.texts, 1)
+	assert.Equal(t, "session:cli:direct", store.sources[0])
+	assert.Contains(t, store.texts[0], "user: my name is Ada")
+
+	require.Len(t, p.chats, 1)
+	assert.Len(t, p.chats[0].Messages, 2, "older turns are dropped")
+
+	sess := loop.Sessions.GetOrCreate("cli:direct")
+	require.Len(t, sess.Messages, 2, "session restarts with the current turn")
+	assert.Equal(t, "what's my name?", sess.Messages[0].Content)
+}
+
+func TestAgentLoop_ContextGuard_ResetWithoutStore(t *testing.T) {
+	loop := newGuardedLoop(t, &guardProvider{}, contextguard.Config{CriticalRatio: 1e-6}, nil)
+
+	resp, err := loop.ProcessDirect(context.Background(), "hello", "cli:direct", "cli", "direct")
+	require.NoError(t, err)
+	assert.Contains(t, resp, "重置")
+	assert.NotContains(t, resp, "知识库", "no store, no promise")
+}
+
+// lockProbeStore records whether the loop's session lock was free while
+// the flush ran.
+type lockProbeStore struct {
+	loop     *AgentLoop
+	unlocked bool
+}
+
+func (s *lockProbeStore) IngestText(context.Context, string, string) (int, error) {
+	if s.loop.sessMu.TryLock() {
+		s.unlocked = true
+		s.loop.sessMu.Unlock()
+	}
+	return 1, nil
+}
+
+func TestAgentLoop_ContextGuard_ResetFlushesUnlocked(t *testing.T) {
+	store := &lockProbeStore{}
+	loop := newGuardedLoop(t, &guardProvider{}, contextguard.Config{CriticalRatio: 1e-6}, store)
+	store.loop = loop
+
+	_, err := loop.ProcessDirect(context.Background(), "hello", "cli:direct", "cli", "direct")
+	require.NoError(t, err)
+	assert.True(t, store.unlocked, "other sessions are not held up by a flush")
+}
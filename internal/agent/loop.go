@@ -2,12 +2,14 @@ package agent
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/dayuer/nanobot-go/internal/bus"
+	"github.com/dayuer/nanobot-go/internal/contextguard"
 	"github.com/dayuer/nanobot-go/internal/providers"
 	"github.com/dayuer/nanobot-go/internal/session"
 	"github.com/dayuer/nanobot-go/internal/tools"
@@ -18,16 +20,37 @@ import (
 type AgentLoop struct {
 	Bus           *bus.MessageBus
 	Provider      providers.LLMProvider
+	AgentID       string // registry ID; scopes per-person memory to this agent
 	Workspace     string
 	Model         string
 	MaxIterations int
 	Temperature   float64
 	MaxTokens     int
 	MemoryWindow  int
+	// MaxParallelTools bounds concurrent tool calls per turn (1 = sequential)
+	MaxParallelTools int
+	// ReasoningEffort and ReasoningBudget ask reasoning models to think
+	// (providers.ChatRequest); zero values use the model default
+	ReasoningEffort string
+	ReasoningBudget int
 
 	Context  *ContextBuilder
 	Sessions *session.Manager
 	Tools    *tools.Registry
+	Executor *tools.Executor // runs Tools with deadlines, panic recovery and output limits
+	// ToolWhitelist is AgentConfig.ToolWhitelist; tools added later (MCP)
+	// are admitted by it too
+	ToolWhitelist []string
+
+	Subagents *SubagentManager // background tasks started by the spawn tool
+
+	Guard     *contextguard.Guard         // nil disables the context pre-check
+	Knowledge contextguard.KnowledgeStore // receives the session on context reset (optional)
+
+	sessMu         sync.Mutex      // guards session mutation and consolidating
+	consolidating  map[string]bool // session keys with a consolidation in flight
+	consolidations sync.WaitGroup
+	memoryMu       sync.Mutex // serializes MEMORY.md rewrites
 
 	running bool
 	mu      sync.Mutex
@@ -35,6 +58,7 @@ type AgentLoop struct {
 
 // AgentConfig holds configuration for creating an AgentLoop.
 type AgentConfig struct {
+	AgentID       string
 	Workspace     string
 	Model         string
 	MaxIterations int
@@ -42,6 +66,36 @@ type AgentConfig struct {
 	MaxTokens     int
 	MemoryWindow  int
 	BraveAPIKey   string
+	// Reasoning effort (low/medium/high) or thinking-token budget for reasoning models
+	ReasoningEffort string
+	ReasoningBudget int
+	// MaxParallelTools bounds concurrent tool calls per turn; 0 = DefaultMaxParallelTools
+	MaxParallelTools int
+	// Tool runtime limits; zero values use the tools.Executor defaults
+	ToolTimeout   time.Duration
+	ToolTimeouts  map[string]time.Duration
+	MaxToolOutput int
+	// ToolFactory builds the agent's tools, limited to ToolWhitelist
+	// (tools.AllTools = all, empty = none)
+	ToolFactory   *tools.Factory
+	ToolWhitelist []string
+	// BoundTools are caller-built tools (e.g. ask_agent), added when whitelisted
+	BoundTools []tools.Tool
+	// SystemPrompt and Skills are agent-specific additions to the system prompt
+	SystemPrompt string
+	Skills       []string
+	// AlwaysSkills are inlined into every prompt, like skills marked always
+	AlwaysSkills []string
+	// ContextGuard thresholds; nil = contextguard.DefaultConfig()
+	ContextGuard *contextguard.Config
+	// Knowledge receives flushed sessions on context reset (e.g. *rag.Store)
+	Knowledge contextguard.KnowledgeStore
+	// MemoryBackend stores scoped long-term memory; nil = files under Workspace/memory
+	MemoryBackend MemoryBackend
+	// SubagentProfiles are the kinds of subagent spawn may start
+	SubagentProfiles []SubagentProfile
+	// MaxSubagents caps running subagents; 0 = DefaultMaxSubagents
+	MaxSubagents int
 }
 
 // NewAgentLoop creates and configures an agent loop.
@@ -62,89 +116,117 @@ func NewAgentLoop(msgBus *bus.MessageBus, provider providers.LLMProvider, cfg Ag
 	if memWin == 0 {
 		memWin = 50
 	}
+	maxParallel := cfg.MaxParallelTools
+	if maxParallel == 0 {
+		maxParallel = DefaultMaxParallelTools
+	}
 
 	loop := &AgentLoop{
-		Bus:           msgBus,
-		Provider:      provider,
-		Workspace:     cfg.Workspace,
-		Model:         model,
-		MaxIterations: maxIter,
-		Temperature:   cfg.Temperature,
-		MaxTokens:     maxTokens,
-		MemoryWindow:  memWin,
-		Context:       NewContextBuilder(cfg.Workspace),
-		Sessions:      session.NewManager(cfg.Workspace),
-		Tools:         tools.NewRegistry(),
+		Bus:              msgBus,
+		Provider:         provider,
+		AgentID:          cfg.AgentID,
+		Workspace:        cfg.Workspace,
+		Model:            model,
+		MaxIterations:    maxIter,
+		Temperature:      cfg.Temperature,
+		MaxTokens:        maxTokens,
+		MemoryWindow:     memWin,
+		MaxParallelTools: maxParallel,
+		ReasoningEffort:  cfg.ReasoningEffort,
+		ReasoningBudget:  cfg.ReasoningBudget,
+		Context:          NewContextBuilder(cfg.Workspace),
+		Sessions:         session.NewManager(cfg.Workspace),
+		Tools:            tools.NewRegistry(),
+	}
+	guardCfg := contextguard.DefaultConfig()
+	if cfg.ContextGuard != nil {
+		guardCfg = *cfg.ContextGuard
 	}
+	loop.Guard = contextguard.NewGuard(guardCfg)
+	loop.Knowledge = cfg.Knowledge
+	if cfg.MemoryBackend != nil {
+		loop.Context.Memory.Backend = cfg.MemoryBackend
+	}
+	loop.Context.AgentPrompt = cfg.SystemPrompt
+	loop.Context.ActiveSkills = cfg.Skills
+	loop.Context.Skills.Always = cfg.AlwaysSkills
+	loop.Subagents = NewSubagentManager(provider, cfg.Workspace, msgBus, model)
+	loop.Subagents.MaxTokens = maxTokens
+	loop.Subagents.ReasoningEffort = cfg.ReasoningEffort
+	loop.Subagents.ReasoningBudget = cfg.ReasoningBudget
+	loop.Subagents.ToolTimeout = cfg.ToolTimeout
+	loop.Subagents.MaxToolOutput = cfg.MaxToolOutput
+	loop.Subagents.ToolFactory = cfg.ToolFactory
+	loop.Subagents.MaxConcurrent = cfg.MaxSubagents
+	if len(cfg.SubagentProfiles) > 0 {
+		loop.Subagents.Profiles = make(map[string]SubagentProfile, len(cfg.SubagentProfiles))
+		for _, p := range cfg.SubagentProfiles {
+			loop.Subagents.Profiles[p.Name] = p
+		}
+	}
+	loop.ToolWhitelist = cfg.ToolWhitelist
+	if cfg.ToolFactory != nil {
+		loop.registerLoopTools(cfg.ToolWhitelist, cfg.BoundTools)
+		if missing := cfg.ToolFactory.BuildInto(loop.Tools, cfg.ToolWhitelist); len(missing) > 0 {
+			var unavailable []string
+			for _, name := range missing {
+				if loop.Tools.Get(name) == nil {
+					unavailable = append(unavailable, name)
+				}
+			}
+			if len(unavailable) > 0 {
+				log.Printf("[Agent] ⚠️ Unavailable tools skipped: %v", unavailable)
+			}
+		}
+	}
+	loop.Executor = newToolExecutor(loop.Tools, cfg)
 	return loop
 }
 
 // RunAgentLoop executes the tool-calling loop until no more tool calls or max iterations.
 func (a *AgentLoop) RunAgentLoop(ctx context.Context, messages []map[string]any) (string, []string, error) {
+	return a.runLoop(ctx, messages, &turnState{})
+}
+
+func (a *AgentLoop) runLoop(ctx context.Context, messages []map[string]any, turn *turnState) (string, []string, error) {
 	iteration := 0
 	var toolsUsed []string
+	emit := streamHandlerFrom(ctx)
 
 	for iteration < a.MaxIterations {
 		iteration++
-
-		// Convert messages to providers.Message
-		providerMsgs := make([]providers.Message, 0, len(messages))
-		for _, m := range messages {
-			role, _ := m["role"].(string)
-			content, _ := m["content"].(string)
-			providerMsgs = append(providerMsgs, providers.Message{Role: role, Content: content})
+		if emit != nil {
+			emit(StreamEvent{Type: EventThinking, Iteration: iteration})
 		}
 
-		resp, err := a.Provider.Chat(ctx, providers.ChatRequest{
-			Messages:    providerMsgs,
-			Tools:       a.Tools.Schemas(),
+		toolSchemas := a.Tools.Schemas()
+		messages = a.guardContext(ctx, messages, toolSchemas, turn)
+		resp, err := chatWithEvents(ctx, a.Provider, providers.ChatRequest{
+			Messages:    ToProviderMessages(messages),
+			Tools:       toolSchemas,
 			Model:       a.Model,
 			MaxTokens:   a.MaxTokens,
 			Temperature: a.Temperature,
-		})
+
+			ReasoningEffort: a.ReasoningEffort,
+			ReasoningBudget: a.ReasoningBudget,
+		}, iteration, emit)
 		if err != nil {
 			return "", toolsUsed, fmt.Errorf("LLM chat: %w", err)
 		}
+		if resp.FinishReason == "error" {
+			// Providers outside this package may still report failures in-band
+			return "", toolsUsed, fmt.Errorf("LLM chat: %s", strings.TrimSpace(derefString(resp.Content)))
+		}
 
 		if resp.HasToolCalls() {
-			// Build tool_calls for assistant message
-			var toolCallDicts []map[string]any
-			for _, tc := range resp.ToolCalls {
-				argsJSON, _ := json.Marshal(tc.Arguments)
-				toolCallDicts = append(toolCallDicts, map[string]any{
-					"id":   tc.ID,
-					"type": "function",
-					"function": map[string]any{
-						"name":      tc.Name,
-						"arguments": string(argsJSON),
-					},
-				})
-			}
-
-			contentStr := ""
-			if resp.Content != nil {
-				contentStr = *resp.Content
-			}
-			rcStr := ""
-			if resp.ReasoningContent != nil {
-				rcStr = *resp.ReasoningContent
-			}
-			messages = a.Context.AddAssistantMessage(messages, contentStr, toolCallDicts, rcStr)
+			messages = appendAssistantResponse(messages, resp)
 
-			// Execute tools
-			for _, tc := range resp.ToolCalls {
+			// Execute tools; results come back in call order
+			results := a.executeToolCalls(ctx, resp.ToolCalls, iteration, emit)
+			for i, tc := range resp.ToolCalls {
 				toolsUsed = append(toolsUsed, tc.Name)
-				tool := a.Tools.Get(tc.Name)
-				var result string
-				if tool != nil {
-					result, err = tool.Execute(ctx, tc.Arguments)
-					if err != nil {
-						result = fmt.Sprintf("Error: %v", err)
-					}
-				} else {
-					result = fmt.Sprintf("Error: unknown tool %q", tc.Name)
-				}
-				messages = a.Context.AddToolResult(messages, tc.ID, tc.Name, result)
+				messages = a.Context.AddToolResult(messages, tc.ID, tc.Name, results[i])
 			}
 		} else {
 			content := ""
@@ -158,7 +240,10 @@ func (a *AgentLoop) RunAgentLoop(ctx context.Context, messages []map[string]any)
 	return "Max iterations reached", toolsUsed, nil
 }
 
-// ProcessDirect processes a message directly (CLI/cron usage).
+// ProcessDirect processes a message directly (CLI/cron usage). Calls for
+// different sessions may run concurrently; tools find the chat through ctx
+// (tools.WithChatTarget). Calls for one session should be serialized, as
+// the gateway's lanes do.
 func (a *AgentLoop) ProcessDirect(ctx context.Context, content, sessionKey, channel, chatID string) (string, error) {
 	if sessionKey == "" {
 		sessionKey = "cli:direct"
@@ -170,18 +255,26 @@ func (a *AgentLoop) ProcessDirect(ctx context.Context, content, sessionKey, chan
 		chatID = "direct"
 	}
 
+	ctx = tools.WithChatTarget(ctx, channel, chatID)
 	sess := a.Sessions.GetOrCreate(sessionKey)
 
 	// Convert session history from []map[string]string to []map[string]any
+	a.sessMu.Lock()
 	hist := sess.GetHistory(a.MemoryWindow)
+	a.sessMu.Unlock()
 	histAny := make([]map[string]any, len(hist))
 	for i, h := range hist {
 		histAny[i] = map[string]any{"role": h["role"], "content": h["content"]}
 	}
 
-	messages := a.Context.BuildMessages(histAny, content, channel, chatID)
+	// Memory is read from and consolidated into the scope the request carries
+	scope := memoryScopeFrom(ctx)
+	scope.AgentID = a.AgentID
+	ctx = WithMemoryScope(ctx, scope)
+	messages := a.Context.BuildMessages(ctx, histAny, content, channel, chatID)
 
-	finalContent, _, err := a.RunAgentLoop(ctx, messages)
+	turn := &turnState{sess: sess}
+	finalContent, _, err := a.runLoop(ctx, messages, turn)
 	if err != nil {
 		return "", err
 	}
@@ -189,13 +282,46 @@ func (a *AgentLoop) ProcessDirect(ctx context.Context, content, sessionKey, chan
 		finalContent = "Completed processing."
 	}
 
+	a.sessMu.Lock()
 	sess.AddMessage("user", content)
 	sess.AddMessage("assistant", finalContent)
 	a.Sessions.Save(sess)
+	a.maybeConsolidate(sess, scope.Target())
+	a.sessMu.Unlock()
 
+	if turn.notice != "" {
+		return turn.notice + "\n\n" + finalContent, nil
+	}
 	return finalContent, nil
 }
 
+// registerLoopTools registers the whitelisted tools bound to this loop's
+// subagents, which a shared tools.Factory cannot construct, and bound.
+func (a *AgentLoop) registerLoopTools(whitelist []string, bound []tools.Tool) {
+	loopTools := []tools.Tool{
+		&tools.SpawnTool{
+			Profiles: a.Subagents.profileNames(),
+			SpawnCallback: func(req tools.SpawnRequest) (string, error) {
+				return a.Subagents.spawn(context.Background(), req, ""), nil
+			},
+		},
+		&tools.SubagentStatusTool{Subagents: a.Subagents},
+		&tools.SubagentCancelTool{Subagents: a.Subagents},
+	}
+	for _, t := range append(loopTools, bound...) {
+		if tools.Whitelisted(whitelist, t.Name()) {
+			a.Tools.Register(t)
+		}
+	}
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
 // Stop signals the agent loop to stop.
 func (a *AgentLoop) Stop() {
 	a.mu.Lock()
@@ -2,10 +2,16 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 
 	"github.com/dayuer/nanobot-go/internal/bus"
 	"github.com/dayuer/nanobot-go/internal/providers"
+	"github.com/dayuer/nanobot-go/internal/tools"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -137,9 +143,290 @@ type mockToolForLoop struct {
 	name string
 }
 
-func (m *mockToolForLoop) Name() string        { return m.name }
-func (m *mockToolForLoop) Description() string  { return "mock" }
+func (m *mockToolForLoop) Name() string               { return m.name }
+func (m *mockToolForLoop) Description() string        { return "mock" }
 func (m *mockToolForLoop) Parameters() map[string]any { return map[string]any{} }
 func (m *mockToolForLoop) Execute(_ context.Context, _ map[string]any) (string, error) {
 	return "mock result", nil
 }
+
+func TestToProviderMessages_PreservesToolFields(t *testing.T) {
+	cb := NewContextBuilder(t.TempDir())
+	msgs := []map[string]any{{"role": "user", "content": "hi"}}
+	msgs = cb.AddAssistantMessage(msgs, "", ToolCallDicts([]providers.ToolCallRequest{
+		{ID: "call_1", Name: "exec", Arguments: map[string]any{"command": "ls"}},
+	}), "thinking")
+	msgs = cb.AddToolResult(msgs, "call_1", "exec", "a.txt")
+
+	out := ToProviderMessages(msgs)
+	require.Len(t, out, 3)
+	require.Len(t, out[1].ToolCalls, 1)
+	assert.Equal(t, "call_1", out[1].ToolCalls[0].ID)
+	assert.Equal(t, "function", out[1].ToolCalls[0].Type)
+	assert.Equal(t, "exec", out[1].ToolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"command":"ls"}`, out[1].ToolCalls[0].Function.Arguments)
+	assert.Equal(t, "thinking", out[1].ReasoningContent)
+	assert.Equal(t, "call_1", out[2].ToolCallID)
+	assert.Equal(t, "exec", out[2].Name)
+}
+
+func TestAppendAssistantResponse_KeepsReasoningBlocks(t *testing.T) {
+	content, reasoning := "Checking.", "Need the weather tool."
+	blocks := []providers.ReasoningBlock{{Thinking: "Need the weather tool.", Signature: "sig-1"}}
+	msgs := appendAssistantResponse(nil, &providers.LLMResponse{
+		Content:          &content,
+		ReasoningContent: &reasoning,
+		ReasoningBlocks:  blocks,
+		ToolCalls:        []providers.ToolCallRequest{{ID: "toolu_1", Name: "weather"}},
+	})
+
+	out := ToProviderMessages(msgs)
+	require.Len(t, out, 1)
+	assert.Equal(t, "Checking.", out[0].Content)
+	assert.Equal(t, "Need the weather tool.", out[0].ReasoningContent)
+	assert.Equal(t, blocks, out[0].ReasoningBlocks)
+	require.Len(t, out[0].ToolCalls, 1)
+}
+
+func TestContract_AgentLoop_ToolConversation_StubServer(t *testing.T) {
+	var requests [][]map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []map[string]any `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, body.Messages)
+
+		w.Header().Set("Content-Type", "application/json")
+		if len(requests) == 1 {
+			json.NewEncoder(w).Encode(map[string]any{
+				"choices": []map[string]any{{
+					"message": map[string]any{
+						"content": nil,
+						"tool_calls": []map[string]any{{
+							"id":       "call_abc",
+							"type":     "function",
+							"function": map[string]any{"name": "list_dir", "arguments": `{"path":"/tmp"}`},
+						}},
+					},
+					"finish_reason": "tool_calls",
+				}},
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{
+				"message":       map[string]any{"content": "Directory listed"},
+				"finish_reason": "stop",
+			}},
+		})
+	}))
+	defer server.Close()
+
+	p := providers.NewProvider("key", server.URL, "gpt-4", "")
+	loop := NewAgentLoop(bus.NewMessageBus(), p, AgentConfig{Workspace: t.TempDir()})
+	loop.Tools.Register(&mockToolForLoop{name: "list_dir"})
+
+	content, _, err := loop.RunAgentLoop(context.Background(), []map[string]any{
+		{"role": "user", "content": "List /tmp"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Directory listed", content)
+	require.Len(t, requests, 2)
+
+	followUp := requests[1]
+	require.Len(t, followUp, 3)
+	calls, ok := followUp[1]["tool_calls"].([]any)
+	require.True(t, ok, "assistant turn must carry tool_calls")
+	assert.Equal(t, "call_abc", calls[0].(map[string]any)["id"])
+	assert.Equal(t, "tool", followUp[2]["role"])
+	assert.Equal(t, "call_abc", followUp[2]["tool_call_id"])
+	assert.Equal(t, "list_dir", followUp[2]["name"])
+	assert.Equal(t, "mock result", followUp[2]["content"])
+}
+
+func TestAgentLoop_RunAgentLoop_StreamsEvents(t *testing.T) {
+	mp := &mockProvider{
+		responses: []*providers.LLMResponse{
+			{
+				Content:      strP(""),
+				FinishReason: "tool_calls",
+				ToolCalls: []providers.ToolCallRequest{
+					{ID: "call_1", Name: "list_dir", Arguments: map[string]any{"path": "/tmp"}},
+				},
+			},
+			{Content: strP("Directory listed"), FinishReason: "stop"},
+		},
+	}
+	loop := NewAgentLoop(bus.NewMessageBus(), mp, AgentConfig{Workspace: t.TempDir()})
+	loop.Tools.Register(&mockToolForLoop{name: "list_dir"})
+
+	var types []string
+	ctx := WithStreamHandler(context.Background(), func(ev StreamEvent) {
+		types = append(types, ev.Type)
+	})
+	content, _, err := loop.RunAgentLoop(ctx, []map[string]any{{"role": "user", "content": "List"}})
+	require.NoError(t, err)
+	assert.Equal(t, "Directory listed", content)
+	assert.Equal(t, []string{
+		EventThinking, EventToolCall, EventToolResult,
+		EventThinking, EventDelta,
+	}, types)
+}
+
+// errProvider always fails with the given error.
+type errProvider struct{ err error }
+
+func (e *errProvider) Chat(_ context.Context, _ providers.ChatRequest) (*providers.LLMResponse, error) {
+	return nil, e.err
+}
+
+func (e *errProvider) DefaultModel() string { return "err-model" }
+
+func TestAgentLoop_ProcessDirect_SurfacesProviderError(t *testing.T) {
+	loop := NewAgentLoop(bus.NewMessageBus(), &errProvider{
+		err: &providers.Error{Kind: providers.ErrRateLimit, StatusCode: 429},
+	}, AgentConfig{Workspace: t.TempDir()})
+
+	content, err := loop.ProcessDirect(context.Background(), "Hi", "test:err", "", "")
+	var pe *providers.Error
+	require.ErrorAs(t, err, &pe)
+	assert.Equal(t, providers.ErrRateLimit, pe.Kind)
+	assert.Empty(t, content)
+	assert.Empty(t, loop.Sessions.GetOrCreate("test:err").Messages, "failed turns are not saved")
+}
+
+func TestAgentLoop_RunAgentLoop_InBandErrorIsNotContent(t *testing.T) {
+	mp := &mockProvider{
+		responses: []*providers.LLMResponse{
+			{Content: strP("Error calling LLM (HTTP 502)"), FinishReason: "error"},
+		},
+	}
+	loop := NewAgentLoop(bus.NewMessageBus(), mp, AgentConfig{Workspace: t.TempDir()})
+
+	content, _, err := loop.RunAgentLoop(context.Background(), []map[string]any{{"role": "user", "content": "Hi"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "HTTP 502")
+	assert.Empty(t, content)
+}
+
+// panicTool crashes on every call.
+type panicTool struct{ mockToolForLoop }
+
+func (p *panicTool) Execute(_ context.Context, _ map[string]any) (string, error) {
+	panic("tool bug")
+}
+
+func TestAgentLoop_ToolPanicBecomesErrorResult(t *testing.T) {
+	mp := &mockProvider{
+		responses: []*providers.LLMResponse{
+			{ToolCalls: []providers.ToolCallRequest{{ID: "c1", Name: "crash"}}, FinishReason: "tool_calls"},
+			{Content: strP("recovered"), FinishReason: "stop"},
+		},
+	}
+	loop := NewAgentLoop(bus.NewMessageBus(), mp, AgentConfig{Workspace: t.TempDir()})
+	loop.Tools.Register(&panicTool{mockToolForLoop{name: "crash"}})
+
+	content, _, err := loop.RunAgentLoop(context.Background(), []map[string]any{{"role": "user", "content": "go"}})
+	require.NoError(t, err)
+	assert.Equal(t, "recovered", content)
+	assert.Equal(t, 1, loop.Executor.Stats()["crash"].Panics)
+}
+
+func TestAgentLoop_MalformedArgumentsReturnedToLLM(t *testing.T) {
+	mp := &mockProvider{
+		responses: []*providers.LLMResponse{
+			{ToolCalls: []providers.ToolCallRequest{{ID: "c1", Name: "echo", ArgumentsError: "arguments are not a valid JSON object"}}, FinishReason: "tool_calls"},
+			{Content: strP("fixed"), FinishReason: "stop"},
+		},
+	}
+	loop := NewAgentLoop(bus.NewMessageBus(), mp, AgentConfig{Workspace: t.TempDir()})
+	loop.Tools.Register(&mockToolForLoop{name: "echo"})
+
+	var results []string
+	ctx := WithStreamHandler(context.Background(), func(ev StreamEvent) {
+		if ev.Type == EventToolResult {
+			results = append(results, ev.Content)
+		}
+	})
+	_, _, err := loop.RunAgentLoop(ctx, []map[string]any{{"role": "user", "content": "go"}})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Contains(t, results[0], `"error":"invalid_arguments"`)
+	assert.NotContains(t, results[0], "mock result", "tool must not run")
+}
+
+func TestNewAgentLoop_BuildsWhitelistedTools(t *testing.T) {
+	factory := tools.NewFactory()
+	factory.Register("alpha", func() tools.Tool { return &mockToolForLoop{name: "alpha"} })
+	factory.Register("message", func() tools.Tool { return &tools.MessageTool{} })
+
+	loop := NewAgentLoop(bus.NewMessageBus(), &mockProvider{}, AgentConfig{
+		Workspace:     t.TempDir(),
+		ToolFactory:   factory,
+		ToolWhitelist: []string{"message"},
+	})
+	assert.Nil(t, loop.Tools.Get("alpha"))
+	require.NotNil(t, loop.Tools.Get("message"))
+}
+
+// messageProvider calls the message tool with the user's text, then stops.
+type messageProvider struct{}
+
+func (messageProvider) Chat(_ context.Context, req providers.ChatRequest) (*providers.LLMResponse, error) {
+	last := req.Messages[len(req.Messages)-1]
+	if last.Role == "tool" {
+		return &providers.LLMResponse{Content: strP("sent"), FinishReason: "stop"}, nil
+	}
+	return &providers.LLMResponse{ToolCalls: []providers.ToolCallRequest{
+		{ID: "c1", Name: "message", Arguments: map[string]any{"content": last.Content}},
+	}}, nil
+}
+
+func (messageProvider) DefaultModel() string { return "mock-model" }
+
+func TestAgentLoop_ProcessDirect_ConcurrentSessions(t *testing.T) {
+	var mu sync.Mutex
+	sent := map[string]string{}
+	loop := NewAgentLoop(bus.NewMessageBus(), messageProvider{}, AgentConfig{Workspace: t.TempDir()})
+	loop.Tools.Register(&tools.MessageTool{SendCallback: func(msg bus.OutboundMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sent[msg.ChatID] = msg.Content
+		return nil
+	}})
+
+	// Each turn's message goes to its own chat, not whichever turn ran last
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		chatID := fmt.Sprint(i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := loop.ProcessDirect(context.Background(), "for "+chatID, "telegram:"+chatID, "telegram", chatID)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, sent, 8)
+	for chatID, content := range sent {
+		assert.Equal(t, "for "+chatID, content)
+	}
+}
+
+func TestAgentLoop_Consult_NoSession(t *testing.T) {
+	p := &memoryProvider{}
+	loop := NewAgentLoop(bus.NewMessageBus(), p, AgentConfig{AgentID: "legal", Workspace: t.TempDir()})
+	require.NoError(t, loop.Context.Memory.Backend.WriteLongTerm(context.Background(), MemoryScope{PersonID: "alice", AgentID: "legal"}, "Alice signed in May."))
+
+	var events int
+	ctx := WithStreamHandler(context.Background(), func(StreamEvent) { events++ })
+	ctx = WithMemoryScope(ctx, MemoryScope{PersonID: "alice"})
+	answer, err := loop.Consult(ctx, "When did Alice sign?")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", answer)
+	assert.Contains(t, p.systems[0], "Alice signed in May.", "reads the person's memory for this agent")
+	assert.Zero(t, events, "consultations are not streamed to the caller")
+	assert.Empty(t, loop.Sessions.ListSessions())
+}
@@ -3,17 +3,197 @@
 package agent
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/dayuer/nanobot-go/internal/utils"
 )
 
+// MemoryScope identifies whose memory a turn reads and consolidates into.
+// The zero scope is the workspace-wide memory (single-user CLI/gateway).
+// A person owns their memory across sessions; without a person, a session
+// key isolates anonymous conversations. AgentID narrows the owner's memory
+// to what one agent has learned.
+type MemoryScope struct {
+	PersonID   string
+	AgentID    string
+	SessionKey string
+}
+
+// owner returns the scope's owner layer: the person, else the session,
+// else the workspace. AgentID is ignored for the workspace scope.
+func (s MemoryScope) owner() MemoryScope {
+	switch {
+	case s.PersonID != "":
+		return MemoryScope{PersonID: s.PersonID}
+	case s.SessionKey != "":
+		return MemoryScope{SessionKey: s.SessionKey}
+	default:
+		return MemoryScope{}
+	}
+}
+
+// Layers returns the scopes loaded into the prompt, broad to narrow: the
+// owner, then the owner narrowed to AgentID. The last layer is where
+// consolidation writes.
+func (s MemoryScope) Layers() []MemoryScope {
+	owner := s.owner()
+	if owner == (MemoryScope{}) || s.AgentID == "" {
+		return []MemoryScope{owner}
+	}
+	narrowed := owner
+	narrowed.AgentID = s.AgentID
+	return []MemoryScope{owner, narrowed}
+}
+
+// Target is the scope consolidation writes to.
+func (s MemoryScope) Target() MemoryScope {
+	layers := s.Layers()
+	return layers[len(layers)-1]
+}
+
+// ErrInvalidMemoryScope is returned for scopes whose IDs cannot name a
+// directory of their own (empty, "." or ".." once sanitized).
+var ErrInvalidMemoryScope = errors.New("invalid memory scope")
+
+// Path returns the scope as relative path segments ("" for the workspace),
+// e.g. "person/42/agent/legal". Callers that touch the filesystem must
+// check the scope with Validate first.
+func (s MemoryScope) Path() string {
+	var parts []string
+	switch {
+	case s.PersonID != "":
+		parts = append(parts, "person", utils.SafeFilename(s.PersonID))
+	case s.SessionKey != "":
+		parts = append(parts, "session", utils.SafeFilename(s.SessionKey))
+	}
+	if s.AgentID != "" && len(parts) > 0 {
+		parts = append(parts, "agent", utils.SafeFilename(s.AgentID))
+	}
+	return strings.Join(parts, "/")
+}
+
+// Validate rejects scopes whose IDs would escape or alias another scope's
+// directory, e.g. PersonID ".." (the workspace memory).
+func (s MemoryScope) Validate() error {
+	p := s.Path()
+	if p == "" {
+		return nil
+	}
+	for _, seg := range strings.Split(p, "/") {
+		if seg == "" || seg == "." || seg == ".." {
+			return fmt.Errorf("%w: %q", ErrInvalidMemoryScope, p)
+		}
+	}
+	return nil
+}
+
+func (s MemoryScope) heading() string {
+	switch {
+	case s.AgentID != "":
+		return "Agent Memory"
+	case s.PersonID != "":
+		return "User Memory"
+	case s.SessionKey != "":
+		return "Conversation Memory"
+	default:
+		return "Long-term Memory"
+	}
+}
+
+type memoryScopeKey struct{}
+
+// WithMemoryScope returns a context whose turn reads and consolidates the
+// memory of scope (PersonID/SessionKey; the agent loop fills in AgentID).
+func WithMemoryScope(ctx context.Context, scope MemoryScope) context.Context {
+	return context.WithValue(ctx, memoryScopeKey{}, scope)
+}
+
+// memoryScopeFrom returns the scope attached to ctx, or the workspace scope.
+func memoryScopeFrom(ctx context.Context) MemoryScope {
+	scope, _ := ctx.Value(memoryScopeKey{}).(MemoryScope)
+	return scope
+}
+
+// MemoryBackend stores long-term memory and history per scope.
+type MemoryBackend interface {
+	ReadLongTerm(ctx context.Context, scope MemoryScope) (string, error)
+	WriteLongTerm(ctx context.Context, scope MemoryScope, content string) error
+	AppendHistory(ctx context.Context, scope MemoryScope, entry string) error
+}
+
+// FileMemory keeps each scope's MEMORY.md and HISTORY.md under Dir; the
+// workspace scope uses Dir itself, others Dir/<scope path>.
+type FileMemory struct {
+	Dir string
+}
+
+func (f *FileMemory) dir(scope MemoryScope) (string, error) {
+	if err := scope.Validate(); err != nil {
+		return "", err
+	}
+	if p := scope.Path(); p != "" {
+		return filepath.Join(f.Dir, filepath.FromSlash(p)), nil
+	}
+	return f.Dir, nil
+}
+
+// ReadLongTerm reads the scope's MEMORY.md; a missing file is empty memory.
+func (f *FileMemory) ReadLongTerm(_ context.Context, scope MemoryScope) (string, error) {
+	dir, err := f.dir(scope)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "MEMORY.md"))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return string(data), err
+}
+
+// WriteLongTerm replaces the scope's MEMORY.md atomically.
+func (f *FileMemory) WriteLongTerm(_ context.Context, scope MemoryScope, content string) error {
+	dir, err := f.dir(scope)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(filepath.Join(dir, "MEMORY.md"), []byte(content), 0o644)
+}
+
+// AppendHistory appends an entry to the scope's HISTORY.md.
+func (f *FileMemory) AppendHistory(_ context.Context, scope MemoryScope, entry string) error {
+	dir, err := f.dir(scope)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filepath.Join(dir, "HISTORY.md"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.WriteString(strings.TrimRight(entry, "\n") + "\n\n")
+	return err
+}
+
 // MemoryStore provides two-layer memory: MEMORY.md (long-term) + HISTORY.md (grep-searchable log).
+// The scope-less methods address the workspace scope.
 type MemoryStore struct {
-	MemoryDir  string
-	MemoryFile string
+	MemoryDir   string
+	MemoryFile  string
 	HistoryFile string
+
+	// Backend stores every scope; NewMemoryStore uses FileMemory on MemoryDir
+	Backend MemoryBackend
 }
 
 // NewMemoryStore creates a MemoryStore rooted at workspace/memory.
@@ -24,39 +204,41 @@ func NewMemoryStore(workspace string) *MemoryStore {
 		MemoryDir:   dir,
 		MemoryFile:  filepath.Join(dir, "MEMORY.md"),
 		HistoryFile: filepath.Join(dir, "HISTORY.md"),
+		Backend:     &FileMemory{Dir: dir},
 	}
 }
 
-// ReadLongTerm reads MEMORY.md.
+// ReadLongTerm reads the workspace MEMORY.md.
 func (m *MemoryStore) ReadLongTerm() string {
-	data, err := os.ReadFile(m.MemoryFile)
-	if err != nil {
-		return ""
-	}
-	return string(data)
+	content, _ := m.Backend.ReadLongTerm(context.Background(), MemoryScope{})
+	return content
 }
 
-// WriteLongTerm writes MEMORY.md.
+// WriteLongTerm replaces the workspace MEMORY.md atomically.
 func (m *MemoryStore) WriteLongTerm(content string) error {
-	return os.WriteFile(m.MemoryFile, []byte(content), 0o644)
+	return m.Backend.WriteLongTerm(context.Background(), MemoryScope{}, content)
 }
 
-// AppendHistory appends an entry to HISTORY.md.
+// AppendHistory appends an entry to the workspace HISTORY.md.
 func (m *MemoryStore) AppendHistory(entry string) error {
-	f, err := os.OpenFile(m.HistoryFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	_, err = f.WriteString(strings.TrimRight(entry, "\n") + "\n\n")
-	return err
+	return m.Backend.AppendHistory(context.Background(), MemoryScope{}, entry)
 }
 
-// GetMemoryContext returns formatted memory for inclusion in prompts.
+// GetMemoryContext returns formatted workspace memory for inclusion in prompts.
 func (m *MemoryStore) GetMemoryContext() string {
-	lt := m.ReadLongTerm()
-	if lt != "" {
-		return fmt.Sprintf("## Long-term Memory\n%s", lt)
+	return m.ContextFor(context.Background(), MemoryScope{})
+}
+
+// ContextFor returns the memory of every layer of scope, formatted for the
+// system prompt. Unreadable layers are skipped.
+func (m *MemoryStore) ContextFor(ctx context.Context, scope MemoryScope) string {
+	var parts []string
+	for _, layer := range scope.Layers() {
+		content, err := m.Backend.ReadLongTerm(ctx, layer)
+		if err != nil || strings.TrimSpace(content) == "" {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("## %s\n%s", layer.heading(), content))
 	}
-	return ""
+	return strings.Join(parts, "\n\n")
 }
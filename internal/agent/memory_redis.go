@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	nanoredis "github.com/dayuer/nanobot-go/internal/redis"
+)
+
+// redisHistoryLimit caps each scope's history list.
+const redisHistoryLimit = 1000
+
+// errRedisUnavailable is returned while the shared Redis client is down.
+var errRedisUnavailable = errors.New("redis unavailable")
+
+// RedisMemory keeps memory in the shared Redis client (internal/redis).
+// Each scope kind has its own key prefix under agentmem:, e.g.
+// agentmem:person:<personID>:agent:<agentID>, apart from the mem: cache
+// the user_memory tool keeps. A person's own layer falls back to that
+// cache, so what user_memory saved keeps reaching the prompt. History is a
+// capped list at <key>:history.
+type RedisMemory struct{}
+
+// redisKeyEscaper escapes IDs so a ":" inside one cannot forge another
+// scope's key.
+var redisKeyEscaper = strings.NewReplacer("%", "%25", ":", "%3A")
+
+// RedisMemoryKey returns the Redis key holding scope's long-term memory.
+func RedisMemoryKey(scope MemoryScope) string {
+	var key string
+	switch {
+	case scope.PersonID != "":
+		key = "person:" + redisKeyEscaper.Replace(scope.PersonID)
+	case scope.SessionKey != "":
+		key = "session:" + redisKeyEscaper.Replace(scope.SessionKey)
+	default:
+		return nanoredis.KeyAgentMemory + "workspace"
+	}
+	if scope.AgentID != "" {
+		key += ":agent:" + redisKeyEscaper.Replace(scope.AgentID)
+	}
+	return nanoredis.KeyAgentMemory + key
+}
+
+// ReadLongTerm reads scope's memory; a missing key is empty memory. The
+// person layer without an agent reads the user_memory cache (mem:<personID>)
+// while it has no memory of its own.
+func (RedisMemory) ReadLongTerm(ctx context.Context, scope MemoryScope) (string, error) {
+	c := nanoredis.Client()
+	if c == nil {
+		return "", errRedisUnavailable
+	}
+	val, err := c.Get(ctx, RedisMemoryKey(scope)).Result()
+	if errors.Is(err, goredis.Nil) && scope.PersonID != "" && scope.AgentID == "" {
+		val, err = c.Get(ctx, nanoredis.MemoryKey(scope.PersonID)).Result()
+	}
+	if errors.Is(err, goredis.Nil) {
+		return "", nil
+	}
+	return val, err
+}
+
+// WriteLongTerm replaces scope's memory. The key does not expire.
+func (RedisMemory) WriteLongTerm(ctx context.Context, scope MemoryScope, content string) error {
+	c := nanoredis.Client()
+	if c == nil {
+		return errRedisUnavailable
+	}
+	return c.Set(ctx, RedisMemoryKey(scope), content, 0).Err()
+}
+
+// AppendHistory appends an entry to scope's history list, keeping the newest
+// redisHistoryLimit entries.
+func (RedisMemory) AppendHistory(ctx context.Context, scope MemoryScope, entry string) error {
+	c := nanoredis.Client()
+	if c == nil {
+		return errRedisUnavailable
+	}
+	key := RedisMemoryKey(scope) + ":history"
+	pipe := c.TxPipeline()
+	pipe.RPush(ctx, key, strings.TrimRight(entry, "\n"))
+	pipe.LTrim(ctx, key, -redisHistoryLimit, -1)
+	_, err := pipe.Exec(ctx)
+	return err
+}
@@ -1,12 +1,25 @@
 package agent
 
 import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	nanoredis "github.com/dayuer/nanobot-go/internal/redis"
+	"github.com/dayuer/nanobot-go/internal/survivaltools"
 )
 
 func TestMemoryStore_ReadWriteLongTerm(t *testing.T) {
@@ -66,3 +79,175 @@ func TestMemoryStore_CreatesDir(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, filepath.Join(dir, "memory"), m.MemoryDir)
 }
+
+func TestMemoryScope_Layers(t *testing.T) {
+	assert.Equal(t, []MemoryScope{{}}, MemoryScope{AgentID: "legal"}.Layers())
+	assert.Equal(t, []MemoryScope{{PersonID: "42"}, {PersonID: "42", AgentID: "legal"}},
+		MemoryScope{PersonID: "42", AgentID: "legal", SessionKey: "api:1"}.Layers())
+	assert.Equal(t, []MemoryScope{{SessionKey: "api:1"}}, MemoryScope{SessionKey: "api:1"}.Layers())
+	assert.Equal(t, MemoryScope{PersonID: "42", AgentID: "legal"}, MemoryScope{PersonID: "42", AgentID: "legal"}.Target())
+
+	assert.Equal(t, "", MemoryScope{}.Path())
+	assert.Equal(t, "person/42/agent/legal", MemoryScope{PersonID: "42", AgentID: "legal"}.Path())
+	assert.Equal(t, "session/api_1", MemoryScope{SessionKey: "api:1"}.Path())
+}
+
+func TestFileMemory_RejectsEscapingScopes(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStore(t.TempDir())
+	require.NoError(t, m.WriteLongTerm("workspace notes"))
+
+	for _, scope := range []MemoryScope{
+		{PersonID: ".."},
+		{PersonID: " . "},
+		{PersonID: "  "},
+		{SessionKey: ".."},
+		{PersonID: "42", AgentID: ".."},
+	} {
+		_, err := m.Backend.ReadLongTerm(ctx, scope)
+		assert.ErrorIs(t, err, ErrInvalidMemoryScope, "%+v", scope)
+		assert.ErrorIs(t, m.Backend.WriteLongTerm(ctx, scope, "overwritten"), ErrInvalidMemoryScope, "%+v", scope)
+		assert.ErrorIs(t, m.Backend.AppendHistory(ctx, scope, "x"), ErrInvalidMemoryScope, "%+v", scope)
+	}
+	assert.Equal(t, "workspace notes", m.ReadLongTerm())
+}
+
+func TestMemoryStore_ScopesAreIsolated(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStore(t.TempDir())
+	alice := MemoryScope{PersonID: "alice", AgentID: "legal"}
+
+	require.NoError(t, m.WriteLongTerm("workspace notes"))
+	require.NoError(t, m.Backend.WriteLongTerm(ctx, MemoryScope{PersonID: "alice"}, "Alice lives in Beijing"))
+	require.NoError(t, m.Backend.WriteLongTerm(ctx, alice, "Alice has a contract dispute"))
+	require.NoError(t, m.Backend.AppendHistory(ctx, alice, "[2026-01-01 10:00] Discussed the contract"))
+
+	got := m.ContextFor(ctx, alice)
+	assert.Equal(t, "## User Memory\nAlice lives in Beijing\n\n## Agent Memory\nAlice has a contract dispute", got)
+	assert.NotContains(t, got, "workspace notes")
+	assert.Empty(t, m.ContextFor(ctx, MemoryScope{PersonID: "bob", AgentID: "legal"}))
+
+	history, err := os.ReadFile(filepath.Join(m.MemoryDir, "person", "alice", "agent", "legal", "HISTORY.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "[2026-01-01 10:00] Discussed the contract\n\n", string(history))
+}
+
+func TestRedisMemoryKey(t *testing.T) {
+	assert.Equal(t, "agentmem:person:42", RedisMemoryKey(MemoryScope{PersonID: "42"}))
+	assert.Equal(t, "agentmem:person:42:agent:legal", RedisMemoryKey(MemoryScope{PersonID: "42", AgentID: "legal"}))
+	assert.Equal(t, "agentmem:session:api%3A1", RedisMemoryKey(MemoryScope{SessionKey: "api:1"}))
+	assert.Equal(t, "agentmem:workspace", RedisMemoryKey(MemoryScope{}))
+
+	// IDs cannot forge another scope's key
+	assert.NotEqual(t, RedisMemoryKey(MemoryScope{SessionKey: "x"}), RedisMemoryKey(MemoryScope{PersonID: "session:x"}))
+	assert.NotEqual(t, RedisMemoryKey(MemoryScope{PersonID: "42", AgentID: "legal"}), RedisMemoryKey(MemoryScope{PersonID: "42:agent:legal"}))
+	assert.NotEqual(t, "mem:42", RedisMemoryKey(MemoryScope{PersonID: "42"}), "user_memory's cache key")
+}
+
+func TestRedisMemory_UnavailableIsAnError(t *testing.T) {
+	_, err := RedisMemory{}.ReadLongTerm(context.Background(), MemoryScope{PersonID: "42"})
+	assert.Error(t, err)
+}
+
+func TestRedisMemory_UserMemoryToolReachesPrompt(t *testing.T) {
+	startFakeRedis(t)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer backend.Close()
+
+	tool := survivaltools.NewMemoryTool(backend.URL, "key")
+	tool.SetPersonID("42")
+	out, err := tool.Execute(context.Background(), map[string]any{"action": "save", "content": "Prefers tea"})
+	require.NoError(t, err)
+	require.Contains(t, out, "saved")
+
+	cb := NewContextBuilder(t.TempDir())
+	cb.Memory.Backend = RedisMemory{}
+	ctx := WithMemoryScope(context.Background(), MemoryScope{PersonID: "42", AgentID: "legal"})
+	prompt := cb.BuildSystemPrompt(ctx, nil)
+	assert.Contains(t, prompt, "## User Memory\nPrefers tea")
+	assert.NotContains(t, prompt, "memory/MEMORY.md", "a user's memory is not the workspace file")
+
+	// The person's own memory, once written, takes precedence
+	require.NoError(t, RedisMemory{}.WriteLongTerm(ctx, MemoryScope{PersonID: "42"}, "Prefers coffee"))
+	assert.Contains(t, cb.BuildSystemPrompt(ctx, nil), "## User Memory\nPrefers coffee")
+}
+
+// startFakeRedis serves GET, SET and PING over RESP2 and connects the
+// shared client to it for the rest of the test.
+func startFakeRedis(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	var mu sync.Mutex
+	data := map[string]string{}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					args, err := readRESPCommand(r)
+					if err != nil {
+						return
+					}
+					mu.Lock()
+					switch strings.ToUpper(args[0]) {
+					case "PING":
+						io.WriteString(conn, "+PONG\r\n")
+					case "GET":
+						if v, ok := data[args[1]]; ok {
+							fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(v), v)
+						} else {
+							io.WriteString(conn, "$-1\r\n")
+						}
+					case "SET":
+						data[args[1]] = args[2]
+						io.WriteString(conn, "+OK\r\n")
+					default:
+						io.WriteString(conn, "-ERR unknown command\r\n")
+					}
+					mu.Unlock()
+				}
+			}()
+		}
+	}()
+	require.True(t, nanoredis.Init(nanoredis.Config{URL: "redis://" + ln.Addr().String()}))
+	t.Cleanup(func() {
+		nanoredis.Close()
+		ln.Close()
+	})
+}
+
+// readRESPCommand reads one command sent as an array of bulk strings.
+func readRESPCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("bad command header %q", line)
+	}
+	args := make([]string, n)
+	for i := range args {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "$")))
+		if err != nil {
+			return nil, fmt.Errorf("bad bulk header %q", header)
+		}
+		arg := make([]byte, size+2) // trailing \r\n
+		if _, err := io.ReadFull(r, arg); err != nil {
+			return nil, err
+		}
+		args[i] = string(arg[:size])
+	}
+	return args, nil
+}
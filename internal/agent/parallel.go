@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dayuer/nanobot-go/internal/providers"
+	"github.com/dayuer/nanobot-go/internal/tools"
+)
+
+// DefaultMaxParallelTools bounds concurrent tool calls within one assistant turn.
+const DefaultMaxParallelTools = 4
+
+// executeToolCalls runs one turn's tool calls and returns their results in
+// call order. Consecutive independent calls run concurrently (at most
+// a.MaxParallelTools at a time); a tools.SequentialTool call waits for every
+// earlier call and runs alone. Events are emitted from the calling goroutine.
+func (a *AgentLoop) executeToolCalls(ctx context.Context, calls []providers.ToolCallRequest, iteration int, emit StreamHandler) []string {
+	results := make([]string, len(calls))
+
+	for start := 0; start < len(calls); {
+		end := start + 1
+		if !a.isSequentialCall(calls[start]) {
+			for end < len(calls) && !a.isSequentialCall(calls[end]) {
+				end++
+			}
+		}
+		batch := calls[start:end]
+
+		if emit != nil {
+			for _, tc := range batch {
+				emit(StreamEvent{Type: EventToolCall, Iteration: iteration, ToolName: tc.Name, ToolCallID: tc.ID, Arguments: tc.Arguments})
+			}
+		}
+
+		a.runBatch(ctx, batch, results[start:end])
+
+		if emit != nil {
+			for i, tc := range batch {
+				emit(StreamEvent{Type: EventToolResult, Iteration: iteration, ToolName: tc.Name, ToolCallID: tc.ID, Content: results[start+i]})
+			}
+		}
+		start = end
+	}
+	return results
+}
+
+// runBatch executes calls concurrently, writing results[i] for calls[i].
+func (a *AgentLoop) runBatch(ctx context.Context, calls []providers.ToolCallRequest, results []string) {
+	workers := a.MaxParallelTools
+	if workers < 1 {
+		workers = 1
+	}
+	if len(calls) == 1 || workers == 1 {
+		for i, tc := range calls {
+			results[i] = a.executeTool(ctx, tc)
+		}
+		return
+	}
+
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, tc := range calls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, tc providers.ToolCallRequest) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = a.executeTool(ctx, tc)
+		}(i, tc)
+	}
+	wg.Wait()
+}
+
+// executeTool runs a single tool call through the executor.
+func (a *AgentLoop) executeTool(ctx context.Context, tc providers.ToolCallRequest) string {
+	return executeCall(ctx, a.Executor, tc).Output
+}
+
+// executeCall runs tc, rejecting calls whose arguments failed to decode.
+func executeCall(ctx context.Context, ex *tools.Executor, tc providers.ToolCallRequest) tools.Result {
+	if tc.ArgumentsError != "" {
+		return ex.Reject(tc.Name, tc.ArgumentsError)
+	}
+	return ex.Execute(ctx, tc.Name, tc.Arguments)
+}
+
+// newToolExecutor wraps reg with the limits from cfg.
+func newToolExecutor(reg *tools.Registry, cfg AgentConfig) *tools.Executor {
+	ex := tools.NewExecutor(reg)
+	if cfg.ToolTimeout != 0 {
+		ex.Timeout = cfg.ToolTimeout
+	}
+	if cfg.MaxToolOutput != 0 {
+		ex.MaxOutput = cfg.MaxToolOutput
+	}
+	ex.Timeouts = cfg.ToolTimeouts
+	return ex
+}
+
+func (a *AgentLoop) isSequentialCall(tc providers.ToolCallRequest) bool {
+	tool := a.Tools.Get(tc.Name)
+	return tool != nil && tools.IsSequential(tool)
+}
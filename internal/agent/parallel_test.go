@@ -0,0 +1,145 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dayuer/nanobot-go/internal/bus"
+	"github.com/dayuer/nanobot-go/internal/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowTool sleeps, then echoes its "id" argument; it tracks peak concurrency.
+type slowTool struct {
+	name    string
+	delay   time.Duration
+	running int32
+	peak    int32
+	log     *callLog
+}
+
+type callLog struct {
+	mu    sync.Mutex
+	order []string
+}
+
+func (l *callLog) add(s string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.order = append(l.order, s)
+}
+
+func (s *slowTool) Name() string               { return s.name }
+func (s *slowTool) Description() string        { return "slow" }
+func (s *slowTool) Parameters() map[string]any { return map[string]any{} }
+func (s *slowTool) Execute(_ context.Context, args map[string]any) (string, error) {
+	n := atomic.AddInt32(&s.running, 1)
+	defer atomic.AddInt32(&s.running, -1)
+	for {
+		p := atomic.LoadInt32(&s.peak)
+		if n <= p || atomic.CompareAndSwapInt32(&s.peak, p, n) {
+			break
+		}
+	}
+	id, _ := args["id"].(string)
+	if s.log != nil {
+		s.log.add("start:" + id)
+	}
+	time.Sleep(s.delay)
+	if s.log != nil {
+		s.log.add("end:" + id)
+	}
+	return "result " + id, nil
+}
+
+// seqTool is a slowTool that opts out of parallel execution.
+type seqTool struct{ slowTool }
+
+func (s *seqTool) Sequential() {}
+
+func calls(name string, ids ...string) []providers.ToolCallRequest {
+	out := make([]providers.ToolCallRequest, len(ids))
+	for i, id := range ids {
+		out[i] = providers.ToolCallRequest{ID: "call_" + id, Name: name, Arguments: map[string]any{"id": id}}
+	}
+	return out
+}
+
+func TestAgentLoop_ParallelToolCalls_OrderedResults(t *testing.T) {
+	tool := &slowTool{name: "fetch", delay: 50 * time.Millisecond}
+	loop := NewAgentLoop(bus.NewMessageBus(), &mockProvider{}, AgentConfig{Workspace: t.TempDir()})
+	loop.Tools.Register(tool)
+
+	start := time.Now()
+	results := loop.executeToolCalls(context.Background(), calls("fetch", "a", "b", "c"), 1, nil)
+	elapsed := time.Since(start)
+
+	assert.Equal(t, []string{"result a", "result b", "result c"}, results)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&tool.peak))
+	assert.Less(t, elapsed, 140*time.Millisecond, "calls should overlap")
+}
+
+func TestAgentLoop_ParallelToolCalls_BoundedWorkers(t *testing.T) {
+	tool := &slowTool{name: "fetch", delay: 20 * time.Millisecond}
+	loop := NewAgentLoop(bus.NewMessageBus(), &mockProvider{}, AgentConfig{
+		Workspace:        t.TempDir(),
+		MaxParallelTools: 2,
+	})
+	loop.Tools.Register(tool)
+
+	results := loop.executeToolCalls(context.Background(), calls("fetch", "a", "b", "c", "d", "e"), 1, nil)
+	assert.Len(t, results, 5)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&tool.peak))
+}
+
+func TestAgentLoop_SequentialToolRunsAlone(t *testing.T) {
+	log := &callLog{}
+	par := &slowTool{name: "fetch", delay: 20 * time.Millisecond, log: log}
+	seq := &seqTool{slowTool{name: "write", delay: 5 * time.Millisecond, log: log}}
+	loop := NewAgentLoop(bus.NewMessageBus(), &mockProvider{}, AgentConfig{Workspace: t.TempDir()})
+	loop.Tools.Register(par)
+	loop.Tools.Register(seq)
+
+	tcs := append(calls("fetch", "a", "b"), calls("write", "w")...)
+	tcs = append(tcs, calls("fetch", "c")...)
+	results := loop.executeToolCalls(context.Background(), tcs, 1, nil)
+
+	assert.Equal(t, []string{"result a", "result b", "result w", "result c"}, results)
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	require.Len(t, log.order, 8)
+	// Both parallel calls finish before the sequential one starts,
+	// which in turn finishes before the trailing call starts.
+	assert.ElementsMatch(t, []string{"start:a", "start:b", "end:a", "end:b"}, log.order[:4])
+	assert.Equal(t, []string{"start:w", "end:w", "start:c", "end:c"}, log.order[4:])
+}
+
+func TestAgentLoop_RunAgentLoop_ParallelTranscriptOrder(t *testing.T) {
+	mp := &mockProvider{
+		responses: []*providers.LLMResponse{
+			{ToolCalls: calls("fetch", "a", "b", "c"), FinishReason: "tool_calls"},
+			{Content: strP("done"), FinishReason: "stop"},
+		},
+	}
+	loop := NewAgentLoop(bus.NewMessageBus(), mp, AgentConfig{Workspace: t.TempDir()})
+	loop.Tools.Register(&slowTool{name: "fetch", delay: 10 * time.Millisecond})
+
+	var events []StreamEvent
+	ctx := WithStreamHandler(context.Background(), func(ev StreamEvent) { events = append(events, ev) })
+	content, toolsUsed, err := loop.RunAgentLoop(ctx, []map[string]any{{"role": "user", "content": "go"}})
+	require.NoError(t, err)
+	assert.Equal(t, "done", content)
+	assert.Equal(t, []string{"fetch", "fetch", "fetch"}, toolsUsed)
+
+	var resultIDs []string
+	for _, ev := range events {
+		if ev.Type == EventToolResult {
+			resultIDs = append(resultIDs, ev.ToolCallID)
+		}
+	}
+	assert.Equal(t, []string{"call_a", "call_b", "call_c"}, resultIDs)
+}
@@ -1,24 +1,139 @@
 package agent
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 // SkillInfo describes a discovered skill.
 type SkillInfo struct {
-	Name   string `json:"name"`
-	Path   string `json:"path"`
-	Source string `json:"source"` // "workspace" or "builtin"
+	Name        string   `json:"name"`
+	Path        string   `json:"path"`
+	Source      string   `json:"source"` // "workspace" or "builtin"
+	Description string   `json:"description"`
+	Always      bool     `json:"always"`
+	Available   bool     `json:"available"`
+	Missing     []string `json:"missing,omitempty"` // unmet requirements, e.g. "bin:gh", "env:GITHUB_TOKEN"
+	Scripts     []string `json:"scripts,omitempty"` // bundled scripts, relative to the skill directory
+}
+
+// SkillRequires lists what a skill needs from the host.
+type SkillRequires struct {
+	Bins []string `yaml:"bins" json:"bins,omitempty"`
+	Env  []string `yaml:"env" json:"env,omitempty"`
+}
+
+// SkillMeta is the typed part of a skill's YAML frontmatter. Upstream
+// nanobot skills nest always and requires under metadata.nanobot; both
+// spellings are accepted.
+type SkillMeta struct {
+	Name        string        `yaml:"name"`
+	Description string        `yaml:"description"`
+	Always      bool          `yaml:"always"`
+	Requires    SkillRequires `yaml:"requires"`
+}
+
+// skillFrontmatter adds the upstream metadata block, which may be a nested
+// map or a JSON string.
+type skillFrontmatter struct {
+	SkillMeta `yaml:",inline"`
+	Metadata  yaml.Node `yaml:"metadata"`
+}
+
+type nanobotMetadata struct {
+	Nanobot struct {
+		Always   bool          `yaml:"always"`
+		Requires SkillRequires `yaml:"requires"`
+	} `yaml:"nanobot"`
+}
+
+var frontmatterRe = regexp.MustCompile(`(?s)^---\r?\n(.*?)\r?\n---`)
+
+// parseFrontmatter splits a skill's YAML frontmatter into its typed fields
+// and its raw top-level values. ok is false without (valid) frontmatter.
+func parseFrontmatter(content string) (meta SkillMeta, raw map[string]any, ok bool) {
+	match := frontmatterRe.FindStringSubmatch(content)
+	if match == nil {
+		return SkillMeta{}, nil, false
+	}
+	if err := yaml.Unmarshal([]byte(match[1]), &raw); err != nil {
+		return SkillMeta{}, nil, false
+	}
+
+	var fm skillFrontmatter
+	var typeErr *yaml.TypeError
+	if err := yaml.Unmarshal([]byte(match[1]), &fm); err != nil && !errors.As(err, &typeErr) {
+		return SkillMeta{}, raw, true
+	}
+	meta = fm.SkillMeta
+
+	var nb nanobotMetadata
+	switch fm.Metadata.Kind {
+	case yaml.ScalarNode:
+		yaml.Unmarshal([]byte(fm.Metadata.Value), &nb)
+	case yaml.MappingNode:
+		fm.Metadata.Decode(&nb)
+	}
+	meta.Always = meta.Always || nb.Nanobot.Always
+	meta.Requires.Bins = append(meta.Requires.Bins, nb.Nanobot.Requires.Bins...)
+	meta.Requires.Env = append(meta.Requires.Env, nb.Nanobot.Requires.Env...)
+	return meta, raw, true
+}
+
+// missingRequirements returns the requirements this host does not meet.
+func missingRequirements(req SkillRequires) []string {
+	var missing []string
+	for _, bin := range req.Bins {
+		if _, err := exec.LookPath(bin); err != nil {
+			missing = append(missing, "bin:"+bin)
+		}
+	}
+	for _, env := range req.Env {
+		if os.Getenv(env) == "" {
+			missing = append(missing, "env:"+env)
+		}
+	}
+	return missing
 }
 
 // SkillsLoader discovers and loads agent skills from workspace and builtin dirs.
+// Discovery results are cached and rebuilt when a skill directory, SKILL.md
+// or scripts directory changes on disk, and at least every skillsRecheck so
+// requirements installed on the host later make a skill available.
 type SkillsLoader struct {
-	Workspace      string
+	Workspace       string
 	WorkspaceSkills string
-	BuiltinSkills  string
+	BuiltinSkills   string
+
+	// Always names skills inlined into every prompt (config agent.alwaysSkills),
+	// in addition to those whose frontmatter sets always
+	Always []string
+
+	mu    sync.Mutex
+	cache *skillsSnapshot
+}
+
+// skillsRecheck bounds how long a discovery pass (and its requirement
+// checks) is reused while the skill files stay unchanged.
+const skillsRecheck = time.Minute
+
+// skillsSnapshot is one discovery pass, valid while stamp is unchanged and
+// for at most skillsRecheck.
+type skillsSnapshot struct {
+	stamp   string
+	at      time.Time
+	skills  []SkillInfo
+	summary string
 }
 
 // NewSkillsLoader creates a SkillsLoader.
@@ -30,63 +145,205 @@ func NewSkillsLoader(workspace string, builtinSkillsDir string) *SkillsLoader {
 	}
 }
 
+// skillSources returns the skill roots in priority order: workspace, then builtin.
+func (s *SkillsLoader) skillSources() []struct{ dir, name string } {
+	return []struct{ dir, name string }{{s.WorkspaceSkills, "workspace"}, {s.BuiltinSkills, "builtin"}}
+}
+
 // ListSkills returns all available skills. Workspace skills override builtins.
 func (s *SkillsLoader) ListSkills() []SkillInfo {
+	return append([]SkillInfo(nil), s.snapshot().skills...)
+}
+
+// GetSkill returns the skill called name.
+func (s *SkillsLoader) GetSkill(name string) (SkillInfo, bool) {
+	for _, info := range s.snapshot().skills {
+		if info.Name == name {
+			return info, true
+		}
+	}
+	return SkillInfo{}, false
+}
+
+// Invalidate drops the cached discovery results, e.g. after changes the
+// mtime stamp cannot see (a requirement installed on the host).
+func (s *SkillsLoader) Invalidate() {
+	s.mu.Lock()
+	s.cache = nil
+	s.mu.Unlock()
+}
+
+// snapshot returns the cached discovery results, rediscovering when the
+// skill files changed since the last pass or the pass is too old.
+func (s *SkillsLoader) snapshot() *skillsSnapshot {
+	stamp := s.stamp()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cache != nil && s.cache.stamp == stamp && time.Since(s.cache.at) < skillsRecheck {
+		return s.cache
+	}
+	skills := s.discover()
+	s.cache = &skillsSnapshot{stamp: stamp, at: time.Now(), skills: skills, summary: buildSkillsSummary(skills)}
+	return s.cache
+}
+
+// stamp fingerprints the skill roots by the mtimes of each root, skill
+// directory, SKILL.md and scripts directory. Adding, removing or editing a
+// skill changes it; reading it costs stats only.
+func (s *SkillsLoader) stamp() string {
+	var b strings.Builder
+	mark := func(path string) {
+		if fi, err := os.Stat(path); err == nil {
+			fmt.Fprintf(&b, "%s:%d:%d;", path, fi.ModTime().UnixNano(), fi.Size())
+		}
+	}
+	for _, src := range s.skillSources() {
+		if src.dir == "" {
+			continue
+		}
+		mark(src.dir)
+		entries, err := os.ReadDir(src.dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				dir := filepath.Join(src.dir, e.Name())
+				mark(dir)
+				mark(filepath.Join(dir, "SKILL.md"))
+				mark(filepath.Join(dir, "scripts"))
+			}
+		}
+	}
+	return b.String()
+}
+
+// discover reads every skill from disk.
+func (s *SkillsLoader) discover() []SkillInfo {
 	var skills []SkillInfo
 	seen := map[string]bool{}
 
-	// Workspace skills (highest priority)
-	if entries, err := os.ReadDir(s.WorkspaceSkills); err == nil {
+	// Workspace skills (highest priority), then builtins
+	for _, src := range s.skillSources() {
+		if src.dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(src.dir)
+		if err != nil {
+			continue
+		}
 		for _, e := range entries {
-			if !e.IsDir() {
+			// Hidden directories are install staging areas, not skills
+			if !e.IsDir() || seen[e.Name()] || strings.HasPrefix(e.Name(), ".") {
 				continue
 			}
-			skillFile := filepath.Join(s.WorkspaceSkills, e.Name(), "SKILL.md")
-			if _, err := os.Stat(skillFile); err == nil {
-				skills = append(skills, SkillInfo{Name: e.Name(), Path: skillFile, Source: "workspace"})
+			if info, ok := s.skillInfo(e.Name(), filepath.Join(src.dir, e.Name()), src.name); ok {
+				skills = append(skills, info)
 				seen[e.Name()] = true
 			}
 		}
 	}
+	return skills
+}
+
+// SkillDir returns the directory of skill name, where its bundled scripts
+// run (tools.SkillDirs).
+func (s *SkillsLoader) SkillDir(name string) (string, bool) {
+	dir, _, ok := s.skillDir(name)
+	return dir, ok
+}
+
+// skillDir finds skill name, workspace first.
+func (s *SkillsLoader) skillDir(name string) (dir, source string, ok bool) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", "", false
+	}
+	for _, src := range s.skillSources() {
+		if src.dir == "" {
+			continue
+		}
+		d := filepath.Join(src.dir, name)
+		if _, err := os.Stat(filepath.Join(d, "SKILL.md")); err == nil {
+			return d, src.name, true
+		}
+	}
+	return "", "", false
+}
 
-	// Builtin skills
-	if s.BuiltinSkills != "" {
-		if entries, err := os.ReadDir(s.BuiltinSkills); err == nil {
-			for _, e := range entries {
-				if !e.IsDir() || seen[e.Name()] {
-					continue
-				}
-				skillFile := filepath.Join(s.BuiltinSkills, e.Name(), "SKILL.md")
-				if _, err := os.Stat(skillFile); err == nil {
-					skills = append(skills, SkillInfo{Name: e.Name(), Path: skillFile, Source: "builtin"})
-				}
+// skillInfo reads the skill in dir.
+func (s *SkillsLoader) skillInfo(name, dir, source string) (SkillInfo, bool) {
+	path := filepath.Join(dir, "SKILL.md")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SkillInfo{}, false
+	}
+	meta, _, _ := parseFrontmatter(string(data))
+	info := SkillInfo{
+		Name:        name,
+		Path:        path,
+		Source:      source,
+		Description: meta.Description,
+		Always:      meta.Always,
+		Missing:     missingRequirements(meta.Requires),
+	}
+	if info.Description == "" {
+		info.Description = name
+	}
+	info.Available = len(info.Missing) == 0
+	if entries, err := os.ReadDir(filepath.Join(dir, "scripts")); err == nil {
+		for _, e := range entries {
+			if !e.IsDir() {
+				info.Scripts = append(info.Scripts, "scripts/"+e.Name())
 			}
 		}
 	}
-	return skills
+	return info, true
 }
 
 // LoadSkill loads a skill's content by name. Returns "" if not found.
 func (s *SkillsLoader) LoadSkill(name string) string {
-	// Workspace first
-	wPath := filepath.Join(s.WorkspaceSkills, name, "SKILL.md")
-	if data, err := os.ReadFile(wPath); err == nil {
-		return string(data)
+	dir, _, ok := s.skillDir(name)
+	if !ok {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "SKILL.md"))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// AlwaysSkills returns the skills inlined into every prompt: the configured
+// Always list, then skills whose frontmatter sets always. Skills with unmet
+// requirements are left out.
+func (s *SkillsLoader) AlwaysSkills() []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, name := range s.Always {
+		if info, ok := s.GetSkill(name); ok && info.Available && !seen[name] {
+			names = append(names, name)
+			seen[name] = true
+		}
 	}
-	// Builtin
-	if s.BuiltinSkills != "" {
-		bPath := filepath.Join(s.BuiltinSkills, name, "SKILL.md")
-		if data, err := os.ReadFile(bPath); err == nil {
-			return string(data)
+	var flagged []string
+	for _, info := range s.ListSkills() {
+		if info.Always && info.Available && !seen[info.Name] {
+			flagged = append(flagged, info.Name)
 		}
 	}
-	return ""
+	sort.Strings(flagged)
+	return append(names, flagged...)
 }
 
 // LoadSkillsForContext loads and formats specific skills for agent context.
 func (s *SkillsLoader) LoadSkillsForContext(names []string) string {
 	var parts []string
+	seen := map[string]bool{}
 	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
 		content := s.LoadSkill(name)
 		if content != "" {
 			content = stripFrontmatter(content)
@@ -98,7 +355,10 @@ func (s *SkillsLoader) LoadSkillsForContext(names []string) string {
 
 // BuildSkillsSummary returns an XML summary of all skills for progressive loading.
 func (s *SkillsLoader) BuildSkillsSummary() string {
-	skills := s.ListSkills()
+	return s.snapshot().summary
+}
+
+func buildSkillsSummary(skills []SkillInfo) string {
 	if len(skills) == 0 {
 		return ""
 	}
@@ -106,71 +366,46 @@ func (s *SkillsLoader) BuildSkillsSummary() string {
 	var lines []string
 	lines = append(lines, "<skills>")
 	for _, sk := range skills {
-		desc := s.getSkillDescription(sk.Name)
-		lines = append(lines, "  <skill available=\"true\">")
+		lines = append(lines, fmt.Sprintf("  <skill available=\"%t\">", sk.Available))
 		lines = append(lines, "    <name>"+escapeXML(sk.Name)+"</name>")
-		lines = append(lines, "    <description>"+escapeXML(desc)+"</description>")
+		lines = append(lines, "    <description>"+escapeXML(sk.Description)+"</description>")
 		lines = append(lines, "    <location>"+sk.Path+"</location>")
+		if len(sk.Missing) > 0 {
+			lines = append(lines, "    <requires>"+escapeXML(strings.Join(sk.Missing, ", "))+"</requires>")
+		}
+		if len(sk.Scripts) > 0 {
+			lines = append(lines, "    <scripts>"+escapeXML(strings.Join(sk.Scripts, ", "))+"</scripts>")
+		}
 		lines = append(lines, "  </skill>")
 	}
 	lines = append(lines, "</skills>")
 	return strings.Join(lines, "\n")
 }
 
-// GetSkillMetadata parses YAML frontmatter from a skill.
+// GetSkillMetadata parses YAML frontmatter from a skill. Scalar values are
+// returned as strings; use GetSkill for requirements and availability.
 func (s *SkillsLoader) GetSkillMetadata(name string) map[string]string {
 	content := s.LoadSkill(name)
-	if content == "" || !strings.HasPrefix(content, "---") {
-		return nil
-	}
-	re := regexp.MustCompile(`(?s)^---\n(.*?)\n---`)
-	match := re.FindStringSubmatch(content)
-	if match == nil {
+	_, raw, ok := parseFrontmatter(content)
+	if !ok {
 		return nil
 	}
 	meta := map[string]string{}
-	var lastKey string
-	for _, line := range strings.Split(match[1], "\n") {
-		if idx := strings.Index(line, ":"); idx > 0 && (len(line) == 0 || line[0] != ' ') {
-			key := strings.TrimSpace(line[:idx])
-			val := strings.TrimSpace(line[idx+1:])
-			val = strings.Trim(val, "\"'")
-			// YAML > (folded) and | (literal) markers — value continues on next lines
-			if val == ">" || val == "|" {
-				val = ""
-			}
-			meta[key] = val
-			lastKey = key
-		} else if lastKey != "" && len(line) > 0 && (line[0] == ' ' || line[0] == '\t') {
-			// Continuation line for multi-line YAML value
-			trimmed := strings.TrimSpace(line)
-			if meta[lastKey] == "" {
-				meta[lastKey] = trimmed
-			} else {
-				meta[lastKey] += " " + trimmed
-			}
-		} else {
-			lastKey = ""
+	for k, v := range raw {
+		switch v := v.(type) {
+		case map[string]any, []any, nil:
+		default:
+			meta[k] = strings.TrimSpace(fmt.Sprint(v))
 		}
 	}
 	return meta
 }
 
-func (s *SkillsLoader) getSkillDescription(name string) string {
-	meta := s.GetSkillMetadata(name)
-	if meta != nil {
-		if desc, ok := meta["description"]; ok && desc != "" {
-			return desc
-		}
-	}
-	return name
-}
-
 func stripFrontmatter(content string) string {
 	if !strings.HasPrefix(content, "---") {
 		return content
 	}
-	re := regexp.MustCompile(`(?s)^---\n.*?\n---\n`)
+	re := regexp.MustCompile(`(?s)^---\r?\n.*?\r?\n---\r?\n`)
 	return strings.TrimSpace(re.ReplaceAllString(content, ""))
 }
 
@@ -0,0 +1,200 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ErrSkillExists is returned by InstallSkill when a workspace skill of the
+// same name is already installed and force is not set.
+var ErrSkillExists = errors.New("skill already installed")
+
+var skillNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ValidateSkill checks the skill in dir and returns its problems; nil means
+// it can be installed. Unmet host requirements are not problems: such a
+// skill installs, but is listed as unavailable.
+func ValidateSkill(dir string) []string {
+	data, err := os.ReadFile(filepath.Join(dir, "SKILL.md"))
+	if err != nil {
+		return []string{"missing SKILL.md"}
+	}
+	content := string(data)
+	meta, _, ok := parseFrontmatter(content)
+	if !ok {
+		if frontmatterRe.MatchString(content) {
+			return []string{"frontmatter is not valid YAML"}
+		}
+		return []string{"missing YAML frontmatter"}
+	}
+
+	var problems []string
+	if strings.TrimSpace(meta.Description) == "" {
+		problems = append(problems, "frontmatter has no description")
+	}
+	if name := skillName(dir, meta); !skillNameRe.MatchString(name) {
+		problems = append(problems, fmt.Sprintf("invalid skill name %q", name))
+	}
+	if strings.TrimSpace(stripFrontmatter(content)) == "" {
+		problems = append(problems, "SKILL.md has no instructions after the frontmatter")
+	}
+	if fi, err := os.Stat(filepath.Join(dir, "scripts")); err == nil && !fi.IsDir() {
+		problems = append(problems, "scripts is not a directory")
+	}
+	return problems
+}
+
+// skillName is the frontmatter name, or the directory name without a
+// trailing .git.
+func skillName(dir string, meta SkillMeta) string {
+	if name := strings.TrimSpace(meta.Name); name != "" {
+		return name
+	}
+	return strings.TrimSuffix(filepath.Base(filepath.Clean(dir)), ".git")
+}
+
+// isGitSource reports whether src should be cloned rather than copied.
+func isGitSource(src string) bool {
+	for _, prefix := range []string{"file://", "git://", "ssh://", "http://", "https://", "git@"} {
+		if strings.HasPrefix(src, prefix) {
+			return true
+		}
+	}
+	// A bare repository on disk (e.g. a local mirror) has no SKILL.md
+	if _, err := os.Stat(filepath.Join(src, "SKILL.md")); err != nil {
+		_, err := os.Stat(filepath.Join(src, "HEAD"))
+		return err == nil
+	}
+	return false
+}
+
+// InstallSkill installs the skill at src into the workspace skills
+// directory. src is a skill directory or a git URL, such as a local mirror;
+// repositories are cloned shallowly with the git binary. The skill is
+// validated first, and an installed skill of the same name is only
+// replaced when force is set.
+func (s *SkillsLoader) InstallSkill(src string, force bool) (SkillInfo, error) {
+	dir := src
+	if isGitSource(src) {
+		tmp, err := os.MkdirTemp("", "nanobot-skill-")
+		if err != nil {
+			return SkillInfo{}, err
+		}
+		defer os.RemoveAll(tmp)
+		dir = filepath.Join(tmp, strings.TrimSuffix(filepath.Base(strings.TrimRight(src, "/")), ".git"))
+		out, err := exec.Command("git", "clone", "--depth", "1", "--quiet", "--", src, dir).CombinedOutput()
+		if err != nil {
+			return SkillInfo{}, fmt.Errorf("git clone %s: %v: %s", src, err, strings.TrimSpace(string(out)))
+		}
+	}
+
+	if problems := ValidateSkill(dir); len(problems) > 0 {
+		return SkillInfo{}, fmt.Errorf("invalid skill %s: %s", src, strings.Join(problems, "; "))
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "SKILL.md"))
+	meta, _, _ := parseFrontmatter(string(data))
+	name := skillName(dir, meta)
+
+	dest := filepath.Join(s.WorkspaceSkills, name)
+	if _, err := os.Stat(dest); err == nil && !force {
+		return SkillInfo{}, fmt.Errorf("%w: %s", ErrSkillExists, name)
+	}
+	if err := os.MkdirAll(s.WorkspaceSkills, 0o755); err != nil {
+		return SkillInfo{}, err
+	}
+
+	// Copy next to the destination, then swap it in
+	staging, err := os.MkdirTemp(s.WorkspaceSkills, "."+name+"-")
+	if err != nil {
+		return SkillInfo{}, err
+	}
+	defer os.RemoveAll(staging)
+	if err := os.Chmod(staging, 0o755); err != nil {
+		return SkillInfo{}, err
+	}
+	if err := copySkillDir(dir, staging); err != nil {
+		return SkillInfo{}, fmt.Errorf("copying skill: %w", err)
+	}
+	if err := os.RemoveAll(dest); err != nil {
+		return SkillInfo{}, err
+	}
+	if err := os.Rename(staging, dest); err != nil {
+		return SkillInfo{}, err
+	}
+
+	s.Invalidate()
+	info, _ := s.GetSkill(name)
+	return info, nil
+}
+
+// RemoveSkill deletes a workspace skill. Builtin skills cannot be removed.
+func (s *SkillsLoader) RemoveSkill(name string) error {
+	dir, source, ok := s.skillDir(name)
+	if !ok {
+		return fmt.Errorf("skill not found: %s", name)
+	}
+	if source != "workspace" {
+		return fmt.Errorf("skill %s is %s and cannot be removed", name, source)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	s.Invalidate()
+	return nil
+}
+
+// copySkillDir copies src into the existing directory dst, keeping file
+// modes (so scripts stay executable) and skipping VCS metadata.
+func copySkillDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if d.Name() == ".git" {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0o700)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			return nil // symlinks and devices are not copied
+		}
+	})
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateSkill(t *testing.T) {
+	dir := t.TempDir()
+	writeSkill(t, dir, "good", "---\ndescription: Good\n---\nDo it.")
+	writeSkill(t, dir, "nodesc", "---\nname: nodesc\n---\nDo it.")
+	writeSkill(t, dir, "nofm", "# Just markdown")
+	writeSkill(t, dir, "badyaml", "---\ndescription: [unclosed\n---\nDo it.")
+	writeSkill(t, dir, "badname", "---\nname: ../escape\ndescription: x\n---\nDo it.")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "empty"), 0o755))
+
+	assert.Empty(t, ValidateSkill(filepath.Join(dir, "good")))
+	assert.Equal(t, []string{"frontmatter has no description"}, ValidateSkill(filepath.Join(dir, "nodesc")))
+	assert.Equal(t, []string{"missing YAML frontmatter"}, ValidateSkill(filepath.Join(dir, "nofm")))
+	assert.Equal(t, []string{"frontmatter is not valid YAML"}, ValidateSkill(filepath.Join(dir, "badyaml")))
+	assert.Equal(t, []string{`invalid skill name "../escape"`}, ValidateSkill(filepath.Join(dir, "badname")))
+	assert.Equal(t, []string{"missing SKILL.md"}, ValidateSkill(filepath.Join(dir, "empty")))
+}
+
+func TestSkillsLoader_InstallAndRemove(t *testing.T) {
+	src := t.TempDir()
+	writeSkill(t, src, "deploy", "---\ndescription: Deploy\n---\nRun scripts/deploy.sh")
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "deploy", "scripts"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "deploy", "scripts", "deploy.sh"), []byte("echo ok"), 0o755))
+
+	ws := t.TempDir()
+	loader := NewSkillsLoader(ws, "")
+	assert.Equal(t, "", loader.BuildSkillsSummary())
+
+	info, err := loader.InstallSkill(filepath.Join(src, "deploy"), false)
+	require.NoError(t, err)
+	assert.Equal(t, "deploy", info.Name)
+	assert.Equal(t, "workspace", info.Source)
+	assert.Equal(t, []string{"scripts/deploy.sh"}, info.Scripts)
+	assert.Contains(t, loader.BuildSkillsSummary(), "<name>deploy</name>")
+
+	fi, err := os.Stat(filepath.Join(ws, "skills", "deploy", "scripts", "deploy.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o755), fi.Mode().Perm(), "scripts stay executable")
+
+	_, err = loader.InstallSkill(filepath.Join(src, "deploy"), false)
+	assert.True(t, errors.Is(err, ErrSkillExists))
+	_, err = loader.InstallSkill(filepath.Join(src, "deploy"), true)
+	assert.NoError(t, err)
+
+	require.NoError(t, loader.RemoveSkill("deploy"))
+	assert.Empty(t, loader.ListSkills())
+	assert.Error(t, loader.RemoveSkill("deploy"))
+}
+
+func TestSkillsLoader_InstallRejectsInvalid(t *testing.T) {
+	src := t.TempDir()
+	writeSkill(t, src, "bad", "no frontmatter")
+	loader := NewSkillsLoader(t.TempDir(), "")
+	_, err := loader.InstallSkill(filepath.Join(src, "bad"), false)
+	assert.ErrorContains(t, err, "missing YAML frontmatter")
+	assert.Empty(t, loader.ListSkills())
+}
+
+func TestSkillsLoader_InstallFromGitMirror(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := filepath.Join(t.TempDir(), "notes")
+	writeSkill(t, filepath.Dir(repo), "notes", "---\ndescription: Notes\n---\nTake notes.")
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=t", "-c", "user.email=t@t"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	git("init", "--quiet")
+	git("add", ".")
+	git("commit", "--quiet", "-m", "skill")
+	mirror := filepath.Join(t.TempDir(), "notes.git")
+	out, err := exec.Command("git", "clone", "--quiet", "--mirror", repo, mirror).CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	ws := t.TempDir()
+	info, err := NewSkillsLoader(ws, "").InstallSkill(mirror, false)
+	require.NoError(t, err)
+	assert.Equal(t, "notes", info.Name)
+	assert.NoDirExists(t, filepath.Join(ws, "skills", "notes", ".git"))
+}
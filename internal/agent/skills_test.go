@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -123,3 +124,102 @@ func TestStripFrontmatter_None(t *testing.T) {
 func TestEscapeXML(t *testing.T) {
 	assert.Equal(t, "&lt;b&gt;text&amp;more&lt;/b&gt;", escapeXML("<b>text&more</b>"))
 }
+
+func writeSkill(t *testing.T, dir, name, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, name), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name, "SKILL.md"), []byte(content), 0o644))
+}
+
+func TestSkillsLoader_Requirements(t *testing.T) {
+	ws := t.TempDir()
+	dir := filepath.Join(ws, "skills")
+	t.Setenv("NANOBOT_TEST_TOKEN", "x")
+	writeSkill(t, dir, "ok", "---\ndescription: Ready\nrequires:\n  bins: [sh]\n  env: [NANOBOT_TEST_TOKEN]\n---\nbody")
+	writeSkill(t, dir, "gh", "---\ndescription: GitHub\nmetadata: {\"nanobot\":{\"requires\":{\"bins\":[\"nanobot-no-such-bin\"],\"env\":[\"NANOBOT_TEST_MISSING\"]}}}\n---\nbody")
+
+	loader := NewSkillsLoader(ws, "")
+	ok, found := loader.GetSkill("ok")
+	require.True(t, found)
+	assert.True(t, ok.Available)
+	assert.Empty(t, ok.Missing)
+
+	gh, _ := loader.GetSkill("gh")
+	assert.False(t, gh.Available)
+	assert.Equal(t, []string{"bin:nanobot-no-such-bin", "env:NANOBOT_TEST_MISSING"}, gh.Missing)
+
+	xml := loader.BuildSkillsSummary()
+	assert.Contains(t, xml, `<skill available="false">`)
+	assert.Contains(t, xml, "<requires>bin:nanobot-no-such-bin, env:NANOBOT_TEST_MISSING</requires>")
+}
+
+func TestSkillsLoader_AlwaysSkills(t *testing.T) {
+	ws := t.TempDir()
+	dir := filepath.Join(ws, "skills")
+	writeSkill(t, dir, "style", "---\ndescription: House style\nalways: true\n---\nbody")
+	writeSkill(t, dir, "upstream", "---\nmetadata:\n  nanobot:\n    always: true\n---\nbody")
+	writeSkill(t, dir, "broken", "---\nalways: true\nrequires:\n  bins: [nanobot-no-such-bin]\n---\nbody")
+	writeSkill(t, dir, "notes", "---\ndescription: Notes\n---\nbody")
+
+	loader := NewSkillsLoader(ws, "")
+	loader.Always = []string{"notes", "style", "missing"}
+	assert.Equal(t, []string{"notes", "style", "upstream"}, loader.AlwaysSkills())
+}
+
+func TestSkillsLoader_Scripts(t *testing.T) {
+	ws := t.TempDir()
+	dir := filepath.Join(ws, "skills")
+	writeSkill(t, dir, "deploy", "---\ndescription: Deploy\n---\nRun scripts/deploy.sh")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "deploy", "scripts"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "deploy", "scripts", "deploy.sh"), []byte("echo ok"), 0o755))
+
+	loader := NewSkillsLoader(ws, "")
+	info, _ := loader.GetSkill("deploy")
+	assert.Equal(t, []string{"scripts/deploy.sh"}, info.Scripts)
+	assert.Contains(t, loader.BuildSkillsSummary(), "<scripts>scripts/deploy.sh</scripts>")
+
+	skillDir, ok := loader.SkillDir("deploy")
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(dir, "deploy"), skillDir)
+	_, ok = loader.SkillDir("../deploy")
+	assert.False(t, ok)
+}
+
+func TestSkillsLoader_CacheInvalidatesOnChange(t *testing.T) {
+	ws := t.TempDir()
+	dir := filepath.Join(ws, "skills")
+	writeSkill(t, dir, "notes", "---\ndescription: Notes\n---\nbody")
+
+	loader := NewSkillsLoader(ws, "")
+	first := loader.snapshot()
+	assert.Same(t, first, loader.snapshot(), "unchanged skills reuse the cached pass")
+	assert.Contains(t, loader.BuildSkillsSummary(), "<description>Notes</description>")
+
+	// Edit SKILL.md
+	path := filepath.Join(dir, "notes", "SKILL.md")
+	require.NoError(t, os.WriteFile(path, []byte("---\ndescription: Meeting notes\n---\nbody"), 0o644))
+	later := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(path, later, later))
+	assert.Contains(t, loader.BuildSkillsSummary(), "<description>Meeting notes</description>")
+
+	// Add a skill
+	writeSkill(t, dir, "todo", "---\ndescription: Todo\n---\nbody")
+	require.NoError(t, os.Chtimes(dir, later.Add(time.Second), later.Add(time.Second)))
+	assert.Contains(t, loader.BuildSkillsSummary(), "<name>todo</name>")
+}
+
+func TestSkillsLoader_RechecksRequirements(t *testing.T) {
+	ws := t.TempDir()
+	writeSkill(t, filepath.Join(ws, "skills"), "gh", "---\nrequires:\n  env: [NANOBOT_TEST_LATER]\n---\nbody")
+
+	loader := NewSkillsLoader(ws, "")
+	gh, _ := loader.GetSkill("gh")
+	require.False(t, gh.Available)
+
+	// The requirement is met without touching the skill files; once the
+	// pass is old enough the skill becomes available
+	t.Setenv("NANOBOT_TEST_LATER", "x")
+	loader.cache.at = time.Now().Add(-skillsRecheck)
+	gh, _ = loader.GetSkill("gh")
+	assert.True(t, gh.Available)
+}
@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/dayuer/nanobot-go/internal/providers"
+)
+
+// Agent progress event types, emitted in this order per iteration:
+// thinking → reasoning/delta* → tool_call → tool_result.
+const (
+	EventThinking   = "thinking"
+	EventReasoning  = "reasoning"
+	EventDelta      = "delta"
+	EventToolCall   = "tool_call"
+	EventToolResult = "tool_result"
+)
+
+// StreamEvent is a progress event emitted by the agent loop while it runs.
+type StreamEvent struct {
+	Type       string         `json:"type"`
+	Iteration  int            `json:"iteration"`
+	Content    string         `json:"content,omitempty"`
+	ToolName   string         `json:"toolName,omitempty"`
+	ToolCallID string         `json:"toolCallId,omitempty"`
+	Arguments  map[string]any `json:"arguments,omitempty"`
+}
+
+// StreamHandler receives agent progress events. It is called synchronously
+// from the agent loop goroutine and must not block for long.
+type StreamHandler func(StreamEvent)
+
+type streamHandlerKey struct{}
+
+// WithStreamHandler returns a context that makes the agent loop stream
+// progress events to h.
+func WithStreamHandler(ctx context.Context, h StreamHandler) context.Context {
+	return context.WithValue(ctx, streamHandlerKey{}, h)
+}
+
+// streamHandlerFrom returns the handler attached to ctx, or nil.
+func streamHandlerFrom(ctx context.Context) StreamHandler {
+	h, _ := ctx.Value(streamHandlerKey{}).(StreamHandler)
+	return h
+}
+
+// chatWithEvents calls the provider, streaming deltas to emit when set.
+// Without a handler it is a plain blocking Chat call.
+func chatWithEvents(ctx context.Context, p providers.LLMProvider, req providers.ChatRequest, iteration int, emit StreamHandler) (*providers.LLMResponse, error) {
+	if emit == nil {
+		return p.Chat(ctx, req)
+	}
+
+	events, err := providers.Stream(ctx, p, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var final *providers.LLMResponse
+	for ev := range events {
+		switch ev.Type {
+		case providers.StreamDelta:
+			emit(StreamEvent{Type: EventDelta, Iteration: iteration, Content: ev.Content})
+		case providers.StreamReasoning:
+			emit(StreamEvent{Type: EventReasoning, Iteration: iteration, Content: ev.Content})
+		case providers.StreamDone:
+			final = ev.Response
+		case providers.StreamError:
+			return nil, ev.Err
+		}
+	}
+	if final == nil {
+		return nil, ctx.Err()
+	}
+	return final, nil
+}
@@ -2,25 +2,108 @@ package agent
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/dayuer/nanobot-go/internal/bus"
 	"github.com/dayuer/nanobot-go/internal/providers"
 	"github.com/dayuer/nanobot-go/internal/tools"
+	"github.com/dayuer/nanobot-go/internal/utils"
 )
 
+// SubagentState is the lifecycle state of a subagent.
+type SubagentState string
+
+const (
+	SubagentRunning   SubagentState = "running"
+	SubagentCompleted SubagentState = "completed"
+	SubagentFailed    SubagentState = "failed"
+	SubagentCancelled SubagentState = "cancelled"
+)
+
+// maxFinishedSubagents bounds how many finished records are kept for
+// inspection; the oldest are dropped first.
+const maxFinishedSubagents = 100
+
+// Subagent errors.
+var (
+	ErrSubagentNotFound = errors.New("subagent not found") // unknown or expired ID
+	ErrSubagentLimit    = errors.New("too many running subagents")
+	ErrSubagentDepth    = errors.New("subagent nesting too deep")
+)
+
+// SubagentRecord describes one subagent run.
+type SubagentRecord struct {
+	ID            string        `json:"id"`
+	Label         string        `json:"label"`
+	Task          string        `json:"task"`
+	Status        SubagentState `json:"status"`
+	Profile       string        `json:"profile"`
+	ParentID      string        `json:"parentId,omitempty"` // the spawning subagent, if nested
+	Depth         int           `json:"depth"`              // 1 = spawned by the agent
+	OriginChannel string        `json:"originChannel,omitempty"`
+	OriginChatID  string        `json:"originChatId,omitempty"`
+	StartedAt     time.Time     `json:"startedAt"`
+	EndedAt       time.Time     `json:"endedAt"`
+	Iterations    int           `json:"iterations"`
+	ToolsUsed     []string      `json:"toolsUsed"`
+	Result        string        `json:"result,omitempty"`
+}
+
+// subagent is a record plus the handles that control it.
+type subagent struct {
+	rec     SubagentRecord
+	profile SubagentProfile
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	children  []*subagent // nested subagents, in spawn order
+	collected int         // children whose results were handed back
+}
+
+// SubagentRequest describes a subagent to start.
+type SubagentRequest struct {
+	Task          string
+	Label         string
+	Profile       string   // name in Profiles; "" = the "default" profile, if any
+	Tools         []string // narrows the profile's tools
+	Model         string   // overrides the profile's model
+	MaxIterations int      // lowers the profile's budget
+	OriginChannel string
+	OriginChatID  string
+	ParentID      string // the spawning subagent; "" when spawned by the agent
+}
+
 // SubagentManager manages background subagent execution.
 type SubagentManager struct {
-	Provider    providers.LLMProvider
-	Workspace   string
-	Bus         *bus.MessageBus
-	Model       string
-	MaxTokens   int
-	Temperature float64
+	Provider      providers.LLMProvider
+	Workspace     string
+	Bus           *bus.MessageBus
+	Model         string
+	MaxTokens     int
+	Temperature   float64
+	ToolTimeout   time.Duration // per-call tool deadline; 0 = tools.DefaultToolTimeout
+	MaxToolOutput int           // tool output cap in bytes; 0 = tools.DefaultMaxOutput
+	// ReasoningEffort and ReasoningBudget are passed on as for AgentLoop
+	ReasoningEffort string
+	ReasoningBudget int
+
+	// ToolFactory builds profile tools; nil = read/write/list files and web_fetch only
+	ToolFactory *tools.Factory
+	Profiles    map[string]SubagentProfile
+	// MaxConcurrent caps running subagents, nested ones included; 0 = DefaultMaxSubagents
+	MaxConcurrent int
 
-	mu      sync.Mutex
-	running map[string]context.CancelFunc
+	mu       sync.Mutex
+	agents   map[string]*subagent
+	finished []string // IDs of finished subagents, oldest first
 }
 
 // NewSubagentManager creates a SubagentManager.
@@ -32,77 +115,297 @@ func NewSubagentManager(provider providers.LLMProvider, workspace string, msgBus
 		Model:       model,
 		MaxTokens:   4096,
 		Temperature: 0.7,
-		running:     make(map[string]context.CancelFunc),
+		agents:      make(map[string]*subagent),
 	}
 }
 
-// Spawn starts a subagent in the background.
+// Spawn starts a subagent in the background and returns a message for the
+// calling agent.
 func (sm *SubagentManager) Spawn(ctx context.Context, task, label, originChannel, originChatID string) string {
-	taskID := fmt.Sprintf("sub-%d", sm.RunningCount()+1)
+	return sm.spawn(ctx, tools.SpawnRequest{Task: task, Label: label, Channel: originChannel, ChatID: originChatID}, "")
+}
+
+// spawn serves a spawn tool call from the agent (parentID "") or from
+// subagent parentID.
+func (sm *SubagentManager) spawn(ctx context.Context, req tools.SpawnRequest, parentID string) string {
+	rec, err := sm.Start(ctx, SubagentRequest{
+		Task:          req.Task,
+		Label:         req.Label,
+		Profile:       req.Profile,
+		Tools:         req.Tools,
+		Model:         req.Model,
+		MaxIterations: req.MaxIterations,
+		OriginChannel: req.Channel,
+		OriginChatID:  req.ChatID,
+		ParentID:      parentID,
+	})
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if parentID != "" {
+		return fmt.Sprintf("Subagent [%s] started (id: %s). Its result will be sent to you once you stop calling tools.", rec.Label, rec.ID)
+	}
+	return fmt.Sprintf("Subagent [%s] started (id: %s). I'll notify you when it completes.", rec.Label, rec.ID)
+}
+
+// Start starts a subagent in the background and returns its initial record.
+// A top-level subagent outlives ctx's cancellation and deadline (ctx is
+// usually a tool call); stop it with Cancel. A nested subagent (ParentID
+// set) runs under ctx, its parent's context, and is stopped with it.
+// Start fails when the profile is unknown or a depth or concurrency guard
+// trips.
+func (sm *SubagentManager) Start(ctx context.Context, req SubagentRequest) (SubagentRecord, error) {
+	profile, err := sm.resolveProfile(req)
+	if err != nil {
+		return SubagentRecord{}, err
+	}
+	label := req.Label
 	if label == "" {
-		if len(task) > 30 {
-			label = task[:30] + "..."
-		} else {
-			label = task
+		label = utils.TruncateRunes(req.Task, 30)
+		if label != req.Task {
+			label += "..."
 		}
 	}
 
-	subCtx, cancel := context.WithCancel(ctx)
 	sm.mu.Lock()
-	sm.running[taskID] = cancel
-	sm.mu.Unlock()
+	defer sm.mu.Unlock()
+	if sm.agents == nil {
+		sm.agents = make(map[string]*subagent)
+	}
+
+	depth := 1
+	var parent *subagent
+	if req.ParentID != "" {
+		parent = sm.agents[req.ParentID]
+		if parent == nil {
+			return SubagentRecord{}, fmt.Errorf("%w: %s", ErrSubagentNotFound, req.ParentID)
+		}
+		if !parent.profile.canSpawn(parent.rec.Depth) {
+			return SubagentRecord{}, ErrSubagentDepth
+		}
+		depth = parent.rec.Depth + 1
+	} else {
+		ctx = context.WithoutCancel(ctx)
+	}
+	limit := sm.MaxConcurrent
+	if limit <= 0 {
+		limit = DefaultMaxSubagents
+	}
+	if sm.runningLocked() >= limit {
+		return SubagentRecord{}, fmt.Errorf("%w (limit %d)", ErrSubagentLimit, limit)
+	}
+
+	id, err := sm.newID()
+	if err != nil {
+		return SubagentRecord{}, err
+	}
+
+	subCtx, cancel := context.WithCancel(ctx)
+	sa := &subagent{
+		rec: SubagentRecord{
+			ID:            id,
+			Label:         label,
+			Task:          req.Task,
+			Status:        SubagentRunning,
+			Profile:       profile.Name,
+			ParentID:      req.ParentID,
+			Depth:         depth,
+			OriginChannel: req.OriginChannel,
+			OriginChatID:  req.OriginChatID,
+			StartedAt:     time.Now(),
+			ToolsUsed:     []string{},
+		},
+		profile: profile,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	sm.agents[sa.rec.ID] = sa
+	if parent != nil {
+		parent.children = append(parent.children, sa)
+	}
 
 	go func() {
-		defer func() {
-			sm.mu.Lock()
-			delete(sm.running, taskID)
-			sm.mu.Unlock()
-			cancel()
-		}()
-		sm.runSubagent(subCtx, taskID, task, label, originChannel, originChatID)
+		defer cancel()
+		sm.runSubagent(subCtx, sa)
 	}()
+	return sa.snapshot(), nil
+}
+
+// newID returns an unused subagent ID. Callers hold sm.mu.
+func (sm *SubagentManager) newID() (string, error) {
+	b := make([]byte, 4)
+	for {
+		if _, err := rand.Read(b); err != nil {
+			return "", fmt.Errorf("subagent id: %w", err)
+		}
+		if id := "sub-" + hex.EncodeToString(b); sm.agents[id] == nil {
+			return id, nil
+		}
+	}
+}
+
+// snapshot copies the record. Callers hold sm.mu.
+func (sa *subagent) snapshot() SubagentRecord {
+	rec := sa.rec
+	rec.ToolsUsed = append([]string{}, sa.rec.ToolsUsed...)
+	return rec
+}
+
+// List returns every known subagent, oldest first.
+func (sm *SubagentManager) List() []SubagentRecord {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	out := make([]SubagentRecord, 0, len(sm.agents))
+	for _, sa := range sm.agents {
+		out = append(out, sa.snapshot())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartedAt.Before(out[j].StartedAt) })
+	return out
+}
+
+// Get returns the record of subagent id.
+func (sm *SubagentManager) Get(id string) (SubagentRecord, bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sa, ok := sm.agents[id]
+	if !ok {
+		return SubagentRecord{}, false
+	}
+	return sa.snapshot(), true
+}
+
+// Cancel stops a running subagent. Cancelling a finished subagent is a
+// no-op; unknown IDs return ErrSubagentNotFound.
+func (sm *SubagentManager) Cancel(id string) error {
+	sm.mu.Lock()
+	sa, ok := sm.agents[id]
+	sm.mu.Unlock()
+	if !ok {
+		return ErrSubagentNotFound
+	}
+	sa.cancel()
+	return nil
+}
+
+// Wait blocks until subagent id finishes or ctx is done, and returns its
+// latest record.
+func (sm *SubagentManager) Wait(ctx context.Context, id string) (SubagentRecord, error) {
+	sm.mu.Lock()
+	sa, ok := sm.agents[id]
+	sm.mu.Unlock()
+	if !ok {
+		return SubagentRecord{}, ErrSubagentNotFound
+	}
+	select {
+	case <-sa.done:
+	case <-ctx.Done():
+		rec, _ := sm.Get(id)
+		return rec, ctx.Err()
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sa.snapshot(), nil
+}
+
+// RunningCount returns the number of active subagents.
+func (sm *SubagentManager) RunningCount() int {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.runningLocked()
+}
+
+func (sm *SubagentManager) runningLocked() int {
+	n := 0
+	for _, sa := range sm.agents {
+		if sa.rec.Status == SubagentRunning {
+			n++
+		}
+	}
+	return n
+}
 
-	return fmt.Sprintf("Subagent [%s] started (id: %s). I'll notify you when it completes.", label, taskID)
+// finish records the outcome, prunes old records and wakes waiters.
+func (sm *SubagentManager) finish(sa *subagent, status SubagentState, result string) {
+	sm.mu.Lock()
+	sa.rec.Status = status
+	sa.rec.Result = result
+	sa.rec.EndedAt = time.Now()
+	sm.finished = append(sm.finished, sa.rec.ID)
+	for len(sm.finished) > maxFinishedSubagents {
+		delete(sm.agents, sm.finished[0])
+		sm.finished = sm.finished[1:]
+	}
+	sm.mu.Unlock()
+	close(sa.done)
 }
 
-func (sm *SubagentManager) runSubagent(ctx context.Context, taskID, task, label, channel, chatID string) {
-	// Build subagent tools (no message/spawn/cron)
-	registry := tools.NewRegistry()
-	registry.Register(&tools.ReadFileTool{})
-	registry.Register(&tools.WriteFileTool{})
-	registry.Register(&tools.ListDirTool{})
-	registry.Register(&tools.WebFetchTool{})
+func (sm *SubagentManager) runSubagent(ctx context.Context, sa *subagent) {
+	task := sa.rec.Task
+	profile := sa.profile
+
+	// Build the profile's tools; spawn only while nesting is allowed
+	dir := sm.workspaceFor(profile)
+	registry := sm.buildTools(profile, dir)
+	if profile.canSpawn(sa.rec.Depth) {
+		registry.Register(&tools.SpawnTool{
+			Profiles:      sm.profileNames(),
+			OriginChannel: sa.rec.OriginChannel,
+			OriginChatID:  sa.rec.OriginChatID,
+			SpawnCallback: func(req tools.SpawnRequest) (string, error) {
+				return sm.spawn(ctx, req, sa.rec.ID), nil
+			},
+		})
+	}
+	executor := newToolExecutor(registry, AgentConfig{ToolTimeout: sm.ToolTimeout, MaxToolOutput: sm.MaxToolOutput})
 
-	prompt := sm.buildSubagentPrompt(task)
+	model := profile.Model
+	if model == "" {
+		model = sm.Model
+	}
+	prompt := sm.buildSubagentPrompt(profile, dir, registry)
 	messages := []map[string]any{
 		{"role": "system", "content": prompt},
 		{"role": "user", "content": task},
 	}
 
-	maxIter := 15
+	maxIter := profile.MaxIterations
 	var finalResult string
+	status := SubagentCompleted
 
 	for i := 0; i < maxIter; i++ {
-		providerMsgs := make([]providers.Message, 0, len(messages))
-		for _, m := range messages {
-			role, _ := m["role"].(string)
-			content, _ := m["content"].(string)
-			providerMsgs = append(providerMsgs, providers.Message{Role: role, Content: content})
-		}
+		sm.mu.Lock()
+		sa.rec.Iterations = i + 1
+		sm.mu.Unlock()
 
 		resp, err := sm.Provider.Chat(ctx, providers.ChatRequest{
-			Messages:    providerMsgs,
+			Messages:    ToProviderMessages(messages),
 			Tools:       registry.Schemas(),
-			Model:       sm.Model,
+			Model:       model,
 			MaxTokens:   sm.MaxTokens,
 			Temperature: sm.Temperature,
+
+			ReasoningEffort: sm.ReasoningEffort,
+			ReasoningBudget: sm.ReasoningBudget,
 		})
 		if err != nil {
 			finalResult = fmt.Sprintf("Error: %v", err)
+			status = SubagentFailed
+			break
+		}
+		if resp.FinishReason == "error" {
+			finalResult = fmt.Sprintf("Error: %s", strings.TrimSpace(derefString(resp.Content)))
+			status = SubagentFailed
 			break
 		}
 
 		if !resp.HasToolCalls() {
+			// Hand back the results of nested subagents before finishing
+			if results := sm.collectChildren(ctx, sa); results != "" {
+				messages = append(messages,
+					map[string]any{"role": "assistant", "content": derefString(resp.Content)},
+					map[string]any{"role": "user", "content": results})
+				continue
+			}
 			if resp.Content != nil {
 				finalResult = *resp.Content
 			} else {
@@ -112,52 +415,76 @@ func (sm *SubagentManager) runSubagent(ctx context.Context, taskID, task, label,
 		}
 
 		// Execute tools
-		contentStr := ""
-		if resp.Content != nil {
-			contentStr = *resp.Content
-		}
-		messages = append(messages, map[string]any{
-			"role":    "assistant",
-			"content": contentStr,
-		})
+		messages = appendAssistantResponse(messages, resp)
 
 		for _, tc := range resp.ToolCalls {
-			tool := registry.Get(tc.Name)
-			var result string
-			if tool != nil {
-				result, err = tool.Execute(ctx, tc.Arguments)
-				if err != nil {
-					result = fmt.Sprintf("Error: %v", err)
-				}
-			} else {
-				result = fmt.Sprintf("Error: unknown tool %q", tc.Name)
-			}
+			sm.mu.Lock()
+			sa.rec.ToolsUsed = append(sa.rec.ToolsUsed, tc.Name)
+			sm.mu.Unlock()
+			result := executeCall(ctx, executor, tc)
 			messages = append(messages, map[string]any{
 				"role":         "tool",
 				"tool_call_id": tc.ID,
 				"name":         tc.Name,
-				"content":      result,
+				"content":      result.Output,
 			})
 		}
 	}
 
+	if ctx.Err() != nil {
+		sm.finish(sa, SubagentCancelled, "Cancelled.")
+		return
+	}
 	if finalResult == "" {
 		finalResult = "Task completed but no response was generated."
 	}
+	sm.finish(sa, status, finalResult)
 
-	// Announce result back via bus
-	if sm.Bus != nil {
-		sm.Bus.PublishInbound(bus.InboundMessage{
+	// Announce result back via bus; nested results go to the parent instead
+	if sm.Bus != nil && sa.rec.ParentID == "" {
+		if err := sm.Bus.PublishInbound(bus.InboundMessage{
 			Channel:  "system",
 			SenderID: "subagent",
-			ChatID:   channel + ":" + chatID,
-			Content:  fmt.Sprintf("[Subagent '%s' completed]\n\nTask: %s\n\nResult:\n%s", label, task, finalResult),
-		})
+			ChatID:   sa.rec.OriginChannel + ":" + sa.rec.OriginChatID,
+			Content:  fmt.Sprintf("[Subagent '%s' %s]\n\nTask: %s\n\nResult:\n%s", sa.rec.Label, status, task, finalResult),
+		}); err != nil {
+			log.Printf("[Subagent] ⚠️ Announcing %s: %v", sa.rec.ID, err)
+		}
+	}
+}
+
+// collectChildren waits for the nested subagents not yet handed back and
+// formats their results; "" when there are none or ctx is done.
+func (sm *SubagentManager) collectChildren(ctx context.Context, sa *subagent) string {
+	sm.mu.Lock()
+	pending := sa.children[sa.collected:]
+	sa.collected = len(sa.children)
+	sm.mu.Unlock()
+
+	var parts []string
+	for _, child := range pending {
+		select {
+		case <-child.done:
+		case <-ctx.Done():
+			return ""
+		}
+		sm.mu.Lock()
+		rec := child.snapshot()
+		sm.mu.Unlock()
+		parts = append(parts, fmt.Sprintf("[Subagent '%s' %s]\n\nTask: %s\n\nResult:\n%s", rec.Label, rec.Status, rec.Task, rec.Result))
 	}
+	return strings.Join(parts, "\n\n---\n\n")
 }
 
-func (sm *SubagentManager) buildSubagentPrompt(task string) string {
-	return fmt.Sprintf(`# Subagent
+func (sm *SubagentManager) buildSubagentPrompt(profile SubagentProfile, dir string, registry *tools.Registry) string {
+	var names []string
+	for _, t := range registry.All() {
+		names = append(names, t.Name())
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `# Subagent
 
 You are a subagent spawned by the main agent to complete a specific task.
 
@@ -166,17 +493,64 @@ You are a subagent spawned by the main agent to complete a specific task.
 2. Your final response will be reported back to the main agent
 3. Be concise but informative
 
-## What You Can Do
-- Read and write files in the workspace
-- Fetch web pages
+## Tools
+%s
 
 ## Workspace
-%s`, sm.Workspace)
+%s`, strings.Join(names, ", "), dir)
+	if registry.Get("spawn") != nil {
+		b.WriteString("\n\n## Delegation\nYou may spawn subagents for independent parts of the task. Their results are sent to you once you stop calling tools.")
+	}
+	if profile.Prompt != "" {
+		b.WriteString("\n\n## Instructions\n" + profile.Prompt)
+	}
+	return b.String()
 }
 
-// RunningCount returns the number of active subagents.
-func (sm *SubagentManager) RunningCount() int {
-	sm.mu.Lock()
-	defer sm.mu.Unlock()
-	return len(sm.running)
+// SubagentStatus reports one subagent, or all of them when id is empty
+// (tools.SubagentCallback).
+func (sm *SubagentManager) SubagentStatus(id string) (string, error) {
+	if id != "" {
+		rec, ok := sm.Get(id)
+		if !ok {
+			return "", fmt.Errorf("%w: %s", ErrSubagentNotFound, id)
+		}
+		return formatSubagent(rec, true), nil
+	}
+	recs := sm.List()
+	if len(recs) == 0 {
+		return "No subagents.", nil
+	}
+	var b strings.Builder
+	for _, rec := range recs {
+		b.WriteString(formatSubagent(rec, false))
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// CancelSubagent cancels subagent id (tools.SubagentCallback).
+func (sm *SubagentManager) CancelSubagent(id string) (string, error) {
+	if err := sm.Cancel(id); err != nil {
+		return "", fmt.Errorf("%w: %s", err, id)
+	}
+	return fmt.Sprintf("Cancellation requested for subagent %s.", id), nil
+}
+
+// formatSubagent renders a record for the agent; full adds the task and result.
+func formatSubagent(rec SubagentRecord, full bool) string {
+	elapsed := time.Since(rec.StartedAt)
+	if !rec.EndedAt.IsZero() {
+		elapsed = rec.EndedAt.Sub(rec.StartedAt)
+	}
+	line := fmt.Sprintf("- %s [%s] %s (%s, %d iterations, tools: %s)",
+		rec.ID, rec.Status, rec.Label, elapsed.Round(time.Second), rec.Iterations, strings.Join(rec.ToolsUsed, ", "))
+	if !full {
+		return line
+	}
+	line += "\n  Task: " + rec.Task
+	if rec.Result != "" {
+		line += "\n  Result: " + rec.Result
+	}
+	return line
 }
@@ -0,0 +1,177 @@
+package agent
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/dayuer/nanobot-go/internal/tools"
+)
+
+// Subagent defaults and guards.
+const (
+	DefaultSubagentIterations = 15
+	DefaultMaxSubagents       = 8 // running subagents per agent, nested ones included
+	MaxSubagentDepth          = 3 // nesting cap, whatever a profile asks for
+)
+
+// DefaultSubagentTools is the tool set of a profile that names none.
+var DefaultSubagentTools = []string{"read_file", "write_file", "list_dir", "web_fetch"}
+
+// SubagentProfile configures one kind of subagent (agents.yaml `subagents:`).
+// The profile named "default" applies to spawn calls that name none.
+type SubagentProfile struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	// Prompt is appended to the subagent system prompt
+	Prompt string `yaml:"prompt,omitempty" json:"prompt,omitempty"`
+	// Tools whitelists tool factory names; empty = DefaultSubagentTools
+	Tools []string `yaml:"tools,omitempty" json:"tools,omitempty"`
+	// Model overrides the agent's model
+	Model string `yaml:"model,omitempty" json:"model,omitempty"`
+	// MaxIterations is the LLM call budget; 0 = DefaultSubagentIterations
+	MaxIterations int `yaml:"max_iterations,omitempty" json:"maxIterations,omitempty"`
+	// Workspace is the working directory, relative to the agent workspace
+	Workspace string `yaml:"workspace,omitempty" json:"workspace,omitempty"`
+	// RestrictToWorkspace confines file and shell tools to Workspace
+	RestrictToWorkspace bool `yaml:"restrict_to_workspace,omitempty" json:"restrictToWorkspace,omitempty"`
+	// MaxDepth lets a subagent spawn its own while its depth (1 = spawned by
+	// the agent) is below MaxDepth; 0 or 1 = no nested spawn
+	MaxDepth int `yaml:"max_depth,omitempty" json:"maxDepth,omitempty"`
+}
+
+// canSpawn reports whether a subagent of this profile at depth may spawn.
+func (p SubagentProfile) canSpawn(depth int) bool {
+	return depth < p.MaxDepth && depth < MaxSubagentDepth
+}
+
+// resolveProfile returns the profile for req with req's overrides applied.
+// Overrides can only narrow the profile: Tools is intersected with the
+// profile's tools and MaxIterations can only lower its budget.
+func (sm *SubagentManager) resolveProfile(req SubagentRequest) (SubagentProfile, error) {
+	name := req.Profile
+	if name == "" {
+		name = "default"
+	}
+	p, ok := sm.Profiles[name]
+	if !ok && req.Profile != "" {
+		return SubagentProfile{}, fmt.Errorf("unknown subagent profile %q", req.Profile)
+	}
+	p.Name = name
+	if len(p.Tools) == 0 {
+		p.Tools = DefaultSubagentTools
+	}
+	if p.MaxIterations <= 0 {
+		p.MaxIterations = DefaultSubagentIterations
+	}
+
+	if len(req.Tools) > 0 {
+		var narrowed []string
+		for _, t := range req.Tools {
+			if tools.Whitelisted(p.Tools, t) {
+				narrowed = append(narrowed, t)
+			}
+		}
+		if len(narrowed) == 0 {
+			return SubagentProfile{}, fmt.Errorf("profile %q allows none of the tools %v", name, req.Tools)
+		}
+		p.Tools = narrowed
+	}
+	if req.Model != "" {
+		p.Model = req.Model
+	}
+	if req.MaxIterations > 0 && req.MaxIterations < p.MaxIterations {
+		p.MaxIterations = req.MaxIterations
+	}
+	return p, nil
+}
+
+// profileNames returns the names a spawn call may pick, sorted.
+func (sm *SubagentManager) profileNames() []string {
+	names := make([]string, 0, len(sm.Profiles))
+	for name := range sm.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// workspaceFor returns (and creates) the working directory of profile p.
+func (sm *SubagentManager) workspaceFor(p SubagentProfile) string {
+	dir := sm.Workspace
+	if p.Workspace != "" {
+		dir = p.Workspace
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(sm.Workspace, dir)
+		}
+		os.MkdirAll(dir, 0o755)
+	}
+	return dir
+}
+
+// buildTools builds profile p's tools from the tool factory, confined to dir
+// when the profile restricts them. Spawn is not built here: it depends on
+// the subagent's depth.
+func (sm *SubagentManager) buildTools(p SubagentProfile, dir string) *tools.Registry {
+	factory := sm.ToolFactory
+	if factory == nil {
+		factory = builtinSubagentTools()
+	}
+	var names []string
+	for _, name := range p.Tools {
+		if !loopToolNames[name] {
+			names = append(names, name)
+		}
+	}
+
+	reg := tools.NewRegistry()
+	if missing := factory.BuildInto(reg, names); len(missing) > 0 {
+		log.Printf("[Subagent] ⚠️ Unavailable tools skipped for profile %s: %v", p.Name, missing)
+	}
+	for _, t := range reg.All() {
+		confineTool(t, dir, p.RestrictToWorkspace)
+	}
+	return reg
+}
+
+// loopToolNames are tools bound to an agent loop rather than built by the
+// tool factory.
+var loopToolNames = map[string]bool{"spawn": true, "subagent_status": true, "subagent_cancel": true}
+
+// builtinSubagentTools is the factory used when the manager has none.
+func builtinSubagentTools() *tools.Factory {
+	f := tools.NewFactory()
+	f.Register("read_file", func() tools.Tool { return &tools.ReadFileTool{} })
+	f.Register("write_file", func() tools.Tool { return &tools.WriteFileTool{} })
+	f.Register("list_dir", func() tools.Tool { return &tools.ListDirTool{} })
+	f.Register("web_fetch", func() tools.Tool { return &tools.WebFetchTool{} })
+	return f
+}
+
+// confineTool points file and shell tools at dir; restrict also forbids
+// leaving it (file paths, exec's working_dir and skill directories).
+func confineTool(t tools.Tool, dir string, restrict bool) {
+	switch t := t.(type) {
+	case *tools.ExecTool:
+		t.WorkingDir = dir
+		t.RestrictToWorkspace = t.RestrictToWorkspace || restrict
+	case *tools.ReadFileTool:
+		if restrict {
+			t.AllowedDir = dir
+		}
+	case *tools.WriteFileTool:
+		if restrict {
+			t.AllowedDir = dir
+		}
+	case *tools.EditFileTool:
+		if restrict {
+			t.AllowedDir = dir
+		}
+	case *tools.ListDirTool:
+		if restrict {
+			t.AllowedDir = dir
+		}
+	}
+}
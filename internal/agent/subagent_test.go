@@ -2,12 +2,17 @@ package agent
 
 import (
 	"context"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/dayuer/nanobot-go/internal/bus"
 	"github.com/dayuer/nanobot-go/internal/providers"
+	"github.com/dayuer/nanobot-go/internal/tools"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSubagentManager_Spawn(t *testing.T) {
@@ -71,3 +76,236 @@ func TestSubagentManager_AnnouncesResult(t *testing.T) {
 		t.Fatal("timeout waiting for bus message")
 	}
 }
+
+func TestSubagentManager_RecordsRun(t *testing.T) {
+	mp := &mockProvider{
+		responses: []*providers.LLMResponse{
+			{ToolCalls: []providers.ToolCallRequest{
+				{ID: "call_1", Name: "list_dir", Arguments: map[string]any{"path": "."}},
+			}},
+			{Content: strP("Found 2 files"), FinishReason: "stop"},
+		},
+	}
+	sm := NewSubagentManager(mp, t.TempDir(), nil, "mock-model")
+
+	rec, err := sm.Start(context.Background(), SubagentRequest{Task: "List the workspace", Label: "ls"})
+	require.NoError(t, err)
+	assert.Equal(t, SubagentRunning, rec.Status)
+	rec, err = sm.Wait(context.Background(), rec.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, SubagentCompleted, rec.Status)
+	assert.Equal(t, "Found 2 files", rec.Result)
+	assert.Equal(t, 2, rec.Iterations)
+	assert.Equal(t, []string{"list_dir"}, rec.ToolsUsed)
+	assert.False(t, rec.EndedAt.IsZero())
+
+	// IDs stay unique once earlier subagents have finished
+	next, err := sm.Start(context.Background(), SubagentRequest{Task: "again"})
+	require.NoError(t, err)
+	assert.NotEqual(t, rec.ID, next.ID)
+	sm.Wait(context.Background(), next.ID)
+	assert.Len(t, sm.List(), 2)
+}
+
+// hangingProvider blocks every call until its context is cancelled.
+type hangingProvider struct{}
+
+func (hangingProvider) Chat(ctx context.Context, _ providers.ChatRequest) (*providers.LLMResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (hangingProvider) DefaultModel() string { return "mock-model" }
+
+func TestSubagentManager_Cancel(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	sm := NewSubagentManager(hangingProvider{}, t.TempDir(), msgBus, "mock-model")
+
+	// The subagent outlives the (tool call) context that started it
+	ctx, cancel := context.WithCancel(context.Background())
+	rec, err := sm.Start(ctx, SubagentRequest{Task: "watch"})
+	require.NoError(t, err)
+	cancel()
+	waitCtx, stop := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer stop()
+	_, err = sm.Wait(waitCtx, rec.ID)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, sm.RunningCount())
+
+	require.NoError(t, sm.Cancel(rec.ID))
+	rec, err = sm.Wait(context.Background(), rec.ID)
+	require.NoError(t, err)
+	assert.Equal(t, SubagentCancelled, rec.Status)
+	assert.Equal(t, 0, sm.RunningCount())
+	assert.Zero(t, msgBus.InboundSize(), "cancelled subagents are not announced")
+
+	assert.ErrorIs(t, sm.Cancel("sub-nope"), ErrSubagentNotFound)
+	_, ok := sm.Get("sub-nope")
+	assert.False(t, ok)
+}
+
+func TestSubagentManager_StatusAndCancelCallbacks(t *testing.T) {
+	sm := NewSubagentManager(hangingProvider{}, t.TempDir(), nil, "mock-model")
+	out, err := sm.SubagentStatus("")
+	require.NoError(t, err)
+	assert.Equal(t, "No subagents.", out)
+
+	rec, err := sm.Start(context.Background(), SubagentRequest{Task: "watch the logs", Label: "logs"})
+	require.NoError(t, err)
+	out, err = sm.SubagentStatus("")
+	require.NoError(t, err)
+	assert.Contains(t, out, rec.ID+" [running] logs")
+
+	_, err = sm.CancelSubagent(rec.ID)
+	require.NoError(t, err)
+	sm.Wait(context.Background(), rec.ID)
+	out, err = sm.SubagentStatus(rec.ID)
+	require.NoError(t, err)
+	assert.Contains(t, out, "[cancelled]")
+	assert.Contains(t, out, "Task: watch the logs")
+
+	_, err = sm.SubagentStatus("sub-nope")
+	assert.ErrorIs(t, err, ErrSubagentNotFound)
+}
+
+func TestNewAgentLoop_RegistersSubagentTools(t *testing.T) {
+	loop := NewAgentLoop(bus.NewMessageBus(), &mockProvider{}, AgentConfig{
+		Workspace:     t.TempDir(),
+		ToolFactory:   tools.NewFactory(),
+		ToolWhitelist: tools.AllTools,
+	})
+	assert.NotNil(t, loop.Tools.Get("spawn"))
+	assert.NotNil(t, loop.Tools.Get("subagent_status"))
+	assert.NotNil(t, loop.Tools.Get("subagent_cancel"))
+
+	loop = NewAgentLoop(bus.NewMessageBus(), &mockProvider{}, AgentConfig{
+		Workspace:     t.TempDir(),
+		ToolFactory:   tools.NewFactory(),
+		ToolWhitelist: []string{"spawn"},
+	})
+	assert.NotNil(t, loop.Tools.Get("spawn"))
+	assert.Nil(t, loop.Tools.Get("subagent_cancel"))
+
+	loop = NewAgentLoop(bus.NewMessageBus(), &mockProvider{}, AgentConfig{
+		Workspace:   t.TempDir(),
+		ToolFactory: tools.NewFactory(),
+	})
+	assert.Empty(t, loop.Tools.All(), "an empty whitelist grants no tools")
+}
+
+// scriptProvider answers with fn, guarded by a mutex for concurrent subagents.
+type scriptProvider struct {
+	mu sync.Mutex
+	fn func(req providers.ChatRequest) *providers.LLMResponse
+}
+
+func (p *scriptProvider) Chat(_ context.Context, req providers.ChatRequest) (*providers.LLMResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.fn(req), nil
+}
+
+func (p *scriptProvider) DefaultModel() string { return "mock-model" }
+
+func schemaNames(schemas []map[string]any) []string {
+	var names []string
+	for _, s := range schemas {
+		names = append(names, s["function"].(map[string]any)["name"].(string))
+	}
+	return names
+}
+
+func TestSubagentManager_Profiles(t *testing.T) {
+	var offered []string
+	var model, system string
+	p := &scriptProvider{fn: func(req providers.ChatRequest) *providers.LLMResponse {
+		offered, model, system = schemaNames(req.Tools), req.Model, req.Messages[0].Content
+		return &providers.LLMResponse{ToolCalls: []providers.ToolCallRequest{
+			{ID: "c", Name: "read_file", Arguments: map[string]any{"path": "../outside.txt"}},
+		}}
+	}}
+	ws := t.TempDir()
+	sm := NewSubagentManager(p, ws, nil, "mock-model")
+	sm.Profiles = map[string]SubagentProfile{
+		"reader": {Name: "reader", Tools: []string{"read_file", "list_dir"}, Model: "small", MaxIterations: 3,
+			Workspace: "scratch", RestrictToWorkspace: true, Prompt: "Only read."},
+	}
+
+	rec, err := sm.Start(context.Background(), SubagentRequest{Task: "read", Profile: "reader", Tools: []string{"read_file", "exec"}, MaxIterations: 10})
+	require.NoError(t, err)
+	rec, _ = sm.Wait(context.Background(), rec.ID)
+
+	assert.Equal(t, "reader", rec.Profile)
+	assert.Equal(t, 3, rec.Iterations, "a spawn call cannot raise the profile's budget")
+	assert.Equal(t, []string{"read_file"}, offered, "requested tools narrow the profile; exec is not in it")
+	assert.Equal(t, "small", model)
+	assert.Contains(t, system, filepath.Join(ws, "scratch"))
+	assert.Contains(t, system, "Only read.")
+	assert.DirExists(t, filepath.Join(ws, "scratch"))
+
+	_, err = sm.Start(context.Background(), SubagentRequest{Task: "x", Profile: "nope"})
+	assert.ErrorContains(t, err, "unknown subagent profile")
+	_, err = sm.Start(context.Background(), SubagentRequest{Task: "x", Profile: "reader", Tools: []string{"exec"}})
+	assert.ErrorContains(t, err, "allows none of the tools")
+}
+
+func TestSubagentManager_NestedSpawn(t *testing.T) {
+	p := &scriptProvider{fn: func(req providers.ChatRequest) *providers.LLMResponse {
+		last := req.Messages[len(req.Messages)-1]
+		switch {
+		case req.Messages[1].Content == "child task":
+			assert.NotContains(t, schemaNames(req.Tools), "spawn", "depth 2 is the profile's limit")
+			return &providers.LLMResponse{Content: strP("child result"), FinishReason: "stop"}
+		case last.Role == "user" && strings.Contains(last.Content, "child result"):
+			return &providers.LLMResponse{Content: strP("parent done with child result"), FinishReason: "stop"}
+		case len(req.Messages) == 2:
+			assert.Contains(t, schemaNames(req.Tools), "spawn")
+			return &providers.LLMResponse{ToolCalls: []providers.ToolCallRequest{
+				{ID: "c1", Name: "spawn", Arguments: map[string]any{"task": "child task", "label": "kid"}},
+			}}
+		default:
+			return &providers.LLMResponse{Content: strP("waiting"), FinishReason: "stop"}
+		}
+	}}
+	msgBus := bus.NewMessageBus()
+	sm := NewSubagentManager(p, t.TempDir(), msgBus, "mock-model")
+	sm.Profiles = map[string]SubagentProfile{"default": {MaxDepth: 2}}
+
+	rec, err := sm.Start(context.Background(), SubagentRequest{Task: "parent task", OriginChannel: "cli", OriginChatID: "direct"})
+	require.NoError(t, err)
+	rec, _ = sm.Wait(context.Background(), rec.ID)
+	assert.Equal(t, "parent done with child result", rec.Result)
+
+	all := sm.List()
+	require.Len(t, all, 2)
+	child := all[0]
+	if child.ID == rec.ID {
+		child = all[1]
+	}
+	assert.Equal(t, rec.ID, child.ParentID)
+	assert.Equal(t, 2, child.Depth)
+
+	// Only the top-level subagent is announced
+	msg := <-msgBus.Inbound
+	assert.Contains(t, msg.Content, "parent task")
+	assert.Eventually(t, func() bool { return msgBus.InboundSize() == 0 }, time.Second, 5*time.Millisecond)
+
+	_, err = sm.Start(context.Background(), SubagentRequest{Task: "grandchild", ParentID: child.ID})
+	assert.ErrorIs(t, err, ErrSubagentDepth)
+}
+
+func TestSubagentManager_ConcurrencyLimit(t *testing.T) {
+	sm := NewSubagentManager(hangingProvider{}, t.TempDir(), nil, "mock-model")
+	sm.MaxConcurrent = 1
+	first, err := sm.Start(context.Background(), SubagentRequest{Task: "a"})
+	require.NoError(t, err)
+	_, err = sm.Start(context.Background(), SubagentRequest{Task: "b"})
+	assert.ErrorIs(t, err, ErrSubagentLimit)
+	assert.Contains(t, sm.Spawn(context.Background(), "b", "", "cli", "direct"), "too many running subagents")
+
+	sm.Cancel(first.ID)
+	sm.Wait(context.Background(), first.ID)
+	_, err = sm.Start(context.Background(), SubagentRequest{Task: "b"})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,164 @@
+// Package bus — backend.go
+// Backends store bus messages from publish until they are acked, so a
+// durable backend can redeliver what a crash interrupted.
+package bus
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Bus topics.
+const (
+	TopicInbound  = "inbound"
+	TopicOutbound = "outbound"
+)
+
+// Entry is a stored message.
+type Entry struct {
+	ID       string          `json:"id"`
+	Data     json.RawMessage `json:"data"` // the encoded message
+	Attempts int             `json:"attempts,omitempty"`
+	// RetryAt holds back redelivery after a nack; zero = deliver now
+	RetryAt time.Time `json:"-"`
+}
+
+// DeadLetter is a message given up on, with the reason.
+type DeadLetter struct {
+	Entry
+	Reason string    `json:"reason"`
+	Time   time.Time `json:"time"`
+}
+
+// Backend stores messages per topic until they are acked or dead-lettered.
+// Implementations must be safe for concurrent use.
+type Backend interface {
+	// Append stores a new message and returns its ID.
+	Append(topic string, data []byte) (string, error)
+	// Delivered records a delivery attempt.
+	Delivered(topic, id string) error
+	// Nacked records that a failed message is not redelivered before retryAt.
+	Nacked(topic, id string, retryAt time.Time) error
+	// Ack removes a processed message.
+	Ack(topic, id string) error
+	// DeadLetter removes a message and keeps it, with reason, for inspection.
+	DeadLetter(topic string, e Entry, reason string) error
+	// Unacked returns the stored messages, oldest first. The bus redelivers
+	// them when it opens.
+	Unacked(topic string) ([]Entry, error)
+	// DeadLetters returns the topic's dead-lettered messages.
+	DeadLetters(topic string) ([]DeadLetter, error)
+	Close() error
+}
+
+// MemoryBackend keeps messages in memory; nothing survives a restart.
+type MemoryBackend struct {
+	mu     sync.Mutex
+	seq    int64
+	topics map[string]*memoryTopic
+}
+
+type memoryTopic struct {
+	order   []string
+	entries map[string]*Entry
+	dead    []DeadLetter
+}
+
+// NewMemoryBackend creates an empty in-memory backend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{topics: make(map[string]*memoryTopic)}
+}
+
+func (m *MemoryBackend) topic(name string) *memoryTopic {
+	t := m.topics[name]
+	if t == nil {
+		t = &memoryTopic{entries: make(map[string]*Entry)}
+		m.topics[name] = t
+	}
+	return t
+}
+
+func (m *MemoryBackend) Append(topic string, data []byte) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seq++
+	id := strconv.FormatInt(m.seq, 10)
+	t := m.topic(topic)
+	t.order = append(t.order, id)
+	t.entries[id] = &Entry{ID: id, Data: data}
+	return id, nil
+}
+
+func (m *MemoryBackend) Delivered(topic, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e := m.topic(topic).entries[id]; e != nil {
+		e.Attempts++
+	}
+	return nil
+}
+
+func (m *MemoryBackend) Nacked(topic, id string, retryAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e := m.topic(topic).entries[id]; e != nil {
+		e.RetryAt = retryAt
+	}
+	return nil
+}
+
+func (m *MemoryBackend) Ack(topic, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.topic(topic).remove(id)
+	return nil
+}
+
+func (m *MemoryBackend) DeadLetter(topic string, e Entry, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := m.topic(topic)
+	t.remove(e.ID)
+	t.dead = append(t.dead, DeadLetter{Entry: e, Reason: reason, Time: time.Now()})
+	return nil
+}
+
+func (m *MemoryBackend) Unacked(topic string) ([]Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := m.topic(topic)
+	out := make([]Entry, 0, len(t.entries))
+	for _, id := range t.order {
+		if e := t.entries[id]; e != nil {
+			out = append(out, *e)
+		}
+	}
+	return out, nil
+}
+
+func (m *MemoryBackend) DeadLetters(topic string) ([]DeadLetter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]DeadLetter(nil), m.topic(topic).dead...), nil
+}
+
+func (m *MemoryBackend) Close() error { return nil }
+
+// remove drops id, compacting the order list once it is mostly acked.
+func (t *memoryTopic) remove(id string) {
+	if _, ok := t.entries[id]; !ok {
+		return
+	}
+	delete(t.entries, id)
+	if len(t.order) > 64 && len(t.order) > 2*len(t.entries) {
+		live := t.order[:0]
+		for _, oid := range t.order {
+			if _, ok := t.entries[oid]; ok {
+				live = append(live, oid)
+			}
+		}
+		t.order = live
+	}
+}
@@ -5,13 +5,14 @@ import "time"
 
 // InboundMessage is received from a chat channel.
 type InboundMessage struct {
-	Channel   string            `json:"channel"`
-	SenderID  string            `json:"sender_id"`
-	ChatID    string            `json:"chat_id"`
-	Content   string            `json:"content"`
-	Timestamp time.Time         `json:"timestamp"`
-	Media     []string          `json:"media,omitempty"`
-	Metadata  map[string]any    `json:"metadata,omitempty"`
+	ID        string         `json:"id,omitempty"` // set by the bus on delivery; ack with it
+	Channel   string         `json:"channel"`
+	SenderID  string         `json:"sender_id"`
+	ChatID    string         `json:"chat_id"`
+	Content   string         `json:"content"`
+	Timestamp time.Time      `json:"timestamp"`
+	Media     []string       `json:"media,omitempty"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
 }
 
 // SessionKey returns the unique key for session identification.
@@ -21,6 +22,7 @@ func (m *InboundMessage) SessionKey() string {
 
 // OutboundMessage is sent to a chat channel.
 type OutboundMessage struct {
+	ID       string         `json:"id,omitempty"` // set by the bus on delivery
 	Channel  string         `json:"channel"`
 	ChatID   string         `json:"chat_id"`
 	Content  string         `json:"content"`
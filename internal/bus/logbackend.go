@@ -0,0 +1,367 @@
+// Package bus — logbackend.go
+// LogBackend: a durable Backend made of append-only JSON-lines logs.
+package bus
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dayuer/nanobot-go/internal/utils"
+)
+
+// compactAfter is how many superseded log records trigger a compaction.
+const compactAfter = 1024
+
+// LogBackend keeps each topic in Dir/<topic>.log, an append-only log of
+// add/try/nack/ack/dead records, and its dead letters in Dir/<topic>.dead.jsonl.
+// Appends are fsynced before Append returns. A log is compacted down to its
+// unacked messages when it is opened and as acks accumulate.
+type LogBackend struct {
+	Dir string
+
+	mu     sync.Mutex
+	topics map[string]*logTopic
+	closed bool
+}
+
+type logTopic struct {
+	path    string
+	file    *os.File
+	seq     int64
+	order   []string
+	entries map[string]*Entry
+	garbage int // records superseded since the last compaction
+}
+
+type logRecord struct {
+	Op       string          `json:"op"` // add, try, nack, ack or dead
+	ID       string          `json:"id"`
+	Data     json.RawMessage `json:"data,omitempty"`
+	Attempts int             `json:"attempts,omitempty"`
+	RetryAt  int64           `json:"retryAt,omitempty"` // Unix ms; nack, and add after compaction
+}
+
+// retryAt converts Entry.RetryAt to a record's RetryAt.
+func retryAt(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+// OpenLogBackend opens (creating if needed) a log backend in dir.
+func OpenLogBackend(dir string) (*LogBackend, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &LogBackend{Dir: dir, topics: make(map[string]*logTopic)}, nil
+}
+
+// topic returns the open log for name, loading it on first use. Callers
+// hold l.mu.
+func (l *LogBackend) topic(name string) (*logTopic, error) {
+	if l.closed {
+		return nil, ErrClosed
+	}
+	if t := l.topics[name]; t != nil {
+		return t, nil
+	}
+	t := &logTopic{path: filepath.Join(l.Dir, name+".log"), entries: make(map[string]*Entry)}
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+	if err := t.compact(); err != nil {
+		return nil, err
+	}
+	l.topics[name] = t
+	return t, nil
+}
+
+// load replays the log. A torn final record (a crash mid-write) is skipped.
+func (t *logTopic) load() error {
+	data, err := os.ReadFile(t.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var rec logRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			log.Printf("[Bus] ⚠️ %s:%d: skipping unreadable record: %v", t.path, n, err)
+			continue
+		}
+		t.apply(rec)
+	}
+	return sc.Err()
+}
+
+func (t *logTopic) apply(rec logRecord) {
+	switch rec.Op {
+	case "add":
+		if seq, err := strconv.ParseInt(rec.ID, 10, 64); err == nil && seq > t.seq {
+			t.seq = seq
+		}
+		if _, ok := t.entries[rec.ID]; !ok {
+			t.order = append(t.order, rec.ID)
+		}
+		e := &Entry{ID: rec.ID, Data: rec.Data, Attempts: rec.Attempts}
+		if rec.RetryAt != 0 {
+			e.RetryAt = time.UnixMilli(rec.RetryAt)
+		}
+		t.entries[rec.ID] = e
+	case "try":
+		if e := t.entries[rec.ID]; e != nil {
+			e.Attempts++
+			e.RetryAt = time.Time{}
+		}
+		t.garbage++
+	case "nack":
+		if e := t.entries[rec.ID]; e != nil {
+			e.RetryAt = time.UnixMilli(rec.RetryAt)
+		}
+		t.garbage++
+	case "ack", "dead":
+		delete(t.entries, rec.ID)
+		t.garbage += 2
+	}
+}
+
+// compact rewrites the log with only the unacked messages and reopens it
+// for appending.
+func (t *logTopic) compact() error {
+	var buf bytes.Buffer
+	live := t.order[:0]
+	for _, id := range t.order {
+		e := t.entries[id]
+		if e == nil {
+			continue
+		}
+		live = append(live, id)
+		line, err := json.Marshal(logRecord{Op: "add", ID: e.ID, Data: e.Data, Attempts: e.Attempts, RetryAt: retryAt(e.RetryAt)})
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	t.order = live
+	if t.file != nil {
+		t.file.Close()
+		t.file = nil
+	}
+	if err := utils.WriteFileAtomic(t.path, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(t.path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	t.file, t.garbage = f, 0
+	return nil
+}
+
+// write appends rec, syncing when durable is set.
+func (t *logTopic) write(rec logRecord, durable bool) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := t.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if durable {
+		return t.file.Sync()
+	}
+	return nil
+}
+
+// settle compacts once superseded records outweigh live ones.
+func (t *logTopic) settle() error {
+	if t.garbage > compactAfter && t.garbage > 2*len(t.entries) {
+		return t.compact()
+	}
+	return nil
+}
+
+func (l *LogBackend) Append(topic string, data []byte) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	t, err := l.topic(topic)
+	if err != nil {
+		return "", err
+	}
+	id := strconv.FormatInt(t.seq+1, 10)
+	if err := t.write(logRecord{Op: "add", ID: id, Data: data}, true); err != nil {
+		return "", fmt.Errorf("appending to %s: %w", t.path, err)
+	}
+	t.seq++
+	t.order = append(t.order, id)
+	t.entries[id] = &Entry{ID: id, Data: data}
+	return id, nil
+}
+
+func (l *LogBackend) Delivered(topic, id string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	t, err := l.topic(topic)
+	if err != nil {
+		return err
+	}
+	e := t.entries[id]
+	if e == nil {
+		return nil
+	}
+	if err := t.write(logRecord{Op: "try", ID: id}, false); err != nil {
+		return err
+	}
+	e.Attempts++
+	e.RetryAt = time.Time{}
+	t.garbage++
+	return t.settle()
+}
+
+func (l *LogBackend) Nacked(topic, id string, at time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	t, err := l.topic(topic)
+	if err != nil {
+		return err
+	}
+	e := t.entries[id]
+	if e == nil {
+		return nil
+	}
+	if err := t.write(logRecord{Op: "nack", ID: id, RetryAt: retryAt(at)}, false); err != nil {
+		return err
+	}
+	e.RetryAt = at
+	t.garbage++
+	return t.settle()
+}
+
+func (l *LogBackend) Ack(topic, id string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	t, err := l.topic(topic)
+	if err != nil {
+		return err
+	}
+	if t.entries[id] == nil {
+		return nil
+	}
+	if err := t.write(logRecord{Op: "ack", ID: id}, false); err != nil {
+		return err
+	}
+	delete(t.entries, id)
+	t.garbage += 2
+	return t.settle()
+}
+
+func (l *LogBackend) DeadLetter(topic string, e Entry, reason string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	t, err := l.topic(topic)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(DeadLetter{Entry: e, Reason: reason, Time: time.Now()})
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.deadPath(topic), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if t.entries[e.ID] == nil {
+		return nil
+	}
+	if err := t.write(logRecord{Op: "dead", ID: e.ID}, false); err != nil {
+		return err
+	}
+	delete(t.entries, e.ID)
+	t.garbage += 2
+	return t.settle()
+}
+
+func (l *LogBackend) Unacked(topic string) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	t, err := l.topic(topic)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Entry, 0, len(t.entries))
+	for _, id := range t.order {
+		if e := t.entries[id]; e != nil {
+			out = append(out, *e)
+		}
+	}
+	return out, nil
+}
+
+func (l *LogBackend) DeadLetters(topic string) ([]DeadLetter, error) {
+	data, err := os.ReadFile(l.deadPath(topic))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []DeadLetter
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var dl DeadLetter
+		if err := json.Unmarshal(line, &dl); err != nil {
+			continue
+		}
+		out = append(out, dl)
+	}
+	return out, nil
+}
+
+func (l *LogBackend) deadPath(topic string) string {
+	return filepath.Join(l.Dir, topic+".dead.jsonl")
+}
+
+// Close closes the topic logs.
+func (l *LogBackend) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var first error
+	for _, t := range l.topics {
+		if t.file != nil {
+			if err := t.file.Close(); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+	l.topics, l.closed = map[string]*logTopic{}, true
+	return first
+}
@@ -0,0 +1,151 @@
+package bus
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogBackend_RedeliversAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	lb, err := OpenLogBackend(dir)
+	require.NoError(t, err)
+	b, err := Open(lb, Options{})
+	require.NoError(t, err)
+
+	for _, c := range []string{"one", "two", "three"} {
+		require.NoError(t, b.PublishInbound(InboundMessage{Channel: "telegram", ChatID: "42", Content: c}))
+	}
+	first := receive(t, b.Inbound)
+	require.NoError(t, b.AckInbound(first.ID))
+	receive(t, b.Inbound) // "two" is delivered but never acked: the process crashes
+	require.NoError(t, b.Close())
+
+	lb, err = OpenLogBackend(dir)
+	require.NoError(t, err)
+	b, err = Open(lb, Options{})
+	require.NoError(t, err)
+	defer b.Close()
+
+	assert.Equal(t, 2, b.InboundSize())
+	two := receive(t, b.Inbound)
+	assert.Equal(t, "two", two.Content)
+	assert.Equal(t, "42", two.ChatID)
+	assert.Equal(t, "three", receive(t, b.Inbound).Content)
+	assert.Equal(t, uint64(1), b.Stats().Inbound.Redelivered)
+}
+
+func TestLogBackend_DeadLettersRepeatedCrashes(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 2; i++ {
+		lb, err := OpenLogBackend(dir)
+		require.NoError(t, err)
+		b, err := Open(lb, Options{MaxDeliveries: 2})
+		require.NoError(t, err)
+		if i == 0 {
+			require.NoError(t, b.PublishInbound(InboundMessage{Content: "poison"}))
+		}
+		receive(t, b.Inbound)
+		require.NoError(t, b.Close())
+	}
+
+	lb, err := OpenLogBackend(dir)
+	require.NoError(t, err)
+	b, err := Open(lb, Options{MaxDeliveries: 2})
+	require.NoError(t, err)
+	defer b.Close()
+
+	assert.Zero(t, b.InboundSize())
+	dead, err := b.DeadLetters(TopicInbound)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, 2, dead[0].Attempts)
+	assert.Contains(t, string(dead[0].Data), "poison")
+}
+
+func TestLogBackend_RestartKeepsRedeliveryBackoff(t *testing.T) {
+	dir := t.TempDir()
+	lb, err := OpenLogBackend(dir)
+	require.NoError(t, err)
+	b, err := Open(lb, Options{RedeliveryDelay: 3 * time.Minute})
+	require.NoError(t, err)
+	require.NoError(t, b.PublishInbound(InboundMessage{Content: "flaky"}))
+	require.NoError(t, b.NackInbound(receive(t, b.Inbound).ID, errors.New("provider down")))
+	require.NoError(t, b.Close())
+
+	lb, err = OpenLogBackend(dir)
+	require.NoError(t, err)
+	entries, err := lb.Unacked(TopicInbound)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.WithinDuration(t, time.Now().Add(3*time.Minute), entries[0].RetryAt, time.Minute)
+
+	b, err = Open(lb, Options{})
+	require.NoError(t, err)
+	defer b.Close()
+	assert.Zero(t, b.InboundSize(), "the backoff survives the restart")
+	assert.Equal(t, 1, b.Stats().Inbound.Delayed)
+}
+
+func TestLogBackend_SkipsTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	lb, err := OpenLogBackend(dir)
+	require.NoError(t, err)
+	id, err := lb.Append(TopicInbound, []byte(`{"content":"kept"}`))
+	require.NoError(t, err)
+	require.NoError(t, lb.Close())
+
+	path := filepath.Join(dir, TopicInbound+".log")
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"op":"add","id":"2","da`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	lb, err = OpenLogBackend(dir)
+	require.NoError(t, err)
+	defer lb.Close()
+	entries, err := lb.Unacked(TopicInbound)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, id, entries[0].ID)
+
+	// IDs keep increasing past the torn record
+	next, err := lb.Append(TopicInbound, []byte(`{}`))
+	require.NoError(t, err)
+	assert.Equal(t, "2", next)
+}
+
+func TestLogBackend_Compacts(t *testing.T) {
+	dir := t.TempDir()
+	lb, err := OpenLogBackend(dir)
+	require.NoError(t, err)
+	defer lb.Close()
+
+	for i := 0; i < compactAfter; i++ {
+		id, err := lb.Append(TopicOutbound, []byte(`{}`))
+		require.NoError(t, err)
+		require.NoError(t, lb.Delivered(TopicOutbound, id))
+		require.NoError(t, lb.Ack(TopicOutbound, id))
+	}
+	_, err = lb.Append(TopicOutbound, []byte(`{"content":"pending"}`))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, TopicOutbound+".log"))
+	require.NoError(t, err)
+	lines := strings.Count(string(data), "\n")
+	assert.Less(t, lines, compactAfter, "acked records are compacted away")
+
+	entries, err := lb.Unacked(TopicOutbound)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, strconv.Itoa(compactAfter+1), entries[0].ID)
+	assert.Contains(t, string(entries[0].Data), "pending")
+}
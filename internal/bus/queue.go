@@ -2,41 +2,166 @@ package bus
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"sync"
+	"time"
 )
 
+// DefaultCapacity is how many undelivered messages a topic queues before
+// publishers block.
+const DefaultCapacity = 1000
+
+// DefaultMaxDeliveries is how many times a message is delivered before it is
+// dead-lettered.
+const DefaultMaxDeliveries = 5
+
+// DefaultRedeliveryDelay is how long a nacked message waits before it is
+// redelivered; the wait doubles with each further attempt.
+const DefaultRedeliveryDelay = 2 * time.Second
+
+// maxRedeliveryDelay caps the redelivery backoff.
+const maxRedeliveryDelay = 5 * time.Minute
+
+// ErrClosed is returned when publishing to a closed bus.
+var ErrClosed = errors.New("message bus closed")
+
 // MessageBus provides async message routing between channels and the agent core.
 // Uses Go channels instead of Python's asyncio.Queue for natural concurrency.
+//
+// Messages are stored in a Backend from publish until the consumer acks
+// them, and handed to readers of Inbound/Outbound one at a time by a pump
+// goroutine that runs only while messages are waiting. Nacked messages are
+// redelivered after a backoff, unacked ones when a bus is opened on a
+// durable backend, and a message that keeps failing is dead-lettered after
+// MaxDeliveries.
 type MessageBus struct {
 	Inbound  chan InboundMessage
 	Outbound chan OutboundMessage
 
 	mu          sync.RWMutex
-	subscribers map[string][]func(OutboundMessage)
+	subscribers map[string][]func(OutboundMessage) error
 	cancel      context.CancelFunc
+
+	backend   Backend
+	in, out   *topicQueue
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Options tune a bus; zero values use the defaults.
+type Options struct {
+	Capacity      int // undelivered messages per topic before Publish blocks
+	MaxDeliveries int // deliveries before a message is dead-lettered
+	// RedeliveryDelay is the wait before a nacked message is redelivered,
+	// doubling per attempt; <0 redelivers at once
+	RedeliveryDelay time.Duration
 }
 
-// NewMessageBus creates a new message bus with buffered channels.
+// NewMessageBus creates a new in-memory message bus.
 func NewMessageBus() *MessageBus {
-	return &MessageBus{
-		Inbound:     make(chan InboundMessage, 100),
-		Outbound:    make(chan OutboundMessage, 100),
-		subscribers: make(map[string][]func(OutboundMessage)),
+	b, _ := Open(NewMemoryBackend(), Options{})
+	return b
+}
+
+// Open creates a bus on backend and queues the messages it holds unacked
+// for redelivery.
+func Open(backend Backend, opts Options) (*MessageBus, error) {
+	if opts.Capacity <= 0 {
+		opts.Capacity = DefaultCapacity
+	}
+	if opts.MaxDeliveries <= 0 {
+		opts.MaxDeliveries = DefaultMaxDeliveries
+	}
+	if opts.RedeliveryDelay == 0 {
+		opts.RedeliveryDelay = DefaultRedeliveryDelay
+	}
+	b := &MessageBus{
+		Inbound:     make(chan InboundMessage),
+		Outbound:    make(chan OutboundMessage),
+		subscribers: make(map[string][]func(OutboundMessage) error),
+		backend:     backend,
+		done:        make(chan struct{}),
+	}
+	b.in = newTopicQueue(TopicInbound, backend, opts)
+	b.in.run = func() { pump(b.in, b.Inbound, b.done, func(m *InboundMessage, id string) { m.ID = id }) }
+	b.out = newTopicQueue(TopicOutbound, backend, opts)
+	b.out.run = func() { pump(b.out, b.Outbound, b.done, func(m *OutboundMessage, id string) { m.ID = id }) }
+	for _, q := range []*topicQueue{b.in, b.out} {
+		if err := q.restore(); err != nil {
+			b.Close()
+			return nil, err
+		}
 	}
+	return b, nil
+}
+
+// Close stops delivery and closes the backend. Unacked messages stay in a
+// durable backend for the next Open.
+func (b *MessageBus) Close() error {
+	var err error
+	b.closeOnce.Do(func() {
+		close(b.done)
+		b.in.close()
+		b.out.close()
+		// a message just handed over must be recorded first
+		b.in.pumps.Wait()
+		b.out.pumps.Wait()
+		err = b.backend.Close()
+	})
+	return err
 }
 
 // PublishInbound sends a message from a channel to the agent.
-func (b *MessageBus) PublishInbound(msg InboundMessage) {
-	b.Inbound <- msg
+func (b *MessageBus) PublishInbound(msg InboundMessage) error {
+	return publish(b.in, msg)
 }
 
 // PublishOutbound sends a response from the agent to channels.
-func (b *MessageBus) PublishOutbound(msg OutboundMessage) {
-	b.Outbound <- msg
+func (b *MessageBus) PublishOutbound(msg OutboundMessage) error {
+	return publish(b.out, msg)
+}
+
+func publish(q *topicQueue, msg any) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if err := q.publish(data); err != nil {
+		log.Printf("[Bus] ❌ Publishing %s message: %v", q.name, err)
+		return err
+	}
+	return nil
 }
 
+// AckInbound marks an inbound message processed.
+func (b *MessageBus) AckInbound(id string) error { return b.in.ack(id) }
+
+// NackInbound returns an inbound message for redelivery after a backoff, or
+// dead-letters it once it has been delivered MaxDeliveries times.
+func (b *MessageBus) NackInbound(id string, reason error) error { return b.in.nack(id, reason) }
+
+// AckOutbound marks an outbound message sent.
+func (b *MessageBus) AckOutbound(id string) error { return b.out.ack(id) }
+
+// NackOutbound returns an outbound message for redelivery after a backoff,
+// or dead-letters it once it has been delivered MaxDeliveries times.
+func (b *MessageBus) NackOutbound(id string, reason error) error { return b.out.nack(id, reason) }
+
 // Subscribe registers a callback for outbound messages on a specific channel.
 func (b *MessageBus) Subscribe(channel string, callback func(OutboundMessage)) {
+	b.SubscribeAcked(channel, func(msg OutboundMessage) error {
+		callback(msg)
+		return nil
+	})
+}
+
+// SubscribeAcked registers a callback for outbound messages on a channel.
+// A message is acked once every callback succeeds; an error sends it back
+// for redelivery.
+func (b *MessageBus) SubscribeAcked(channel string, callback func(OutboundMessage) error) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	b.subscribers[channel] = append(b.subscribers[channel], callback)
@@ -52,8 +177,16 @@ func (b *MessageBus) DispatchOutbound(ctx context.Context) {
 			b.mu.RLock()
 			subs := b.subscribers[msg.Channel]
 			b.mu.RUnlock()
+			var errs []error
 			for _, cb := range subs {
-				cb(msg)
+				if err := cb(msg); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			if err := errors.Join(errs...); err != nil {
+				b.NackOutbound(msg.ID, err)
+			} else {
+				b.AckOutbound(msg.ID)
 			}
 		}
 	}
@@ -61,10 +194,337 @@ func (b *MessageBus) DispatchOutbound(ctx context.Context) {
 
 // InboundSize returns the number of pending inbound messages.
 func (b *MessageBus) InboundSize() int {
-	return len(b.Inbound)
+	return b.in.depth()
 }
 
 // OutboundSize returns the number of pending outbound messages.
 func (b *MessageBus) OutboundSize() int {
-	return len(b.Outbound)
+	return b.out.depth()
+}
+
+// DeadLetters returns the messages given up on for topic (TopicInbound or
+// TopicOutbound).
+func (b *MessageBus) DeadLetters(topic string) ([]DeadLetter, error) {
+	return b.backend.DeadLetters(topic)
+}
+
+// TopicStats are delivery and backpressure counters for one topic.
+type TopicStats struct {
+	Depth        int           `json:"depth"`     // published, not yet delivered
+	InFlight     int           `json:"inFlight"`  // delivered, not yet acked
+	Delayed      int           `json:"delayed"`   // nacked, waiting out the redelivery backoff
+	HighWater    int           `json:"highWater"` // largest Depth seen
+	Capacity     int           `json:"capacity"`
+	Published    uint64        `json:"published"`
+	Delivered    uint64        `json:"delivered"`
+	Acked        uint64        `json:"acked"`
+	Redelivered  uint64        `json:"redelivered"`
+	DeadLettered uint64        `json:"deadLettered"`
+	Blocked      uint64        `json:"blocked"`     // publishes that waited for space
+	BlockedTime  time.Duration `json:"blockedTime"` // total time publishers waited
+}
+
+// Stats reports the bus's queues.
+type Stats struct {
+	Inbound  TopicStats `json:"inbound"`
+	Outbound TopicStats `json:"outbound"`
+}
+
+// Stats returns a snapshot of the delivery and backpressure counters.
+func (b *MessageBus) Stats() Stats {
+	return Stats{Inbound: b.in.snapshot(), Outbound: b.out.snapshot()}
+}
+
+// topicQueue orders one topic's messages between publish and delivery and
+// tracks those delivered but not acked.
+type topicQueue struct {
+	name            string
+	backend         Backend
+	capacity        int
+	maxDeliveries   int
+	redeliveryDelay time.Duration
+	run             func() // pumps the backlog to consumers until it is empty
+
+	mu       sync.Mutex
+	notFull  *sync.Cond
+	backlog  []Entry
+	pumping  bool // a pump goroutine is running
+	pumps    sync.WaitGroup
+	handing  bool // the pump holds an entry it is handing over
+	inflight map[string]Entry
+	delayed  map[string]*time.Timer // nacked entries waiting out their backoff
+	closed   bool
+	stats    TopicStats
+}
+
+func newTopicQueue(name string, backend Backend, opts Options) *topicQueue {
+	q := &topicQueue{
+		name:            name,
+		backend:         backend,
+		capacity:        opts.Capacity,
+		maxDeliveries:   opts.MaxDeliveries,
+		redeliveryDelay: opts.RedeliveryDelay,
+		inflight:        make(map[string]Entry),
+		delayed:         make(map[string]*time.Timer),
+	}
+	q.notFull = sync.NewCond(&q.mu)
+	return q
+}
+
+// restore queues the backend's unacked messages, dead-lettering those
+// already delivered MaxDeliveries times (e.g. ones that crash the process).
+// Nacked messages still wait out the backoff recorded before a restart.
+func (q *topicQueue) restore() error {
+	entries, err := q.backend.Unacked(q.name)
+	if err != nil {
+		return err
+	}
+	var pending []Entry
+	for _, e := range entries {
+		if e.Attempts >= q.maxDeliveries {
+			q.deadLetter(e, fmt.Sprintf("unacked after %d deliveries", e.Attempts))
+			continue
+		}
+		pending = append(pending, e)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, e := range pending {
+		if e.Attempts > 0 {
+			q.stats.Redelivered++
+		}
+		q.scheduleLocked(e, e.RetryAt)
+	}
+	if n := len(pending); n > 0 {
+		log.Printf("[Bus] 🔁 Redelivering %d unacked %s messages", n, q.name)
+	}
+	q.stats.HighWater = len(q.backlog)
+	return nil
+}
+
+func (q *topicQueue) publish(data []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.backlog) >= q.capacity && !q.closed {
+		q.stats.Blocked++
+		log.Printf("[Bus] ⚠️ %s queue full (%d), publisher waiting", q.name, q.capacity)
+		start := time.Now()
+		for len(q.backlog) >= q.capacity && !q.closed {
+			q.notFull.Wait()
+		}
+		q.stats.BlockedTime += time.Since(start)
+	}
+	if q.closed {
+		return ErrClosed
+	}
+	id, err := q.backend.Append(q.name, data)
+	if err != nil {
+		return err
+	}
+	q.backlog = append(q.backlog, Entry{ID: id, Data: data})
+	q.stats.Published++
+	if d := q.depthLocked(); d > q.stats.HighWater {
+		q.stats.HighWater = d
+	}
+	q.wakeLocked()
+	return nil
+}
+
+// wakeLocked starts a pump when messages are waiting and none is running.
+// Callers hold q.mu.
+func (q *topicQueue) wakeLocked() {
+	if q.pumping || q.closed || len(q.backlog) == 0 || q.run == nil {
+		return
+	}
+	q.pumping = true
+	q.pumps.Add(1)
+	go func() {
+		defer q.pumps.Done()
+		q.run()
+	}()
+}
+
+// next returns the next message to deliver, or false once the backlog is
+// empty or the queue is closed, which ends the pump. The message counts as
+// in flight from here, so a consumer may ack it before handed runs.
+func (q *topicQueue) next() (Entry, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed || len(q.backlog) == 0 {
+		q.pumping = false
+		return Entry{}, false
+	}
+	e := q.backlog[0]
+	q.backlog = q.backlog[1:]
+	e.Attempts++
+	e.RetryAt = time.Time{}
+	q.inflight[e.ID] = e
+	q.handing = true
+	q.notFull.Signal()
+	return e, true
+}
+
+// handed records that e reached a consumer.
+func (q *topicQueue) handed(e Entry) {
+	if err := q.backend.Delivered(q.name, e.ID); err != nil {
+		log.Printf("[Bus] ⚠️ Recording delivery of %s/%s: %v", q.name, e.ID, err)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handing = false
+	q.stats.Delivered++
+}
+
+// putBack returns an entry the pump could not hand over to the front.
+func (q *topicQueue) putBack(e Entry) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.inflight, e.ID)
+	e.Attempts--
+	q.handing = false
+	q.pumping = false
+	q.backlog = append([]Entry{e}, q.backlog...)
+}
+
+func (q *topicQueue) ack(id string) error {
+	q.mu.Lock()
+	if _, ok := q.inflight[id]; ok {
+		delete(q.inflight, id)
+		q.stats.Acked++
+	}
+	q.mu.Unlock()
+	return q.backend.Ack(q.name, id)
+}
+
+func (q *topicQueue) nack(id string, reason error) error {
+	q.mu.Lock()
+	e, ok := q.inflight[id]
+	if !ok {
+		q.mu.Unlock()
+		return nil
+	}
+	delete(q.inflight, id)
+	q.mu.Unlock()
+	if e.Attempts >= q.maxDeliveries {
+		return q.deadLetter(e, fmt.Sprintf("failed %d deliveries: %v", e.Attempts, reason))
+	}
+
+	// Record the backoff first, so a restart keeps waiting it out
+	retryAt := time.Now().Add(q.backoff(e.Attempts))
+	err := q.backend.Nacked(q.name, id, retryAt)
+	if err != nil {
+		log.Printf("[Bus] ⚠️ Recording nack of %s/%s: %v", q.name, id, err)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.stats.Redelivered++
+		q.scheduleLocked(e, retryAt)
+	}
+	return err
+}
+
+// backoff is the wait before redelivering a message nacked after attempts
+// deliveries: RedeliveryDelay, doubled per further attempt, capped.
+func (q *topicQueue) backoff(attempts int) time.Duration {
+	if q.redeliveryDelay <= 0 {
+		return 0
+	}
+	d := q.redeliveryDelay
+	for i := 1; i < attempts && d < maxRedeliveryDelay; i++ {
+		d *= 2
+	}
+	return min(d, maxRedeliveryDelay)
+}
+
+// scheduleLocked queues e for delivery at the given time, or now if that
+// has passed. Callers hold q.mu.
+func (q *topicQueue) scheduleLocked(e Entry, at time.Time) {
+	wait := time.Until(at)
+	if wait <= 0 {
+		q.backlog = append(q.backlog, e)
+		q.wakeLocked()
+		return
+	}
+	q.delayed[e.ID] = time.AfterFunc(wait, func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if q.closed {
+			return
+		}
+		delete(q.delayed, e.ID)
+		q.backlog = append(q.backlog, e)
+		q.wakeLocked()
+	})
+}
+
+func (q *topicQueue) deadLetter(e Entry, reason string) error {
+	log.Printf("[Bus] 💀 Dead-lettering %s message %s: %s", q.name, e.ID, reason)
+	q.mu.Lock()
+	q.stats.DeadLettered++
+	q.mu.Unlock()
+	return q.backend.DeadLetter(q.name, e, reason)
+}
+
+func (q *topicQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	for id, t := range q.delayed {
+		t.Stop()
+		delete(q.delayed, id)
+	}
+	q.notFull.Broadcast()
+}
+
+func (q *topicQueue) depth() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.depthLocked()
+}
+
+func (q *topicQueue) depthLocked() int {
+	if q.handing {
+		return len(q.backlog) + 1
+	}
+	return len(q.backlog)
+}
+
+func (q *topicQueue) snapshot() TopicStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	s := q.stats
+	s.Depth = q.depthLocked()
+	s.InFlight = len(q.inflight)
+	s.Delayed = len(q.delayed)
+	s.Capacity = q.capacity
+	return s
+}
+
+// pump hands q's messages, decoded, to readers of ch until done closes.
+func pump[M any](q *topicQueue, ch chan<- M, done <-chan struct{}, setID func(*M, string)) {
+	for {
+		e, ok := q.next()
+		if !ok {
+			return
+		}
+		var msg M
+		if err := json.Unmarshal(e.Data, &msg); err != nil {
+			q.mu.Lock()
+			delete(q.inflight, e.ID)
+			q.handing = false
+			q.mu.Unlock()
+			q.deadLetter(e, fmt.Sprintf("undecodable: %v", err))
+			continue
+		}
+		setID(&msg, e.ID)
+		select {
+		case ch <- msg:
+			q.handed(e)
+		case <-done:
+			q.putBack(e)
+			return
+		}
+	}
 }
@@ -2,11 +2,13 @@ package bus
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewMessageBus(t *testing.T) {
@@ -95,3 +97,174 @@ func TestMessageBus_ConcurrentPublish(t *testing.T) {
 	wg.Wait()
 	assert.Equal(t, 100, bus.InboundSize())
 }
+
+func receive(t *testing.T, ch <-chan InboundMessage) InboundMessage {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("no message delivered")
+		return InboundMessage{}
+	}
+}
+
+func TestMessageBus_AckAndNack(t *testing.T) {
+	b, err := Open(NewMemoryBackend(), Options{MaxDeliveries: 2, RedeliveryDelay: -1})
+	require.NoError(t, err)
+	defer b.Close()
+
+	require.NoError(t, b.PublishInbound(InboundMessage{Content: "ok"}))
+	require.NoError(t, b.PublishInbound(InboundMessage{Content: "poison"}))
+
+	ok := receive(t, b.Inbound)
+	assert.NotEmpty(t, ok.ID)
+	require.NoError(t, b.AckInbound(ok.ID))
+
+	// Nacked once: redelivered, then dead-lettered on the second failure
+	msg := receive(t, b.Inbound)
+	assert.Equal(t, "poison", msg.Content)
+	require.NoError(t, b.NackInbound(msg.ID, errors.New("boom")))
+	again := receive(t, b.Inbound)
+	assert.Equal(t, msg.ID, again.ID)
+	require.NoError(t, b.NackInbound(again.ID, errors.New("boom")))
+
+	dead, err := b.DeadLetters(TopicInbound)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, msg.ID, dead[0].ID)
+	assert.Contains(t, dead[0].Reason, "boom")
+
+	s := b.Stats().Inbound
+	assert.Equal(t, uint64(2), s.Published)
+	assert.Equal(t, uint64(3), s.Delivered)
+	assert.Equal(t, uint64(1), s.Acked)
+	assert.Equal(t, uint64(1), s.Redelivered)
+	assert.Equal(t, uint64(1), s.DeadLettered)
+	assert.Zero(t, s.InFlight)
+	assert.Zero(t, s.Depth)
+}
+
+func TestMessageBus_NackBacksOff(t *testing.T) {
+	b, err := Open(NewMemoryBackend(), Options{RedeliveryDelay: 100 * time.Millisecond})
+	require.NoError(t, err)
+	defer b.Close()
+
+	require.NoError(t, b.PublishInbound(InboundMessage{Content: "flaky"}))
+	msg := receive(t, b.Inbound)
+	nacked := time.Now()
+	require.NoError(t, b.NackInbound(msg.ID, errors.New("provider down")))
+	assert.Equal(t, 1, b.Stats().Inbound.Delayed)
+
+	// A message published meanwhile is not held up by the delayed one
+	require.NoError(t, b.PublishInbound(InboundMessage{Content: "fresh"}))
+	assert.Equal(t, "fresh", receive(t, b.Inbound).Content)
+
+	again := receive(t, b.Inbound)
+	assert.Equal(t, msg.ID, again.ID)
+	assert.GreaterOrEqual(t, time.Since(nacked), 100*time.Millisecond)
+	assert.Zero(t, b.Stats().Inbound.Delayed)
+
+	// The second redelivery waits twice as long
+	nacked = time.Now()
+	require.NoError(t, b.NackInbound(again.ID, errors.New("provider down")))
+	receive(t, b.Inbound)
+	assert.GreaterOrEqual(t, time.Since(nacked), 200*time.Millisecond)
+}
+
+func TestMessageBus_PumpsStopWhenIdle(t *testing.T) {
+	b := NewMessageBus()
+	defer b.Close()
+	idle := func() bool {
+		b.in.mu.Lock()
+		defer b.in.mu.Unlock()
+		return !b.in.pumping
+	}
+	assert.True(t, idle(), "no pump runs before anything is published")
+
+	require.NoError(t, b.PublishInbound(InboundMessage{Content: "hi"}))
+	b.AckInbound(receive(t, b.Inbound).ID)
+	assert.Eventually(t, idle, time.Second, time.Millisecond)
+
+	require.NoError(t, b.PublishInbound(InboundMessage{Content: "again"}))
+	assert.Equal(t, "again", receive(t, b.Inbound).Content)
+}
+
+func TestMessageBus_Backpressure(t *testing.T) {
+	b, err := Open(NewMemoryBackend(), Options{Capacity: 2})
+	require.NoError(t, err)
+	defer b.Close()
+
+	// The pump holds one message, so capacity+1 publishes fit without a reader
+	require.NoError(t, b.PublishInbound(InboundMessage{Content: "msg"}))
+	require.Eventually(t, func() bool {
+		b.in.mu.Lock()
+		defer b.in.mu.Unlock()
+		return b.in.handing
+	}, time.Second, time.Millisecond)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, b.PublishInbound(InboundMessage{Content: "msg"}))
+	}
+	done := make(chan error)
+	go func() { done <- b.PublishInbound(InboundMessage{Content: "blocked"}) }()
+
+	select {
+	case <-done:
+		t.Fatal("publish should block while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	receive(t, b.Inbound)
+	require.NoError(t, <-done)
+
+	s := b.Stats().Inbound
+	assert.Equal(t, uint64(1), s.Blocked)
+	assert.Positive(t, s.BlockedTime)
+	assert.Equal(t, 3, s.HighWater)
+	assert.Equal(t, 2, s.Capacity)
+}
+
+func TestMessageBus_CloseUnblocksPublishers(t *testing.T) {
+	b, err := Open(NewMemoryBackend(), Options{Capacity: 1})
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, b.PublishInbound(InboundMessage{}))
+	}
+	done := make(chan error)
+	go func() { done <- b.PublishInbound(InboundMessage{}) }()
+	time.Sleep(20 * time.Millisecond)
+
+	require.NoError(t, b.Close())
+	assert.ErrorIs(t, <-done, ErrClosed)
+	assert.ErrorIs(t, b.PublishOutbound(OutboundMessage{}), ErrClosed)
+}
+
+func TestMessageBus_FailedSendIsRedelivered(t *testing.T) {
+	b, err := Open(NewMemoryBackend(), Options{RedeliveryDelay: time.Millisecond})
+	require.NoError(t, err)
+	defer b.Close()
+
+	var mu sync.Mutex
+	attempts := 0
+	b.SubscribeAcked("telegram", func(msg OutboundMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts == 1 {
+			return errors.New("network down")
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.DispatchOutbound(ctx)
+
+	require.NoError(t, b.PublishOutbound(OutboundMessage{Channel: "telegram", Content: "reply"}))
+	assert.Eventually(t, func() bool {
+		return b.Stats().Outbound.Acked == 1
+	}, time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 2, attempts)
+}
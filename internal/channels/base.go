@@ -3,6 +3,7 @@ package channels
 
 import (
 	"context"
+	"log"
 	"strings"
 
 	"github.com/dayuer/nanobot-go/internal/bus"
@@ -74,5 +75,7 @@ func (b *BaseChannel) HandleMessage(senderID, chatID, content string, media []st
 		Media:    media,
 		Metadata: metadata,
 	}
-	b.Bus.PublishInbound(msg)
+	if err := b.Bus.PublishInbound(msg); err != nil {
+		log.Printf("[%s] ⚠️ Dropped message from %s: %v", b.ChannelName, senderID, err)
+	}
 }
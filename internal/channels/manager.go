@@ -59,10 +59,12 @@ func (m *Manager) StartAll(ctx context.Context) error {
 	for name, ch := range m.channels {
 		chName := name
 		channel := ch
-		m.Bus.Subscribe(chName, func(msg bus.OutboundMessage) {
+		m.Bus.SubscribeAcked(chName, func(msg bus.OutboundMessage) error {
 			if err := channel.Send(msg); err != nil {
 				log.Printf("Error sending to %s: %v", chName, err)
+				return err
 			}
+			return nil
 		})
 	}
 
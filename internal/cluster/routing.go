@@ -1,17 +1,16 @@
 package cluster
 
-// routing.go — keyword routing, user memory injection, and route info formatting.
+// routing.go — keyword routing, user memory scoping, and route info formatting.
 // Mirrors survival/nanobot/server.py: _route_superdriver, _inject_user_memory,
 // _build_route_info, _format_route_header, _check_mention.
 
 import (
 	"context"
 	"fmt"
-	"log"
 	"strings"
-	"time"
 
-	nanoredis "github.com/dayuer/nanobot-go/internal/redis"
+	"github.com/dayuer/nanobot-go/internal/agent"
+	"github.com/dayuer/nanobot-go/internal/lane"
 	"github.com/dayuer/nanobot-go/internal/router"
 )
 
@@ -109,21 +108,16 @@ func (s *Server) resolveRoute(ctx context.Context, content, roleID string) (reso
 	return "general", "default", nil
 }
 
-// injectUserMemory reads user's personal memory from Redis and prepends
-// it to the message content. Mirrors Python _inject_user_memory.
-func injectUserMemory(ctx context.Context, personID, content string) string {
-	if personID == "" || !nanoredis.IsAvailable() {
-		return content
+// memoryScope returns the memory scope for a request: the person's memory
+// when the caller identifies one, else the session's, so anonymous users of
+// the shared API never see each other's memory. Mirrors Python
+// _inject_user_memory: with Redis memory the person layer still reads
+// mem:<personId>, which the user_memory tool writes.
+func memoryScope(req lane.ChatRequest) agent.MemoryScope {
+	if req.PersonID != "" {
+		return agent.MemoryScope{PersonID: req.PersonID}
 	}
-
-	key := nanoredis.MemoryKey(personID)
-	memory := nanoredis.CacheGet(ctx, key)
-	if memory == "" {
-		return content
-	}
-
-	// Prepend memory context to the message
-	return fmt.Sprintf("[当前用户的个人记忆]\n%s\n\n---\n\n%s", memory, content)
+	return agent.MemoryScope{SessionKey: req.SessionKey}
 }
 
 // RouteInfo describes the routing decision for API responses.
@@ -131,11 +125,12 @@ type RouteInfo struct {
 	AgentID     string   `json:"agentId"`
 	AgentName   string   `json:"agentName,omitempty"`
 	Description string   `json:"description,omitempty"`
-	Method      string   `json:"method"`      // "keyword" | "llm" | "mention" | "explicit" | "default"
+	Method      string   `json:"method"` // "keyword" | "llm" | "mention" | "explicit" | "default"
 	Reason      string   `json:"reason,omitempty"`
 	Related     []string `json:"related,omitempty"`
 	Domains     []string `json:"domains,omitempty"`
 	Summary     string   `json:"summary,omitempty"`
+	Consulted   []string `json:"consulted,omitempty"` // experts whose answers informed the reply
 }
 
 // buildRouteInfo constructs the route info for API responses.
@@ -206,6 +201,9 @@ func (s *Server) formatRouteHeader(info RouteInfo) string {
 		}
 		b.WriteString(fmt.Sprintf("> 🔗 相关专家: %s\n", strings.Join(names, " · ")))
 	}
+	if len(info.Consulted) > 0 {
+		b.WriteString(fmt.Sprintf("> 🤝 已咨询: %s\n", strings.Join(info.Consulted, " · ")))
+	}
 
 	return b.String()
 }
@@ -229,39 +227,3 @@ func extractAgentName(roleID, desc string) string {
 	}
 	return desc
 }
-
-// stripThinking removes leaked internal reasoning from LLM responses.
-// Mirrors Python _strip_thinking.
-func stripThinking(text string) string {
-	// Remove common thinking patterns
-	patterns := []string{
-		"**Reflection**", "**Next Steps**", "**Analysis**",
-		"**思考过程**", "**推理过程**", "**内部分析**",
-	}
-
-	for _, p := range patterns {
-		idx := strings.Index(text, p)
-		if idx >= 0 {
-			// Find the section end (next double newline or end)
-			rest := text[idx:]
-			end := strings.Index(rest, "\n\n")
-			if end >= 0 {
-				text = text[:idx] + text[idx+end+2:]
-			} else {
-				text = text[:idx]
-			}
-		}
-	}
-
-	return strings.TrimSpace(text)
-}
-
-// saveUserMemoryUpdate stores updated user memory to Redis.
-func saveUserMemoryUpdate(ctx context.Context, personID, memory string) {
-	if personID == "" || memory == "" || !nanoredis.IsAvailable() {
-		return
-	}
-	key := nanoredis.MemoryKey(personID)
-	nanoredis.CacheSet(ctx, key, memory, 24*time.Hour)
-	log.Printf("[Memory] Saved memory for %s (%d chars)", personID, len(memory))
-}
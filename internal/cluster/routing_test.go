@@ -0,0 +1,19 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/dayuer/nanobot-go/internal/agent"
+	"github.com/dayuer/nanobot-go/internal/lane"
+)
+
+func TestMemoryScope(t *testing.T) {
+	got := memoryScope(lane.ChatRequest{PersonID: "42", SessionKey: "api:1"})
+	if got != (agent.MemoryScope{PersonID: "42"}) {
+		t.Errorf("with person: got %+v", got)
+	}
+	got = memoryScope(lane.ChatRequest{SessionKey: "api:1"})
+	if got != (agent.MemoryScope{SessionKey: "api:1"}) {
+		t.Errorf("anonymous: got %+v", got)
+	}
+}
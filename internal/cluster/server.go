@@ -13,9 +13,11 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gorilla/websocket"
 
+	"github.com/dayuer/nanobot-go/internal/agent"
 	"github.com/dayuer/nanobot-go/internal/confighub"
 	"github.com/dayuer/nanobot-go/internal/events"
 	"github.com/dayuer/nanobot-go/internal/lane"
@@ -25,11 +27,11 @@ import (
 
 // Server is the Survival HTTP API server.
 type Server struct {
-	port       int
-	apiKey     string
-	instanceID string
-	registry   *registry.Registry
-	configHub  *confighub.ConfigHub
+	port        int
+	apiKey      string
+	instanceID  string
+	registry    *registry.Registry
+	configHub   *confighub.ConfigHub
 	laneManager *lane.Manager
 
 	// Routing
@@ -102,6 +104,8 @@ func NewServer(cfg ServerConfig) *Server {
 	s.mux.HandleFunc("/api/config", s.withAuth(s.handleConfig))
 	s.mux.HandleFunc("/api/events", s.withAuth(s.handleEvents))
 	s.mux.HandleFunc("/api/roles", s.withAuth(s.handleRoles))
+	s.mux.HandleFunc("/api/subagents", s.withAuth(s.handleSubagents))
+	s.mux.HandleFunc("/api/subagents/", s.withAuth(s.handleSubagent))
 
 	return s
 }
@@ -197,11 +201,11 @@ func (s *Server) handleStatus(w http.ResponseWriter, _ *http.Request) {
 func (s *Server) handleLoad(w http.ResponseWriter, _ *http.Request) {
 	avgMs, recentCount := s.latencyWin.Avg()
 	writeJSON(w, map[string]any{
-		"activeRequests":  s.activeRequests.Load(),
-		"totalRequests":   s.totalRequests.Load(),
-		"avgLatencyMs":    avgMs,
-		"recentRequests":  recentCount,
-		"windowSeconds":   60,
+		"activeRequests": s.activeRequests.Load(),
+		"totalRequests":  s.totalRequests.Load(),
+		"avgLatencyMs":   avgMs,
+		"recentRequests": recentCount,
+		"windowSeconds":  60,
 	})
 }
 
@@ -235,6 +239,10 @@ func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
 	if req.SessionKey == "" {
 		req.SessionKey = fmt.Sprintf("%s:%s", req.Channel, req.ChatID)
 	}
+	if err := (agent.MemoryScope{PersonID: req.PersonID, SessionKey: req.SessionKey}).Validate(); err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	s.activeRequests.Add(1)
 	start := time.Now()
@@ -275,7 +283,7 @@ func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleChatStream is the SSE streaming chat endpoint.
-// SSE events: thinking → tool_call → tool_result → done / error
+// SSE events: routing → thinking → reasoning / delta / tool_call → tool_result → done / error
 // Mirrors Python handle_chat_stream().
 func (s *Server) handleChatStream(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -295,6 +303,10 @@ func (s *Server) handleChatStream(w http.ResponseWriter, r *http.Request) {
 	if req.SessionKey == "" {
 		req.SessionKey = fmt.Sprintf("%s:%s", req.Channel, req.ChatID)
 	}
+	if err := (agent.MemoryScope{PersonID: req.PersonID, SessionKey: req.SessionKey}).Validate(); err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// Set SSE headers
 	w.Header().Set("Content-Type", "text/event-stream")
@@ -309,12 +321,25 @@ func (s *Server) handleChatStream(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Helper: send one SSE event
+	// Helper: send one SSE event. Events may arrive from the lane worker
+	// goroutine, so writes are serialized and dropped once the handler returns.
+	var sseMu sync.Mutex
+	sseClosed := false
 	sendSSE := func(event string, data any) {
+		sseMu.Lock()
+		defer sseMu.Unlock()
+		if sseClosed {
+			return
+		}
 		payload, _ := json.Marshal(data)
 		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
 		flusher.Flush()
 	}
+	defer func() {
+		sseMu.Lock()
+		sseClosed = true
+		sseMu.Unlock()
+	}()
 
 	s.activeRequests.Add(1)
 	start := time.Now()
@@ -332,11 +357,26 @@ func (s *Server) handleChatStream(w http.ResponseWriter, r *http.Request) {
 	// Send routing event
 	sendSSE("routing", routeInfo)
 
-	// Send thinking event
-	sendSSE("thinking", map[string]any{
-		"role":      roleID,
-		"iteration": 1,
-	})
+	// Agent progress → SSE events (thinking / delta / tool_call / tool_result)
+	var progressMu sync.Mutex
+	iterations := 0
+	toolsUsed := []string{}
+	onEvent := func(event string, data any) {
+		ev, ok := data.(agent.StreamEvent)
+		if !ok {
+			sendSSE(event, data)
+			return
+		}
+		progressMu.Lock()
+		if ev.Iteration > iterations {
+			iterations = ev.Iteration
+		}
+		if ev.Type == agent.EventToolCall {
+			toolsUsed = append(toolsUsed, ev.ToolName)
+		}
+		progressMu.Unlock()
+		sendSSE(event, sseEventPayload(roleID, ev))
+	}
 
 	// Submit to lane (blocks until completion)
 	mode := lane.Mode(req.Mode)
@@ -348,6 +388,7 @@ func (s *Server) handleChatStream(w http.ResponseWriter, r *http.Request) {
 		PersonID:   req.PersonID,
 		RoleID:     req.RoleID,
 		Metadata:   req.Metadata,
+		Events:     onEvent,
 	}, mode)
 
 	if err != nil {
@@ -367,18 +408,56 @@ func (s *Server) handleChatStream(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Send done event
-	sendSSE("done", map[string]any{
+	progressMu.Lock()
+	doneIterations, doneTools := iterations, toolsUsed
+	progressMu.Unlock()
+	done := map[string]any{
 		"content":    result.Content,
 		"role":       result.AgentID,
 		"sessionKey": req.SessionKey,
-		"iterations": 1,
-		"toolsUsed":  []string{},
+		"iterations": doneIterations,
+		"toolsUsed":  doneTools,
 		"latencyMs":  time.Since(start).Milliseconds(),
-	})
+	}
+	if info, ok := result.RouteInfo.(*RouteInfo); ok && len(info.Consulted) > 0 {
+		done["consulted"] = info.Consulted
+	}
+	sendSSE("done", done)
+}
+
+// sseResultPreviewLen caps tool_result payloads sent over SSE.
+const sseResultPreviewLen = 2000
+
+// sseEventPayload converts an agent progress event into its SSE data payload.
+func sseEventPayload(roleID string, ev agent.StreamEvent) map[string]any {
+	data := map[string]any{"iteration": ev.Iteration}
+	switch ev.Type {
+	case agent.EventThinking:
+		data["role"] = roleID
+	case agent.EventDelta, agent.EventReasoning:
+		data["content"] = ev.Content
+	case agent.EventToolCall:
+		data["tool"] = ev.ToolName
+		data["toolCallId"] = ev.ToolCallID
+		data["arguments"] = ev.Arguments
+	case agent.EventToolResult:
+		result := ev.Content
+		if len(result) > sseResultPreviewLen {
+			cut := sseResultPreviewLen
+			for cut > 0 && !utf8.RuneStart(result[cut]) {
+				cut--
+			}
+			result = result[:cut] + "..."
+		}
+		data["tool"] = ev.ToolName
+		data["toolCallId"] = ev.ToolCallID
+		data["result"] = result
+	}
+	return data
 }
 
 // laneHandler is the actual chat processing function called by the lane worker.
-// It performs routing, user memory injection, agent dispatch, and response cleanup.
+// It performs routing, memory scoping, expert consultation and agent dispatch.
 func (s *Server) laneHandler(ctx context.Context, req lane.ChatRequest) lane.ChatResult {
 	if s.registry == nil {
 		return lane.ChatResult{Error: "no agent registry configured"}
@@ -392,18 +471,30 @@ func (s *Server) laneHandler(ctx context.Context, req lane.ChatRequest) lane.Cha
 
 	log.Printf("[Chat] %s → %s (method=%s)", req.SessionKey, roleID, routeMethod)
 
-	// 3. Inject user memory
-	content := injectUserMemory(ctx, req.PersonID, req.Content)
+	// 3. Scope memory to the person (or session) the request carries
+	ctx = agent.WithMemoryScope(ctx, memoryScope(req))
+
+	// 4. Consult related experts on their sub-questions; the primary agent
+	// may consult more via ask_agent
+	ctx, consults := registry.WithConsultLog(ctx)
+	if routeResult != nil && len(routeResult.SubTasks) > 0 {
+		ctx = agent.WithConsultations(ctx, s.registry.ConsultAll(ctx, roleID, routeResult.SubTasks))
+	}
 
-	// 4. Dispatch to agent
-	resp, err := s.registry.ProcessDirect(ctx, content, req.SessionKey, req.Channel, req.ChatID, roleID)
+	// 5. Dispatch to agent (streaming progress to SSE callers)
+	if req.Events != nil {
+		ctx = agent.WithStreamHandler(ctx, func(ev agent.StreamEvent) {
+			req.Events(ev.Type, ev)
+		})
+	}
+	resp, err := s.registry.ProcessDirect(ctx, req.Content, req.SessionKey, req.Channel, req.ChatID, roleID)
 	if err != nil {
 		return lane.ChatResult{Error: err.Error(), AgentID: roleID}
 	}
+	routeInfo.Consulted = consults.Agents()
 
-	// 5. Strip leaked thinking
-	resp = stripThinking(resp)
-
+	// Reasoning never reaches resp: providers split it off (including inline
+	// <think> blocks) and it is streamed as reasoning events
 	return lane.ChatResult{
 		Content:   resp,
 		AgentID:   roleID,
@@ -465,7 +556,7 @@ func (s *Server) handleRoles(w http.ResponseWriter, _ *http.Request) {
 		ID          string   `json:"id"`
 		Description string   `json:"description"`
 		Keywords    []string `json:"keywords,omitempty"`
-		IsDefault   bool    `json:"isDefault"`
+		IsDefault   bool     `json:"isDefault"`
 	}
 
 	var roles []roleInfo
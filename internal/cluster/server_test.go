@@ -6,6 +6,9 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"unicode/utf8"
+
+	"github.com/dayuer/nanobot-go/internal/agent"
 )
 
 func newTestServer() *Server {
@@ -82,6 +85,21 @@ func TestHandleChat_EmptyContent(t *testing.T) {
 	}
 }
 
+func TestHandleChat_InvalidPersonID(t *testing.T) {
+	s := newTestServer()
+	for _, path := range []string{"/api/chat", "/api/chat/stream"} {
+		body := `{"content":"hi","sessionKey":"s1","personId":".."}`
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		s.mux.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", path, w.Code)
+		}
+	}
+}
+
 func TestHandleChat_MethodNotAllowed(t *testing.T) {
 	s := newTestServer()
 	req := httptest.NewRequest("GET", "/api/chat", nil)
@@ -127,3 +145,14 @@ func TestHandleAgents_NoRegistry(t *testing.T) {
 		t.Errorf("total = %v, want 0", total)
 	}
 }
+
+func TestSSEEventPayload_PreviewKeepsUTF8(t *testing.T) {
+	ev := agent.StreamEvent{Type: agent.EventToolResult, Content: "a" + strings.Repeat("中", sseResultPreviewLen)}
+	result, _ := sseEventPayload("general", ev)["result"].(string)
+	if !utf8.ValidString(result) {
+		t.Errorf("preview is not valid UTF-8: %q", result[len(result)-8:])
+	}
+	if !strings.HasSuffix(result, "中...") {
+		t.Errorf("preview should end on a whole character, got %q", result[len(result)-8:])
+	}
+}
@@ -0,0 +1,107 @@
+package cluster
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/dayuer/nanobot-go/internal/agent"
+)
+
+// subagentInfo is a subagent record tagged with the agent that spawned it.
+type subagentInfo struct {
+	AgentID string `json:"agentId"`
+	agent.SubagentRecord
+}
+
+// handleSubagents lists the subagents of every registered agent, oldest
+// first. ?agentId= restricts the list to one agent.
+//
+//	GET /api/subagents
+func (s *Server) handleSubagents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	filter := r.URL.Query().Get("agentId")
+
+	subagents := []subagentInfo{}
+	if s.registry != nil {
+		for _, id := range s.registry.AgentIDs() {
+			if filter != "" && id != filter {
+				continue
+			}
+			loop := s.registry.Get(id)
+			if loop == nil || loop.Subagents == nil {
+				continue
+			}
+			for _, rec := range loop.Subagents.List() {
+				subagents = append(subagents, subagentInfo{AgentID: id, SubagentRecord: rec})
+			}
+		}
+	}
+	sort.SliceStable(subagents, func(i, j int) bool {
+		return subagents[i].StartedAt.Before(subagents[j].StartedAt)
+	})
+	writeJSON(w, map[string]any{
+		"subagents": subagents,
+		"total":     len(subagents),
+	})
+}
+
+// handleSubagent inspects or cancels one subagent.
+//
+//	GET  /api/subagents/{id}
+//	POST /api/subagents/{id}/cancel
+func (s *Server) handleSubagent(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/subagents/"), "/")
+	if id == "" {
+		s.handleSubagents(w, r)
+		return
+	}
+
+	agentID, mgr := s.findSubagent(id)
+	if mgr == nil {
+		writeJSONError(w, "subagent not found", http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+	case action == "cancel" && r.Method == http.MethodPost:
+		if err := mgr.Cancel(id); err != nil {
+			writeJSONError(w, err.Error(), http.StatusNotFound)
+			return
+		}
+	case action == "" || action == "cancel":
+		writeJSONError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	default:
+		writeJSONError(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	rec, ok := mgr.Get(id)
+	if !ok {
+		writeJSONError(w, "subagent not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, subagentInfo{AgentID: agentID, SubagentRecord: rec})
+}
+
+// findSubagent returns the agent owning subagent id and its manager.
+func (s *Server) findSubagent(id string) (string, *agent.SubagentManager) {
+	if s.registry == nil {
+		return "", nil
+	}
+	for _, agentID := range s.registry.AgentIDs() {
+		loop := s.registry.Get(agentID)
+		if loop == nil || loop.Subagents == nil {
+			continue
+		}
+		if _, ok := loop.Subagents.Get(id); ok {
+			return agentID, loop.Subagents
+		}
+	}
+	return "", nil
+}
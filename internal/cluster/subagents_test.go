@@ -0,0 +1,77 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dayuer/nanobot-go/internal/agent"
+	"github.com/dayuer/nanobot-go/internal/providers"
+	"github.com/dayuer/nanobot-go/internal/registry"
+)
+
+// hangingProvider blocks every call until its context is cancelled.
+type hangingProvider struct{}
+
+func (hangingProvider) Chat(ctx context.Context, _ providers.ChatRequest) (*providers.LLMResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (hangingProvider) DefaultModel() string { return "m" }
+
+func TestHandleSubagents(t *testing.T) {
+	reg := registry.NewRegistry(registry.RegistryConfig{DefaultProvider: hangingProvider{}, Workspace: t.TempDir()})
+	if err := reg.Register(registry.AgentSpec{ID: "ops"}); err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(ServerConfig{Registry: reg})
+	mgr := reg.Get("ops").Subagents
+	rec, err := mgr.Start(context.Background(), agent.SubagentRequest{Task: "watch the logs", Label: "logs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/subagents", nil))
+	var list struct {
+		Subagents []subagentInfo `json:"subagents"`
+		Total     int            `json:"total"`
+	}
+	json.NewDecoder(w.Body).Decode(&list)
+	if list.Total != 1 || list.Subagents[0].ID != rec.ID || list.Subagents[0].AgentID != "ops" {
+		t.Errorf("list = %+v", list)
+	}
+
+	w = httptest.NewRecorder()
+	s.mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/subagents/"+rec.ID+"/cancel", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET cancel: status = %d, want 405", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	s.mux.ServeHTTP(w, httptest.NewRequest("POST", "/api/subagents/"+rec.ID+"/cancel", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("cancel: status = %d, want 200", w.Code)
+	}
+	done, err := mgr.Wait(context.Background(), rec.ID)
+	if err != nil || done.Status != agent.SubagentCancelled {
+		t.Errorf("after cancel: %+v, %v", done, err)
+	}
+
+	w = httptest.NewRecorder()
+	s.mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/subagents/"+rec.ID, nil))
+	var got subagentInfo
+	json.NewDecoder(w.Body).Decode(&got)
+	if got.Status != agent.SubagentCancelled || got.Task != "watch the logs" {
+		t.Errorf("get = %+v", got)
+	}
+
+	w = httptest.NewRecorder()
+	s.mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/subagents/sub-nope", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown id: status = %d, want 404", w.Code)
+	}
+}
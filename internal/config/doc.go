@@ -17,6 +17,11 @@ type Config struct {
 	RouterModel  RouterModelConfig  `json:"routerModel"`
 	ContentModel ContentModelConfig `json:"contentModel"`
 	Embedding    EmbeddingConfig    `json:"embedding"`
+	Cron         CronConfig         `json:"cron"`
+	Heartbeat    HeartbeatConfig    `json:"heartbeat"`
+	Bus          BusConfig          `json:"bus"`
+	Lanes        LanesConfig        `json:"lanes"`
+	MCPServe     MCPServeConfig     `json:"mcpServe"`
 }
 
 // ChannelConfig holds per-channel settings.
@@ -72,12 +77,12 @@ type DingTalkConfig struct {
 
 // EmailConfig holds email channel settings.
 type EmailConfig struct {
-	IMAPServer   string   `json:"imapServer"`
-	SMTPServer   string   `json:"smtpServer"`
-	Email        string   `json:"email"`
-	Password     string   `json:"password"`
-	CheckInterval int    `json:"checkInterval,omitempty"`
-	AllowFrom    []string `json:"allowFrom,omitempty"`
+	IMAPServer    string   `json:"imapServer"`
+	SMTPServer    string   `json:"smtpServer"`
+	Email         string   `json:"email"`
+	Password      string   `json:"password"`
+	CheckInterval int      `json:"checkInterval,omitempty"`
+	AllowFrom     []string `json:"allowFrom,omitempty"`
 }
 
 // QQConfig holds QQ bot settings.
@@ -102,6 +107,10 @@ type AgentConfig struct {
 	Workspace     string   `json:"workspace,omitempty"`
 	AlwaysSkills  []string `json:"alwaysSkills,omitempty"`
 
+	// Reasoning models: effort (low/medium/high) or thinking-token budget
+	ReasoningEffort string `json:"reasoningEffort,omitempty"`
+	ReasoningBudget int    `json:"reasoningBudget,omitempty"`
+
 	// MCP server configurations
 	MCPServers []MCPServerConfig `json:"mcpServers,omitempty"`
 }
@@ -113,12 +122,22 @@ type MCPServerConfig struct {
 	Args    []string          `json:"args,omitempty"`
 	URL     string            `json:"url,omitempty"`
 	Env     map[string]string `json:"env,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"` // sent with HTTP requests, e.g. Authorization
+}
+
+// MCPServeConfig secures `nanobot mcp serve --http`.
+type MCPServeConfig struct {
+	Token   string   `json:"token,omitempty"`   // bearer token clients must send; required for HTTP
+	Origins []string `json:"origins,omitempty"` // origins allowed besides the loopback ones
 }
 
 // ToolsConfig holds tool-related settings.
 type ToolsConfig struct {
-	RestrictToWorkspace bool       `json:"restrictToWorkspace,omitempty"`
-	Exec                ExecConfig `json:"exec,omitempty"`
+	RestrictToWorkspace bool           `json:"restrictToWorkspace,omitempty"`
+	Exec                ExecConfig     `json:"exec,omitempty"`
+	Timeout             int            `json:"timeout,omitempty"`        // per-call deadline in seconds
+	Timeouts            map[string]int `json:"timeouts,omitempty"`       // per-tool deadline overrides in seconds
+	MaxOutputBytes      int            `json:"maxOutputBytes,omitempty"` // tool output cap in bytes, cut on a UTF-8 boundary
 }
 
 // ExecConfig holds shell execution settings.
@@ -143,8 +162,8 @@ type WebSearchConfig struct {
 
 // SurvivalConfig holds Survival backend connection settings.
 type SurvivalConfig struct {
-	APIURL string `json:"apiUrl,omitempty"` // Backend URL (e.g. http://host.docker.internal:3000)
-	APIKey string `json:"apiKey,omitempty"` // Backend auth key (SURVIVAL_API_KEY)
+	APIURL        string `json:"apiUrl,omitempty"`        // Backend URL (e.g. http://host.docker.internal:3000)
+	APIKey        string `json:"apiKey,omitempty"`        // Backend auth key (SURVIVAL_API_KEY)
 	NanobotAPIKey string `json:"nanobotApiKey,omitempty"` // HTTP API auth key (NANOBOT_API_KEY)
 }
 
@@ -162,7 +181,7 @@ type APIKeysConfig struct {
 
 // RedisConfig holds Redis connection settings.
 type RedisConfig struct {
-	URL      string `json:"url,omitempty"`      // redis://host:port
+	URL      string `json:"url,omitempty"` // redis://host:port
 	Password string `json:"password,omitempty"`
 	DB       int    `json:"db,omitempty"`
 }
@@ -178,7 +197,7 @@ type RouterModelConfig struct {
 type ContentModelConfig struct {
 	APIBase string `json:"apiBase,omitempty"` // e.g. "http://115.190.110.32:5000/v1"
 	APIKey  string `json:"apiKey,omitempty"`
-	Model   string `json:"model,omitempty"`   // e.g. "DeepSeek-R1"
+	Model   string `json:"model,omitempty"` // e.g. "DeepSeek-R1"
 }
 
 // EmbeddingConfig holds embedding model settings (for RAG).
@@ -187,6 +206,41 @@ type EmbeddingConfig struct {
 	BaseURL string `json:"baseUrl,omitempty"` // e.g. "https://dashscope.aliyuncs.com/compatible-mode/v1"
 }
 
+// CronConfig holds scheduled job settings.
+type CronConfig struct {
+	Timezone string `json:"timezone,omitempty"` // IANA zone for cron expressions and at times; empty = local
+	CatchUp  string `json:"catchUp,omitempty"`  // missed-run policy: skip, once (default) or all
+}
+
+// HeartbeatConfig holds settings for the periodic HEARTBEAT.md check.
+type HeartbeatConfig struct {
+	Enabled    bool   `json:"enabled,omitempty"`
+	Interval   int    `json:"interval,omitempty"`   // seconds between checks; 0 = 30 minutes
+	QuietHours string `json:"quietHours,omitempty"` // e.g. "22:00-07:00": no checks inside
+	Timezone   string `json:"timezone,omitempty"`   // IANA zone for quietHours; empty = local
+	Channel    string `json:"channel,omitempty"`    // where the agent's output is sent
+	ChatID     string `json:"chatId,omitempty"`
+}
+
+// BusConfig holds message bus settings for gateway mode.
+type BusConfig struct {
+	Backend       string `json:"backend,omitempty"`       // memory (default) or log: a durable log under dir
+	Dir           string `json:"dir,omitempty"`           // log directory; empty = <workspace>/bus
+	Capacity      int    `json:"capacity,omitempty"`      // queued messages per direction before publishers block; 0 = 1000
+	MaxDeliveries int    `json:"maxDeliveries,omitempty"` // deliveries before a message is dead-lettered; 0 = 5
+	// RedeliveryDelay is the wait in milliseconds before a failed message is
+	// redelivered, doubling per attempt; 0 = 2000
+	RedeliveryDelay int `json:"redeliveryDelay,omitempty"`
+}
+
+// LanesConfig holds how gateway mode schedules agent turns: one lane per
+// session, processed in order, with lanes running in parallel.
+type LanesConfig struct {
+	Mode          string `json:"mode,omitempty"`          // followup (default), collect or interrupt
+	CollectWindow int    `json:"collectWindow,omitempty"` // collect mode merge window in milliseconds; 0 = 2000
+	MaxConcurrent int    `json:"maxConcurrent,omitempty"` // agent turns running at once across sessions; 0 = 4
+}
+
 // DefaultConfig returns a Config with sensible defaults.
 func DefaultConfig() Config {
 	return Config{
@@ -249,4 +303,3 @@ func (c *Config) ExportEnv() {
 	// Router model
 	setIfNotEmpty("ROUTER_MODEL", c.RouterModel.Model)
 }
-
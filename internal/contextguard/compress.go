@@ -0,0 +1,179 @@
+package contextguard
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dayuer/nanobot-go/internal/providers"
+)
+
+// SummaryPrefix marks the synthetic message that replaces compressed turns.
+const SummaryPrefix = "[Conversation summary]"
+
+// maxTranscriptField is the default per-message cap in summary requests,
+// so one huge tool result cannot dominate the summary.
+const maxTranscriptField = 2000
+
+const summaryPrompt = `You compress conversation history for an AI assistant whose context window is nearly full.
+Summarize the conversation below so the assistant can continue seamlessly:
+- keep facts, decisions, user preferences, open tasks and commitments
+- keep names, numbers, file paths, IDs and URLs exactly
+- drop greetings, repetition and raw tool output that has already been acted on
+Write in the same language as the conversation. Output only the summary.`
+
+// OlderTurns returns the bounds [start, end) of the messages that precede
+// the current turn: everything after the leading system prompt(s) up to the
+// last user message. An earlier summary counts as an older turn, so repeated
+// compression folds it into the new summary. start == end when there is
+// nothing to compress.
+func OlderTurns(messages []map[string]any) (start, end int) {
+	for start < len(messages) && messages[start]["role"] == "system" && !isSummary(messages[start]) {
+		start++
+	}
+	end = start
+	for i := len(messages) - 1; i >= start; i-- {
+		if messages[i]["role"] == "user" {
+			end = i
+			break
+		}
+	}
+	return start, end
+}
+
+// FormatTranscript renders messages as "role: content" lines. Content longer
+// than maxField bytes is cut and marked with how much was dropped; maxField
+// <= 0 keeps every message whole.
+func FormatTranscript(messages []map[string]any, maxField int) string {
+	var b strings.Builder
+	for _, m := range messages {
+		role, _ := m["role"].(string)
+		content, _ := m["content"].(string)
+		if name, ok := m["name"].(string); ok && role == "tool" {
+			role = "tool " + name
+		}
+		if calls := toolCallNames(m["tool_calls"]); len(calls) > 0 {
+			content = strings.TrimSpace(content + " [called: " + strings.Join(calls, ", ") + "]")
+		}
+		if content == "" {
+			continue
+		}
+		if maxField > 0 && len(content) > maxField {
+			cut := maxField
+			for cut > 0 && !utf8.RuneStart(content[cut]) {
+				cut--
+			}
+			content = fmt.Sprintf("%s… [%d chars truncated]", content[:cut], utf8.RuneCountInString(content[cut:]))
+		}
+		fmt.Fprintf(&b, "%s: %s\n", role, content)
+	}
+	return b.String()
+}
+
+func toolCallNames(v any) []string {
+	var names []string
+	eachToolCall(v, func(c map[string]any) {
+		if fn, ok := c["function"].(map[string]any); ok {
+			if name, ok := fn["name"].(string); ok {
+				names = append(names, name)
+			}
+		}
+	})
+	return names
+}
+
+// eachToolCall visits the entries of a tool_calls value, which is
+// []map[string]any when built in-process and []any after a JSON round trip.
+func eachToolCall(v any, fn func(map[string]any)) {
+	switch calls := v.(type) {
+	case []map[string]any:
+		for _, c := range calls {
+			fn(c)
+		}
+	case []any:
+		for _, c := range calls {
+			if cm, ok := c.(map[string]any); ok {
+				fn(cm)
+			}
+		}
+	}
+}
+
+// SummaryMessage builds the synthetic message that stands in for
+// compressed turns.
+func SummaryMessage(summary string) map[string]any {
+	return map[string]any{"role": "system", "content": SummaryPrefix + "\n" + summary}
+}
+
+func isSummary(m map[string]any) bool {
+	content, _ := m["content"].(string)
+	return m["role"] == "system" && strings.HasPrefix(content, SummaryPrefix)
+}
+
+// Compressor summarizes older turns with an LLM.
+type Compressor struct {
+	Provider  providers.LLMProvider
+	Model     string
+	MaxTokens int // summary budget; 0 = 1024
+	MaxField  int // per-message cap in the transcript sent for summary; 0 = 2000, <0 = none
+}
+
+// Compress replaces the turns before the current one with a single summary
+// message. It returns the new message list, the summary, and how many
+// messages were replaced (0 when there was nothing to compress).
+func (c *Compressor) Compress(ctx context.Context, messages []map[string]any) ([]map[string]any, string, int, error) {
+	start, end := OlderTurns(messages)
+	if end-start == 0 || (end-start == 1 && isSummary(messages[start])) {
+		return messages, "", 0, nil // nothing left to compress
+	}
+
+	maxTokens := c.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = 1024
+	}
+	maxField := c.MaxField
+	if maxField == 0 {
+		maxField = maxTranscriptField
+	}
+	resp, err := c.Provider.Chat(ctx, providers.ChatRequest{
+		Messages: []providers.Message{
+			{Role: "system", Content: summaryPrompt},
+			{Role: "user", Content: FormatTranscript(messages[start:end], maxField)},
+		},
+		Model:       c.Model,
+		MaxTokens:   maxTokens,
+		Temperature: 0.2,
+	})
+	if err != nil {
+		return messages, "", 0, fmt.Errorf("summarize: %w", err)
+	}
+	if resp.FinishReason == "error" || resp.Content == nil || strings.TrimSpace(*resp.Content) == "" {
+		return messages, "", 0, fmt.Errorf("summarize: empty summary")
+	}
+	summary := strings.TrimSpace(*resp.Content)
+
+	out := make([]map[string]any, 0, len(messages)-(end-start)+1)
+	out = append(out, messages[:start]...)
+	out = append(out, SummaryMessage(summary))
+	out = append(out, messages[end:]...)
+	return out, summary, end - start, nil
+}
+
+// KnowledgeStore receives flushed conversation memory; *rag.Store implements it.
+type KnowledgeStore interface {
+	IngestText(ctx context.Context, text, source string) (int, error)
+}
+
+// Flush saves a transcript of messages into store under source. Messages
+// are kept whole: the knowledge base chunks long text itself.
+func Flush(ctx context.Context, store KnowledgeStore, source string, messages []map[string]any) error {
+	text := FormatTranscript(messages, 0)
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	if _, err := store.IngestText(ctx, text, source); err != nil {
+		return fmt.Errorf("flush to knowledge base: %w", err)
+	}
+	return nil
+}
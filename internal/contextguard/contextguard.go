@@ -6,7 +6,9 @@ package contextguard
 import (
 	"fmt"
 	"log"
-	"strings"
+	"sync"
+
+	"github.com/dayuer/nanobot-go/internal/providers"
 )
 
 // Action describes the pre-check result.
@@ -25,6 +27,7 @@ type PreCheckResult struct {
 	TokenEstimate int
 	TokenLimit    int
 	Ratio         float64
+	MemoryFlushed bool // set by the caller once a reset saved the session to the knowledge base
 }
 
 // ShouldNotifyUser returns true if the user should be informed (on reset).
@@ -37,68 +40,46 @@ func (r PreCheckResult) NotificationMessage() string {
 	if r.Action != ActionReset {
 		return ""
 	}
+	if !r.MemoryFlushed {
+		return fmt.Sprintf("⚠️ 对话上下文已超出模型限制 (%.0f%%)，已自动重置会话。", r.Ratio*100)
+	}
 	return fmt.Sprintf("⚠️ 对话上下文已超出模型限制 (%.0f%%)，已自动重置会话。之前的对话记忆已保存到知识库中，可通过 knowledge_search 工具检索。",
 		r.Ratio*100)
 }
 
-// ModelTokenLimits maps model names to their context window sizes.
-var ModelTokenLimits = map[string]int{
-	// DeepSeek
-	"deepseek/deepseek-chat":     64_000,
-	"deepseek/deepseek-reasoner": 64_000,
-	// OpenAI
-	"gpt-4o":                          128_000,
-	"gpt-4o-mini":                     128_000,
-	"gpt-4-turbo":                     128_000,
-	"openai/gpt-4o":                   128_000,
-	// Anthropic
-	"anthropic/claude-sonnet-4-20250514":  200_000,
-	"anthropic/claude-opus-4-5":       200_000,
-	// ZhipuAI
-	"zhipuai/glm-5":                   128_000,
-	"zhipuai/glm-4-flash":             128_000,
-	// Default
-	"_default": 64_000,
-}
-
-// GetModelLimit returns the token limit for a model.
+// GetModelLimit returns the context window of a model, from the provider
+// registry (providers.ProviderSpec.ContextWindow / ModelLimits).
 func GetModelLimit(model string) int {
-	if limit, ok := ModelTokenLimits[model]; ok {
-		return limit
-	}
-	// Try prefix match
-	for k, v := range ModelTokenLimits {
-		if strings.HasPrefix(model, k) {
-			return v
-		}
-	}
-	return ModelTokenLimits["_default"]
+	return providers.ContextWindow(model)
 }
 
-// EstimateTokens estimates the token count for a list of messages.
-// Uses a rough heuristic: 1 Chinese char ≈ 1.5 tokens, total chars / 2.
-// Errs on the side of overestimation (safer).
+// EstimateTokens estimates the token count for a list of messages with the
+// CJK-aware approximation. Use PreCheck or CheckRequest for a count with the
+// model's own tokenizer.
 func EstimateTokens(messages []map[string]any) int {
-	total := 0
-	for _, msg := range messages {
-		if content, ok := msg["content"].(string); ok {
-			total += len(content)
-		}
-		// Count tool calls
-		if tc, ok := msg["tool_calls"].([]any); ok {
-			for _, call := range tc {
-				if callMap, ok := call.(map[string]any); ok {
-					if fn, ok := callMap["function"].(map[string]any); ok {
-						if args, ok := fn["arguments"].(string); ok {
-							total += len(args)
-						}
-					}
-				}
-			}
-		}
+	return countMessages(providers.ApproxCounter, messages)
+}
+
+// countMessages counts context messages (the agent's map form) the way
+// providers.CountMessages counts wire messages.
+func countMessages(c providers.TokenCounter, messages []map[string]any) int {
+	msgs := make([]providers.Message, 0, len(messages))
+	for _, m := range messages {
+		msg := providers.Message{}
+		msg.Role, _ = m["role"].(string)
+		msg.Content, _ = m["content"].(string)
+		msg.Name, _ = m["name"].(string)
+		msg.ReasoningContent, _ = m["reasoning_content"].(string)
+		eachToolCall(m["tool_calls"], func(call map[string]any) {
+			fn, _ := call["function"].(map[string]any)
+			tc := providers.ToolCall{}
+			tc.Function.Name, _ = fn["name"].(string)
+			tc.Function.Arguments, _ = fn["arguments"].(string)
+			msg.ToolCalls = append(msg.ToolCalls, tc)
+		})
+		msgs = append(msgs, msg)
 	}
-	// Rough estimate: total chars / 2 (works well for Chinese-English mix)
-	return total / 2
+	return providers.CountMessages(c, msgs)
 }
 
 // Config holds ContextGuard thresholds.
@@ -120,12 +101,13 @@ func DefaultConfig() Config {
 // Guard is the context guard that monitors token usage.
 type Guard struct {
 	cfg Config
+	mu  sync.Mutex
 
 	// Stats
-	TotalChecks     int
-	WarningCount    int
+	TotalChecks      int
+	WarningCount     int
 	CompressionCount int
-	ResetCount      int
+	ResetCount       int
 }
 
 // NewGuard creates a new context guard.
@@ -133,13 +115,25 @@ func NewGuard(cfg Config) *Guard {
 	return &Guard{cfg: cfg}
 }
 
-// PreCheck performs a token pre-check before calling the LLM.
-// Returns the action to take based on current token usage.
+// PreCheck performs a token pre-check before calling the LLM, counting with
+// the model's tokenizer. Returns the action to take based on current token usage.
 func (g *Guard) PreCheck(messages []map[string]any, model string) PreCheckResult {
+	window, tokenizer := providers.ModelLimits(model)
+	return g.check(countMessages(providers.NewTokenCounter(tokenizer), messages), window)
+}
+
+// CheckRequest is PreCheck for a complete request, so tool schemas count
+// against the window too.
+func (g *Guard) CheckRequest(req providers.ChatRequest) PreCheckResult {
+	window, tokenizer := providers.ModelLimits(req.Model)
+	return g.check(providers.CountRequest(providers.NewTokenCounter(tokenizer), req), window)
+}
+
+func (g *Guard) check(tokenEstimate, tokenLimit int) PreCheckResult {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	g.TotalChecks++
 
-	tokenEstimate := EstimateTokens(messages)
-	tokenLimit := GetModelLimit(model)
 	ratio := float64(tokenEstimate) / float64(tokenLimit)
 
 	result := PreCheckResult{
@@ -176,6 +170,8 @@ func (g *Guard) PreCheck(messages []map[string]any, model string) PreCheckResult
 
 // Stats returns guard statistics.
 func (g *Guard) Stats() map[string]any {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	return map[string]any{
 		"totalChecks":      g.TotalChecks,
 		"warningCount":     g.WarningCount,
@@ -0,0 +1,171 @@
+package contextguard
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dayuer/nanobot-go/internal/providers"
+)
+
+type summaryProvider struct {
+	summary string
+	err     error
+	got     []providers.ChatRequest
+}
+
+func (p *summaryProvider) Chat(_ context.Context, req providers.ChatRequest) (*providers.LLMResponse, error) {
+	p.got = append(p.got, req)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &providers.LLMResponse{Content: &p.summary, FinishReason: "stop"}, nil
+}
+
+func (p *summaryProvider) DefaultModel() string { return "m" }
+
+type memStore struct{ texts, sources []string }
+
+func (s *memStore) IngestText(_ context.Context, text, source string) (int, error) {
+	s.texts = append(s.texts, text)
+	s.sources = append(s.sources, source)
+	return 1, nil
+}
+
+func msg(role, content string) map[string]any {
+	return map[string]any{"role": role, "content": content}
+}
+
+func conversation() []map[string]any {
+	return []map[string]any{
+		msg("system", "You are nanobot."),
+		msg("user", "my name is Ada"),
+		msg("assistant", "hi Ada"),
+		msg("user", "what's my name?"),
+	}
+}
+
+func TestPreCheck_Actions(t *testing.T) {
+	g := NewGuard(DefaultConfig())
+	long := strings.Repeat("x", 64_000*7/2) // ≈ 64k tokens at 3.5 chars per token
+	assert.Equal(t, ActionPass, g.PreCheck([]map[string]any{msg("user", "hi")}, "unknown").Action)
+	assert.Equal(t, ActionCompressed, g.PreCheck([]map[string]any{msg("user", long[:len(long)*85/100])}, "unknown").Action)
+	assert.Equal(t, ActionReset, g.PreCheck([]map[string]any{msg("user", long)}, "unknown").Action)
+	assert.Equal(t, 3, g.Stats()["totalChecks"])
+}
+
+func TestCheckRequest_CountsToolSchemas(t *testing.T) {
+	g := NewGuard(DefaultConfig())
+	msgs := []providers.Message{{Role: "user", Content: "hi"}}
+	tool := map[string]any{"type": "function", "function": map[string]any{
+		"name": "huge", "description": strings.Repeat("x", 64_000*4),
+	}}
+
+	bare := g.CheckRequest(providers.ChatRequest{Messages: msgs, Model: "unknown"})
+	withTools := g.CheckRequest(providers.ChatRequest{Messages: msgs, Tools: []map[string]any{tool}, Model: "unknown"})
+	assert.Equal(t, ActionPass, bare.Action)
+	assert.Equal(t, ActionReset, withTools.Action)
+	assert.Greater(t, withTools.TokenEstimate, bare.TokenEstimate)
+}
+
+func TestGetModelLimit_FromRegistry(t *testing.T) {
+	assert.Equal(t, 200_000, GetModelLimit("anthropic/claude-opus-4-5"))
+	assert.Equal(t, 128_000, GetModelLimit("openai/gpt-4o"))
+	assert.Equal(t, providers.DefaultContextWindow, GetModelLimit("unknown"))
+}
+
+func TestEstimateTokens_CountsToolCallArguments(t *testing.T) {
+	plain := []map[string]any{msg("assistant", "")}
+	withCall := []map[string]any{{
+		"role": "assistant", "content": "",
+		"tool_calls": []any{map[string]any{"function": map[string]any{
+			"name": "exec", "arguments": `{"command":"ls -la /tmp"}`,
+		}}},
+	}}
+	assert.Greater(t, EstimateTokens(withCall), EstimateTokens(plain))
+}
+
+func TestNotificationMessage_OnlyPromisesFlushWhenDone(t *testing.T) {
+	r := PreCheckResult{Action: ActionReset, Ratio: 0.97}
+	assert.NotContains(t, r.NotificationMessage(), "知识库")
+	r.MemoryFlushed = true
+	assert.Contains(t, r.NotificationMessage(), "知识库")
+	assert.Empty(t, PreCheckResult{Action: ActionWarn}.NotificationMessage())
+}
+
+func TestOlderTurns(t *testing.T) {
+	start, end := OlderTurns(conversation())
+	assert.Equal(t, 1, start)
+	assert.Equal(t, 3, end)
+
+	// a previous summary is part of the older turns
+	msgs := append([]map[string]any{msg("system", "sys"), SummaryMessage("old")}, conversation()[1:]...)
+	start, end = OlderTurns(msgs)
+	assert.Equal(t, 1, start)
+	assert.Equal(t, 4, end)
+
+	start, end = OlderTurns([]map[string]any{msg("system", "sys"), msg("user", "hi")})
+	assert.Equal(t, start, end)
+}
+
+func TestCompressor_SummarizesOlderTurns(t *testing.T) {
+	p := &summaryProvider{summary: "User is Ada."}
+	c := &Compressor{Provider: p, Model: "m"}
+
+	out, summary, replaced, err := c.Compress(context.Background(), conversation())
+	require.NoError(t, err)
+	assert.Equal(t, "User is Ada.", summary)
+	assert.Equal(t, 2, replaced)
+	require.Len(t, out, 3)
+	assert.Equal(t, "You are nanobot.", out[0]["content"])
+	assert.Equal(t, SummaryPrefix+"\nUser is Ada.", out[1]["content"])
+	assert.Equal(t, "what's my name?", out[2]["content"])
+
+	require.Len(t, p.got, 1)
+	transcript := p.got[0].Messages[1].Content
+	assert.Contains(t, transcript, "user: my name is Ada")
+	assert.Contains(t, transcript, "assistant: hi Ada")
+	assert.NotContains(t, transcript, "what's my name?", "the current turn is not summarized")
+
+	// compressing an already-compressed context is a no-op
+	_, _, replaced, err = c.Compress(context.Background(), out)
+	require.NoError(t, err)
+	assert.Zero(t, replaced)
+	assert.Len(t, p.got, 1)
+}
+
+func TestCompressor_ProviderErrorKeepsMessages(t *testing.T) {
+	c := &Compressor{Provider: &summaryProvider{err: errors.New("down")}}
+	in := conversation()
+	out, _, replaced, err := c.Compress(context.Background(), in)
+	assert.Error(t, err)
+	assert.Zero(t, replaced)
+	assert.Equal(t, in, out)
+}
+
+func TestFlush(t *testing.T) {
+	store := &memStore{}
+	require.NoError(t, Flush(context.Background(), store, "session:cli", conversation()[1:3]))
+	require.Len(t, store.texts, 1)
+	assert.Equal(t, "session:cli", store.sources[0])
+	assert.Equal(t, "user: my name is Ada\nassistant: hi Ada\n", store.texts[0])
+
+	require.NoError(t, Flush(context.Background(), store, "empty", nil))
+	assert.Len(t, store.texts, 1, "empty transcripts are not ingested")
+}
+
+func TestFlush_KeepsLongMessages(t *testing.T) {
+	store := &memStore{}
+	long := strings.Repeat("y", 3*maxTranscriptField)
+	require.NoError(t, Flush(context.Background(), store, "session:cli", []map[string]any{msg("tool", long)}))
+	assert.Equal(t, "tool: "+long+"\n", store.texts[0])
+}
+
+func TestFormatTranscript_MarksTruncation(t *testing.T) {
+	text := FormatTranscript([]map[string]any{msg("user", strings.Repeat("é", 10))}, 5)
+	assert.Equal(t, "user: éé… [8 chars truncated]\n", text)
+}
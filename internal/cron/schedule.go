@@ -0,0 +1,301 @@
+// Package cron — schedule.go
+// Schedules: fixed intervals, five-field cron expressions and one-shot times.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule kinds.
+const (
+	KindEvery = "every" // every EverySeconds
+	KindCron  = "cron"  // five-field cron expression in TZ
+	KindAt    = "at"    // once, at At
+)
+
+// Schedule says when a job runs.
+type Schedule struct {
+	Kind         string     `json:"kind"`
+	EverySeconds int        `json:"everySeconds,omitempty"`
+	Expr         string     `json:"expr,omitempty"`
+	TZ           string     `json:"tz,omitempty"` // IANA zone for Expr; empty = service default
+	At           *time.Time `json:"at,omitempty"`
+}
+
+// Validate checks the schedule can produce run times.
+func (s Schedule) Validate() error {
+	switch s.Kind {
+	case KindEvery:
+		if s.EverySeconds <= 0 {
+			return fmt.Errorf("every_seconds must be positive")
+		}
+	case KindCron:
+		if _, err := ParseExpr(s.Expr, time.UTC); err != nil {
+			return err
+		}
+		if _, err := loadLocation(s.TZ, time.UTC); err != nil {
+			return err
+		}
+	case KindAt:
+		if s.At == nil {
+			return fmt.Errorf("at time is required")
+		}
+	default:
+		return fmt.Errorf("unknown schedule kind %q", s.Kind)
+	}
+	return nil
+}
+
+// Next returns the first run time strictly after after, or the zero time
+// when the schedule has no further runs. loc is the zone for cron
+// expressions without their own.
+func (s Schedule) Next(after time.Time, loc *time.Location) time.Time {
+	switch s.Kind {
+	case KindEvery:
+		if s.EverySeconds <= 0 {
+			return time.Time{}
+		}
+		return after.Add(time.Duration(s.EverySeconds) * time.Second)
+	case KindCron:
+		loc, err := loadLocation(s.TZ, loc)
+		if err != nil {
+			return time.Time{}
+		}
+		expr, err := ParseExpr(s.Expr, loc)
+		if err != nil {
+			return time.Time{}
+		}
+		return expr.Next(after)
+	case KindAt:
+		if s.At != nil && s.At.After(after) {
+			return *s.At
+		}
+	}
+	return time.Time{}
+}
+
+// String describes the schedule for listings.
+func (s Schedule) String() string {
+	switch s.Kind {
+	case KindEvery:
+		return "every " + (time.Duration(s.EverySeconds) * time.Second).String()
+	case KindCron:
+		if s.TZ != "" {
+			return fmt.Sprintf("cron %q (%s)", s.Expr, s.TZ)
+		}
+		return fmt.Sprintf("cron %q", s.Expr)
+	case KindAt:
+		if s.At != nil {
+			return "at " + s.At.Format(time.RFC3339)
+		}
+	}
+	return s.Kind
+}
+
+func loadLocation(name string, def *time.Location) (*time.Location, error) {
+	if name == "" {
+		return def, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// Expr is a parsed five-field cron expression:
+// minute hour day-of-month month day-of-week.
+type Expr struct {
+	minute, hour, dom, month, dow uint64 // bit n set = value n allowed
+	domAny, dowAny                bool   // field was * (day matching rule)
+	loc                           *time.Location
+}
+
+type field struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = field{min: 0, max: 59}
+	hourField   = field{min: 0, max: 23}
+	domField    = field{min: 1, max: 31}
+	monthField  = field{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is accepted as Sunday and folded onto 0
+	dowField = field{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var exprMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseExpr parses a standard five-field cron expression evaluated in loc.
+// Fields take *, values, names (jan, mon), ranges, lists and /steps; the
+// @daily-style macros are accepted, and a leading CRON_TZ=<zone> (or
+// TZ=<zone>) overrides loc. As in Vixie cron, when both day fields are
+// restricted a day matching either one runs.
+func ParseExpr(spec string, loc *time.Location) (*Expr, error) {
+	tz, spec := SplitTZ(spec)
+	loc, err := loadLocation(tz, loc)
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := exprMacros[strings.ToLower(spec)]; ok {
+		spec = m
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q: want 5 fields, got %d", spec, len(fields))
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+
+	e := &Expr{loc: loc}
+	parse := func(i int, f field) uint64 {
+		if err != nil {
+			return 0
+		}
+		var bits uint64
+		bits, err = f.parse(fields[i])
+		if err != nil {
+			err = fmt.Errorf("cron expression %q: %w", spec, err)
+		}
+		return bits
+	}
+	e.minute = parse(0, minuteField)
+	e.hour = parse(1, hourField)
+	e.dom = parse(2, domField)
+	e.month = parse(3, monthField)
+	e.dow = parse(4, dowField)
+	if err != nil {
+		return nil, err
+	}
+	if e.dow&(1<<7) != 0 {
+		e.dow = e.dow&^(1<<7) | 1
+	}
+	e.domAny = strings.HasPrefix(fields[2], "*") || fields[2] == "?"
+	e.dowAny = strings.HasPrefix(fields[4], "*") || fields[4] == "?"
+	return e, nil
+}
+
+// SplitTZ separates a leading CRON_TZ=<zone> or TZ=<zone> from a cron
+// expression.
+func SplitTZ(spec string) (tz, expr string) {
+	spec = strings.TrimSpace(spec)
+	if !strings.HasPrefix(spec, "CRON_TZ=") && !strings.HasPrefix(spec, "TZ=") {
+		return "", spec
+	}
+	prefix, rest, _ := strings.Cut(spec, " ")
+	_, tz, _ = strings.Cut(prefix, "=")
+	return tz, strings.TrimSpace(rest)
+}
+
+// parse returns the allowed values of one field as a bitset.
+func (f field) parse(s string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case rng == "*" || rng == "?":
+		case strings.Contains(rng, "-"):
+			a, b, _ := strings.Cut(rng, "-")
+			var err error
+			if lo, err = f.value(a); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(b); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		default:
+			v, err := f.value(rng)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			if !hasStep {
+				hi = v // a/n runs from a to the end of the range
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (f field) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %q out of range %d-%d", s, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next returns the first matching minute strictly after t, or the zero time
+// if none falls within five years.
+func (e *Expr) Next(t time.Time) time.Time {
+	t = t.In(e.loc).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		y, m, d := t.Date()
+		var next time.Time
+		switch {
+		case e.month&(1<<uint(m)) == 0:
+			next = time.Date(y, m+1, 1, 0, 0, 0, 0, e.loc)
+		case !e.dayMatches(t):
+			next = time.Date(y, m, d+1, 0, 0, 0, 0, e.loc)
+		case e.hour&(1<<uint(t.Hour())) == 0:
+			next = time.Date(y, m, d, t.Hour()+1, 0, 0, 0, e.loc)
+		case e.minute&(1<<uint(t.Minute())) == 0:
+			next = t.Add(time.Minute)
+		default:
+			return t
+		}
+		// Around DST changes a wall-clock jump can land on or before t
+		if !next.After(t) {
+			next = t.Add(time.Minute)
+		}
+		t = next
+	}
+	return time.Time{}
+}
+
+func (e *Expr) dayMatches(t time.Time) bool {
+	dom := e.dom&(1<<uint(t.Day())) != 0
+	dow := e.dow&(1<<uint(t.Weekday())) != 0
+	if e.domAny || e.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
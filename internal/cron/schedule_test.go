@@ -0,0 +1,101 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustTime(t *testing.T, s string) time.Time {
+	t.Helper()
+	tm, err := time.Parse(time.RFC3339, s)
+	require.NoError(t, err)
+	return tm
+}
+
+func TestExpr_Next(t *testing.T) {
+	// 2025-01-03 is a Friday
+	cases := []struct {
+		expr, after, want string
+	}{
+		{"*/15 * * * *", "2025-01-03T10:07:30Z", "2025-01-03T10:15:00Z"},
+		{"*/15 * * * *", "2025-01-03T10:15:00Z", "2025-01-03T10:30:00Z"},
+		{"0 9 * * 1-5", "2025-01-03T09:00:00Z", "2025-01-06T09:00:00Z"},
+		{"30 8 * * mon,wed", "2025-01-03T00:00:00Z", "2025-01-06T08:30:00Z"},
+		{"0 0 1 jan *", "2025-01-03T00:00:00Z", "2026-01-01T00:00:00Z"},
+		{"0 0 29 2 *", "2025-01-03T00:00:00Z", "2028-02-29T00:00:00Z"},
+		{"0 12 * * 7", "2025-01-03T00:00:00Z", "2025-01-05T12:00:00Z"},
+		{"5/20 * * * *", "2025-01-03T10:46:00Z", "2025-01-03T11:05:00Z"},
+		{"@daily", "2025-01-03T10:00:00Z", "2025-01-04T00:00:00Z"},
+		// Both day fields restricted: the 13th or any Friday
+		{"0 0 13 * fri", "2025-01-03T01:00:00Z", "2025-01-10T00:00:00Z"},
+		{"0 0 13 * fri", "2025-01-10T01:00:00Z", "2025-01-13T00:00:00Z"},
+	}
+	for _, c := range cases {
+		e, err := ParseExpr(c.expr, time.UTC)
+		require.NoError(t, err, c.expr)
+		assert.Equal(t, mustTime(t, c.want), e.Next(mustTime(t, c.after)).UTC(), c.expr)
+	}
+}
+
+func TestExpr_TimeZone(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+	after := mustTime(t, "2025-01-03T00:00:00Z") // 08:00 in Shanghai
+
+	e, err := ParseExpr("0 9 * * *", shanghai)
+	require.NoError(t, err)
+	assert.Equal(t, mustTime(t, "2025-01-03T01:00:00Z"), e.Next(after).UTC())
+
+	// CRON_TZ overrides the default zone
+	e, err = ParseExpr("CRON_TZ=Asia/Shanghai 0 9 * * *", time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, mustTime(t, "2025-01-03T01:00:00Z"), e.Next(after).UTC())
+}
+
+func TestExpr_DSTGap(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	// 2025-03-09 02:30 does not exist in New York; the next run is a day later
+	e, err := ParseExpr("30 2 * * *", ny)
+	require.NoError(t, err)
+	next := e.Next(time.Date(2025, 3, 9, 0, 0, 0, 0, ny))
+	assert.Equal(t, time.Date(2025, 3, 10, 2, 30, 0, 0, ny), next)
+}
+
+func TestParseExpr_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *",
+		"* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *",
+		"CRON_TZ=Nowhere/Zone * * * * *", "foo * * * *",
+	} {
+		_, err := ParseExpr(expr, time.UTC)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	now := mustTime(t, "2025-01-03T10:00:00Z")
+
+	every := Schedule{Kind: KindEvery, EverySeconds: 90}
+	assert.Equal(t, now.Add(90*time.Second), every.Next(now, time.UTC))
+
+	at := now.Add(time.Hour)
+	once := Schedule{Kind: KindAt, At: &at}
+	assert.Equal(t, at, once.Next(now, time.UTC))
+	assert.True(t, once.Next(at, time.UTC).IsZero(), "one-shot has no run after its time")
+
+	tz := Schedule{Kind: KindCron, Expr: "0 9 * * *", TZ: "Asia/Shanghai"}
+	assert.Equal(t, mustTime(t, "2025-01-04T01:00:00Z"), tz.Next(now, time.UTC).UTC())
+}
+
+func TestSchedule_Validate(t *testing.T) {
+	assert.NoError(t, Schedule{Kind: KindEvery, EverySeconds: 1}.Validate())
+	assert.Error(t, Schedule{Kind: KindEvery}.Validate())
+	assert.Error(t, Schedule{Kind: KindCron, Expr: "* *"}.Validate())
+	assert.Error(t, Schedule{Kind: KindCron, Expr: "* * * * *", TZ: "Nowhere/Zone"}.Validate())
+	assert.Error(t, Schedule{Kind: KindAt}.Validate())
+	assert.Error(t, Schedule{Kind: "weekly"}.Validate())
+}
@@ -0,0 +1,705 @@
+// Package cron — service.go
+// Service runs scheduled jobs from a JSON store in the workspace and
+// implements tools.CronCallback for the cron tool.
+package cron
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dayuer/nanobot-go/internal/bus"
+	"github.com/dayuer/nanobot-go/internal/utils"
+)
+
+// Catch-up policies for runs missed while the service was not running.
+const (
+	CatchUpSkip = "skip" // drop missed runs
+	CatchUpOnce = "once" // run once for any number of missed runs
+	CatchUpAll  = "all"  // replay each missed run, up to MaxCatchUp
+)
+
+// MaxCatchUp bounds the missed runs CatchUpAll replays.
+const MaxCatchUp = 10
+
+// missedAfter is how late a run may start before it counts as missed.
+const missedAfter = time.Minute
+
+// pollInterval bounds how long the scheduler sleeps, so edits made to the
+// store by other processes (e.g. the CLI) are picked up.
+const pollInterval = 30 * time.Second
+
+// HistoryLimit is how many runs each job keeps in its history.
+const HistoryLimit = 20
+
+// maxResultChars bounds the result text kept per run.
+const maxResultChars = 500
+
+// Run triggers.
+const (
+	TriggerSchedule = "schedule"
+	TriggerCatchUp  = "catch-up" // replaying runs missed while down
+	TriggerManual   = "manual"   // requested with RequestRun
+)
+
+// ErrJobNotFound is returned for unknown job IDs.
+var ErrJobNotFound = errors.New("job not found")
+
+// Payload is what a job does when it fires: send Message to the agent and
+// deliver the reply to Channel/ChatID.
+type Payload struct {
+	Message string `json:"message"`
+	Channel string `json:"channel,omitempty"`
+	ChatID  string `json:"chatId,omitempty"`
+}
+
+// JobState is the scheduler's bookkeeping for a job.
+type JobState struct {
+	NextRun    *time.Time `json:"nextRunAt,omitempty"`
+	LastRun    *time.Time `json:"lastRunAt,omitempty"`
+	LastStatus string     `json:"lastStatus,omitempty"` // "ok" or "error"
+	LastError  string     `json:"lastError,omitempty"`
+	LastResult string     `json:"lastResult,omitempty"`
+	Runs       int        `json:"runs,omitempty"`
+	Failures   int        `json:"failures,omitempty"`
+	// RunRequested asks the scheduler to run the job once, out of schedule
+	RunRequested bool `json:"runRequested,omitempty"`
+}
+
+// Run records one execution of a job.
+type Run struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Trigger string    `json:"trigger"`
+	Status  string    `json:"status"` // "ok" or "error"
+	Result  string    `json:"result,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// Job is a scheduled agent turn.
+type Job struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Enabled  bool     `json:"enabled"`
+	Schedule Schedule `json:"schedule"`
+	Payload  Payload  `json:"payload"`
+	// CatchUp is the missed-run policy; empty = the service default
+	CatchUp string `json:"catchUp,omitempty"`
+	// DeleteAfterRun removes a one-shot job once it has run; otherwise it
+	// is disabled and kept
+	DeleteAfterRun bool      `json:"deleteAfterRun,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+	State          JobState  `json:"state"`
+	// History holds the latest runs, oldest first, up to HistoryLimit
+	History []Run `json:"history,omitempty"`
+}
+
+// storeFile is the on-disk layout of the job store.
+type storeFile struct {
+	Version int    `json:"version"`
+	Jobs    []*Job `json:"jobs"`
+}
+
+// Handler runs a fired job and returns its result.
+type Handler func(ctx context.Context, job Job) (string, error)
+
+// Service schedules the jobs in a JSON store. The store is re-read whenever
+// it changes on disk, so several processes can share it; only one of them
+// should call Run.
+type Service struct {
+	Path    string
+	Handler Handler
+	// Location is the zone for cron expressions and at times that do not
+	// name one; nil = time.Local
+	Location *time.Location
+	// CatchUp is the default missed-run policy; empty = CatchUpOnce
+	CatchUp string
+
+	mu      sync.Mutex
+	jobs    []*Job
+	modTime time.Time
+	running map[string]bool
+	wg      sync.WaitGroup
+	wake    chan struct{}
+	now     func() time.Time
+}
+
+// NewService creates a service storing jobs in workspace/cron/jobs.json.
+func NewService(workspace string, handler Handler) *Service {
+	return &Service{
+		Path:    filepath.Join(workspace, "cron", "jobs.json"),
+		Handler: handler,
+		running: make(map[string]bool),
+		wake:    make(chan struct{}, 1),
+		now:     time.Now,
+	}
+}
+
+// Agent is the part of *agent.AgentLoop that jobs run through.
+type Agent interface {
+	ProcessDirect(ctx context.Context, content, sessionKey, channel, chatID string) (string, error)
+}
+
+// AgentHandler runs each job's message as an agent turn in the job's own
+// session ("cron:<id>") and publishes the reply to the chat that scheduled
+// it.
+func AgentHandler(a Agent, msgBus *bus.MessageBus) Handler {
+	return func(ctx context.Context, job Job) (string, error) {
+		resp, err := a.ProcessDirect(ctx, job.Payload.Message, "cron:"+job.ID, job.Payload.Channel, job.Payload.ChatID)
+		if err != nil {
+			return "", err
+		}
+		if msgBus != nil && resp != "" && job.Payload.Channel != "" && job.Payload.ChatID != "" {
+			if err := msgBus.PublishOutbound(bus.OutboundMessage{
+				Channel: job.Payload.Channel,
+				ChatID:  job.Payload.ChatID,
+				Content: resp,
+			}); err != nil {
+				return resp, fmt.Errorf("publishing reply: %w", err)
+			}
+		}
+		return resp, nil
+	}
+}
+
+func (s *Service) location() *time.Location {
+	if s.Location != nil {
+		return s.Location
+	}
+	return time.Local
+}
+
+func (s *Service) catchUp(job *Job) string {
+	switch {
+	case job.CatchUp != "":
+		return job.CatchUp
+	case s.CatchUp != "":
+		return s.CatchUp
+	}
+	return CatchUpOnce
+}
+
+// ValidCatchUp reports whether policy is a known catch-up policy.
+func ValidCatchUp(policy string) bool {
+	return policy == CatchUpSkip || policy == CatchUpOnce || policy == CatchUpAll
+}
+
+// ── CronCallback ──
+
+// AddJob schedules message for delivery to channel/chatID. Exactly one of
+// everySeconds, cronExpr and at must be set. cronExpr may start with
+// CRON_TZ=<zone>; at is an ISO date-time, in the service's zone unless it
+// carries an offset. One-shot jobs are deleted after they run.
+func (s *Service) AddJob(name, message, channel, chatID string, everySeconds int, cronExpr string, at string) (string, error) {
+	var sched Schedule
+	set := 0
+	if everySeconds > 0 {
+		sched, set = Schedule{Kind: KindEvery, EverySeconds: everySeconds}, set+1
+	}
+	if cronExpr != "" {
+		tz, expr := SplitTZ(cronExpr)
+		sched, set = Schedule{Kind: KindCron, Expr: expr, TZ: tz}, set+1
+	}
+	if at != "" {
+		t, err := ParseTime(at, s.location())
+		if err != nil {
+			return "", err
+		}
+		sched, set = Schedule{Kind: KindAt, At: &t}, set+1
+	}
+	if set != 1 {
+		return "", fmt.Errorf("exactly one of every_seconds, cron_expr or at is required")
+	}
+
+	job, err := s.Add(Job{
+		Name:           name,
+		Schedule:       sched,
+		Payload:        Payload{Message: message, Channel: channel, ChatID: chatID},
+		DeleteAfterRun: sched.Kind == KindAt,
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Created job '%s' (id: %s), %s, next run %s",
+		job.Name, job.ID, job.Schedule, job.State.NextRun.Format(time.RFC3339)), nil
+}
+
+// ListJobs describes the scheduled jobs.
+func (s *Service) ListJobs() (string, error) {
+	jobs, err := s.Jobs()
+	if err != nil {
+		return "", err
+	}
+	if len(jobs) == 0 {
+		return "No scheduled jobs.", nil
+	}
+	var sb strings.Builder
+	sb.WriteString("Scheduled jobs:\n")
+	for _, j := range jobs {
+		next := "none"
+		if j.State.NextRun != nil {
+			next = j.State.NextRun.Format(time.RFC3339)
+		}
+		if !j.Enabled {
+			next = "disabled"
+		}
+		fmt.Fprintf(&sb, "- %s (id: %s): %s, next run %s\n", j.Name, j.ID, j.Schedule, next)
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// RemoveJob deletes a job.
+func (s *Service) RemoveJob(jobID string) (string, error) {
+	if err := s.Remove(jobID); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Removed job %s", jobID), nil
+}
+
+// ── Store operations ──
+
+// Add validates job, assigns its ID and first run time, and stores it
+// enabled.
+func (s *Service) Add(job Job) (Job, error) {
+	if strings.TrimSpace(job.Payload.Message) == "" {
+		return Job{}, fmt.Errorf("message is required")
+	}
+	if err := job.Schedule.Validate(); err != nil {
+		return Job{}, err
+	}
+	if job.CatchUp != "" && !ValidCatchUp(job.CatchUp) {
+		return Job{}, fmt.Errorf("unknown catch-up policy %q", job.CatchUp)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		return Job{}, err
+	}
+	now := s.now()
+	next := job.Schedule.Next(now, s.location())
+	if next.IsZero() {
+		return Job{}, fmt.Errorf("schedule %s never runs after %s", job.Schedule, now.Format(time.RFC3339))
+	}
+	id, err := newJobID()
+	if err != nil {
+		return Job{}, err
+	}
+	job.ID = id
+	if job.Name == "" {
+		job.Name = job.ID
+	}
+	job.Enabled = true
+	job.CreatedAt, job.UpdatedAt = now, now
+	job.State = JobState{NextRun: &next}
+	s.jobs = append(s.jobs, &job)
+	if err := s.saveLocked(); err != nil {
+		return Job{}, err
+	}
+	s.notify()
+	return job, nil
+}
+
+// Remove deletes the job with id.
+func (s *Service) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		return err
+	}
+	for i, j := range s.jobs {
+		if j.ID == id {
+			s.jobs = append(s.jobs[:i], s.jobs[i+1:]...)
+			return s.saveLocked()
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrJobNotFound, id)
+}
+
+// Get returns a copy of the job with id.
+func (s *Service) Get(id string) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		return Job{}, err
+	}
+	j := s.findLocked(id)
+	if j == nil {
+		return Job{}, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	return *j, nil
+}
+
+// SetEnabled enables or disables a job. An enabled job is scheduled from
+// now; a disabled one has no next run.
+func (s *Service) SetEnabled(id string, enabled bool) (Job, error) {
+	return s.update(id, func(j *Job, now time.Time) error {
+		if !enabled {
+			j.Enabled, j.State.NextRun = false, nil
+			return nil
+		}
+		next := j.Schedule.Next(now, s.location())
+		if next.IsZero() {
+			return fmt.Errorf("schedule %s never runs after %s", j.Schedule, now.Format(time.RFC3339))
+		}
+		j.Enabled, j.State.NextRun = true, &next
+		return nil
+	})
+}
+
+// RequestRun asks the scheduler to run a job once as soon as possible,
+// whether or not it is enabled; its schedule is unchanged. The run happens
+// in whichever process calls Run on the store.
+func (s *Service) RequestRun(id string) (Job, error) {
+	return s.update(id, func(j *Job, _ time.Time) error {
+		j.State.RunRequested = true
+		return nil
+	})
+}
+
+// update applies fn to the job with id and saves the store.
+func (s *Service) update(id string, fn func(j *Job, now time.Time) error) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		return Job{}, err
+	}
+	j := s.findLocked(id)
+	if j == nil {
+		return Job{}, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	now := s.now()
+	if err := fn(j, now); err != nil {
+		return Job{}, err
+	}
+	j.UpdatedAt = now
+	if err := s.saveLocked(); err != nil {
+		return Job{}, err
+	}
+	s.notify()
+	return *j, nil
+}
+
+// Jobs returns copies of all jobs, soonest next run first.
+func (s *Service) Jobs() ([]Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		return nil, err
+	}
+	out := make([]Job, len(s.jobs))
+	for i, j := range s.jobs {
+		out[i] = *j
+	}
+	sort.SliceStable(out, func(a, b int) bool {
+		na, nb := out[a].State.NextRun, out[b].State.NextRun
+		if na == nil || nb == nil {
+			return nb == nil && na != nil
+		}
+		return na.Before(*nb)
+	})
+	return out, nil
+}
+
+// loadLocked re-reads the store if it changed on disk since it was last
+// read or written. A missing store is empty.
+func (s *Service) loadLocked() error {
+	fi, err := os.Stat(s.Path)
+	if os.IsNotExist(err) {
+		s.jobs, s.modTime = nil, time.Time{}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.ModTime().Equal(s.modTime) {
+		return nil
+	}
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return err
+	}
+	var sf storeFile
+	if err := json.Unmarshal(data, &sf); err != nil {
+		return fmt.Errorf("reading %s: %w", s.Path, err)
+	}
+	s.jobs, s.modTime = sf.Jobs, fi.ModTime()
+	return nil
+}
+
+func (s *Service) saveLocked() error {
+	data, err := json.MarshalIndent(storeFile{Version: 1, Jobs: s.jobs}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+		return err
+	}
+	if err := utils.WriteFileAtomic(s.Path, data, 0o644); err != nil {
+		return err
+	}
+	if fi, err := os.Stat(s.Path); err == nil {
+		s.modTime = fi.ModTime()
+	}
+	return nil
+}
+
+func (s *Service) findLocked(id string) *Job {
+	for _, j := range s.jobs {
+		if j.ID == id {
+			return j
+		}
+	}
+	return nil
+}
+
+// notify wakes Run to reconsider the schedule.
+func (s *Service) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// ── Scheduler ──
+
+// Run fires due jobs until ctx is cancelled, then waits for running jobs
+// to finish. Runs missed while the service was down are handled by each
+// job's catch-up policy.
+func (s *Service) Run(ctx context.Context) error {
+	log.Printf("[Cron] ⏰ Scheduler started (%s)", s.Path)
+	for {
+		wait := s.runDue(ctx)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			s.wg.Wait()
+			return nil
+		case <-timer.C:
+		case <-s.wake:
+			timer.Stop()
+		}
+	}
+}
+
+// runDue starts the jobs that are due and returns how long to sleep before
+// the next one.
+func (s *Service) runDue(ctx context.Context) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		log.Printf("[Cron] ❌ %v", err)
+		return pollInterval
+	}
+
+	now := s.now()
+	wait := pollInterval
+	changed := false
+	for i := 0; i < len(s.jobs); i++ {
+		job := s.jobs[i]
+		if s.running[job.ID] {
+			continue
+		}
+		if job.State.RunRequested {
+			job.State.RunRequested = false
+			changed = true
+			s.start(ctx, job, 1, TriggerManual)
+			continue
+		}
+		if !job.Enabled {
+			continue
+		}
+		if job.State.NextRun == nil {
+			// e.g. re-enabled by another process
+			next := job.Schedule.Next(now, s.location())
+			if next.IsZero() {
+				job.Enabled = false
+			} else {
+				job.State.NextRun = &next
+			}
+			changed = true
+		}
+		if job.State.NextRun == nil {
+			continue
+		}
+		if due := job.State.NextRun.Sub(now); due > 0 {
+			wait = min(wait, due)
+			continue
+		}
+
+		runs, trigger := s.missedRuns(job, now)
+		changed = true
+		if next := job.Schedule.Next(now, s.location()); next.IsZero() {
+			job.State.NextRun = nil
+		} else {
+			job.State.NextRun = &next
+			wait = min(wait, next.Sub(now))
+		}
+		if runs == 0 {
+			log.Printf("[Cron] ⏭️ Skipped missed run of %s (%s)", job.ID, job.Name)
+			if job.State.NextRun == nil && s.finishLocked(job) {
+				i--
+			}
+			continue
+		}
+		s.start(ctx, job, runs, trigger)
+	}
+	if changed {
+		if err := s.saveLocked(); err != nil {
+			log.Printf("[Cron] ❌ Saving jobs: %v", err)
+		}
+	}
+	return wait
+}
+
+// missedRuns returns how many times a due job should run now, and why. A
+// run that is only slightly late runs once; runs missed for longer follow
+// the job's catch-up policy.
+func (s *Service) missedRuns(job *Job, now time.Time) (int, string) {
+	due := *job.State.NextRun
+	if now.Sub(due) <= missedAfter {
+		return 1, TriggerSchedule
+	}
+	switch s.catchUp(job) {
+	case CatchUpSkip:
+		return 0, TriggerCatchUp
+	case CatchUpAll:
+		n := 0
+		for t := due; !t.IsZero() && !t.After(now) && n < MaxCatchUp; t = job.Schedule.Next(t, s.location()) {
+			n++
+		}
+		return n, TriggerCatchUp
+	default:
+		return 1, TriggerCatchUp
+	}
+}
+
+// start runs job in the background. Callers hold s.mu.
+func (s *Service) start(ctx context.Context, job *Job, runs int, trigger string) {
+	s.running[job.ID] = true
+	s.wg.Add(1)
+	go s.execute(ctx, *job, runs, trigger)
+}
+
+// finishLocked retires a job with no further runs, deleting it when it is
+// one-shot, and reports whether it was deleted.
+func (s *Service) finishLocked(job *Job) bool {
+	if job.DeleteAfterRun {
+		for i, j := range s.jobs {
+			if j == job {
+				s.jobs = append(s.jobs[:i], s.jobs[i+1:]...)
+				return true
+			}
+		}
+		return false
+	}
+	job.Enabled = false
+	return false
+}
+
+// execute runs job runs times and records the outcomes.
+func (s *Service) execute(ctx context.Context, job Job, runs int, trigger string) {
+	defer s.wg.Done()
+	var history []Run
+	for i := 0; i < runs && ctx.Err() == nil; i++ {
+		log.Printf("[Cron] ▶️ Running %s (%s, %s)", job.ID, job.Name, trigger)
+		run := Run{Start: s.now(), Trigger: trigger, Status: "ok"}
+		var (
+			out string
+			err error
+		)
+		if s.Handler == nil {
+			err = fmt.Errorf("no job handler configured")
+		} else {
+			out, err = s.Handler(ctx, job)
+		}
+		run.End = s.now()
+		run.Result = truncate(out, maxResultChars)
+		if err != nil {
+			log.Printf("[Cron] ❌ Job %s failed: %v", job.ID, err)
+			run.Status, run.Error = "error", err.Error()
+		}
+		history = append(history, run)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, job.ID)
+	if err := s.loadLocked(); err != nil {
+		log.Printf("[Cron] ❌ %v", err)
+		return
+	}
+	j := s.findLocked(job.ID)
+	if j == nil {
+		return // removed while running
+	}
+	for _, run := range history {
+		j.record(run)
+	}
+	if trigger != TriggerManual && j.Schedule.Kind == KindAt && j.State.NextRun == nil {
+		s.finishLocked(j)
+	}
+	if err := s.saveLocked(); err != nil {
+		log.Printf("[Cron] ❌ Saving jobs: %v", err)
+	}
+	s.notify()
+}
+
+// record updates the job's state and history with a finished run.
+func (j *Job) record(run Run) {
+	start := run.Start
+	j.State.LastRun = &start
+	j.State.LastStatus, j.State.LastError, j.State.LastResult = run.Status, run.Error, run.Result
+	j.State.Runs++
+	if run.Status != "ok" {
+		j.State.Failures++
+	}
+	j.History = append(j.History, run)
+	if n := len(j.History) - HistoryLimit; n > 0 {
+		j.History = append([]Run(nil), j.History[n:]...)
+	}
+}
+
+// ParseTime parses an ISO 8601 date-time. Times without an offset are in
+// loc.
+func ParseTime(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02T15:04", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: want ISO 8601, e.g. 2006-01-02T15:04:05", s)
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("job id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// truncate shortens s to at most n runes.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,356 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dayuer/nanobot-go/internal/bus"
+)
+
+// recorder is a Handler that records the jobs it ran.
+type recorder struct {
+	mu   sync.Mutex
+	runs []string
+	err  error
+}
+
+func (r *recorder) handle(_ context.Context, job Job) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs = append(r.runs, job.ID)
+	return "done", r.err
+}
+
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.runs)
+}
+
+// newTestService returns a service in a temp workspace with a settable clock.
+func newTestService(t *testing.T, h Handler) (*Service, *time.Time) {
+	t.Helper()
+	now := mustTime(t, "2025-01-03T10:00:00Z")
+	s := NewService(t.TempDir(), h)
+	s.Location = time.UTC
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+// tick runs due jobs and waits for them to finish.
+func tick(s *Service) {
+	s.runDue(context.Background())
+	s.wg.Wait()
+}
+
+func TestService_AddJob(t *testing.T) {
+	s, _ := newTestService(t, nil)
+
+	out, err := s.AddJob("water", "Drink water", "telegram", "42", 3600, "", "")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Created job 'water'")
+	assert.Contains(t, out, "2025-01-03T11:00:00Z")
+
+	_, err = s.AddJob("standup", "Standup", "telegram", "42", 0, "CRON_TZ=Asia/Shanghai 0 9 * * 1-5", "")
+	require.NoError(t, err)
+
+	_, err = s.AddJob("once", "Call mom", "telegram", "42", 0, "", "2025-01-03T18:30:00")
+	require.NoError(t, err)
+
+	jobs, err := s.Jobs()
+	require.NoError(t, err)
+	require.Len(t, jobs, 3)
+	assert.Equal(t, "water", jobs[0].Name, "soonest first")
+	assert.Equal(t, "once", jobs[1].Name)
+	assert.Equal(t, mustTime(t, "2025-01-03T18:30:00Z"), *jobs[1].State.NextRun)
+	assert.True(t, jobs[1].DeleteAfterRun)
+	assert.Equal(t, "Asia/Shanghai", jobs[2].Schedule.TZ)
+	assert.Equal(t, "0 9 * * 1-5", jobs[2].Schedule.Expr)
+	assert.Equal(t, mustTime(t, "2025-01-06T01:00:00Z"), jobs[2].State.NextRun.UTC())
+
+	list, err := s.ListJobs()
+	require.NoError(t, err)
+	assert.Contains(t, list, "- once (id: ")
+}
+
+func TestService_AddJob_Invalid(t *testing.T) {
+	s, _ := newTestService(t, nil)
+	cases := []struct {
+		every      int
+		expr, at   string
+		wantSubstr string
+	}{
+		{0, "", "", "exactly one"},
+		{60, "* * * * *", "", "exactly one"},
+		{0, "not a cron", "", "5 fields"},
+		{0, "", "tomorrow", "invalid time"},
+		{0, "", "2024-01-01T00:00:00Z", "never runs"},
+	}
+	for _, c := range cases {
+		_, err := s.AddJob("x", "msg", "t", "1", c.every, c.expr, c.at)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), c.wantSubstr)
+	}
+	jobs, _ := s.Jobs()
+	assert.Empty(t, jobs)
+}
+
+func TestService_PersistsAcrossRestarts(t *testing.T) {
+	s, _ := newTestService(t, nil)
+	_, err := s.AddJob("water", "Drink water", "telegram", "42", 3600, "", "")
+	require.NoError(t, err)
+
+	reopened := NewService(filepath.Dir(filepath.Dir(s.Path)), nil)
+	jobs, err := reopened.Jobs()
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "Drink water", jobs[0].Payload.Message)
+	assert.Equal(t, "42", jobs[0].Payload.ChatID)
+
+	_, err = reopened.RemoveJob(jobs[0].ID)
+	require.NoError(t, err)
+	// The first service sees the other process's edit
+	jobs, err = s.Jobs()
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+
+	_, err = s.RemoveJob("nope")
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
+
+func TestService_RunsDueJobs(t *testing.T) {
+	rec := &recorder{}
+	s, now := newTestService(t, rec.handle)
+	_, err := s.AddJob("water", "Drink water", "telegram", "42", 60, "", "")
+	require.NoError(t, err)
+
+	tick(s)
+	assert.Equal(t, 0, rec.count(), "not due yet")
+
+	*now = now.Add(60 * time.Second)
+	tick(s)
+	assert.Equal(t, 1, rec.count())
+
+	jobs, _ := s.Jobs()
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "ok", jobs[0].State.LastStatus)
+	assert.Equal(t, *now, *jobs[0].State.LastRun)
+	assert.Equal(t, now.Add(60*time.Second), *jobs[0].State.NextRun)
+}
+
+func TestService_RecordsFailures(t *testing.T) {
+	rec := &recorder{err: errors.New("provider down")}
+	s, now := newTestService(t, rec.handle)
+	_, err := s.AddJob("water", "Drink water", "telegram", "42", 60, "", "")
+	require.NoError(t, err)
+
+	*now = now.Add(time.Minute)
+	tick(s)
+	jobs, _ := s.Jobs()
+	assert.Equal(t, "error", jobs[0].State.LastStatus)
+	assert.Equal(t, "provider down", jobs[0].State.LastError)
+	assert.True(t, jobs[0].Enabled, "recurring jobs keep running after a failure")
+}
+
+func TestService_History(t *testing.T) {
+	rec := &recorder{}
+	s, now := newTestService(t, rec.handle)
+	job, err := s.Add(Job{Name: "tick", Schedule: Schedule{Kind: KindEvery, EverySeconds: 60}, Payload: Payload{Message: "tick"}})
+	require.NoError(t, err)
+
+	for i := 0; i < HistoryLimit+2; i++ {
+		*now = now.Add(time.Minute)
+		if i == 0 {
+			rec.err = errors.New("boom")
+		} else {
+			rec.err = nil
+		}
+		tick(s)
+	}
+
+	job, err = s.Get(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, HistoryLimit+2, job.State.Runs)
+	assert.Equal(t, 1, job.State.Failures)
+	assert.Equal(t, "done", job.State.LastResult)
+	require.Len(t, job.History, HistoryLimit)
+	assert.Equal(t, *now, job.History[HistoryLimit-1].Start, "newest last")
+	assert.Equal(t, TriggerSchedule, job.History[0].Trigger)
+}
+
+func TestService_SetEnabled(t *testing.T) {
+	rec := &recorder{}
+	s, now := newTestService(t, rec.handle)
+	job, err := s.Add(Job{Schedule: Schedule{Kind: KindEvery, EverySeconds: 60}, Payload: Payload{Message: "tick"}})
+	require.NoError(t, err)
+
+	job, err = s.SetEnabled(job.ID, false)
+	require.NoError(t, err)
+	assert.False(t, job.Enabled)
+	assert.Nil(t, job.State.NextRun)
+
+	*now = now.Add(time.Hour)
+	tick(s)
+	assert.Equal(t, 0, rec.count(), "disabled jobs do not run")
+
+	job, err = s.SetEnabled(job.ID, true)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Minute), *job.State.NextRun, "rescheduled from now, nothing to catch up")
+
+	_, err = s.SetEnabled("nope", true)
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
+
+func TestService_RequestRun(t *testing.T) {
+	rec := &recorder{}
+	s, _ := newTestService(t, rec.handle)
+	job, err := s.Add(Job{Schedule: Schedule{Kind: KindEvery, EverySeconds: 3600}, Payload: Payload{Message: "tick"}})
+	require.NoError(t, err)
+	_, err = s.SetEnabled(job.ID, false)
+	require.NoError(t, err)
+
+	// Requested from another process, e.g. the CLI
+	other := NewService(filepath.Dir(filepath.Dir(s.Path)), nil)
+	_, err = other.RequestRun(job.ID)
+	require.NoError(t, err)
+
+	tick(s)
+	assert.Equal(t, 1, rec.count(), "manual runs ignore enabled and schedule")
+	tick(s)
+	assert.Equal(t, 1, rec.count(), "the request is consumed")
+
+	job, err = other.Get(job.ID)
+	require.NoError(t, err)
+	assert.False(t, job.State.RunRequested)
+	require.Len(t, job.History, 1)
+	assert.Equal(t, TriggerManual, job.History[0].Trigger)
+}
+
+func TestService_OneShotDeletedAfterRun(t *testing.T) {
+	rec := &recorder{}
+	s, now := newTestService(t, rec.handle)
+	_, err := s.AddJob("once", "Call mom", "telegram", "42", 0, "", "2025-01-03T10:30:00Z")
+	require.NoError(t, err)
+
+	*now = now.Add(30 * time.Minute)
+	tick(s)
+	assert.Equal(t, 1, rec.count())
+	jobs, _ := s.Jobs()
+	assert.Empty(t, jobs)
+}
+
+func TestService_CatchUpPolicies(t *testing.T) {
+	cases := []struct {
+		policy string
+		want   int
+	}{
+		{CatchUpSkip, 0},
+		{CatchUpOnce, 1},
+		{CatchUpAll, 5}, // runs at 10:01 … 10:05
+	}
+	for _, c := range cases {
+		t.Run(c.policy, func(t *testing.T) {
+			rec := &recorder{}
+			s, now := newTestService(t, rec.handle)
+			s.CatchUp = c.policy
+			_, err := s.AddJob("tick", "tick", "telegram", "42", 60, "", "")
+			require.NoError(t, err)
+
+			// Down from 10:00:30 to 10:05:30
+			*now = now.Add(5*time.Minute + 30*time.Second)
+			tick(s)
+			assert.Equal(t, c.want, rec.count())
+
+			jobs, _ := s.Jobs()
+			assert.True(t, jobs[0].State.NextRun.After(*now), "rescheduled after now")
+			if c.want > 0 {
+				assert.Equal(t, TriggerCatchUp, jobs[0].History[0].Trigger)
+			}
+		})
+	}
+}
+
+func TestService_CatchUpAllIsBounded(t *testing.T) {
+	rec := &recorder{}
+	s, now := newTestService(t, rec.handle)
+	s.CatchUp = CatchUpAll
+	_, err := s.AddJob("tick", "tick", "telegram", "42", 60, "", "")
+	require.NoError(t, err)
+
+	*now = now.Add(24 * time.Hour)
+	tick(s)
+	assert.Equal(t, MaxCatchUp, rec.count())
+}
+
+func TestService_SkippedOneShotIsRetired(t *testing.T) {
+	rec := &recorder{}
+	s, now := newTestService(t, rec.handle)
+	s.CatchUp = CatchUpSkip
+	_, err := s.AddJob("once", "Call mom", "telegram", "42", 0, "", "2025-01-03T10:30:00Z")
+	require.NoError(t, err)
+
+	*now = now.Add(2 * time.Hour)
+	tick(s)
+	assert.Equal(t, 0, rec.count())
+	jobs, _ := s.Jobs()
+	assert.Empty(t, jobs)
+}
+
+func TestService_Run(t *testing.T) {
+	ran := make(chan Job, 1)
+	s := NewService(t.TempDir(), func(_ context.Context, job Job) (string, error) {
+		ran <- job
+		return "ok", nil
+	})
+	_, err := s.AddJob("soon", "ping", "telegram", "42", 0, "", time.Now().Add(50*time.Millisecond).Format(time.RFC3339Nano))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { s.Run(ctx); close(done) }()
+
+	select {
+	case job := <-ran:
+		assert.Equal(t, "ping", job.Payload.Message)
+	case <-time.After(5 * time.Second):
+		t.Fatal("job did not fire")
+	}
+	cancel()
+	<-done
+}
+
+type fakeAgent struct {
+	sessionKey, channel, chatID string
+}
+
+func (f *fakeAgent) ProcessDirect(_ context.Context, content, sessionKey, channel, chatID string) (string, error) {
+	f.sessionKey, f.channel, f.chatID = sessionKey, channel, chatID
+	return "reminder: " + content, nil
+}
+
+func TestAgentHandler_DeliversToOriginatingChat(t *testing.T) {
+	a := &fakeAgent{}
+	msgBus := bus.NewMessageBus()
+	h := AgentHandler(a, msgBus)
+
+	out, err := h(context.Background(), Job{
+		ID:      "abc",
+		Payload: Payload{Message: "Drink water", Channel: "telegram", ChatID: "42"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "reminder: Drink water", out)
+	assert.Equal(t, "cron:abc", a.sessionKey)
+	assert.Equal(t, "telegram", a.channel)
+
+	msg := <-msgBus.Outbound
+	assert.Equal(t, "telegram", msg.Channel)
+	assert.Equal(t, "42", msg.ChatID)
+	assert.Equal(t, "reminder: Drink water", msg.Content)
+}
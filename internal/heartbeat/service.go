@@ -0,0 +1,214 @@
+// Package heartbeat — service.go
+// Service periodically wakes the agent to act on the tasks listed in the
+// workspace HEARTBEAT.md.
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dayuer/nanobot-go/internal/bus"
+)
+
+// DefaultInterval is the time between heartbeats when none is configured.
+const DefaultInterval = 30 * time.Minute
+
+// DefaultSessionKey is the session heartbeat turns run in.
+const DefaultSessionKey = "heartbeat"
+
+// OKToken is the reply that means nothing needed attention.
+const OKToken = "HEARTBEAT_OK"
+
+// Prompt is sent to the agent on each heartbeat.
+const Prompt = `Read HEARTBEAT.md in your workspace (if it exists).
+Follow any instructions or tasks listed there.
+If nothing needs attention, reply with just: HEARTBEAT_OK`
+
+// Agent is the part of *agent.AgentLoop heartbeats run through.
+type Agent interface {
+	ProcessDirect(ctx context.Context, content, sessionKey, channel, chatID string) (string, error)
+}
+
+// Service runs a heartbeat every Interval, outside QuietHours, while
+// HEARTBEAT.md has actionable content. Replies other than OKToken are
+// published to Channel/ChatID.
+type Service struct {
+	Workspace  string
+	Agent      Agent
+	Bus        *bus.MessageBus
+	Interval   time.Duration // 0 = DefaultInterval
+	QuietHours *QuietHours   // nil = none
+	Location   *time.Location
+	SessionKey string // "" = DefaultSessionKey
+	// Channel and ChatID receive the agent's output; empty = not delivered
+	Channel string
+	ChatID  string
+
+	now func() time.Time // nil = time.Now
+}
+
+// NewService creates a heartbeat for the agent working in workspace.
+func NewService(workspace string, agent Agent, msgBus *bus.MessageBus) *Service {
+	return &Service{Workspace: workspace, Agent: agent, Bus: msgBus}
+}
+
+// File is the path of the heartbeat task list.
+func (s *Service) File() string {
+	return filepath.Join(s.Workspace, "HEARTBEAT.md")
+}
+
+func (s *Service) interval() time.Duration {
+	if s.Interval > 0 {
+		return s.Interval
+	}
+	return DefaultInterval
+}
+
+// Run beats every interval until ctx is cancelled.
+func (s *Service) Run(ctx context.Context) error {
+	log.Printf("[Heartbeat] 💓 Started (every %s)", s.interval())
+	ticker := time.NewTicker(s.interval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := s.Beat(ctx); err != nil {
+				log.Printf("[Heartbeat] ❌ %v", err)
+			}
+		}
+	}
+}
+
+// Beat runs one heartbeat and returns the agent's reply. It returns "" with
+// no error when the beat is skipped: during quiet hours, or when
+// HEARTBEAT.md is missing or has nothing actionable.
+func (s *Service) Beat(ctx context.Context) (string, error) {
+	now := time.Now()
+	if s.now != nil {
+		now = s.now()
+	}
+	if s.Location != nil {
+		now = now.In(s.Location)
+	}
+	if s.QuietHours != nil && s.QuietHours.Contains(now) {
+		return "", nil
+	}
+	data, err := os.ReadFile(s.File())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	if !Actionable(string(data)) {
+		return "", nil
+	}
+
+	key := s.SessionKey
+	if key == "" {
+		key = DefaultSessionKey
+	}
+	resp, err := s.Agent.ProcessDirect(ctx, Prompt, key, s.Channel, s.ChatID)
+	if err != nil {
+		return "", fmt.Errorf("heartbeat turn: %w", err)
+	}
+	if IsOK(resp) {
+		log.Println("[Heartbeat] ✅ Nothing to do")
+		return resp, nil
+	}
+	log.Println("[Heartbeat] 📝 Acted on HEARTBEAT.md")
+	if s.Bus != nil && s.Channel != "" && s.ChatID != "" && strings.TrimSpace(resp) != "" {
+		if err := s.Bus.PublishOutbound(bus.OutboundMessage{Channel: s.Channel, ChatID: s.ChatID, Content: resp}); err != nil {
+			return resp, fmt.Errorf("heartbeat reply: %w", err)
+		}
+	}
+	return resp, nil
+}
+
+// IsOK reports whether resp says nothing needed attention.
+func IsOK(resp string) bool {
+	norm := strings.ToUpper(strings.ReplaceAll(resp, "_", ""))
+	return strings.Contains(norm, strings.ReplaceAll(OKToken, "_", ""))
+}
+
+// Actionable reports whether a HEARTBEAT.md has anything to act on: text
+// other than headings, HTML comments, blank lines, empty checkboxes and
+// completed (- [x]) items.
+func Actionable(content string) bool {
+	inComment := false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if inComment {
+			if _, after, ok := strings.Cut(line, "-->"); ok {
+				inComment, line = false, strings.TrimSpace(after)
+			} else {
+				continue
+			}
+		}
+		if strings.HasPrefix(line, "<!--") {
+			if _, after, ok := strings.Cut(line[4:], "-->"); ok {
+				line = strings.TrimSpace(after)
+			} else {
+				inComment = true
+				continue
+			}
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		item := strings.TrimSpace(strings.TrimLeft(line, "-*+ "))
+		lower := strings.ToLower(item)
+		if lower == "" || lower == "[ ]" || strings.HasPrefix(lower, "[x]") {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// QuietHours is a daily window, which may span midnight, when no
+// heartbeats run.
+type QuietHours struct {
+	Start, End int // minutes after midnight; Start == End means none
+}
+
+// ParseQuietHours parses a window such as "22:00-07:00".
+func ParseQuietHours(s string) (*QuietHours, error) {
+	from, to, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return nil, fmt.Errorf("quiet hours %q: want HH:MM-HH:MM", s)
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return nil, fmt.Errorf("quiet hours %q: %w", s, err)
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return nil, fmt.Errorf("quiet hours %q: %w", s, err)
+	}
+	return &QuietHours{Start: start, End: end}, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Contains reports whether t's wall-clock time falls in the window.
+func (q QuietHours) Contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if q.Start <= q.End {
+		return m >= q.Start && m < q.End
+	}
+	return m >= q.Start || m < q.End
+}
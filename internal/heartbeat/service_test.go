@@ -0,0 +1,143 @@
+package heartbeat
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dayuer/nanobot-go/internal/bus"
+)
+
+type fakeAgent struct {
+	reply string
+	err   error
+	calls int
+	key   string
+}
+
+func (f *fakeAgent) ProcessDirect(_ context.Context, content, sessionKey, channel, chatID string) (string, error) {
+	f.calls++
+	f.key = sessionKey
+	return f.reply, f.err
+}
+
+func newTestService(t *testing.T, heartbeat string, a *fakeAgent) (*Service, *bus.MessageBus) {
+	t.Helper()
+	ws := t.TempDir()
+	if heartbeat != "" {
+		require.NoError(t, os.WriteFile(filepath.Join(ws, "HEARTBEAT.md"), []byte(heartbeat), 0o644))
+	}
+	msgBus := bus.NewMessageBus()
+	s := NewService(ws, a, msgBus)
+	s.Channel, s.ChatID = "telegram", "42"
+	return s, msgBus
+}
+
+func TestActionable(t *testing.T) {
+	empty := []string{
+		"",
+		"# Heartbeat\n\n## Tasks\n",
+		"<!-- add tasks below -->\n- [ ]\n* [ ]\n",
+		"# Tasks\n- [x] water the plants\n- [X] done too\n-\n",
+		"<!--\nmulti-line\ncomment\n-->\n",
+	}
+	for _, c := range empty {
+		assert.False(t, Actionable(c), "%q", c)
+	}
+	actionable := []string{
+		"Check the server disk usage",
+		"# Tasks\n- [ ] send the weekly report\n",
+		"<!-- note --> ping Bob",
+		"<!--\ncomment\n--> follow up with Alice",
+	}
+	for _, c := range actionable {
+		assert.True(t, Actionable(c), "%q", c)
+	}
+}
+
+func TestIsOK(t *testing.T) {
+	assert.True(t, IsOK("HEARTBEAT_OK"))
+	assert.True(t, IsOK("heartbeat_ok"))
+	assert.True(t, IsOK("All good. HEARTBEAT_OK"))
+	assert.False(t, IsOK("Sent the weekly report."))
+}
+
+func TestQuietHours(t *testing.T) {
+	q, err := ParseQuietHours("22:00-07:00")
+	require.NoError(t, err)
+	at := func(h, m int) time.Time { return time.Date(2025, 1, 3, h, m, 0, 0, time.UTC) }
+	assert.True(t, q.Contains(at(23, 0)))
+	assert.True(t, q.Contains(at(6, 59)))
+	assert.False(t, q.Contains(at(7, 0)))
+	assert.False(t, q.Contains(at(12, 0)))
+
+	q, err = ParseQuietHours("12:00-13:30")
+	require.NoError(t, err)
+	assert.True(t, q.Contains(at(13, 0)))
+	assert.False(t, q.Contains(at(13, 30)))
+
+	for _, bad := range []string{"22:00", "25:00-07:00", "noon-1"} {
+		_, err := ParseQuietHours(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestBeat_DeliversOutput(t *testing.T) {
+	a := &fakeAgent{reply: "Sent the weekly report."}
+	s, msgBus := newTestService(t, "- [ ] send the weekly report\n", a)
+
+	out, err := s.Beat(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Sent the weekly report.", out)
+	assert.Equal(t, DefaultSessionKey, a.key)
+
+	msg := <-msgBus.Outbound
+	assert.Equal(t, "telegram", msg.Channel)
+	assert.Equal(t, "42", msg.ChatID)
+	assert.Equal(t, "Sent the weekly report.", msg.Content)
+}
+
+func TestBeat_OKIsNotDelivered(t *testing.T) {
+	a := &fakeAgent{reply: "HEARTBEAT_OK"}
+	s, msgBus := newTestService(t, "check the inbox", a)
+
+	_, err := s.Beat(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, a.calls)
+	assert.Zero(t, msgBus.OutboundSize())
+}
+
+func TestBeat_Skips(t *testing.T) {
+	a := &fakeAgent{reply: "did things"}
+
+	s, _ := newTestService(t, "", a)
+	out, err := s.Beat(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, out, "no HEARTBEAT.md")
+
+	s, _ = newTestService(t, "# Tasks\n- [x] done\n", a)
+	_, err = s.Beat(context.Background())
+	require.NoError(t, err)
+
+	s, _ = newTestService(t, "check the inbox", a)
+	s.QuietHours = &QuietHours{Start: 22 * 60, End: 7 * 60}
+	s.Location = time.UTC
+	s.now = func() time.Time { return time.Date(2025, 1, 3, 23, 30, 0, 0, time.UTC) }
+	_, err = s.Beat(context.Background())
+	require.NoError(t, err)
+
+	assert.Zero(t, a.calls)
+}
+
+func TestBeat_AgentError(t *testing.T) {
+	a := &fakeAgent{err: errors.New("provider down")}
+	s, _ := newTestService(t, "check the inbox", a)
+	_, err := s.Beat(context.Background())
+	assert.ErrorContains(t, err, "provider down")
+}
@@ -13,6 +13,7 @@ package lane
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -39,6 +40,11 @@ type ChatRequest struct {
 	RoleID     string
 	Metadata   map[string]any
 	Timestamp  time.Time
+
+	// Events receives streaming progress (event name + payload) for SSE
+	// callers. Nil for blocking requests. In Collect mode only the first
+	// request's Events is used for the merged run.
+	Events func(event string, data any)
 }
 
 // ChatResult is the processing result.
@@ -50,7 +56,17 @@ type ChatResult struct {
 	RouteInfo      any // routing decision metadata (cluster.RouteInfo)
 }
 
-// ChatHandler processes a single (possibly merged) chat request.
+// Interrupted is the ChatResult.Error of a request replaced by a newer one
+// in Interrupt mode.
+const Interrupted = "interrupted by newer message"
+
+// errInterrupted cancels a running handler when a newer message arrives in
+// Interrupt mode.
+var errInterrupted = errors.New(Interrupted)
+
+// ChatHandler processes a single (possibly merged) chat request. ctx is
+// cancelled when the manager stops, or in Interrupt mode when a newer
+// message arrives for the session.
 type ChatHandler func(ctx context.Context, req ChatRequest) ChatResult
 
 // laneItem wraps a request with its result channel.
@@ -65,7 +81,10 @@ type lane struct {
 	mode          Mode
 	collectWindow time.Duration
 	queue         chan laneItem
+	overflow      []laneItem // requests waiting for room in queue, oldest first
 	idle          bool
+	closed        bool                    // worker exited; submit to a new lane
+	cancel        context.CancelCauseFunc // cancels the running handler; nil when none runs
 	lastActive    time.Time
 	mu            sync.Mutex
 }
@@ -79,6 +98,9 @@ type Manager struct {
 	collectWindow   time.Duration
 	maxLanes        int
 	cleanupInterval time.Duration
+	slots           chan struct{} // bounds concurrent handler calls; nil = unbounded
+	ctx             context.Context
+	cancel          context.CancelFunc
 	stopCh          chan struct{}
 }
 
@@ -89,6 +111,7 @@ type ManagerConfig struct {
 	CollectWindow   time.Duration // Collect window (default 2s)
 	MaxLanes        int           // Max concurrent lanes (default 1000)
 	CleanupInterval time.Duration // Idle lane cleanup interval (default 10m)
+	MaxConcurrent   int           // Max handler calls at once across lanes (0 = unlimited)
 }
 
 // NewManager creates a lane manager.
@@ -115,6 +138,10 @@ func NewManager(cfg ManagerConfig) *Manager {
 		cleanupInterval: cfg.CleanupInterval,
 		stopCh:          make(chan struct{}),
 	}
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	if cfg.MaxConcurrent > 0 {
+		m.slots = make(chan struct{}, cfg.MaxConcurrent)
+	}
 
 	go m.periodicCleanup()
 	return m
@@ -122,30 +149,79 @@ func NewManager(cfg ManagerConfig) *Manager {
 
 // Submit sends a chat request to its session's lane and waits for the result.
 func (m *Manager) Submit(ctx context.Context, req ChatRequest, mode Mode) (ChatResult, error) {
+	done, err := m.Enqueue(ctx, req, mode)
+	if err != nil {
+		return ChatResult{}, err
+	}
+
+	select {
+	case result := <-done:
+		return result, nil
+	case <-ctx.Done():
+		return ChatResult{}, ctx.Err()
+	}
+}
+
+// Enqueue queues a chat request on its session's lane and returns a channel
+// that receives the result. Requests enqueued one after another from a
+// single goroutine are processed in that order. Enqueue does not wait for
+// a busy lane: once its queue is full, requests line up behind it. On an
+// Interrupt lane it cancels the request being handled.
+func (m *Manager) Enqueue(ctx context.Context, req ChatRequest, mode Mode) (<-chan ChatResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if mode == "" {
 		mode = m.defaultMode
 	}
 	if req.Timestamp.IsZero() {
 		req.Timestamp = time.Now()
 	}
-
-	l := m.getOrCreateLane(req.SessionKey, mode)
 	item := laneItem{
 		request: req,
 		done:    make(chan ChatResult, 1),
 	}
 
-	select {
-	case l.queue <- item:
-	case <-ctx.Done():
-		return ChatResult{}, ctx.Err()
+	for {
+		l := m.getOrCreateLane(req.SessionKey, mode)
+		l.mu.Lock()
+		if l.closed {
+			// The worker exited after we found the lane; it is gone from the map
+			l.mu.Unlock()
+			continue
+		}
+		if l.mode == ModeInterrupt && l.cancel != nil {
+			l.cancel(errInterrupted)
+		}
+		queued := false
+		if len(l.overflow) == 0 {
+			select {
+			case l.queue <- item:
+				queued = true
+			default:
+			}
+		}
+		if !queued {
+			// Queue full: the worker moves the request over as it makes room
+			l.overflow = append(l.overflow, item)
+		}
+		l.mu.Unlock()
+		return item.done, nil
 	}
+}
 
-	select {
-	case result := <-item.done:
-		return result, nil
-	case <-ctx.Done():
-		return ChatResult{}, ctx.Err()
+// refill moves overflowed requests into the queue while it has room.
+func (l *lane) refill() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for len(l.overflow) > 0 {
+		select {
+		case l.queue <- l.overflow[0]:
+			l.overflow[0] = laneItem{}
+			l.overflow = l.overflow[1:]
+		default:
+			return
+		}
 	}
 }
 
@@ -182,6 +258,7 @@ func (m *Manager) runWorker(l *lane) {
 	for {
 		select {
 		case item := <-l.queue:
+			l.refill()
 			l.mu.Lock()
 			l.idle = false
 			l.lastActive = time.Now()
@@ -209,7 +286,18 @@ func (m *Manager) runWorker(l *lane) {
 		case <-time.After(5 * time.Minute):
 			// No activity for 5 minutes, exit worker
 			m.mu.Lock()
-			delete(m.lanes, l.sessionKey)
+			l.mu.Lock()
+			if len(l.queue) > 0 || len(l.overflow) > 0 {
+				// A request arrived as the timer fired
+				l.mu.Unlock()
+				m.mu.Unlock()
+				continue
+			}
+			l.closed = true
+			if m.lanes[l.sessionKey] == l {
+				delete(m.lanes, l.sessionKey)
+			}
+			l.mu.Unlock()
 			m.mu.Unlock()
 			return
 
@@ -220,8 +308,8 @@ func (m *Manager) runWorker(l *lane) {
 }
 
 // processFollowup: direct processing, one at a time.
-func (m *Manager) processFollowup(_ *lane, item laneItem) ChatResult {
-	return m.handler(context.Background(), item.request)
+func (m *Manager) processFollowup(l *lane, item laneItem) ChatResult {
+	return m.handle(l, item.request)
 }
 
 // processCollect: wait a window, merge queued messages, process once.
@@ -237,6 +325,7 @@ func (m *Manager) processCollect(l *lane, item laneItem) ChatResult {
 	for collecting := true; collecting; {
 		select {
 		case extra := <-l.queue:
+			l.refill()
 			merged = append(merged, extra.request.Content)
 			extras = append(extras, extra)
 		case <-timer.C:
@@ -248,7 +337,7 @@ func (m *Manager) processCollect(l *lane, item laneItem) ChatResult {
 	mergedReq := item.request
 	mergedReq.Content = strings.Join(merged, "\n")
 
-	result := m.handler(context.Background(), mergedReq)
+	result := m.handle(l, mergedReq)
 	result.RequestsMerged = len(merged)
 
 	if len(merged) > 1 {
@@ -271,15 +360,54 @@ func (m *Manager) processInterrupt(l *lane, item laneItem) ChatResult {
 	for {
 		select {
 		case newer := <-l.queue:
+			l.refill()
 			// Return empty result to discarded request
-			latest.done <- ChatResult{Content: "", Error: "interrupted by newer message"}
+			latest.done <- ChatResult{Content: "", Error: Interrupted}
 			latest = newer
 		default:
 			goto process
 		}
 	}
 process:
-	return m.handler(context.Background(), latest.request)
+	return m.handle(l, latest.request)
+}
+
+// handle runs the handler once a concurrency slot is free, with a context
+// the lane cancels on interrupt. A failure caused by the interrupt is
+// reported as Interrupted.
+func (m *Manager) handle(l *lane, req ChatRequest) ChatResult {
+	ctx, cancel := context.WithCancelCause(m.ctx)
+	defer cancel(nil)
+	l.mu.Lock()
+	l.cancel = cancel
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		l.cancel = nil
+		l.mu.Unlock()
+	}()
+
+	if m.slots != nil {
+		select {
+		case m.slots <- struct{}{}:
+			defer func() { <-m.slots }()
+		case <-ctx.Done():
+			return ChatResult{Error: cancelReason(ctx)}
+		}
+	}
+	result := m.handler(ctx, req)
+	if result.Error != "" && ctx.Err() != nil {
+		result.Error = cancelReason(ctx)
+	}
+	return result
+}
+
+// cancelReason is the ChatResult.Error of a request whose ctx was cancelled.
+func cancelReason(ctx context.Context) string {
+	if context.Cause(ctx) == errInterrupted {
+		return Interrupted
+	}
+	return ctx.Err().Error()
 }
 
 // cleanupIdleLanes removes long-idle lanes (called under lock).
@@ -310,8 +438,9 @@ func (m *Manager) periodicCleanup() {
 	}
 }
 
-// Stop shuts down the manager.
+// Stop shuts down the manager and cancels running handlers.
 func (m *Manager) Stop() {
+	m.cancel()
 	close(m.stopCh)
 }
 
@@ -2,6 +2,8 @@ package lane
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -110,6 +112,38 @@ func TestInterrupt_DiscardsOld(t *testing.T) {
 	}
 }
 
+func TestInterrupt_CancelsRunningHandler(t *testing.T) {
+	started := make(chan struct{})
+	handler := func(ctx context.Context, req ChatRequest) ChatResult {
+		if req.Content == "msg1" {
+			close(started)
+			<-ctx.Done()
+			return ChatResult{Error: ctx.Err().Error()}
+		}
+		return ChatResult{Content: "done: " + req.Content}
+	}
+
+	m := NewManager(ManagerConfig{Handler: handler, DefaultMode: ModeInterrupt})
+	defer m.Stop()
+
+	ctx := context.Background()
+	first, err := m.Enqueue(ctx, ChatRequest{Content: "msg1", SessionKey: "user1"}, "")
+	if err != nil {
+		t.Fatalf("Enqueue() error: %v", err)
+	}
+	<-started
+	result, err := m.Submit(ctx, ChatRequest{Content: "msg2", SessionKey: "user1"}, "")
+	if err != nil {
+		t.Fatalf("Submit() error: %v", err)
+	}
+	if result.Content != "done: msg2" {
+		t.Errorf("result.Content = %q, want %q", result.Content, "done: msg2")
+	}
+	if r := <-first; r.Error != Interrupted {
+		t.Errorf("interrupted result.Error = %q, want %q", r.Error, Interrupted)
+	}
+}
+
 func TestManager_MultipleSessionsIndependent(t *testing.T) {
 	m := NewManager(ManagerConfig{
 		Handler:     echoHandler,
@@ -130,6 +164,117 @@ func TestManager_MultipleSessionsIndependent(t *testing.T) {
 	}
 }
 
+func TestEnqueue_KeepsSessionOrder(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	handler := func(_ context.Context, req ChatRequest) ChatResult {
+		mu.Lock()
+		order = append(order, req.Content)
+		mu.Unlock()
+		return ChatResult{Content: req.Content}
+	}
+	m := NewManager(ManagerConfig{Handler: handler, DefaultMode: ModeFollowup})
+	defer m.Stop()
+
+	var results []<-chan ChatResult
+	for i := 0; i < 5; i++ {
+		done, err := m.Enqueue(context.Background(), ChatRequest{Content: fmt.Sprint(i), SessionKey: "user1"}, "")
+		if err != nil {
+			t.Fatalf("Enqueue() error: %v", err)
+		}
+		results = append(results, done)
+	}
+	for i, done := range results {
+		if r := <-done; r.Content != fmt.Sprint(i) {
+			t.Errorf("result %d = %q", i, r.Content)
+		}
+	}
+	if got := fmt.Sprint(order); got != "[0 1 2 3 4]" {
+		t.Errorf("processed in order %s", got)
+	}
+}
+
+func TestEnqueue_FullLaneDoesNotBlock(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var order []int
+	handler := func(_ context.Context, req ChatRequest) ChatResult {
+		<-release
+		var n int
+		fmt.Sscan(req.Content, &n)
+		mu.Lock()
+		order = append(order, n)
+		mu.Unlock()
+		return ChatResult{Content: req.Content}
+	}
+	m := NewManager(ManagerConfig{Handler: handler, DefaultMode: ModeFollowup})
+	defer m.Stop()
+
+	// Far more than the lane's queue holds, while the handler is stuck
+	const n = 250
+	var results []<-chan ChatResult
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		done, err := m.Enqueue(context.Background(), ChatRequest{Content: fmt.Sprint(i), SessionKey: "user1"}, "")
+		if err != nil {
+			t.Fatalf("Enqueue() error: %v", err)
+		}
+		results = append(results, done)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Enqueue blocked on a full lane for %v", elapsed)
+	}
+
+	close(release)
+	for _, done := range results {
+		<-done
+	}
+	for i, got := range order {
+		if got != i {
+			t.Fatalf("request %d processed at position %d", got, i)
+		}
+	}
+	if len(order) != n {
+		t.Errorf("processed %d requests, want %d", len(order), n)
+	}
+}
+
+func TestManager_MaxConcurrent(t *testing.T) {
+	var running, peak atomic.Int32
+	handler := func(_ context.Context, req ChatRequest) ChatResult {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(30 * time.Millisecond)
+		running.Add(-1)
+		return ChatResult{Content: req.Content}
+	}
+	m := NewManager(ManagerConfig{Handler: handler, DefaultMode: ModeFollowup, MaxConcurrent: 2})
+	defer m.Stop()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m.Submit(context.Background(), ChatRequest{Content: "x", SessionKey: fmt.Sprint("s", i)}, "")
+		}(i)
+	}
+	wg.Wait()
+
+	if p := peak.Load(); p != 2 {
+		t.Errorf("peak concurrency = %d, want 2", p)
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("6 calls capped at 2 finished in %v", elapsed)
+	}
+}
+
 func TestManager_Stats(t *testing.T) {
 	m := NewManager(ManagerConfig{
 		Handler:     echoHandler,
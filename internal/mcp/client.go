@@ -0,0 +1,226 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dayuer/nanobot-go/internal/config"
+)
+
+// ClientInfo is sent to servers in initialize.
+var ClientInfo = Implementation{Name: "nanobot-go", Version: "0.1.0"}
+
+// Reconnection settings: after a transport failure the client re-dials
+// with exponential backoff, then retries the failed call once.
+const (
+	reconnectAttempts = 3
+	reconnectDelay    = 200 * time.Millisecond
+)
+
+// Client is a connection to one MCP server. It is safe for concurrent use
+// and reconnects transparently when the server process or session goes away.
+type Client struct {
+	Config config.MCPServerConfig
+
+	mu     sync.Mutex
+	t      transport
+	server InitializeResult
+	nextID atomic.Int64
+}
+
+// NewClient creates an unconnected client.
+func NewClient(cfg config.MCPServerConfig) *Client {
+	return &Client{Config: cfg}
+}
+
+// Name returns the configured server name.
+func (c *Client) Name() string { return c.Config.Name }
+
+// ServerInfo returns what the server reported in initialize.
+func (c *Client) ServerInfo() InitializeResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.server
+}
+
+// Connect dials the server and performs the initialize handshake.
+func (c *Client) Connect(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.connectLocked(ctx)
+}
+
+func (c *Client) connectLocked(ctx context.Context) error {
+	if c.t != nil {
+		c.t.close()
+		c.t = nil
+	}
+	t, err := dial(c.Config)
+	if err != nil {
+		return err
+	}
+
+	var result InitializeResult
+	err = c.roundTrip(ctx, t, "initialize", InitializeParams{
+		ProtocolVersion: ProtocolVersion,
+		Capabilities:    map[string]any{},
+		ClientInfo:      ClientInfo,
+	}, &result)
+	if err != nil {
+		t.close()
+		return fmt.Errorf("mcp %s initialize: %w", c.Config.Name, err)
+	}
+	if ht, ok := t.(*httpTransport); ok {
+		ht.setProtocolVersion(result.ProtocolVersion)
+	}
+
+	note, _ := newRequest(0, "notifications/initialized", nil)
+	if err := t.notify(ctx, note); err != nil {
+		t.close()
+		return fmt.Errorf("mcp %s initialized: %w", c.Config.Name, err)
+	}
+
+	c.t = t
+	c.server = result
+	return nil
+}
+
+// Close shuts the connection down.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.t == nil {
+		return nil
+	}
+	err := c.t.close()
+	c.t = nil
+	return err
+}
+
+// ListTools returns every tool the server offers, following pagination.
+func (c *Client) ListTools(ctx context.Context) ([]ToolInfo, error) {
+	var all []ToolInfo
+	cursor := ""
+	for {
+		params := map[string]any{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		var page ListToolsResult
+		if err := c.call(ctx, "tools/list", params, &page); err != nil {
+			return nil, err
+		}
+		all = append(all, page.Tools...)
+		if page.NextCursor == "" {
+			return all, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool invokes a remote tool.
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]any) (*CallToolResult, error) {
+	var result CallToolResult
+	if err := c.call(ctx, "tools/call", CallToolParams{Name: name, Arguments: args}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// call performs a request. On transport failure it reconnects, then retries
+// idempotent methods and requests the server never processed. Others are
+// not re-sent: the server may already have acted on them (a tools/call can
+// have side effects).
+func (c *Client) call(ctx context.Context, method string, params, out any) error {
+	t, err := c.transport(ctx)
+	if err != nil {
+		return err
+	}
+	err = c.roundTrip(ctx, t, method, params, out)
+	if err == nil || !errors.Is(err, ErrClosed) {
+		return err
+	}
+
+	log.Printf("[MCP] ⚠️ %s: %v, reconnecting", c.Config.Name, err)
+	t, rerr := c.reconnect(ctx, t)
+	if rerr != nil {
+		return fmt.Errorf("mcp %s: %w (reconnect failed: %v)", c.Config.Name, err, rerr)
+	}
+	if !idempotent(method) && !errors.Is(err, errUnsent) {
+		return fmt.Errorf("mcp %s: %s: %w", c.Config.Name, method, err)
+	}
+	return c.roundTrip(ctx, t, method, params, out)
+}
+
+// idempotent reports whether method is safe to re-send after a failure.
+func idempotent(method string) bool {
+	return method == "initialize" || method == "tools/list"
+}
+
+// transport returns the live transport, connecting lazily.
+func (c *Client) transport(ctx context.Context) (transport, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.t == nil {
+		if err := c.connectLocked(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return c.t, nil
+}
+
+// reconnect replaces failed with a fresh connection. If another goroutine
+// already reconnected, its transport is reused.
+func (c *Client) reconnect(ctx context.Context, failed transport) (transport, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.t != nil && c.t != failed {
+		return c.t, nil
+	}
+
+	delay := reconnectDelay
+	var err error
+	for attempt := 1; attempt <= reconnectAttempts; attempt++ {
+		if err = c.connectLocked(ctx); err == nil {
+			log.Printf("[MCP] 🔄 %s reconnected", c.Config.Name)
+			return c.t, nil
+		}
+		if attempt == reconnectAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return nil, err
+}
+
+func (c *Client) roundTrip(ctx context.Context, t transport, method string, params, out any) error {
+	req, err := newRequest(c.nextID.Add(1), method, params)
+	if err != nil {
+		return err
+	}
+	resp, err := t.call(ctx, req)
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if out == nil || len(resp.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, out); err != nil {
+		return fmt.Errorf("decode %s result: %w", method, err)
+	}
+	return nil
+}
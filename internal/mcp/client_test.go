@@ -0,0 +1,284 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dayuer/nanobot-go/internal/config"
+	"github.com/dayuer/nanobot-go/internal/tools"
+)
+
+var (
+	fakeServerOnce sync.Once
+	fakeServerPath string
+	fakeServerErr  error
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if fakeServerPath != "" {
+		os.RemoveAll(filepath.Dir(fakeServerPath))
+	}
+	os.Exit(code)
+}
+
+// buildFakeServer compiles testdata/fakeserver once per test run.
+func buildFakeServer(t *testing.T) string {
+	t.Helper()
+	fakeServerOnce.Do(func() {
+		dir, err := os.MkdirTemp("", "mcp-fakeserver")
+		if err != nil {
+			fakeServerErr = err
+			return
+		}
+		fakeServerPath = filepath.Join(dir, "fakeserver")
+		out, err := exec.Command("go", "build", "-o", fakeServerPath, "./testdata/fakeserver").CombinedOutput()
+		if err != nil {
+			fakeServerErr = fmt.Errorf("build fake server: %v\n%s", err, out)
+		}
+	})
+	if fakeServerErr != nil {
+		t.Skip(fakeServerErr)
+	}
+	return fakeServerPath
+}
+
+func stdioConfig(t *testing.T) config.MCPServerConfig {
+	return config.MCPServerConfig{
+		Name:    "fake",
+		Command: buildFakeServer(t),
+		Env:     map[string]string{"FAKE_MCP_GREETING": "bonjour"},
+	}
+}
+
+func testCtx(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestClient_Stdio_InitializeAndListTools(t *testing.T) {
+	c := NewClient(stdioConfig(t))
+	defer c.Close()
+	ctx := testCtx(t)
+
+	require.NoError(t, c.Connect(ctx))
+	assert.Equal(t, "fake", c.ServerInfo().ServerInfo.Name)
+
+	infos, err := c.ListTools(ctx)
+	require.NoError(t, err)
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name)
+	}
+	// five tools across three pages
+	assert.Equal(t, []string{"echo", "add", "fail", "crash", "env"}, names)
+}
+
+func TestClient_Stdio_CallTool(t *testing.T) {
+	c := NewClient(stdioConfig(t))
+	defer c.Close()
+	ctx := testCtx(t)
+
+	res, err := c.CallTool(ctx, "add", map[string]any{"a": 2, "b": 3})
+	require.NoError(t, err)
+	assert.Equal(t, "5", res.Text())
+	assert.False(t, res.IsError)
+
+	res, err = c.CallTool(ctx, "env", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "bonjour", res.Text())
+
+	_, err = c.CallTool(ctx, "nope", nil)
+	var rpcErr *RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, CodeInvalidParams, rpcErr.Code)
+}
+
+func TestClient_Stdio_ReconnectsAfterCrash(t *testing.T) {
+	cfg := stdioConfig(t)
+	crashLog := filepath.Join(t.TempDir(), "crashes")
+	cfg.Env["FAKE_MCP_CRASH_LOG"] = crashLog
+	c := NewClient(cfg)
+	defer c.Close()
+	ctx := testCtx(t)
+
+	_, err := c.CallTool(ctx, "crash", nil)
+	require.Error(t, err) // the call that kills the server cannot succeed
+	data, err := os.ReadFile(crashLog)
+	require.NoError(t, err)
+	assert.Equal(t, "crash\n", string(data), "tools/call is not re-sent after a failure")
+
+	res, err := c.CallTool(ctx, "echo", map[string]any{"text": "back"})
+	require.NoError(t, err)
+	assert.Equal(t, "back", res.Text())
+}
+
+func TestConnectServers_RegistersNamespacedTools(t *testing.T) {
+	reg := tools.NewRegistry()
+	m := ConnectServers(testCtx(t), []config.MCPServerConfig{
+		stdioConfig(t),
+		{Name: "broken", Command: "/nonexistent/mcp-server"},
+	}, reg, tools.AllTools)
+	defer m.Close()
+
+	require.Len(t, m.Clients(), 1)
+	assert.Len(t, reg.All(), 5)
+
+	echo := reg.Get("mcp_fake_echo")
+	require.NotNil(t, echo)
+	assert.Equal(t, "Echo text back", echo.Description())
+	assert.Equal(t, []any{"text"}, echo.Parameters()["required"])
+
+	out, err := echo.Execute(context.Background(), map[string]any{"text": "hi"})
+	require.NoError(t, err)
+	assert.Equal(t, "hi", out)
+
+	_, err = reg.Get("mcp_fake_fail").Execute(context.Background(), nil)
+	assert.EqualError(t, err, "something went wrong")
+
+	// tools without a schema still advertise an object
+	assert.Equal(t, "object", reg.Get("mcp_fake_crash").Parameters()["type"])
+}
+
+func TestConnectServers_OnlyWhitelistedTools(t *testing.T) {
+	reg := tools.NewRegistry()
+	m := ConnectServers(testCtx(t), []config.MCPServerConfig{stdioConfig(t)}, reg, []string{"mcp_fake_echo", "mcp_other_*"})
+	defer m.Close()
+
+	require.Len(t, m.Clients(), 1)
+	assert.Len(t, reg.All(), 1)
+	assert.NotNil(t, reg.Get("mcp_fake_echo"))
+
+	reg = tools.NewRegistry()
+	m2 := ConnectServers(testCtx(t), []config.MCPServerConfig{stdioConfig(t)}, reg, nil)
+	defer m2.Close()
+	assert.Empty(t, reg.All(), "an empty whitelist admits no remote tools")
+}
+
+func TestToolName_Sanitizes(t *testing.T) {
+	assert.Equal(t, "mcp_my_server_search", ToolName("my server", "search"))
+	assert.Equal(t, "mcp_gh-1_issues", ToolName("gh-1", "issues"))
+	assert.Equal(t, "mcp_fs_files_read", ToolName("fs", "files/read"))
+
+	valid := regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+	long := ToolName("server", strings.Repeat("x", 80))
+	assert.Regexp(t, valid, long)
+	assert.NotEqual(t, long, ToolName("server", strings.Repeat("x", 81)), "cut names stay distinct")
+}
+
+// fakeHTTPServer is a streamable HTTP MCP endpoint. It answers tools/call
+// with SSE and everything else with plain JSON, and can drop its session.
+type fakeHTTPServer struct {
+	mu       sync.Mutex
+	sessions map[string]bool
+	next     int
+	versions []string
+}
+
+func (s *fakeHTTPServer) expireAll() {
+	s.mu.Lock()
+	s.sessions = map[string]bool{}
+	s.mu.Unlock()
+}
+
+func (s *fakeHTTPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Method == http.MethodDelete {
+		delete(s.sessions, r.Header.Get("Mcp-Session-Id"))
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	var msg Message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+
+	if msg.Method == "initialize" {
+		s.next++
+		id := fmt.Sprintf("session-%d", s.next)
+		s.sessions[id] = true
+		w.Header().Set("Mcp-Session-Id", id)
+	} else if !s.sessions[r.Header.Get("Mcp-Session-Id")] {
+		http.NotFound(w, r)
+		return
+	} else {
+		s.versions = append(s.versions, r.Header.Get("MCP-Protocol-Version"))
+	}
+	if msg.ID == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	var result any
+	switch msg.Method {
+	case "initialize":
+		result = InitializeResult{ProtocolVersion: ProtocolVersion, ServerInfo: Implementation{Name: "fake-http"}}
+	case "tools/list":
+		result = ListToolsResult{Tools: []ToolInfo{{Name: "lookup"}}}
+	case "tools/call":
+		var p CallToolParams
+		json.Unmarshal(msg.Params, &p)
+		result = CallToolResult{Content: []Content{{Type: "text", Text: "found " + fmt.Sprint(p.Arguments["q"])}}}
+	}
+	raw, _ := json.Marshal(result)
+	reply, _ := json.Marshal(Message{JSONRPC: "2.0", ID: msg.ID, Result: raw})
+
+	if msg.Method == "tools/call" {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\n")
+		fmt.Fprintf(w, "event: message\ndata: %s\n\n", reply)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(reply)
+}
+
+func TestClient_HTTP(t *testing.T) {
+	fake := &fakeHTTPServer{sessions: map[string]bool{}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	c := NewClient(config.MCPServerConfig{Name: "remote", URL: srv.URL})
+	defer c.Close()
+	ctx := testCtx(t)
+
+	infos, err := c.ListTools(ctx)
+	require.NoError(t, err)
+	require.Len(t, infos, 1)
+	assert.Equal(t, "fake-http", c.ServerInfo().ServerInfo.Name)
+
+	res, err := c.CallTool(ctx, "lookup", map[string]any{"q": "go"})
+	require.NoError(t, err)
+	assert.Equal(t, "found go", res.Text())
+
+	// An expired session triggers a fresh initialize and the call succeeds.
+	fake.expireAll()
+	res, err = c.CallTool(ctx, "lookup", map[string]any{"q": "again"})
+	require.NoError(t, err)
+	assert.Equal(t, "found again", res.Text())
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	assert.Equal(t, 2, fake.next)
+	for _, v := range fake.versions {
+		assert.Equal(t, ProtocolVersion, v)
+	}
+}
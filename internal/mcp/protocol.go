@@ -0,0 +1,143 @@
+// Package mcp implements the Model Context Protocol (JSON-RPC 2.0).
+//
+// The client pulls tools from external MCP servers into an agent's tool
+// registry; mirrors upstream agent/tools/mcp.py, each remote tool is wrapped
+// as a tools.Tool named "mcp_<server>_<tool>". The server does the reverse
+// and exposes a tools.Registry to other MCP clients.
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ProtocolVersion is the MCP revision this client speaks.
+const ProtocolVersion = "2025-03-26"
+
+// JSON-RPC 2.0 error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Message is a JSON-RPC 2.0 request, notification or response.
+// Requests have Method and ID, notifications only Method, responses only ID.
+type Message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *RPCError        `json:"error,omitempty"`
+}
+
+// IsResponse reports whether m answers a request.
+func (m *Message) IsResponse() bool { return m.Method == "" && m.ID != nil }
+
+// IsRequest reports whether m is a request expecting a response.
+func (m *Message) IsRequest() bool { return m.Method != "" && m.ID != nil }
+
+// RPCError is a JSON-RPC error object.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("MCP error %d: %s", e.Code, e.Message)
+}
+
+// Implementation identifies a client or server.
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// InitializeParams is sent by the client in the initialize request.
+type InitializeParams struct {
+	ProtocolVersion string         `json:"protocolVersion"`
+	Capabilities    map[string]any `json:"capabilities"`
+	ClientInfo      Implementation `json:"clientInfo"`
+}
+
+// InitializeResult is the server's answer to initialize.
+type InitializeResult struct {
+	ProtocolVersion string         `json:"protocolVersion"`
+	Capabilities    map[string]any `json:"capabilities"`
+	ServerInfo      Implementation `json:"serverInfo"`
+	Instructions    string         `json:"instructions,omitempty"`
+}
+
+// ToolInfo describes one tool offered by a server.
+type ToolInfo struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+// ListToolsResult is one page of tools/list.
+type ListToolsResult struct {
+	Tools      []ToolInfo `json:"tools"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+// CallToolParams is sent with tools/call.
+type CallToolParams struct {
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+}
+
+// Content is one item of a tool result.
+type Content struct {
+	Type     string          `json:"type"` // text, image, audio, resource
+	Text     string          `json:"text,omitempty"`
+	Data     string          `json:"data,omitempty"`
+	MimeType string          `json:"mimeType,omitempty"`
+	Resource json.RawMessage `json:"resource,omitempty"`
+}
+
+// CallToolResult is the server's answer to tools/call.
+type CallToolResult struct {
+	Content []Content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// Text flattens the result into plain text for the LLM.
+func (r *CallToolResult) Text() string {
+	var out []byte
+	for i, c := range r.Content {
+		if i > 0 {
+			out = append(out, '\n')
+		}
+		switch c.Type {
+		case "text":
+			out = append(out, c.Text...)
+		case "resource":
+			out = append(out, c.Resource...)
+		default:
+			out = append(out, fmt.Sprintf("[%s content: %s]", c.Type, c.MimeType)...)
+		}
+	}
+	return string(out)
+}
+
+// newRequest builds a request (id != 0) or notification (id == 0).
+func newRequest(id int64, method string, params any) (*Message, error) {
+	msg := &Message{JSONRPC: "2.0", Method: method}
+	if id != 0 {
+		raw := json.RawMessage(fmt.Sprintf("%d", id))
+		msg.ID = &raw
+	}
+	if params != nil {
+		b, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("marshal %s params: %w", method, err)
+		}
+		msg.Params = b
+	}
+	return msg, nil
+}
@@ -0,0 +1,337 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dayuer/nanobot-go/internal/tools"
+)
+
+// ServerInfo is reported to clients in initialize.
+var ServerInfo = Implementation{Name: "nanobot-go", Version: "0.1.0"}
+
+// maxRequestBody caps one HTTP JSON-RPC message.
+const maxRequestBody = 4 << 20
+
+// sessionIdle is how long an HTTP session survives without requests;
+// clients that vanish without a DELETE would otherwise leak sessions.
+const sessionIdle = 30 * time.Minute
+
+// Server exposes a tools.Registry to MCP clients. Calls go through a
+// tools.Executor, so served tools get the same validation, deadlines and
+// output limits as they do inside the agent loop.
+type Server struct {
+	Info         Implementation
+	Instructions string
+	Executor     *tools.Executor
+
+	// Token is the bearer token HTTP requests must carry; "" disables the check
+	Token string
+	// Origins are the origins allowed to call over HTTP; requests from any
+	// other origin are rejected. Requests without an Origin header (non-
+	// browser clients) are accepted only once Token has been checked.
+	Origins []string
+
+	allow    map[string]bool
+	allowAll bool
+
+	mu       sync.Mutex
+	sessions map[string]time.Time // session ID → last request
+}
+
+// NewServer serves the tools in reg that allow names, with the same
+// semantics as AgentSpec.Tools: "*" serves every tool, an empty list none.
+func NewServer(reg *tools.Registry, allow []string) *Server {
+	s := &Server{
+		Info:     ServerInfo,
+		Executor: tools.NewExecutor(reg),
+		allow:    make(map[string]bool, len(allow)),
+		sessions: make(map[string]time.Time),
+	}
+	for _, name := range allow {
+		if name == "*" {
+			s.allowAll = true
+		}
+		s.allow[name] = true
+	}
+	return s
+}
+
+// Tools returns the served tools, sorted by name.
+func (s *Server) Tools() []tools.Tool {
+	var out []tools.Tool
+	for _, t := range s.Executor.Registry.All() {
+		if s.allowed(t.Name()) {
+			out = append(out, t)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name() < out[j].Name() })
+	return out
+}
+
+func (s *Server) allowed(name string) bool {
+	return s.allowAll || s.allow[name]
+}
+
+// Handle answers one JSON-RPC message. It returns nil for notifications
+// and for responses (the server never issues requests of its own).
+func (s *Server) Handle(ctx context.Context, msg *Message) *Message {
+	if !msg.IsRequest() {
+		return nil
+	}
+	result, rpcErr := s.dispatch(ctx, msg)
+	reply := &Message{JSONRPC: "2.0", ID: msg.ID, Error: rpcErr}
+	if rpcErr == nil {
+		b, err := json.Marshal(result)
+		if err != nil {
+			reply.Error = &RPCError{Code: CodeInternalError, Message: err.Error()}
+		} else {
+			reply.Result = b
+		}
+	}
+	return reply
+}
+
+func (s *Server) dispatch(ctx context.Context, msg *Message) (any, *RPCError) {
+	switch msg.Method {
+	case "initialize":
+		var p InitializeParams
+		if len(msg.Params) > 0 {
+			if err := json.Unmarshal(msg.Params, &p); err != nil {
+				return nil, &RPCError{Code: CodeInvalidParams, Message: err.Error()}
+			}
+		}
+		version := ProtocolVersion
+		if p.ProtocolVersion != "" && p.ProtocolVersion < version {
+			version = p.ProtocolVersion // older clients get their own revision back
+		}
+		return InitializeResult{
+			ProtocolVersion: version,
+			Capabilities:    map[string]any{"tools": map[string]any{"listChanged": false}},
+			ServerInfo:      s.Info,
+			Instructions:    s.Instructions,
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		list := ListToolsResult{Tools: []ToolInfo{}}
+		for _, t := range s.Tools() {
+			list.Tools = append(list.Tools, ToolInfo{
+				Name:        t.Name(),
+				Description: t.Description(),
+				InputSchema: t.Parameters(),
+			})
+		}
+		return list, nil
+	case "tools/call":
+		var p CallToolParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, &RPCError{Code: CodeInvalidParams, Message: err.Error()}
+		}
+		if !s.allowed(p.Name) || s.Executor.Registry.Get(p.Name) == nil {
+			return nil, &RPCError{Code: CodeInvalidParams, Message: "unknown tool: " + p.Name}
+		}
+		res := s.Executor.Execute(ctx, p.Name, p.Arguments)
+		return CallToolResult{
+			Content: []Content{{Type: "text", Text: res.Output}},
+			IsError: res.Outcome != tools.OutcomeOK,
+		}, nil
+	default:
+		return nil, &RPCError{Code: CodeMethodNotFound, Message: "method not found: " + msg.Method}
+	}
+}
+
+// ServeStdio reads newline-delimited JSON-RPC from r and writes replies to w
+// until r is exhausted or ctx is cancelled. Requests run concurrently.
+func (s *Server) ServeStdio(ctx context.Context, r io.Reader, w io.Writer) error {
+	var (
+		writeMu sync.Mutex
+		wg      sync.WaitGroup
+	)
+	enc := json.NewEncoder(w)
+	send := func(m *Message) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if err := enc.Encode(m); err != nil {
+			log.Printf("[MCP] ⚠️ write: %v", err)
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRequestBody)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			break
+		}
+		var msg Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			send(parseError(err))
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if reply := s.Handle(ctx, &msg); reply != nil {
+				send(reply)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// ServeHTTP implements the streamable HTTP transport with plain JSON
+// responses: POST carries one message, DELETE ends the session. Requests
+// need the bearer Token and an allowed Origin, and every request after
+// initialize needs its Mcp-Session-Id.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !s.allowedOrigin(r.Header.Get("Origin"), s.Token != "") {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+	case http.MethodDelete:
+		s.mu.Lock()
+		delete(s.sessions, r.Header.Get("Mcp-Session-Id"))
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var msg Message
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestBody)).Decode(&msg); err != nil {
+		writeJSON(w, parseError(err))
+		return
+	}
+
+	sessionID := r.Header.Get("Mcp-Session-Id")
+	if msg.Method == "initialize" {
+		id, err := newSessionID()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.mu.Lock()
+		s.expireSessionsLocked()
+		s.sessions[id] = time.Now()
+		s.mu.Unlock()
+		w.Header().Set("Mcp-Session-Id", id)
+	} else if sessionID == "" {
+		http.Error(w, "missing Mcp-Session-Id", http.StatusBadRequest)
+		return
+	} else if !s.validSession(sessionID) {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+
+	reply := s.Handle(r.Context(), &msg)
+	if reply == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	writeJSON(w, reply)
+}
+
+// validSession reports whether id is a live session and marks it used.
+func (s *Server) validSession(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	last, ok := s.sessions[id]
+	if !ok || time.Since(last) > sessionIdle {
+		delete(s.sessions, id)
+		return false
+	}
+	s.sessions[id] = time.Now()
+	return true
+}
+
+// expireSessionsLocked drops sessions idle for longer than sessionIdle.
+// Callers hold s.mu.
+func (s *Server) expireSessionsLocked() {
+	for id, last := range s.sessions {
+		if time.Since(last) > sessionIdle {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if s.Token == "" {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) == 1
+}
+
+// allowedOrigin checks the Origin header when present; an absent one is
+// allowed only for authenticated requests.
+func (s *Server) allowedOrigin(origin string, authenticated bool) bool {
+	if origin == "" {
+		return authenticated
+	}
+	for _, o := range s.Origins {
+		if strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// LocalOrigins are the loopback origins of a server listening on addr.
+func LocalOrigins(addr string) []string {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, host := range []string{"127.0.0.1", "localhost", "[::1]"} {
+		out = append(out, "http://"+host+":"+port)
+	}
+	return out
+}
+
+// parseError replies to a message that could not be decoded; its ID is
+// unknown, so it is null.
+func parseError(err error) *Message {
+	null := json.RawMessage("null")
+	return &Message{JSONRPC: "2.0", ID: &null, Error: &RPCError{Code: CodeParseError, Message: err.Error()}}
+}
+
+func writeJSON(w http.ResponseWriter, msg *Message) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(msg)
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("session id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,254 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dayuer/nanobot-go/internal/config"
+	"github.com/dayuer/nanobot-go/internal/tools"
+)
+
+// upperTool returns its "text" argument upper-cased, or fails on "boom".
+type upperTool struct{ name string }
+
+func (t *upperTool) Name() string        { return t.name }
+func (t *upperTool) Description() string { return "Upper-case text" }
+func (t *upperTool) Parameters() map[string]any {
+	return map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"text": map[string]any{"type": "string"}},
+		"required":   []string{"text"},
+	}
+}
+func (t *upperTool) Execute(_ context.Context, args map[string]any) (string, error) {
+	text, _ := args["text"].(string)
+	if text == "boom" {
+		return "", errors.New("exploded")
+	}
+	out := []rune(text)
+	for i, r := range out {
+		if r >= 'a' && r <= 'z' {
+			out[i] = r - 32
+		}
+	}
+	return string(out), nil
+}
+
+func newTestServer(allow ...string) *Server {
+	reg := tools.NewRegistry()
+	reg.Register(&upperTool{name: "upper"})
+	reg.Register(&upperTool{name: "secret"})
+	return NewServer(reg, allow)
+}
+
+func request(t *testing.T, id int64, method string, params any) *Message {
+	t.Helper()
+	msg, err := newRequest(id, method, params)
+	require.NoError(t, err)
+	return msg
+}
+
+func TestServer_ListAndCall(t *testing.T) {
+	s := newTestServer("*")
+	ctx := context.Background()
+
+	reply := s.Handle(ctx, request(t, 1, "tools/list", nil))
+	require.Nil(t, reply.Error)
+	var list ListToolsResult
+	require.NoError(t, json.Unmarshal(reply.Result, &list))
+	require.Len(t, list.Tools, 2)
+	assert.Equal(t, "secret", list.Tools[0].Name)
+	assert.Equal(t, "object", list.Tools[1].InputSchema["type"])
+
+	reply = s.Handle(ctx, request(t, 2, "tools/call", CallToolParams{Name: "upper", Arguments: map[string]any{"text": "hi"}}))
+	require.Nil(t, reply.Error)
+	var res CallToolResult
+	require.NoError(t, json.Unmarshal(reply.Result, &res))
+	assert.False(t, res.IsError)
+	assert.Equal(t, "HI", res.Text())
+}
+
+func TestServer_CallFailuresAreToolErrors(t *testing.T) {
+	s := newTestServer("*")
+	ctx := context.Background()
+
+	for _, args := range []map[string]any{{"text": "boom"}, {}} {
+		reply := s.Handle(ctx, request(t, 1, "tools/call", CallToolParams{Name: "upper", Arguments: args}))
+		require.Nil(t, reply.Error)
+		var res CallToolResult
+		require.NoError(t, json.Unmarshal(reply.Result, &res))
+		assert.True(t, res.IsError, "args %v", args)
+	}
+}
+
+func TestServer_AllowList(t *testing.T) {
+	s := newTestServer("upper")
+	ctx := context.Background()
+
+	require.Len(t, s.Tools(), 1)
+	assert.Len(t, newTestServer("*").Tools(), 2)
+	assert.Empty(t, newTestServer().Tools())
+	reply := s.Handle(ctx, request(t, 1, "tools/call", CallToolParams{Name: "secret", Arguments: map[string]any{"text": "x"}}))
+	require.NotNil(t, reply.Error)
+	assert.Equal(t, CodeInvalidParams, reply.Error.Code)
+}
+
+func TestServer_ProtocolMessages(t *testing.T) {
+	s := newTestServer("*")
+	ctx := context.Background()
+
+	reply := s.Handle(ctx, request(t, 1, "initialize", InitializeParams{ProtocolVersion: "2024-11-05"}))
+	var init InitializeResult
+	require.NoError(t, json.Unmarshal(reply.Result, &init))
+	assert.Equal(t, "2024-11-05", init.ProtocolVersion)
+	assert.Equal(t, "nanobot-go", init.ServerInfo.Name)
+
+	assert.Nil(t, s.Handle(ctx, request(t, 0, "notifications/initialized", nil)))
+	assert.Equal(t, CodeMethodNotFound, s.Handle(ctx, request(t, 2, "resources/list", nil)).Error.Code)
+}
+
+func TestServer_Stdio(t *testing.T) {
+	s := newTestServer("*")
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ServeStdio(context.Background(), inR, outW)
+		outW.Close()
+	}()
+
+	enc := json.NewEncoder(inW)
+	require.NoError(t, enc.Encode(request(t, 7, "tools/call", CallToolParams{Name: "upper", Arguments: map[string]any{"text": "abc"}})))
+
+	line, err := bufio.NewReader(outR).ReadBytes('\n')
+	require.NoError(t, err)
+	var reply Message
+	require.NoError(t, json.Unmarshal(line, &reply))
+	assert.Equal(t, "7", idKey(reply.ID))
+	var res CallToolResult
+	require.NoError(t, json.Unmarshal(reply.Result, &res))
+	assert.Equal(t, "ABC", res.Text())
+
+	inW.Close()
+	assert.NoError(t, <-done)
+}
+
+// newHTTPTestServer serves s over HTTP with token "secret" and the
+// listener's loopback origins allowed.
+func newHTTPTestServer(s *Server) *httptest.Server {
+	s.Token = "secret"
+	srv := httptest.NewServer(s)
+	s.Origins = LocalOrigins(srv.Listener.Addr().String())
+	return srv
+}
+
+func TestServer_HTTP_RoundTripWithClient(t *testing.T) {
+	srv := newHTTPTestServer(newTestServer("upper"))
+	defer srv.Close()
+
+	reg := tools.NewRegistry()
+	m := ConnectServers(testCtx(t), []config.MCPServerConfig{{
+		Name:    "nano",
+		URL:     srv.URL,
+		Headers: map[string]string{"Authorization": "Bearer secret"},
+	}}, reg, tools.AllTools)
+	defer m.Close()
+
+	require.Len(t, reg.All(), 1)
+	out, err := reg.Get("mcp_nano_upper").Execute(context.Background(), map[string]any{"text": "go"})
+	require.NoError(t, err)
+	assert.Equal(t, "GO", out)
+}
+
+func TestServer_HTTP_RejectsUnauthenticatedRequests(t *testing.T) {
+	srv := newHTTPTestServer(newTestServer("*"))
+	defer srv.Close()
+
+	post := func(body string, headers map[string]string) *http.Response {
+		req, err := http.NewRequest("POST", srv.URL, strings.NewReader(body))
+		require.NoError(t, err)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize"}`
+	list := `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`
+	ok := map[string]string{"Authorization": "Bearer secret", "Origin": srv.URL}
+
+	assert.Equal(t, http.StatusUnauthorized, post(initialize, map[string]string{"Origin": srv.URL}).StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, post(initialize, map[string]string{"Authorization": "Bearer wrong", "Origin": srv.URL}).StatusCode)
+	assert.Equal(t, http.StatusOK, post(initialize, map[string]string{"Authorization": "Bearer secret"}).StatusCode, "non-browser clients send no Origin")
+	assert.Equal(t, http.StatusForbidden, post(initialize, map[string]string{"Authorization": "Bearer secret", "Origin": "http://evil.example"}).StatusCode)
+
+	resp := post(initialize, ok)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	session := resp.Header.Get("Mcp-Session-Id")
+	require.NotEmpty(t, session)
+
+	assert.Equal(t, http.StatusBadRequest, post(list, ok).StatusCode)
+	assert.Equal(t, http.StatusNotFound, post(list, map[string]string{"Authorization": "Bearer secret", "Origin": srv.URL, "Mcp-Session-Id": "nope"}).StatusCode)
+	assert.Equal(t, http.StatusOK, post(list, map[string]string{"Authorization": "Bearer secret", "Origin": srv.URL, "Mcp-Session-Id": session}).StatusCode)
+}
+
+func TestServer_HTTP_IdleSessionsExpire(t *testing.T) {
+	s := newTestServer("*")
+	srv := newHTTPTestServer(s)
+	defer srv.Close()
+
+	post := func(body, session string) *http.Response {
+		req, err := http.NewRequest("POST", srv.URL, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer secret")
+		if session != "" {
+			req.Header.Set("Mcp-Session-Id", session)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+	idle := post(`{"jsonrpc":"2.0","id":1,"method":"initialize"}`, "").Header.Get("Mcp-Session-Id")
+	s.mu.Lock()
+	s.sessions[idle] = time.Now().Add(-sessionIdle - time.Second)
+	s.mu.Unlock()
+
+	assert.Equal(t, http.StatusNotFound, post(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`, idle).StatusCode)
+	s.mu.Lock()
+	assert.Empty(t, s.sessions)
+	s.mu.Unlock()
+}
+
+func TestServer_ParseErrorHasNullID(t *testing.T) {
+	srv := newHTTPTestServer(newTestServer("*"))
+	defer srv.Close()
+
+	req, err := http.NewRequest("POST", srv.URL, strings.NewReader("{not json"))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Origin", srv.URL)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var reply map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&reply))
+	id, hasID := reply["id"]
+	assert.True(t, hasID)
+	assert.Nil(t, id)
+	assert.NotNil(t, reply["error"])
+}
@@ -0,0 +1,117 @@
+// Command fakeserver is a minimal stdio MCP server used by the mcp package tests.
+//
+// Tools: echo, add, fail (isError result), crash (exits the process),
+// env (returns $FAKE_MCP_GREETING). tools/list pages two tools at a time.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  any              `json:"result,omitempty"`
+	Error   any              `json:"error,omitempty"`
+}
+
+var tools = []map[string]any{
+	{"name": "echo", "description": "Echo text back", "inputSchema": map[string]any{
+		"type": "object", "properties": map[string]any{"text": map[string]any{"type": "string"}}, "required": []string{"text"},
+	}},
+	{"name": "add", "description": "Add two numbers", "inputSchema": map[string]any{
+		"type": "object", "properties": map[string]any{"a": map[string]any{"type": "number"}, "b": map[string]any{"type": "number"}},
+	}},
+	{"name": "fail", "description": "Always fails"},
+	{"name": "crash", "description": "Exit the server process"},
+	{"name": "env", "description": "Return FAKE_MCP_GREETING"},
+}
+
+const pageSize = 2
+
+func main() {
+	out := json.NewEncoder(os.Stdout)
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var msg message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil || msg.ID == nil {
+			continue // notifications need no answer
+		}
+		result, rpcErr := handle(msg.Method, msg.Params)
+		reply := message{JSONRPC: "2.0", ID: msg.ID}
+		if rpcErr != nil {
+			reply.Error = rpcErr
+		} else {
+			reply.Result = result
+		}
+		out.Encode(reply)
+	}
+}
+
+func handle(method string, params json.RawMessage) (any, any) {
+	switch method {
+	case "initialize":
+		return map[string]any{
+			"protocolVersion": "2025-03-26",
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "fake", "version": "1.0"},
+		}, nil
+	case "tools/list":
+		var p struct {
+			Cursor string `json:"cursor"`
+		}
+		json.Unmarshal(params, &p)
+		start, _ := strconv.Atoi(p.Cursor)
+		end := start + pageSize
+		result := map[string]any{}
+		if end < len(tools) {
+			result["nextCursor"] = strconv.Itoa(end)
+		} else {
+			end = len(tools)
+		}
+		result["tools"] = tools[start:end]
+		return result, nil
+	case "tools/call":
+		var p struct {
+			Name      string         `json:"name"`
+			Arguments map[string]any `json:"arguments"`
+		}
+		json.Unmarshal(params, &p)
+		return callTool(p.Name, p.Arguments)
+	default:
+		return nil, map[string]any{"code": -32601, "message": "method not found: " + method}
+	}
+}
+
+func callTool(name string, args map[string]any) (any, any) {
+	text := func(s string, isError bool) map[string]any {
+		return map[string]any{"content": []any{map[string]any{"type": "text", "text": s}}, "isError": isError}
+	}
+	switch name {
+	case "echo":
+		return text(fmt.Sprint(args["text"]), false), nil
+	case "add":
+		a, _ := args["a"].(float64)
+		b, _ := args["b"].(float64)
+		return text(strconv.FormatFloat(a+b, 'f', -1, 64), false), nil
+	case "fail":
+		return text("something went wrong", true), nil
+	case "crash":
+		if path := os.Getenv("FAKE_MCP_CRASH_LOG"); path != "" {
+			if f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644); err == nil {
+				f.WriteString("crash\n")
+				f.Close()
+			}
+		}
+		os.Exit(1)
+	case "env":
+		return text(os.Getenv("FAKE_MCP_GREETING"), false), nil
+	}
+	return nil, map[string]any{"code": -32602, "message": "unknown tool: " + name}
+}
@@ -0,0 +1,159 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/dayuer/nanobot-go/internal/config"
+	"github.com/dayuer/nanobot-go/internal/tools"
+)
+
+// connectTimeout bounds initialize + tools/list for one server at startup.
+const connectTimeout = 30 * time.Second
+
+// Tool wraps a remote MCP tool as a tools.Tool.
+type Tool struct {
+	client *Client
+	info   ToolInfo
+}
+
+// NewTool wraps info, served by client.
+func NewTool(client *Client, info ToolInfo) *Tool {
+	return &Tool{client: client, info: info}
+}
+
+// maxToolName is the longest function name LLM APIs accept.
+const maxToolName = 64
+
+// ToolName namespaces a remote tool by its server: "mcp_<server>_<tool>",
+// within the ^[a-zA-Z0-9_-]{1,64}$ LLM APIs accept for function names. Names
+// that would be longer are cut and end in a hash of the original, so they
+// stay distinct. Calls go out under the tool's original name.
+func ToolName(server, tool string) string {
+	name := "mcp_" + sanitizeName(server) + "_" + sanitizeName(tool)
+	if len(name) <= maxToolName {
+		return name
+	}
+	sum := sha256.Sum256([]byte(server + "\x00" + tool))
+	suffix := "_" + hex.EncodeToString(sum[:4])
+	return name[:maxToolName-len(suffix)] + suffix
+}
+
+// sanitizeName keeps names within the function-name charset LLM APIs accept.
+func sanitizeName(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+func (t *Tool) Name() string { return ToolName(t.client.Name(), t.info.Name) }
+
+func (t *Tool) Description() string {
+	if t.info.Description != "" {
+		return t.info.Description
+	}
+	return "MCP tool " + t.info.Name + " from server " + t.client.Name()
+}
+
+func (t *Tool) Parameters() map[string]any {
+	if len(t.info.InputSchema) > 0 {
+		return t.info.InputSchema
+	}
+	return map[string]any{"type": "object", "properties": map[string]any{}}
+}
+
+// Execute calls the remote tool. A result flagged isError becomes an error
+// so the executor reports it as a failed call.
+func (t *Tool) Execute(ctx context.Context, args map[string]any) (string, error) {
+	result, err := t.client.CallTool(ctx, t.info.Name, args)
+	if err != nil {
+		return "", err
+	}
+	if result.IsError {
+		return "", errors.New(result.Text())
+	}
+	text := result.Text()
+	if text == "" {
+		text = "(no output)"
+	}
+	return text, nil
+}
+
+// Manager owns the clients for an agent's configured MCP servers.
+type Manager struct {
+	clients []*Client
+}
+
+// ConnectServers connects to every server in servers and registers the tools
+// whitelist admits into reg (tools.Whitelisted: tools.AllTools, or entries
+// such as "mcp_<server>_*"). Servers that fail to connect are logged and
+// skipped so one broken server does not keep the agent from starting.
+func ConnectServers(ctx context.Context, servers []config.MCPServerConfig, reg *tools.Registry, whitelist []string) *Manager {
+	m := &Manager{}
+	for _, cfg := range servers {
+		client := NewClient(cfg)
+		n, err := registerServer(ctx, client, reg, whitelist)
+		if err != nil {
+			log.Printf("[MCP] ❌ %s: %v", cfg.Name, err)
+			client.Close()
+			continue
+		}
+		m.clients = append(m.clients, client)
+		log.Printf("[MCP] ✅ Connected %s (%d tools)", cfg.Name, n)
+	}
+	return m
+}
+
+// registerServer connects client and registers its whitelisted tools,
+// returning how many were registered.
+func registerServer(ctx context.Context, client *Client, reg *tools.Registry, whitelist []string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+
+	if err := client.Connect(ctx); err != nil {
+		return 0, err
+	}
+	infos, err := client.ListTools(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	seen := make(map[string]bool, len(infos))
+	for _, info := range infos {
+		tool := NewTool(client, info)
+		name := tool.Name()
+		switch {
+		case !tools.Whitelisted(whitelist, name):
+			continue
+		case seen[name]:
+			log.Printf("[MCP] ⚠️ %s: tool %q skipped, its name %s is taken", client.Name(), info.Name, name)
+			continue
+		}
+		seen[name] = true
+		reg.Register(tool)
+		n++
+	}
+	return n, nil
+}
+
+// Clients returns the connected clients.
+func (m *Manager) Clients() []*Client { return m.clients }
+
+// Close disconnects every server.
+func (m *Manager) Close() {
+	for _, c := range m.clients {
+		c.Close()
+	}
+}
@@ -0,0 +1,413 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dayuer/nanobot-go/internal/config"
+)
+
+// ErrClosed is returned when the connection to the server is gone
+// (process exited, HTTP session expired). The client reconnects on it.
+var ErrClosed = errors.New("mcp: connection closed")
+
+// errUnsent wraps ErrClosed when the server cannot have acted on the
+// request, so any request may be retried.
+var errUnsent = fmt.Errorf("%w before the request was processed", ErrClosed)
+
+// transport moves JSON-RPC messages to and from one server.
+type transport interface {
+	// call sends a request and waits for the response with the same ID.
+	call(ctx context.Context, req *Message) (*Message, error)
+	// notify sends a notification (no response expected).
+	notify(ctx context.Context, n *Message) error
+	close() error
+}
+
+// dial opens the transport described by cfg: URL → streamable HTTP,
+// Command → stdio subprocess.
+func dial(cfg config.MCPServerConfig) (transport, error) {
+	switch {
+	case cfg.URL != "":
+		return newHTTPTransport(cfg.URL, cfg.Headers), nil
+	case cfg.Command != "":
+		return startStdio(cfg)
+	default:
+		return nil, fmt.Errorf("mcp server %q: neither command nor url configured", cfg.Name)
+	}
+}
+
+func idKey(id *json.RawMessage) string {
+	if id == nil {
+		return ""
+	}
+	return string(*id)
+}
+
+// ─────────────────────────────────────────────────────────────
+// stdio: newline-delimited JSON over a subprocess's stdin/stdout
+// ─────────────────────────────────────────────────────────────
+
+type stdioTransport struct {
+	name  string
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	pending map[string]chan *Message
+	done    chan struct{}
+	closed  bool
+}
+
+func startStdio(cfg config.MCPServerConfig) (*stdioTransport, error) {
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	cmd.Env = os.Environ()
+	for k, v := range cfg.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start mcp server %q: %w", cfg.Name, err)
+	}
+
+	t := &stdioTransport{
+		name:    cfg.Name,
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[string]chan *Message),
+		done:    make(chan struct{}),
+	}
+	go t.readLoop(stdout)
+	go t.logStderr(stderr)
+	return t, nil
+}
+
+func (t *stdioTransport) readLoop(r io.Reader) {
+	defer t.shutdown()
+	reader := bufio.NewReaderSize(r, 64*1024)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var msg Message
+			if jerr := json.Unmarshal(line, &msg); jerr == nil {
+				t.dispatch(&msg)
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (t *stdioTransport) dispatch(msg *Message) {
+	switch {
+	case msg.IsResponse():
+		t.mu.Lock()
+		ch, ok := t.pending[idKey(msg.ID)]
+		delete(t.pending, idKey(msg.ID))
+		t.mu.Unlock()
+		if ok {
+			ch <- msg
+		}
+	case msg.IsRequest():
+		// Server-initiated requests: answer ping, refuse the rest
+		reply := &Message{JSONRPC: "2.0", ID: msg.ID}
+		if msg.Method == "ping" {
+			reply.Result = json.RawMessage(`{}`)
+		} else {
+			reply.Error = &RPCError{Code: CodeMethodNotFound, Message: "method not supported by client: " + msg.Method}
+		}
+		t.write(reply)
+	}
+}
+
+func (t *stdioTransport) logStderr(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		log.Printf("[MCP:%s] %s", t.name, scanner.Text())
+	}
+}
+
+// shutdown marks the transport dead and fails every pending call.
+func (t *stdioTransport) shutdown() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return
+	}
+	t.closed = true
+	close(t.done)
+	for id, ch := range t.pending {
+		close(ch)
+		delete(t.pending, id)
+	}
+}
+
+func (t *stdioTransport) write(msg *Message) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if _, err := t.stdin.Write(append(b, '\n')); err != nil {
+		return errUnsent
+	}
+	return nil
+}
+
+func (t *stdioTransport) call(ctx context.Context, req *Message) (*Message, error) {
+	ch := make(chan *Message, 1)
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil, errUnsent
+	}
+	t.pending[idKey(req.ID)] = ch
+	t.mu.Unlock()
+
+	if err := t.write(req); err != nil {
+		t.mu.Lock()
+		delete(t.pending, idKey(req.ID))
+		t.mu.Unlock()
+		return nil, err
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return nil, ErrClosed
+		}
+		return resp, nil
+	case <-ctx.Done():
+		t.mu.Lock()
+		delete(t.pending, idKey(req.ID))
+		t.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (t *stdioTransport) notify(_ context.Context, n *Message) error {
+	return t.write(n)
+}
+
+// close ends stdin so the server can exit cleanly, then kills it if needed.
+func (t *stdioTransport) close() error {
+	t.stdin.Close()
+	exited := make(chan struct{})
+	go func() {
+		t.cmd.Wait()
+		close(exited)
+	}()
+	select {
+	case <-exited:
+	case <-time.After(2 * time.Second):
+		t.cmd.Process.Kill()
+		<-exited
+	}
+	t.shutdown()
+	return nil
+}
+
+// ─────────────────────────────────────────────────────────────
+// Streamable HTTP: one POST per message, JSON or SSE responses
+// ─────────────────────────────────────────────────────────────
+
+type httpTransport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+
+	mu              sync.Mutex
+	sessionID       string
+	protocolVersion string
+}
+
+func newHTTPTransport(rawURL string, headers map[string]string) *httpTransport {
+	return &httpTransport{url: rawURL, headers: headers, client: &http.Client{Timeout: 120 * time.Second}}
+}
+
+func (t *httpTransport) post(ctx context.Context, msg *Message) (*http.Response, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	t.setHeaders(req)
+
+	t.mu.Lock()
+	sessionID, version := t.sessionID, t.protocolVersion
+	t.mu.Unlock()
+	if sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", sessionID)
+	}
+	if version != "" {
+		req.Header.Set("MCP-Protocol-Version", version)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w: %v", ErrClosed, err)
+	}
+	if id := resp.Header.Get("Mcp-Session-Id"); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+	if resp.StatusCode == http.StatusNotFound && sessionID != "" {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: session expired", errUnsent)
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1000))
+		return nil, fmt.Errorf("mcp HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	return resp, nil
+}
+
+// setHeaders adds the configured headers (e.g. Authorization). Origin
+// defaults to the server's own, which servers that check it (nanobot's
+// does) accept.
+func (t *httpTransport) setHeaders(req *http.Request) {
+	req.Header.Set("Origin", req.URL.Scheme+"://"+req.URL.Host)
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+}
+
+func (t *httpTransport) call(ctx context.Context, req *Message) (*Message, error) {
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	want := idKey(req.ID)
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return readSSEResponse(resp.Body, want)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrClosed, err)
+	}
+	return findResponse(body, want)
+}
+
+// readSSEResponse scans "data:" events until the response for id arrives.
+func readSSEResponse(r io.Reader, id string) (*Message, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "data:") {
+			data.WriteString(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+			continue
+		}
+		if line == "" && data.Len() > 0 {
+			if msg, err := findResponse([]byte(data.String()), id); err == nil {
+				return msg, nil
+			}
+			data.Reset()
+		}
+	}
+	if data.Len() > 0 {
+		return findResponse([]byte(data.String()), id)
+	}
+	return nil, fmt.Errorf("%w: stream ended without response", ErrClosed)
+}
+
+// findResponse decodes a single message or a batch and picks the one for id.
+func findResponse(body []byte, id string) (*Message, error) {
+	body = bytes.TrimSpace(body)
+	var msgs []Message
+	if len(body) > 0 && body[0] == '[' {
+		if err := json.Unmarshal(body, &msgs); err != nil {
+			return nil, fmt.Errorf("decode mcp batch: %w", err)
+		}
+	} else {
+		var m Message
+		if err := json.Unmarshal(body, &m); err != nil {
+			return nil, fmt.Errorf("decode mcp response: %w", err)
+		}
+		msgs = []Message{m}
+	}
+	for i := range msgs {
+		if msgs[i].IsResponse() && idKey(msgs[i].ID) == id {
+			return &msgs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("mcp response %s not found", id)
+}
+
+func (t *httpTransport) notify(ctx context.Context, n *Message) error {
+	resp, err := t.post(ctx, n)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// close ends the server-side session (best effort).
+func (t *httpTransport) close() error {
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "DELETE", t.url, nil)
+	if err != nil {
+		return err
+	}
+	t.setHeaders(req)
+	req.Header.Set("Mcp-Session-Id", sessionID)
+	if resp, err := t.client.Do(req); err == nil {
+		resp.Body.Close()
+	}
+	return nil
+}
+
+func (t *httpTransport) setProtocolVersion(v string) {
+	t.mu.Lock()
+	t.protocolVersion = v
+	t.mu.Unlock()
+}
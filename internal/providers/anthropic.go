@@ -0,0 +1,329 @@
+// Package providers — anthropic.go
+// Native Anthropic Messages API provider (POST /v1/messages).
+// Converts OpenAI-style messages into Anthropic content blocks: system prompt
+// extraction, tool_use / tool_result blocks, and thinking → ReasoningContent.
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// AnthropicVersion is the default anthropic-version header value.
+const AnthropicVersion = "2023-06-01"
+
+// minThinkingBudget is the smallest budget_tokens the API accepts.
+const minThinkingBudget = 1024
+
+// AnthropicProvider talks to the Anthropic Messages API directly.
+type AnthropicProvider struct {
+	APIKey       string
+	APIBase      string
+	Model        string // default model
+	Version      string // anthropic-version header
+	ExtraHeaders map[string]string
+	HTTPClient   *http.Client
+	Retry        RetryPolicy // transient-error retries; zero value = single attempt
+}
+
+// NewAnthropicProvider creates an AnthropicProvider.
+// Empty apiKey falls back to ANTHROPIC_API_KEY; empty apiBase to the spec default.
+func NewAnthropicProvider(apiKey, apiBase, defaultModel string) *AnthropicProvider {
+	if defaultModel == "" {
+		defaultModel = "claude-sonnet-4-5"
+	}
+	spec := FindByName("anthropic")
+	if apiKey == "" && spec != nil {
+		apiKey = os.Getenv(spec.EnvKey)
+	}
+	if apiBase == "" && spec != nil {
+		apiBase = spec.DefaultAPIBase
+	}
+	return &AnthropicProvider{
+		APIKey:     apiKey,
+		APIBase:    apiBase,
+		Model:      defaultModel,
+		Version:    AnthropicVersion,
+		HTTPClient: &http.Client{Timeout: 120 * time.Second},
+		Retry:      DefaultRetryPolicy,
+	}
+}
+
+// DefaultModel satisfies the LLMProvider interface.
+func (p *AnthropicProvider) DefaultModel() string { return p.Model }
+
+// Chat sends a Messages API request.
+// Failures are returned as *Error; transient ones are retried per p.Retry.
+func (p *AnthropicProvider) Chat(ctx context.Context, req ChatRequest) (*LLMResponse, error) {
+	model := req.Model
+	if model == "" {
+		model = p.Model
+	}
+	req.MaxTokens = ClampMaxTokens(req, model)
+	return withRetry(ctx, p.Retry, func() (*LLMResponse, error) {
+		return p.chatOnce(ctx, req)
+	})
+}
+
+// chatOnce makes a single /messages attempt.
+func (p *AnthropicProvider) chatOnce(ctx context.Context, req ChatRequest) (*LLMResponse, error) {
+	model := req.Model
+	if model == "" {
+		model = p.Model
+	}
+	if idx := strings.Index(model, "/"); idx >= 0 {
+		model = model[idx+1:]
+	}
+
+	maxTokens := req.MaxTokens
+	if maxTokens < 1 {
+		maxTokens = defaultMaxTokens
+	}
+
+	msgs := req.Messages
+	if !ReasoningFor(model).Echo {
+		msgs = stripReasoning(msgs)
+	}
+	system, messages := toAnthropicMessages(msgs)
+	body := map[string]any{
+		"model":       model,
+		"messages":    messages,
+		"max_tokens":  maxTokens,
+		"temperature": req.Temperature,
+	}
+	// Extended thinking: the budget must stay below max_tokens, which is
+	// already clamped to the context window, and the API only accepts the
+	// default temperature. Without room for the minimum budget, no thinking.
+	if budget := min(ReasoningBudget(req), maxTokens-1); budget >= minThinkingBudget {
+		body["thinking"] = map[string]any{"type": "enabled", "budget_tokens": budget}
+		body["temperature"] = 1.0
+	}
+	if system != "" {
+		body["system"] = system
+	}
+	if len(req.Tools) > 0 {
+		body["tools"] = toAnthropicTools(req.Tools)
+		body["tool_choice"] = map[string]any{"type": "auto"}
+	}
+
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	endpoint := strings.TrimRight(p.APIBase, "/") + "/messages"
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	version := p.Version
+	if version == "" {
+		version = AnthropicVersion
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("anthropic-version", version)
+	if p.APIKey != "" {
+		httpReq.Header.Set("x-api-key", p.APIKey)
+	}
+	for k, v := range p.ExtraHeaders {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := p.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, newNetworkError("anthropic", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, newNetworkError("anthropic", err)
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, newHTTPError("anthropic", resp, respBody)
+	}
+
+	return parseAnthropicResponse(respBody)
+}
+
+// toAnthropicMessages extracts system prompts and converts the remaining
+// messages into Anthropic content blocks. Consecutive messages with the same
+// role are merged, since the API requires user/assistant alternation.
+func toAnthropicMessages(msgs []Message) (string, []map[string]any) {
+	var systemParts []string
+	var out []map[string]any
+
+	appendBlocks := func(role string, blocks []map[string]any) {
+		if len(blocks) == 0 {
+			return
+		}
+		if n := len(out); n > 0 && out[n-1]["role"] == role {
+			out[n-1]["content"] = append(out[n-1]["content"].([]map[string]any), blocks...)
+			return
+		}
+		out = append(out, map[string]any{"role": role, "content": blocks})
+	}
+
+	for _, m := range msgs {
+		switch m.Role {
+		case "system":
+			if m.Content != "" {
+				systemParts = append(systemParts, m.Content)
+			}
+
+		case "assistant":
+			var blocks []map[string]any
+			// Thinking blocks go back verbatim, each with its own signature
+			for _, rb := range m.ReasoningBlocks {
+				if rb.Signature == "" {
+					continue
+				}
+				blocks = append(blocks, map[string]any{
+					"type":      "thinking",
+					"thinking":  rb.Thinking,
+					"signature": rb.Signature,
+				})
+			}
+			if m.Content != "" {
+				blocks = append(blocks, map[string]any{"type": "text", "text": m.Content})
+			}
+			for _, tc := range m.ToolCalls {
+				input := map[string]any{}
+				if tc.Function.Arguments != "" {
+					json.Unmarshal([]byte(tc.Function.Arguments), &input)
+				}
+				blocks = append(blocks, map[string]any{
+					"type":  "tool_use",
+					"id":    tc.ID,
+					"name":  tc.Function.Name,
+					"input": input,
+				})
+			}
+			appendBlocks("assistant", blocks)
+
+		case "tool":
+			appendBlocks("user", []map[string]any{{
+				"type":        "tool_result",
+				"tool_use_id": m.ToolCallID,
+				"content":     m.Content,
+			}})
+
+		default:
+			appendBlocks("user", []map[string]any{{"type": "text", "text": m.Content}})
+		}
+	}
+	return strings.Join(systemParts, "\n\n"), out
+}
+
+// toAnthropicTools converts OpenAI function schemas into Anthropic tool definitions.
+func toAnthropicTools(tools []map[string]any) []map[string]any {
+	out := make([]map[string]any, 0, len(tools))
+	for _, t := range tools {
+		fn, ok := t["function"].(map[string]any)
+		if !ok {
+			continue
+		}
+		schema, _ := fn["parameters"].(map[string]any)
+		if schema == nil {
+			schema = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		tool := map[string]any{
+			"name":         fn["name"],
+			"input_schema": schema,
+		}
+		if desc, ok := fn["description"].(string); ok && desc != "" {
+			tool["description"] = desc
+		}
+		out = append(out, tool)
+	}
+	return out
+}
+
+// anthropicResponse mirrors the Messages API response structure.
+type anthropicResponse struct {
+	Content []struct {
+		Type      string         `json:"type"`
+		Text      string         `json:"text"`
+		Thinking  string         `json:"thinking"`
+		Signature string         `json:"signature"`
+		ID        string         `json:"id"`
+		Name      string         `json:"name"`
+		Input     map[string]any `json:"input"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+	Usage      *struct {
+		InputTokens              int `json:"input_tokens"`
+		OutputTokens             int `json:"output_tokens"`
+		CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+		CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	} `json:"usage"`
+}
+
+// anthropicStopReasons maps Anthropic stop reasons to OpenAI finish reasons.
+var anthropicStopReasons = map[string]string{
+	"end_turn":      "stop",
+	"stop_sequence": "stop",
+	"tool_use":      "tool_calls",
+	"max_tokens":    "length",
+}
+
+func parseAnthropicResponse(body []byte) (*LLMResponse, error) {
+	var resp anthropicResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, &Error{Kind: ErrServer, Provider: "anthropic", Message: "parsing response", Err: err}
+	}
+
+	var text, thinking []string
+	var reasoning []ReasoningBlock
+	var toolCalls []ToolCallRequest
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			text = append(text, block.Text)
+		case "thinking":
+			thinking = append(thinking, block.Thinking)
+			reasoning = append(reasoning, ReasoningBlock{Thinking: block.Thinking, Signature: block.Signature})
+		case "tool_use":
+			toolCalls = append(toolCalls, ToolCallRequest{
+				ID:        block.ID,
+				Name:      block.Name,
+				Arguments: block.Input,
+			})
+		}
+	}
+
+	usage := map[string]int{}
+	if resp.Usage != nil {
+		prompt := resp.Usage.InputTokens + resp.Usage.CacheCreationInputTokens + resp.Usage.CacheReadInputTokens
+		usage["prompt_tokens"] = prompt
+		usage["completion_tokens"] = resp.Usage.OutputTokens
+		usage["total_tokens"] = prompt + resp.Usage.OutputTokens
+	}
+
+	finishReason := anthropicStopReasons[resp.StopReason]
+	if finishReason == "" {
+		finishReason = "stop"
+	}
+
+	out := &LLMResponse{
+		ToolCalls:    toolCalls,
+		FinishReason: finishReason,
+		Usage:        usage,
+	}
+	if len(text) > 0 {
+		out.Content = strPtr(strings.Join(text, ""))
+	}
+	if len(thinking) > 0 {
+		out.ReasoningContent = strPtr(strings.Join(thinking, "\n"))
+		out.ReasoningBlocks = reasoning // a merged block would not verify
+	}
+	return out, nil
+}
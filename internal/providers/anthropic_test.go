@@ -0,0 +1,236 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnthropicProvider_ImplementsLLMProvider(t *testing.T) {
+	var _ LLMProvider = &AnthropicProvider{}
+}
+
+func TestFindByModel_Claude_AnthropicProtocol(t *testing.T) {
+	spec := FindByModel("claude-sonnet-4-5")
+	require.NotNil(t, spec)
+	assert.Equal(t, ProtocolAnthropic, spec.Protocol)
+	assert.Equal(t, "https://api.anthropic.com/v1", spec.DefaultAPIBase)
+}
+
+func TestNewLLMProvider_SelectsByProtocol(t *testing.T) {
+	_, ok := NewLLMProvider("key", "", "claude-sonnet-4-5", "").(*AnthropicProvider)
+	assert.True(t, ok, "claude models should use the native Anthropic provider")
+
+	_, ok = NewLLMProvider("key", "", "deepseek-chat", "").(*Provider)
+	assert.True(t, ok)
+
+	// Gateways speak OpenAI-compatible wire format even for Claude models
+	_, ok = NewLLMProvider("sk-or-abc", "", "anthropic/claude-sonnet-4-5", "openrouter").(*Provider)
+	assert.True(t, ok)
+}
+
+func TestToAnthropicMessages_ToolConversation(t *testing.T) {
+	msgs := []Message{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "user", Content: "Weather in Paris and Rome?"},
+		{Role: "assistant", Content: "Checking.", ReasoningContent: "need two calls", ToolCalls: []ToolCall{
+			{ID: "toolu_1", Type: "function", Function: ToolCallFunction{Name: "weather", Arguments: `{"city":"Paris"}`}},
+			{ID: "toolu_2", Type: "function", Function: ToolCallFunction{Name: "weather", Arguments: `{"city":"Rome"}`}},
+		}},
+		{Role: "tool", ToolCallID: "toolu_1", Name: "weather", Content: "sunny"},
+		{Role: "tool", ToolCallID: "toolu_2", Name: "weather", Content: "rainy"},
+	}
+
+	system, out := toAnthropicMessages(msgs)
+	assert.Equal(t, "You are helpful.", system)
+	require.Len(t, out, 3)
+
+	assert.Equal(t, "user", out[0]["role"])
+
+	assert.Equal(t, "assistant", out[1]["role"])
+	blocks := out[1]["content"].([]map[string]any)
+	require.Len(t, blocks, 3)
+	assert.Equal(t, "text", blocks[0]["type"])
+	assert.Equal(t, "tool_use", blocks[1]["type"])
+	assert.Equal(t, "toolu_1", blocks[1]["id"])
+	assert.Equal(t, map[string]any{"city": "Paris"}, blocks[1]["input"])
+
+	// Consecutive tool results are merged into one user turn
+	assert.Equal(t, "user", out[2]["role"])
+	results := out[2]["content"].([]map[string]any)
+	require.Len(t, results, 2)
+	assert.Equal(t, "tool_result", results[0]["type"])
+	assert.Equal(t, "toolu_1", results[0]["tool_use_id"])
+	assert.Equal(t, "rainy", results[1]["content"])
+}
+
+func TestParseAnthropicResponse(t *testing.T) {
+	body := `{
+		"content": [
+			{"type":"thinking","thinking":"Let me look it up."},
+			{"type":"text","text":"Looking up."},
+			{"type":"tool_use","id":"toolu_1","name":"weather","input":{"city":"Paris"}}
+		],
+		"stop_reason": "tool_use",
+		"usage": {"input_tokens":10,"cache_read_input_tokens":5,"output_tokens":7}
+	}`
+	resp, err := parseAnthropicResponse([]byte(body))
+	require.NoError(t, err)
+
+	require.NotNil(t, resp.Content)
+	assert.Equal(t, "Looking up.", *resp.Content)
+	require.NotNil(t, resp.ReasoningContent)
+	assert.Equal(t, "Let me look it up.", *resp.ReasoningContent)
+	assert.Equal(t, "tool_calls", resp.FinishReason)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "weather", resp.ToolCalls[0].Name)
+	assert.Equal(t, "Paris", resp.ToolCalls[0].Arguments["city"])
+	assert.Equal(t, 15, resp.Usage["prompt_tokens"])
+	assert.Equal(t, 7, resp.Usage["completion_tokens"])
+	assert.Equal(t, 22, resp.Usage["total_tokens"])
+}
+
+func TestAnthropicProvider_Chat_MockServer(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/messages", r.URL.Path)
+		assert.Equal(t, "test-key", r.Header.Get("x-api-key"))
+		assert.Equal(t, AnthropicVersion, r.Header.Get("anthropic-version"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"content":[{"type":"text","text":"Bonjour!"}],"stop_reason":"end_turn","usage":{"input_tokens":3,"output_tokens":2}}`))
+	}))
+	defer server.Close()
+
+	p := NewAnthropicProvider("test-key", server.URL, "claude-sonnet-4-5")
+	resp, err := p.Chat(context.Background(), ChatRequest{
+		Model: "anthropic/claude-sonnet-4-5",
+		Messages: []Message{
+			{Role: "system", Content: "Reply in French."},
+			{Role: "user", Content: "Hello"},
+		},
+		Tools: []map[string]any{{
+			"type": "function",
+			"function": map[string]any{
+				"name":        "weather",
+				"description": "Get weather",
+				"parameters":  map[string]any{"type": "object", "properties": map[string]any{"city": map[string]any{"type": "string"}}},
+			},
+		}},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resp.Content)
+	assert.Equal(t, "Bonjour!", *resp.Content)
+	assert.Equal(t, "stop", resp.FinishReason)
+	assert.Equal(t, 5, resp.Usage["total_tokens"])
+
+	assert.Equal(t, "claude-sonnet-4-5", got["model"])
+	assert.Equal(t, "Reply in French.", got["system"])
+	assert.Equal(t, float64(4096), got["max_tokens"])
+	tools := got["tools"].([]any)
+	require.Len(t, tools, 1)
+	tool := tools[0].(map[string]any)
+	assert.Equal(t, "weather", tool["name"])
+	assert.Contains(t, tool, "input_schema")
+	assert.Len(t, got["messages"].([]any), 1)
+}
+
+func TestAnthropicProvider_Chat_HTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(401)
+		w.Write([]byte(`{"type":"error","error":{"type":"authentication_error"}}`))
+	}))
+	defer server.Close()
+
+	p := NewAnthropicProvider("bad", server.URL, "claude-sonnet-4-5")
+	resp, err := p.Chat(context.Background(), ChatRequest{Messages: []Message{{Role: "user", Content: "hi"}}})
+	assert.Nil(t, resp)
+	var pe *Error
+	require.ErrorAs(t, err, &pe)
+	assert.Equal(t, ErrAuth, pe.Kind)
+	assert.Equal(t, 401, pe.StatusCode)
+	assert.False(t, IsRetryable(err))
+}
+
+func TestAnthropicProvider_Chat_Thinking(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"content":[{"type":"thinking","thinking":"Check the city.","signature":"sig-2"},{"type":"thinking","thinking":"Paris it is.","signature":"sig-3"},{"type":"text","text":"Sunny."}],"stop_reason":"end_turn"}`))
+	}))
+	defer server.Close()
+
+	p := NewAnthropicProvider("test-key", server.URL, "claude-sonnet-4-5")
+	resp, err := p.Chat(context.Background(), ChatRequest{
+		Messages: []Message{
+			{Role: "user", Content: "Weather in Paris?"},
+			{
+				Role: "assistant", ReasoningContent: "Need the weather tool.\nAsk for Paris.",
+				ReasoningBlocks: []ReasoningBlock{{Thinking: "Need the weather tool.", Signature: "sig-1"}, {Thinking: "Ask for Paris.", Signature: "sig-1b"}},
+				ToolCalls:       []ToolCall{{ID: "toolu_1", Type: "function", Function: ToolCallFunction{Name: "weather", Arguments: `{"city":"Paris"}`}}},
+			},
+			{Role: "tool", ToolCallID: "toolu_1", Name: "weather", Content: "sunny"},
+		},
+		MaxTokens:       4096,
+		Temperature:     0.2,
+		ReasoningEffort: ReasoningLow,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Check the city.\nParis it is.", *resp.ReasoningContent)
+	assert.Equal(t, []ReasoningBlock{
+		{Thinking: "Check the city.", Signature: "sig-2"},
+		{Thinking: "Paris it is.", Signature: "sig-3"},
+	}, resp.ReasoningBlocks, "each block keeps its own signature")
+
+	assert.Equal(t, map[string]any{"type": "enabled", "budget_tokens": float64(1024)}, got["thinking"])
+	assert.Equal(t, float64(4096), got["max_tokens"])
+	assert.Equal(t, float64(1), got["temperature"])
+
+	assistant := got["messages"].([]any)[1].(map[string]any)
+	content := assistant["content"].([]any)
+	require.Len(t, content, 3, "two thinking blocks and the tool call")
+	for i, want := range []ReasoningBlock{{"Need the weather tool.", "sig-1"}, {"Ask for Paris.", "sig-1b"}} {
+		block := content[i].(map[string]any)
+		assert.Equal(t, "thinking", block["type"])
+		assert.Equal(t, want.Thinking, block["thinking"])
+		assert.Equal(t, want.Signature, block["signature"])
+	}
+}
+
+func TestAnthropicProvider_Chat_ThinkingBudgetFitsMaxTokens(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn"}`))
+	}))
+	defer server.Close()
+
+	p := NewAnthropicProvider("test-key", server.URL, "claude-sonnet-4-5")
+	chat := func(maxTokens int) {
+		_, err := p.Chat(context.Background(), ChatRequest{
+			Messages:        []Message{{Role: "user", Content: "hi"}},
+			MaxTokens:       maxTokens,
+			Temperature:     0.2,
+			ReasoningEffort: ReasoningHigh,
+		})
+		require.NoError(t, err)
+	}
+
+	chat(2000)
+	assert.Equal(t, float64(2000), got["max_tokens"], "never raised past the clamp")
+	assert.Equal(t, map[string]any{"type": "enabled", "budget_tokens": float64(1999)}, got["thinking"])
+
+	chat(1024)
+	assert.Equal(t, float64(1024), got["max_tokens"])
+	assert.NotContains(t, got, "thinking", "no room for the minimum budget")
+	assert.Equal(t, 0.2, got["temperature"])
+}
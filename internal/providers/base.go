@@ -8,6 +8,9 @@ type ToolCallRequest struct {
 	ID        string         `json:"id"`
 	Name      string         `json:"name"`
 	Arguments map[string]any `json:"arguments"`
+	// ArgumentsError is set when the model's argument JSON could not be
+	// decoded; Arguments is then nil and the call must not be executed.
+	ArgumentsError string `json:"arguments_error,omitempty"`
 }
 
 // LLMResponse is the standardized response from any LLM provider.
@@ -17,6 +20,15 @@ type LLMResponse struct {
 	FinishReason     string            `json:"finish_reason"`
 	Usage            map[string]int    `json:"usage,omitempty"`
 	ReasoningContent *string           `json:"reasoning_content,omitempty"`
+	// ReasoningBlocks keeps each signed thinking block when the reasoning
+	// must be echoed back verbatim (Anthropic)
+	ReasoningBlocks []ReasoningBlock `json:"reasoning_blocks,omitempty"`
+}
+
+// ReasoningBlock is one thinking block and the signature that authenticates it.
+type ReasoningBlock struct {
+	Thinking  string `json:"thinking"`
+	Signature string `json:"signature"`
 }
 
 // HasToolCalls returns true if the response contains tool calls.
@@ -24,19 +36,43 @@ func (r *LLMResponse) HasToolCalls() bool {
 	return len(r.ToolCalls) > 0
 }
 
-// Message represents a chat message.
+// Message represents a chat message in OpenAI wire format.
+// Assistant turns may carry ToolCalls; tool results carry ToolCallID and Name.
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role             string     `json:"role"`
+	Content          string     `json:"content"`
+	ToolCalls        []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID       string     `json:"tool_call_id,omitempty"`
+	Name             string     `json:"name,omitempty"`
+	ReasoningContent string     `json:"reasoning_content,omitempty"`
+	// ReasoningBlocks are never sent on the OpenAI wire
+	ReasoningBlocks []ReasoningBlock `json:"-"`
+}
+
+// ToolCall is a tool invocation recorded on an assistant message.
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction holds the function name and JSON-encoded arguments.
+type ToolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
 }
 
 // ChatRequest holds all parameters for a chat completion call.
 type ChatRequest struct {
-	Messages    []Message      `json:"messages"`
+	Messages    []Message        `json:"messages"`
 	Tools       []map[string]any `json:"tools,omitempty"`
-	Model       string         `json:"model,omitempty"`
-	MaxTokens   int            `json:"max_tokens"`
-	Temperature float64        `json:"temperature"`
+	Model       string           `json:"model,omitempty"`
+	MaxTokens   int              `json:"max_tokens"`
+	Temperature float64          `json:"temperature"`
+	// ReasoningEffort (low/medium/high) and ReasoningBudget (thinking tokens)
+	// ask reasoning models to think; each backend uses the form it accepts
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
+	ReasoningBudget int    `json:"reasoning_budget,omitempty"`
 }
 
 // LLMProvider is the interface for all LLM backends.
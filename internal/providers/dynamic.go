@@ -28,6 +28,15 @@ func (d *DynamicProvider) Chat(ctx context.Context, req ChatRequest) (*LLMRespon
 	return p.Chat(ctx, req)
 }
 
+// ChatStream delegates to the current inner provider. Inner providers without
+// native streaming are emulated from a single Chat call.
+func (d *DynamicProvider) ChatStream(ctx context.Context, req ChatRequest) (<-chan StreamEvent, error) {
+	d.mu.RLock()
+	p := d.inner
+	d.mu.RUnlock()
+	return Stream(ctx, p, req)
+}
+
 // DefaultModel returns the current inner provider's default model.
 func (d *DynamicProvider) DefaultModel() string {
 	d.mu.RLock()
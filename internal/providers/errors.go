@@ -0,0 +1,137 @@
+// Package providers — errors.go
+// Typed errors for LLM calls. Providers return *Error instead of fake
+// responses so callers can tell transient failures (retry / fail over)
+// from permanent ones (surface to the user).
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrorKind classifies a provider failure.
+type ErrorKind string
+
+const (
+	ErrRateLimit  ErrorKind = "rate_limit"  // HTTP 429
+	ErrServer     ErrorKind = "server"      // HTTP 5xx / overloaded / malformed response
+	ErrNetwork    ErrorKind = "network"     // transport failure, timeout
+	ErrAuth       ErrorKind = "auth"        // HTTP 401 / 403
+	ErrBadRequest ErrorKind = "bad_request" // other 4xx: the request itself is wrong
+)
+
+// Error is a failed LLM call.
+type Error struct {
+	Kind       ErrorKind
+	Provider   string        // provider name, e.g. "openai", "anthropic"
+	StatusCode int           // HTTP status, 0 for transport errors
+	RetryAfter time.Duration // server-requested delay, 0 if absent
+	Message    string
+	Err        error // underlying transport error, if any
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString("LLM ")
+	if e.Provider != "" {
+		b.WriteString(e.Provider + " ")
+	}
+	b.WriteString(string(e.Kind) + " error")
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&b, " (HTTP %d)", e.StatusCode)
+	}
+	if e.Message != "" {
+		b.WriteString(": " + e.Message)
+	}
+	if e.Err != nil {
+		b.WriteString(": " + e.Err.Error())
+	}
+	return b.String()
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Retryable reports whether repeating the same request may succeed.
+func (e *Error) Retryable() bool {
+	switch e.Kind {
+	case ErrRateLimit, ErrServer, ErrNetwork:
+		return true
+	}
+	return false
+}
+
+// IsRetryable reports whether err is a transient provider failure.
+// Context cancellation is never retryable.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var pe *Error
+	return errors.As(err, &pe) && pe.Retryable()
+}
+
+// errorKindForStatus maps an HTTP status code to an ErrorKind.
+func errorKindForStatus(status int) ErrorKind {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return ErrRateLimit
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrAuth
+	case status == http.StatusRequestTimeout || status >= 500:
+		return ErrServer
+	default:
+		return ErrBadRequest
+	}
+}
+
+// maxErrorBody caps how much of an error response body is kept in Message.
+const maxErrorBody = 1000
+
+// newHTTPError builds an Error from a non-200 response.
+func newHTTPError(provider string, resp *http.Response, body []byte) *Error {
+	msg := strings.TrimSpace(string(body))
+	if len(msg) > maxErrorBody {
+		msg = msg[:maxErrorBody] + "..."
+	}
+	return &Error{
+		Kind:       errorKindForStatus(resp.StatusCode),
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		Message:    msg,
+	}
+}
+
+// newNetworkError wraps a transport failure. Context errors pass through
+// unchanged so callers can still match them with errors.Is.
+func newNetworkError(provider string, err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return &Error{Kind: ErrNetwork, Provider: provider, Err: err}
+}
+
+// parseRetryAfter parses a Retry-After header (delta-seconds or HTTP date).
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseFloat(v, 64); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		return time.Duration(secs * float64(time.Second))
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,200 @@
+// Package providers — fallback.go
+// FallbackProvider tries an ordered chain of providers, skipping ones whose
+// circuit breaker is open after repeated failures.
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Circuit breaker states.
+const (
+	BreakerClosed   = "closed"    // calls flow normally
+	BreakerOpen     = "open"      // calls are skipped until the cooldown expires
+	BreakerHalfOpen = "half_open" // one probe call is allowed through
+)
+
+// CircuitBreaker stops calling a provider after consecutive failures and
+// lets a single probe through once the cooldown has passed.
+type CircuitBreaker struct {
+	FailureThreshold int
+	Cooldown         time.Duration
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	now      func() time.Time
+}
+
+// NewCircuitBreaker creates a closed breaker.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &CircuitBreaker{
+		FailureThreshold: threshold,
+		Cooldown:         cooldown,
+		state:            BreakerClosed,
+		now:              time.Now,
+	}
+}
+
+// Allow reports whether a call may proceed. An open breaker whose cooldown
+// has expired moves to half-open and admits exactly one probe.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.Cooldown {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		return true
+	case BreakerHalfOpen:
+		return false // probe already in flight
+	default:
+		return true
+	}
+}
+
+// RecordSuccess closes the breaker.
+func (b *CircuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = BreakerClosed
+	b.failures = 0
+}
+
+// RecordFailure counts a failure; a failed probe reopens immediately.
+func (b *CircuitBreaker) RecordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.FailureThreshold {
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// State returns the current breaker state.
+func (b *CircuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Default breaker settings used by NewFallbackProvider.
+const (
+	DefaultBreakerThreshold = 3
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+// ErrNoProviderAvailable is returned when every breaker in the chain is open.
+var ErrNoProviderAvailable = errors.New("no LLM provider available (all circuits open)")
+
+type fallbackEntry struct {
+	provider LLMProvider
+	breaker  *CircuitBreaker
+}
+
+// FallbackProvider calls providers in order until one succeeds.
+//
+// The first provider receives the request as-is. Fallbacks run with their own
+// DefaultModel, since the caller's model name usually belongs to the primary.
+// Bad requests and context cancellation are returned immediately: they would
+// fail the same way on every provider.
+type FallbackProvider struct {
+	entries []fallbackEntry
+}
+
+// NewFallbackProvider creates a chain with default circuit breakers.
+func NewFallbackProvider(chain ...LLMProvider) *FallbackProvider {
+	f := &FallbackProvider{}
+	for _, p := range chain {
+		f.entries = append(f.entries, fallbackEntry{
+			provider: p,
+			breaker:  NewCircuitBreaker(DefaultBreakerThreshold, DefaultBreakerCooldown),
+		})
+	}
+	return f
+}
+
+// DefaultModel returns the primary provider's model.
+func (f *FallbackProvider) DefaultModel() string {
+	if len(f.entries) == 0 {
+		return ""
+	}
+	return f.entries[0].provider.DefaultModel()
+}
+
+// Breaker returns the circuit breaker guarding the i-th provider.
+func (f *FallbackProvider) Breaker(i int) *CircuitBreaker {
+	return f.entries[i].breaker
+}
+
+// Chat tries each available provider in order.
+func (f *FallbackProvider) Chat(ctx context.Context, req ChatRequest) (*LLMResponse, error) {
+	return fallbackCall(ctx, f, req, func(p LLMProvider, r ChatRequest) (*LLMResponse, error) {
+		return p.Chat(ctx, r)
+	})
+}
+
+// ChatStream fails over only while establishing the stream; once events
+// flow, errors are delivered on the channel.
+func (f *FallbackProvider) ChatStream(ctx context.Context, req ChatRequest) (<-chan StreamEvent, error) {
+	return fallbackCall(ctx, f, req, func(p LLMProvider, r ChatRequest) (<-chan StreamEvent, error) {
+		return Stream(ctx, p, r)
+	})
+}
+
+func fallbackCall[T any](ctx context.Context, f *FallbackProvider, req ChatRequest, call func(LLMProvider, ChatRequest) (T, error)) (T, error) {
+	var zero T
+	var lastErr error
+	for i, e := range f.entries {
+		if !e.breaker.Allow() {
+			continue
+		}
+		r := req
+		if i > 0 {
+			r.Model = e.provider.DefaultModel()
+		}
+
+		v, err := call(e.provider, r)
+		if err == nil {
+			e.breaker.RecordSuccess()
+			return v, nil
+		}
+		if !shouldFailover(ctx, err) {
+			// Not the provider's fault: don't count it against the breaker
+			e.breaker.RecordSuccess()
+			return zero, err
+		}
+		e.breaker.RecordFailure()
+		lastErr = err
+		log.Printf("[Fallback] ⚠️ provider %d (%s) failed: %v", i, e.provider.DefaultModel(), err)
+	}
+	if lastErr == nil {
+		return zero, ErrNoProviderAvailable
+	}
+	return zero, fmt.Errorf("all LLM providers failed: %w", lastErr)
+}
+
+// shouldFailover reports whether another provider might succeed where this
+// one failed.
+func shouldFailover(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var pe *Error
+	if errors.As(err, &pe) && pe.Kind == ErrBadRequest {
+		return false
+	}
+	return true
+}
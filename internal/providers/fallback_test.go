@@ -0,0 +1,199 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedProvider returns the queued errors in order, then succeeds.
+type scriptedProvider struct {
+	model  string
+	errs   []error
+	calls  int
+	models []string
+}
+
+func (s *scriptedProvider) Chat(ctx context.Context, req ChatRequest) (*LLMResponse, error) {
+	s.calls++
+	s.models = append(s.models, req.Model)
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		return nil, err
+	}
+	content := "ok from " + s.model
+	return &LLMResponse{Content: &content, FinishReason: "stop"}, nil
+}
+
+func (s *scriptedProvider) DefaultModel() string { return s.model }
+
+func failing(n int, kind ErrorKind) []error {
+	errs := make([]error, n)
+	for i := range errs {
+		errs[i] = &Error{Kind: kind}
+	}
+	return errs
+}
+
+// --- Errors ---
+
+func TestErrorKindForStatus(t *testing.T) {
+	assert.Equal(t, ErrRateLimit, errorKindForStatus(429))
+	assert.Equal(t, ErrAuth, errorKindForStatus(401))
+	assert.Equal(t, ErrAuth, errorKindForStatus(403))
+	assert.Equal(t, ErrServer, errorKindForStatus(503))
+	assert.Equal(t, ErrServer, errorKindForStatus(529))
+	assert.Equal(t, ErrBadRequest, errorKindForStatus(400))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, 3*time.Second, parseRetryAfter("3", now))
+	assert.Equal(t, 10*time.Second, parseRetryAfter(now.Add(10*time.Second).Format(http.TimeFormat), now))
+	assert.Zero(t, parseRetryAfter("", now))
+	assert.Zero(t, parseRetryAfter("soon", now))
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, IsRetryable(&Error{Kind: ErrRateLimit}))
+	assert.True(t, IsRetryable(&Error{Kind: ErrNetwork}))
+	assert.False(t, IsRetryable(&Error{Kind: ErrBadRequest}))
+	assert.False(t, IsRetryable(context.Canceled))
+	assert.False(t, IsRetryable(errors.New("plain")))
+}
+
+// --- Retry ---
+
+func TestProvider_Chat_RetriesWithRetryAfter(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "0.01")
+			w.WriteHeader(429)
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"content":"Hello!"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	p := NewProvider("key", server.URL, "gpt-4", "")
+	p.Retry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour}
+
+	resp, err := p.Chat(context.Background(), ChatRequest{Messages: []Message{{Role: "user", Content: "hi"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello!", *resp.Content)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestWithRetry_StopsOnNonRetryable(t *testing.T) {
+	calls := 0
+	_, err := withRetry(context.Background(), RetryPolicy{MaxAttempts: 5}, func() (int, error) {
+		calls++
+		return 0, &Error{Kind: ErrBadRequest}
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestWithRetry_ExhaustsAttempts(t *testing.T) {
+	calls := 0
+	_, err := withRetry(context.Background(), RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}, func() (int, error) {
+		calls++
+		return 0, &Error{Kind: ErrServer}
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestWithRetry_RetryAfterBeyondMaxDelayGivesUp(t *testing.T) {
+	calls := 0
+	_, err := withRetry(context.Background(), RetryPolicy{MaxAttempts: 3, MaxDelay: time.Second}, func() (int, error) {
+		calls++
+		return 0, &Error{Kind: ErrRateLimit, RetryAfter: time.Minute}
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+// --- Circuit breaker ---
+
+func TestCircuitBreaker_OpensAndProbes(t *testing.T) {
+	now := time.Now()
+	b := NewCircuitBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.RecordFailure()
+	assert.True(t, b.Allow())
+	b.RecordFailure()
+	assert.Equal(t, BreakerOpen, b.State())
+	assert.False(t, b.Allow())
+
+	now = now.Add(time.Minute)
+	assert.True(t, b.Allow(), "cooldown elapsed: one probe")
+	assert.False(t, b.Allow(), "only one probe while half-open")
+
+	b.RecordSuccess()
+	assert.Equal(t, BreakerClosed, b.State())
+	assert.True(t, b.Allow())
+}
+
+// --- Fallback ---
+
+func TestFallbackProvider_FailsOver(t *testing.T) {
+	primary := &scriptedProvider{model: "primary", errs: failing(1, ErrServer)}
+	backup := &scriptedProvider{model: "backup"}
+	f := NewFallbackProvider(primary, backup)
+
+	resp, err := f.Chat(context.Background(), ChatRequest{Model: "primary"})
+	require.NoError(t, err)
+	assert.Equal(t, "ok from backup", *resp.Content)
+	assert.Equal(t, []string{"backup"}, backup.models, "fallbacks use their own model")
+	assert.Equal(t, "primary", f.DefaultModel())
+}
+
+func TestFallbackProvider_BadRequestDoesNotFailOver(t *testing.T) {
+	primary := &scriptedProvider{model: "primary", errs: failing(1, ErrBadRequest)}
+	backup := &scriptedProvider{model: "backup"}
+	f := NewFallbackProvider(primary, backup)
+
+	_, err := f.Chat(context.Background(), ChatRequest{})
+	var pe *Error
+	require.ErrorAs(t, err, &pe)
+	assert.Equal(t, ErrBadRequest, pe.Kind)
+	assert.Equal(t, 0, backup.calls)
+}
+
+func TestFallbackProvider_SkipsOpenCircuit(t *testing.T) {
+	primary := &scriptedProvider{model: "primary", errs: failing(DefaultBreakerThreshold, ErrServer)}
+	backup := &scriptedProvider{model: "backup"}
+	f := NewFallbackProvider(primary, backup)
+
+	for i := 0; i < DefaultBreakerThreshold; i++ {
+		_, err := f.Chat(context.Background(), ChatRequest{})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, BreakerOpen, f.Breaker(0).State())
+
+	_, err := f.Chat(context.Background(), ChatRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultBreakerThreshold, primary.calls, "open primary is skipped")
+}
+
+func TestFallbackProvider_AllFail(t *testing.T) {
+	f := NewFallbackProvider(
+		&scriptedProvider{model: "a", errs: failing(1, ErrServer)},
+		&scriptedProvider{model: "b", errs: failing(1, ErrRateLimit)},
+	)
+	_, err := f.Chat(context.Background(), ChatRequest{})
+	var pe *Error
+	require.ErrorAs(t, err, &pe)
+	assert.Equal(t, ErrRateLimit, pe.Kind, "last error is preserved")
+}
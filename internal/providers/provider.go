@@ -24,6 +24,7 @@ type Provider struct {
 	Model        string // default model
 	ExtraHeaders map[string]string
 	HTTPClient   *http.Client
+	Retry        RetryPolicy // transient-error retries; zero value = single attempt
 
 	gateway *ProviderSpec // detected gateway, if any
 }
@@ -39,43 +40,144 @@ func NewProvider(apiKey, apiBase, defaultModel, providerName string) *Provider {
 		APIBase:    apiBase,
 		Model:      defaultModel,
 		HTTPClient: &http.Client{Timeout: 120 * time.Second},
+		Retry:      DefaultRetryPolicy,
 	}
 
 	p.gateway = FindGateway(providerName, apiKey, apiBase)
 	return p
 }
 
+// NewLLMProvider creates the provider matching the model's wire protocol.
+// Without a gateway, models whose spec declares ProtocolAnthropic get a native
+// AnthropicProvider; everything else uses the OpenAI-compatible Provider.
+func NewLLMProvider(apiKey, apiBase, defaultModel, providerName string) LLMProvider {
+	if FindGateway(providerName, apiKey, apiBase) == nil {
+		if spec := FindByModel(defaultModel); spec != nil && spec.Protocol == ProtocolAnthropic {
+			return NewAnthropicProvider(apiKey, apiBase, defaultModel)
+		}
+	}
+	return NewProvider(apiKey, apiBase, defaultModel, providerName)
+}
+
 // DefaultModel satisfies the LLMProvider interface.
 func (p *Provider) DefaultModel() string { return p.Model }
 
-// Chat sends a chat completion request.
-func (p *Provider) Chat(ctx context.Context, req ChatRequest) (*LLMResponse, error) {
-	model := req.Model
+// nativeProvider returns a protocol-specific provider for models that do not
+// speak /chat/completions, when no gateway or explicit API base is configured.
+func (p *Provider) nativeProvider(model string) LLMProvider {
+	if p.gateway != nil || p.APIBase != "" {
+		return nil
+	}
 	if model == "" {
 		model = p.Model
 	}
-	model = p.resolveModel(model)
+	spec := FindByModel(model)
+	if spec == nil || spec.Protocol != ProtocolAnthropic {
+		return nil
+	}
+	ap := NewAnthropicProvider(p.APIKey, "", model)
+	ap.ExtraHeaders = p.ExtraHeaders
+	ap.Retry = p.Retry
+	if p.HTTPClient != nil {
+		ap.HTTPClient = p.HTTPClient
+	}
+	return ap
+}
+
+// Chat sends a chat completion request.
+// Failures are returned as *Error; transient ones are retried per p.Retry.
+func (p *Provider) Chat(ctx context.Context, req ChatRequest) (*LLMResponse, error) {
+	if native := p.nativeProvider(req.Model); native != nil {
+		return native.Chat(ctx, req)
+	}
+	req.MaxTokens = ClampMaxTokens(req, p.modelFor(req))
+	return withRetry(ctx, p.Retry, func() (*LLMResponse, error) {
+		return p.chatOnce(ctx, req)
+	})
+}
+
+// chatOnce makes a single /chat/completions attempt.
+func (p *Provider) chatOnce(ctx context.Context, req ChatRequest) (*LLMResponse, error) {
+	httpReq, err := p.newChatRequest(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, newNetworkError(p.name(), err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, newNetworkError(p.name(), err)
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, newHTTPError(p.name(), resp, respBody)
+	}
+
+	return p.parseResponse(respBody)
+}
+
+// modelFor returns the model a request targets: its own or the default.
+func (p *Provider) modelFor(req ChatRequest) string {
+	if req.Model != "" {
+		return req.Model
+	}
+	return p.Model
+}
+
+// name identifies the backend in errors: the gateway, else the model's provider.
+func (p *Provider) name() string {
+	if p.gateway != nil {
+		return p.gateway.Name
+	}
+	if spec := FindByModel(p.Model); spec != nil {
+		return spec.Name
+	}
+	return "openai"
+}
+
+// newChatRequest builds the HTTP request for a /chat/completions call.
+// When stream is true the body asks the backend for SSE chunks.
+func (p *Provider) newChatRequest(ctx context.Context, req ChatRequest, stream bool) (*http.Request, error) {
+	reasoning := ReasoningFor(p.modelFor(req))
+	model := p.resolveModel(p.modelFor(req))
 
 	maxTokens := req.MaxTokens
 	if maxTokens < 1 {
-		maxTokens = 4096
+		maxTokens = defaultMaxTokens
 	}
 
 	temp := req.Temperature
 	// Apply model overrides
 	p.applyModelOverrides(model, &temp)
 
+	// Reasoning is only sent back to models that need it
+	messages := req.Messages
+	if !reasoning.Echo {
+		messages = stripReasoning(messages)
+	}
+
 	// Build OpenAI-compatible request body
 	body := map[string]any{
 		"model":       model,
-		"messages":    req.Messages,
+		"messages":    messages,
 		"max_tokens":  maxTokens,
 		"temperature": temp,
 	}
+	if effort := ReasoningEffort(req); effort != "" && reasoning.Effort {
+		body["reasoning_effort"] = effort
+	}
 	if len(req.Tools) > 0 {
 		body["tools"] = req.Tools
 		body["tool_choice"] = "auto"
 	}
+	if stream {
+		body["stream"] = true
+	}
 
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
@@ -118,38 +220,16 @@ func (p *Provider) Chat(ctx context.Context, req ChatRequest) (*LLMResponse, err
 		return nil, fmt.Errorf("create request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
+	if stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
 	if apiKey != "" {
 		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
 	}
 	for k, v := range p.ExtraHeaders {
 		httpReq.Header.Set(k, v)
 	}
-
-	resp, err := p.HTTPClient.Do(httpReq)
-	if err != nil {
-		return &LLMResponse{
-			Content:      strPtr(fmt.Sprintf("Error calling LLM: %v", err)),
-			FinishReason: "error",
-		}, nil
-	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return &LLMResponse{
-			Content:      strPtr(fmt.Sprintf("Error reading response: %v", err)),
-			FinishReason: "error",
-		}, nil
-	}
-
-	if resp.StatusCode != 200 {
-		return &LLMResponse{
-			Content:      strPtr(fmt.Sprintf("Error calling LLM (HTTP %d): %s", resp.StatusCode, string(respBody))),
-			FinishReason: "error",
-		}, nil
-	}
-
-	return p.parseResponse(respBody)
+	return httpReq, nil
 }
 
 func (p *Provider) resolveModel(model string) string {
@@ -219,17 +299,11 @@ type openAIResponse struct {
 func (p *Provider) parseResponse(body []byte) (*LLMResponse, error) {
 	var resp openAIResponse
 	if err := json.Unmarshal(body, &resp); err != nil {
-		return &LLMResponse{
-			Content:      strPtr(fmt.Sprintf("Error parsing response: %v", err)),
-			FinishReason: "error",
-		}, nil
+		return nil, &Error{Kind: ErrServer, Provider: p.name(), Message: "parsing response", Err: err}
 	}
 
 	if len(resp.Choices) == 0 {
-		return &LLMResponse{
-			Content:      strPtr("Error: no choices in response"),
-			FinishReason: "error",
-		}, nil
+		return nil, &Error{Kind: ErrServer, Provider: p.name(), Message: "no choices in response"}
 	}
 
 	choice := resp.Choices[0]
@@ -237,14 +311,12 @@ func (p *Provider) parseResponse(body []byte) (*LLMResponse, error) {
 
 	var toolCalls []ToolCallRequest
 	for _, tc := range msg.ToolCalls {
-		var args map[string]any
-		if tc.Function.Arguments != "" {
-			json.Unmarshal([]byte(tc.Function.Arguments), &args)
-		}
+		args, argsErr := parseToolArguments(tc.Function.Arguments)
 		toolCalls = append(toolCalls, ToolCallRequest{
-			ID:        tc.ID,
-			Name:      tc.Function.Name,
-			Arguments: args,
+			ID:             tc.ID,
+			Name:           tc.Function.Name,
+			Arguments:      args,
+			ArgumentsError: argsErr,
 		})
 	}
 
@@ -260,13 +332,51 @@ func (p *Provider) parseResponse(body []byte) (*LLMResponse, error) {
 		finishReason = "stop"
 	}
 
-	return &LLMResponse{
+	out := &LLMResponse{
 		Content:          msg.Content,
 		ToolCalls:        toolCalls,
 		FinishReason:     finishReason,
 		Usage:            usage,
 		ReasoningContent: msg.ReasoningContent,
-	}, nil
+	}
+	splitInlineThinking(out)
+	return out, nil
+}
+
+// splitInlineThinking moves a leading <think> block from resp's content into
+// its reasoning.
+func splitInlineThinking(resp *LLMResponse) {
+	if resp.Content == nil {
+		return
+	}
+	thought, answer := SplitThinking(*resp.Content)
+	if thought == "" {
+		return
+	}
+	resp.Content = strPtr(answer)
+	if resp.ReasoningContent != nil && *resp.ReasoningContent != "" {
+		thought = *resp.ReasoningContent + "\n" + thought
+	}
+	resp.ReasoningContent = strPtr(thought)
+}
+
+// maxArgumentsEcho caps how much malformed argument text is quoted back.
+const maxArgumentsEcho = 200
+
+// parseToolArguments decodes a tool call's JSON arguments. On failure it
+// returns a description of the problem instead of silently dropping them.
+func parseToolArguments(raw string) (map[string]any, string) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, ""
+	}
+	var args map[string]any
+	if err := json.Unmarshal([]byte(raw), &args); err != nil {
+		if len(raw) > maxArgumentsEcho {
+			raw = raw[:maxArgumentsEcho] + "..."
+		}
+		return nil, fmt.Sprintf("arguments are not a valid JSON object (%v): %s", err, raw)
+	}
+	return args, ""
 }
 
 func strPtr(s string) *string { return &s }
@@ -88,16 +88,17 @@ func TestParseResponse_ReasoningContent(t *testing.T) {
 func TestParseResponse_EmptyChoices(t *testing.T) {
 	p := &Provider{}
 	body := `{"choices":[]}`
-	resp, err := p.parseResponse([]byte(body))
-	require.NoError(t, err)
-	assert.Contains(t, *resp.Content, "no choices")
+	_, err := p.parseResponse([]byte(body))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no choices")
 }
 
 func TestParseResponse_InvalidJSON(t *testing.T) {
 	p := &Provider{}
-	resp, err := p.parseResponse([]byte("not json"))
-	require.NoError(t, err) // graceful error
-	assert.Contains(t, *resp.Content, "Error parsing")
+	_, err := p.parseResponse([]byte("not json"))
+	var pe *Error
+	require.ErrorAs(t, err, &pe)
+	assert.Equal(t, ErrServer, pe.Kind)
 }
 
 // --- Integration with Mock Server ---
@@ -188,12 +189,16 @@ func TestProvider_Chat_HTTPError(t *testing.T) {
 	defer server.Close()
 
 	p := NewProvider("key", server.URL, "gpt-4", "")
+	p.Retry = RetryPolicy{}
 	resp, err := p.Chat(context.Background(), ChatRequest{
 		Messages: []Message{{Role: "user", Content: "Hello"}},
 	})
-	require.NoError(t, err)
-	assert.Contains(t, *resp.Content, "Error calling LLM")
-	assert.Equal(t, "error", resp.FinishReason)
+	assert.Nil(t, resp)
+	var pe *Error
+	require.ErrorAs(t, err, &pe)
+	assert.Equal(t, ErrServer, pe.Kind)
+	assert.Equal(t, 500, pe.StatusCode)
+	assert.Contains(t, pe.Message, "internal error")
 }
 
 func TestProvider_Chat_ExtraHeaders(t *testing.T) {
@@ -228,3 +233,74 @@ func TestProvider_DefaultModelFallback(t *testing.T) {
 	p := NewProvider("", "", "", "")
 	assert.Equal(t, "anthropic/claude-sonnet-4-5", p.DefaultModel())
 }
+
+// --- Contract: multi-turn tool conversation ---
+
+func TestContract_Provider_Chat_ToolConversationWireFormat(t *testing.T) {
+	var got struct {
+		Messages []map[string]any `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{
+				{"message": map[string]any{"content": "done"}, "finish_reason": "stop"},
+			},
+		})
+	}))
+	defer server.Close()
+
+	// kimi-k2-thinking needs its reasoning echoed back (ProviderSpec.Reasoning)
+	p := NewProvider("key", server.URL, "moonshot/kimi-k2-thinking", "")
+	_, err := p.Chat(context.Background(), ChatRequest{
+		Messages: []Message{
+			{Role: "user", Content: "List files"},
+			{
+				Role:             "assistant",
+				ReasoningContent: "need exec",
+				ToolCalls: []ToolCall{{
+					ID:       "call_1",
+					Type:     "function",
+					Function: ToolCallFunction{Name: "exec", Arguments: `{"command":"ls"}`},
+				}},
+			},
+			{Role: "tool", ToolCallID: "call_1", Name: "exec", Content: "a.txt"},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, got.Messages, 3)
+
+	user := got.Messages[0]
+	assert.NotContains(t, user, "tool_calls")
+	assert.NotContains(t, user, "tool_call_id")
+
+	assistant := got.Messages[1]
+	assert.Equal(t, "need exec", assistant["reasoning_content"])
+	calls, ok := assistant["tool_calls"].([]any)
+	require.True(t, ok)
+	require.Len(t, calls, 1)
+	call := calls[0].(map[string]any)
+	assert.Equal(t, "call_1", call["id"])
+	assert.Equal(t, "function", call["type"])
+	fn := call["function"].(map[string]any)
+	assert.Equal(t, "exec", fn["name"])
+	assert.Equal(t, `{"command":"ls"}`, fn["arguments"])
+
+	tool := got.Messages[2]
+	assert.Equal(t, "tool", tool["role"])
+	assert.Equal(t, "call_1", tool["tool_call_id"])
+	assert.Equal(t, "exec", tool["name"])
+	assert.Equal(t, "a.txt", tool["content"])
+}
+
+func TestParseResponse_MalformedToolArguments(t *testing.T) {
+	p := &Provider{}
+	body := `{"choices":[{"message":{"tool_calls":[{"id":"c1","function":{"name":"exec","arguments":"{\"command\": \"ls"}}]},"finish_reason":"tool_calls"}]}`
+	resp, err := p.parseResponse([]byte(body))
+	require.NoError(t, err)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Nil(t, resp.ToolCalls[0].Arguments)
+	assert.Contains(t, resp.ToolCalls[0].ArgumentsError, "not a valid JSON object")
+	assert.Contains(t, resp.ToolCalls[0].ArgumentsError, `{"command": "ls`)
+}
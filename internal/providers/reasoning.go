@@ -0,0 +1,183 @@
+// Package providers — reasoning.go
+// Reasoning-model support: per-model echo/effort behaviour, effort ↔ budget
+// mapping, and separating inline <think>…</think> blocks from answers.
+package providers
+
+import "strings"
+
+// Reasoning effort levels for ChatRequest.ReasoningEffort.
+const (
+	ReasoningLow    = "low"
+	ReasoningMedium = "medium"
+	ReasoningHigh   = "high"
+)
+
+// ReasoningModel describes how a reasoning model's thinking is handled. It
+// is matched against the model name like ModelLimit.
+type ReasoningModel struct {
+	Pattern string // substring to match in model name (lowercase)
+	Echo    bool   // reasoning must be sent back on assistant turns; otherwise it is stripped
+	Effort  bool   // the API accepts reasoning_effort
+}
+
+// ReasoningFor returns the reasoning behaviour of model. The zero value
+// (no match) strips reasoning from requests and sends no effort.
+func ReasoningFor(model string) ReasoningModel {
+	spec := FindByModel(model)
+	if spec == nil {
+		return ReasoningModel{}
+	}
+	lower := strings.ToLower(model)
+	for _, rm := range spec.Reasoning {
+		if strings.Contains(lower, rm.Pattern) {
+			return rm
+		}
+	}
+	return ReasoningModel{}
+}
+
+// reasoningBudgets maps effort levels to thinking-token budgets for APIs
+// that take a budget (Anthropic).
+var reasoningBudgets = map[string]int{
+	ReasoningLow:    1024,
+	ReasoningMedium: 4096,
+	ReasoningHigh:   16384,
+}
+
+// ReasoningBudget returns the thinking-token budget req asks for:
+// ReasoningBudget, else the budget for ReasoningEffort, else 0 (no thinking).
+func ReasoningBudget(req ChatRequest) int {
+	if req.ReasoningBudget > 0 {
+		return req.ReasoningBudget
+	}
+	return reasoningBudgets[req.ReasoningEffort]
+}
+
+// ReasoningEffort returns the effort level req asks for: ReasoningEffort,
+// else the level closest to ReasoningBudget, else "" (model default).
+func ReasoningEffort(req ChatRequest) string {
+	switch {
+	case req.ReasoningEffort != "":
+		return req.ReasoningEffort
+	case req.ReasoningBudget <= 0:
+		return ""
+	case req.ReasoningBudget <= reasoningBudgets[ReasoningLow]:
+		return ReasoningLow
+	case req.ReasoningBudget <= reasoningBudgets[ReasoningMedium]:
+		return ReasoningMedium
+	default:
+		return ReasoningHigh
+	}
+}
+
+// stripReasoning returns msgs without reasoning, copying only when needed.
+func stripReasoning(msgs []Message) []Message {
+	out, copied := msgs, false
+	for i := range msgs {
+		if msgs[i].ReasoningContent == "" && len(msgs[i].ReasoningBlocks) == 0 {
+			continue
+		}
+		if !copied {
+			out, copied = append([]Message(nil), msgs...), true
+		}
+		out[i].ReasoningContent = ""
+		out[i].ReasoningBlocks = nil
+	}
+	return out
+}
+
+const (
+	thinkOpen  = "<think>"
+	thinkClose = "</think>"
+)
+
+// SplitThinking separates a leading <think>…</think> block, as inlined by
+// R1-style models, from the answer. An unclosed block (output cut off
+// mid-thought) is all reasoning. Content without a leading block is
+// returned unchanged.
+func SplitThinking(content string) (reasoning, answer string) {
+	var s thinkSplitter
+	r1, a1 := s.feed(content)
+	r2, a2 := s.flush()
+	return r1 + r2, a1 + a2
+}
+
+// thinkSplitter separates a leading <think> block from streamed content,
+// holding back text while a tag may still be arriving.
+type thinkSplitter struct {
+	state int
+	buf   string
+}
+
+const (
+	thinkUndecided = iota // before any non-space text: may open a block
+	thinkInside           // inside the block
+	thinkAfter            // block closed: skipping whitespace before the answer
+	thinkDone             // passing the answer through
+)
+
+// feed returns the reasoning and answer parts of the next content delta.
+func (s *thinkSplitter) feed(delta string) (reasoning, answer string) {
+	s.buf += delta
+	for {
+		switch s.state {
+		case thinkUndecided:
+			t := strings.TrimLeft(s.buf, " \t\r\n")
+			if strings.HasPrefix(t, thinkOpen) {
+				s.state, s.buf = thinkInside, t[len(thinkOpen):]
+				continue
+			}
+			if strings.HasPrefix(thinkOpen, t) {
+				return reasoning, answer // wait for more
+			}
+			s.state = thinkDone
+
+		case thinkInside:
+			if i := strings.Index(s.buf, thinkClose); i >= 0 {
+				reasoning += s.buf[:i]
+				s.state, s.buf = thinkAfter, s.buf[i+len(thinkClose):]
+				continue
+			}
+			keep := partialSuffix(s.buf, thinkClose)
+			reasoning += s.buf[:len(s.buf)-keep]
+			s.buf = s.buf[len(s.buf)-keep:]
+			return reasoning, answer
+
+		case thinkAfter:
+			s.buf = strings.TrimLeft(s.buf, " \t\r\n")
+			if s.buf == "" {
+				return reasoning, answer
+			}
+			s.state = thinkDone
+
+		default:
+			answer += s.buf
+			s.buf = ""
+			return reasoning, answer
+		}
+	}
+}
+
+// flush returns whatever is still held back at the end of the stream.
+func (s *thinkSplitter) flush() (reasoning, answer string) {
+	rest := s.buf
+	s.buf = ""
+	if s.state == thinkInside {
+		return rest, ""
+	}
+	if s.state == thinkAfter {
+		return "", ""
+	}
+	return "", rest
+}
+
+// partialSuffix returns the length of the longest suffix of s that is a
+// proper prefix of tag.
+func partialSuffix(s, tag string) int {
+	for n := len(tag) - 1; n > 0; n-- {
+		if strings.HasSuffix(s, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,147 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitThinking(t *testing.T) {
+	cases := []struct {
+		in, reasoning, answer string
+	}{
+		{"<think>plan it</think>\n\nThe answer.", "plan it", "The answer."},
+		{"  \n<think>a</think>b", "a", "b"},
+		{"No thinking here.", "", "No thinking here."},
+		{"Answer with <think>inline</think> tag", "", "Answer with <think>inline</think> tag"},
+		{"<think>cut off mid-thou", "cut off mid-thou", ""},
+		{"<thi", "", "<thi"},
+		{"", "", ""},
+	}
+	for _, c := range cases {
+		reasoning, answer := SplitThinking(c.in)
+		assert.Equal(t, c.reasoning, reasoning, c.in)
+		assert.Equal(t, c.answer, answer, c.in)
+	}
+}
+
+func TestThinkSplitter_ChunkBoundaries(t *testing.T) {
+	var s thinkSplitter
+	var reasoning, answer string
+	for _, chunk := range []string{"<th", "ink>step ", "one</th", "ink>", "\n\n", "Fin", "al"} {
+		r, a := s.feed(chunk)
+		reasoning += r
+		answer += a
+	}
+	r, a := s.flush()
+	assert.Equal(t, "step one", reasoning+r)
+	assert.Equal(t, "Final", answer+a)
+}
+
+func TestReasoningFor(t *testing.T) {
+	assert.True(t, ReasoningFor("moonshot/kimi-k2-thinking").Echo)
+	assert.True(t, ReasoningFor("anthropic/claude-sonnet-4-5").Echo)
+	assert.False(t, ReasoningFor("deepseek/deepseek-reasoner").Echo)
+	assert.True(t, ReasoningFor("openai/o3-mini").Effort)
+	assert.Equal(t, ReasoningModel{}, ReasoningFor("gpt-4"))
+	assert.Equal(t, ReasoningModel{}, ReasoningFor("unknown-model"))
+}
+
+func TestReasoningEffortAndBudget(t *testing.T) {
+	assert.Equal(t, "", ReasoningEffort(ChatRequest{}))
+	assert.Equal(t, "high", ReasoningEffort(ChatRequest{ReasoningEffort: "high", ReasoningBudget: 10}))
+	assert.Equal(t, "low", ReasoningEffort(ChatRequest{ReasoningBudget: 1000}))
+	assert.Equal(t, "medium", ReasoningEffort(ChatRequest{ReasoningBudget: 4096}))
+	assert.Equal(t, "high", ReasoningEffort(ChatRequest{ReasoningBudget: 30000}))
+
+	assert.Equal(t, 0, ReasoningBudget(ChatRequest{}))
+	assert.Equal(t, 4096, ReasoningBudget(ChatRequest{ReasoningEffort: "medium"}))
+	assert.Equal(t, 2000, ReasoningBudget(ChatRequest{ReasoningEffort: "medium", ReasoningBudget: 2000}))
+}
+
+// captureChat serves one completion and records the request body.
+func captureChat(t *testing.T, content string) (*httptest.Server, *map[string]any) {
+	got := map[string]any{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{
+				{"message": map[string]any{"content": content}, "finish_reason": "stop"},
+			},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, &got
+}
+
+func TestProvider_Chat_StripsReasoningAndSendsEffort(t *testing.T) {
+	msgs := []Message{
+		{Role: "user", Content: "hi"},
+		{Role: "assistant", Content: "hello", ReasoningContent: "greet back"},
+		{Role: "user", Content: "again"},
+	}
+
+	server, got := captureChat(t, "ok")
+	p := NewProvider("key", server.URL, "openai/o3-mini", "")
+	_, err := p.Chat(context.Background(), ChatRequest{Messages: msgs, ReasoningEffort: "high"})
+	require.NoError(t, err)
+	assistant := (*got)["messages"].([]any)[1].(map[string]any)
+	assert.NotContains(t, assistant, "reasoning_content", "o3 does not take reasoning back")
+	assert.Equal(t, "high", (*got)["reasoning_effort"])
+	assert.Equal(t, "greet back", msgs[1].ReasoningContent, "caller's messages untouched")
+
+	server, got = captureChat(t, "ok")
+	p = NewProvider("key", server.URL, "gpt-4", "")
+	_, err = p.Chat(context.Background(), ChatRequest{Messages: msgs, ReasoningEffort: "high"})
+	require.NoError(t, err)
+	assert.NotContains(t, *got, "reasoning_effort", "gpt-4 does not accept reasoning_effort")
+}
+
+func TestProvider_Chat_SplitsInlineThinking(t *testing.T) {
+	server, _ := captureChat(t, "<think>The user greets me.</think>\nHi there!")
+	p := NewProvider("key", server.URL, "deepseek/deepseek-r1", "")
+	resp, err := p.Chat(context.Background(), ChatRequest{Messages: []Message{{Role: "user", Content: "hi"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "Hi there!", *resp.Content)
+	require.NotNil(t, resp.ReasoningContent)
+	assert.Equal(t, "The user greets me.", *resp.ReasoningContent)
+}
+
+func TestProvider_ChatStream_SplitsInlineThinking(t *testing.T) {
+	server := sseServer(t, []string{
+		`{"choices":[{"delta":{"content":"<think>"}}]}`,
+		`{"choices":[{"delta":{"content":"check the </thi"}}]}`,
+		`{"choices":[{"delta":{"content":"nk>\n\nIt is"}}]}`,
+		`{"choices":[{"delta":{"content":" sunny."}}]}`,
+		`{"choices":[{"delta":{},"finish_reason":"stop"}]}`,
+	})
+	defer server.Close()
+
+	p := NewProvider("key", server.URL, "qwq-32b", "")
+	events, err := p.ChatStream(context.Background(), ChatRequest{Messages: []Message{{Role: "user", Content: "weather?"}}})
+	require.NoError(t, err)
+
+	var reasoning, deltas string
+	var final *LLMResponse
+	for ev := range events {
+		switch ev.Type {
+		case StreamReasoning:
+			reasoning += ev.Content
+		case StreamDelta:
+			deltas += ev.Content
+		case StreamDone:
+			final = ev.Response
+		}
+	}
+	assert.Equal(t, "check the ", reasoning)
+	assert.Equal(t, "It is sunny.", deltas)
+	require.NotNil(t, final)
+	assert.Equal(t, "It is sunny.", *final.Content)
+	assert.Equal(t, "check the ", *final.ReasoningContent)
+}
@@ -7,28 +7,44 @@ import "strings"
 
 // ProviderSpec holds metadata for one LLM provider.
 type ProviderSpec struct {
-	Name               string            // config field name, e.g. "dashscope"
-	Keywords           []string          // model-name keywords for matching (lowercase)
-	EnvKey             string            // env var for API key, e.g. "DASHSCOPE_API_KEY"
-	DisplayName        string            // shown in status
-	LiteLLMPrefix      string            // prefix for model routing
-	SkipPrefixes       []string          // don't add prefix if model already starts with these
-	EnvExtras          [][2]string       // extra env vars: {name, value template}
-	IsGateway          bool              // can route any model (OpenRouter, AiHubMix)
-	IsLocal            bool              // local deployment (vLLM, Ollama)
-	DetectByKeyPrefix  string            // match api_key prefix
-	DetectByBaseKW     string            // match substring in api_base URL
-	DefaultAPIBase     string            // fallback base URL
-	StripModelPrefix   bool              // strip "provider/" before re-prefixing
-	ModelOverrides     []ModelOverride   // per-model param overrides
+	Name              string           // config field name, e.g. "dashscope"
+	Keywords          []string         // model-name keywords for matching (lowercase)
+	EnvKey            string           // env var for API key, e.g. "DASHSCOPE_API_KEY"
+	DisplayName       string           // shown in status
+	LiteLLMPrefix     string           // prefix for model routing
+	SkipPrefixes      []string         // don't add prefix if model already starts with these
+	EnvExtras         [][2]string      // extra env vars: {name, value template}
+	IsGateway         bool             // can route any model (OpenRouter, AiHubMix)
+	IsLocal           bool             // local deployment (vLLM, Ollama)
+	DetectByKeyPrefix string           // match api_key prefix
+	DetectByBaseKW    string           // match substring in api_base URL
+	DefaultAPIBase    string           // fallback base URL
+	StripModelPrefix  bool             // strip "provider/" before re-prefixing
+	ModelOverrides    []ModelOverride  // per-model param overrides
+	Protocol          string           // wire protocol: "" (OpenAI-compatible) or ProtocolAnthropic
+	ContextWindow     int              // default context window in tokens (0 = DefaultContextWindow)
+	Tokenizer         string           // TokenizerCL100k / TokenizerO200k; "" = CJK-aware approximation
+	ModelLimits       []ModelLimit     // per-model context window / tokenizer
+	Reasoning         []ReasoningModel // reasoning models: echo / effort support
 }
 
+// ProtocolAnthropic marks specs whose models speak the Anthropic Messages API.
+const ProtocolAnthropic = "anthropic"
+
 // ModelOverride applies parameter overrides when a model name matches a pattern.
 type ModelOverride struct {
 	Pattern   string         // substring to match in model name (lowercase)
 	Overrides map[string]any // params to override
 }
 
+// ModelLimit refines ContextWindow and Tokenizer when a model name matches a
+// pattern. The first match wins, so list specific patterns first.
+type ModelLimit struct {
+	Pattern       string // substring to match in model name (lowercase)
+	ContextWindow int    // 0 = keep the spec default
+	Tokenizer     string // "" = keep the spec default
+}
+
 // Label returns a display label.
 func (s *ProviderSpec) Label() string {
 	if s.DisplayName != "" {
@@ -44,7 +60,7 @@ var Providers = []*ProviderSpec{
 		Name: "custom", Keywords: nil, EnvKey: "OPENAI_API_KEY",
 		DisplayName: "Custom", LiteLLMPrefix: "openai",
 		SkipPrefixes: []string{"openai/"},
-		IsGateway: true, StripModelPrefix: true,
+		IsGateway:    true, StripModelPrefix: true,
 	},
 	// OpenRouter
 	{
@@ -59,19 +75,38 @@ var Providers = []*ProviderSpec{
 		Name: "aihubmix", Keywords: []string{"aihubmix"},
 		EnvKey: "OPENAI_API_KEY", DisplayName: "AiHubMix",
 		LiteLLMPrefix: "openai", IsGateway: true,
-		DetectByBaseKW: "aihubmix",
-		DefaultAPIBase: "https://aihubmix.com/v1",
+		DetectByBaseKW:   "aihubmix",
+		DefaultAPIBase:   "https://aihubmix.com/v1",
 		StripModelPrefix: true,
 	},
 	// Anthropic
 	{
 		Name: "anthropic", Keywords: []string{"anthropic", "claude"},
 		EnvKey: "ANTHROPIC_API_KEY", DisplayName: "Anthropic",
+		DefaultAPIBase: "https://api.anthropic.com/v1",
+		Protocol:       ProtocolAnthropic,
+		ContextWindow:  200_000,
+		Reasoning:      []ReasoningModel{{Pattern: "claude", Echo: true}},
 	},
 	// OpenAI
 	{
 		Name: "openai", Keywords: []string{"openai", "gpt"},
 		EnvKey: "OPENAI_API_KEY", DisplayName: "OpenAI",
+		ContextWindow: 128_000, Tokenizer: TokenizerO200k,
+		ModelLimits: []ModelLimit{
+			{Pattern: "gpt-4o"},
+			{Pattern: "gpt-4.1", ContextWindow: 1_047_576},
+			{Pattern: "gpt-5", ContextWindow: 400_000},
+			{Pattern: "gpt-4-turbo", Tokenizer: TokenizerCL100k},
+			{Pattern: "gpt-4", ContextWindow: 8_192, Tokenizer: TokenizerCL100k},
+			{Pattern: "gpt-3.5", ContextWindow: 16_385, Tokenizer: TokenizerCL100k},
+		},
+		Reasoning: []ReasoningModel{
+			{Pattern: "gpt-5", Effort: true},
+			{Pattern: "o1", Effort: true},
+			{Pattern: "o3", Effort: true},
+			{Pattern: "o4", Effort: true},
+		},
 	},
 	// DeepSeek
 	{
@@ -79,47 +114,58 @@ var Providers = []*ProviderSpec{
 		EnvKey: "DEEPSEEK_API_KEY", DisplayName: "DeepSeek",
 		LiteLLMPrefix: "deepseek", SkipPrefixes: []string{"deepseek/"},
 		DefaultAPIBase: "https://api.deepseek.com/v1",
+		ContextWindow:  64_000, Tokenizer: TokenizerCL100k,
+		// deepseek-reasoner rejects reasoning_content in input messages
+		Reasoning: []ReasoningModel{{Pattern: "deepseek-reasoner"}, {Pattern: "deepseek-r1"}},
 	},
 	// Gemini
 	{
 		Name: "gemini", Keywords: []string{"gemini"},
 		EnvKey: "GEMINI_API_KEY", DisplayName: "Gemini",
 		LiteLLMPrefix: "gemini", SkipPrefixes: []string{"gemini/"},
+		ContextWindow: 1_048_576,
+		Reasoning:     []ReasoningModel{{Pattern: "gemini-2.5", Effort: true}},
 	},
 	// Zhipu
 	{
 		Name: "zhipu", Keywords: []string{"zhipu", "glm", "zai"},
 		EnvKey: "ZAI_API_KEY", DisplayName: "Zhipu AI",
-		LiteLLMPrefix: "zai",
-		SkipPrefixes: []string{"zhipu/", "zai/", "openrouter/", "hosted_vllm/"},
-		EnvExtras: [][2]string{{"ZHIPUAI_API_KEY", "{api_key}"}},
+		LiteLLMPrefix:  "zai",
+		SkipPrefixes:   []string{"zhipu/", "zai/", "openrouter/", "hosted_vllm/"},
+		EnvExtras:      [][2]string{{"ZHIPUAI_API_KEY", "{api_key}"}},
 		DefaultAPIBase: "https://open.bigmodel.cn/api/paas/v4",
+		ContextWindow:  128_000,
 	},
 	// DashScope
 	{
 		Name: "dashscope", Keywords: []string{"qwen", "dashscope"},
 		EnvKey: "DASHSCOPE_API_KEY", DisplayName: "DashScope",
 		LiteLLMPrefix: "dashscope",
-		SkipPrefixes: []string{"dashscope/", "openrouter/"},
+		SkipPrefixes:  []string{"dashscope/", "openrouter/"},
+		ContextWindow: 131_072,
 	},
 	// Moonshot
 	{
 		Name: "moonshot", Keywords: []string{"moonshot", "kimi"},
 		EnvKey: "MOONSHOT_API_KEY", DisplayName: "Moonshot",
-		LiteLLMPrefix: "moonshot",
-		SkipPrefixes: []string{"moonshot/", "openrouter/"},
-		EnvExtras:    [][2]string{{"MOONSHOT_API_BASE", "{api_base}"}},
+		LiteLLMPrefix:  "moonshot",
+		SkipPrefixes:   []string{"moonshot/", "openrouter/"},
+		EnvExtras:      [][2]string{{"MOONSHOT_API_BASE", "{api_base}"}},
 		DefaultAPIBase: "https://api.moonshot.ai/v1",
 		ModelOverrides: []ModelOverride{
 			{Pattern: "kimi-k2.5", Overrides: map[string]any{"temperature": 1.0}},
 		},
+		ContextWindow: 128_000,
+		ModelLimits:   []ModelLimit{{Pattern: "kimi-k2", ContextWindow: 262_144}},
+		// kimi-k2-thinking needs its reasoning back across tool calls
+		Reasoning: []ReasoningModel{{Pattern: "kimi-k2-thinking", Echo: true}},
 	},
 	// MiniMax
 	{
 		Name: "minimax", Keywords: []string{"minimax"},
 		EnvKey: "MINIMAX_API_KEY", DisplayName: "MiniMax",
-		LiteLLMPrefix: "minimax",
-		SkipPrefixes: []string{"minimax/", "openrouter/"},
+		LiteLLMPrefix:  "minimax",
+		SkipPrefixes:   []string{"minimax/", "openrouter/"},
 		DefaultAPIBase: "https://api.minimax.io/v1",
 	},
 	// vLLM / Local
@@ -133,6 +179,7 @@ var Providers = []*ProviderSpec{
 		Name: "groq", Keywords: []string{"groq"},
 		EnvKey: "GROQ_API_KEY", DisplayName: "Groq",
 		LiteLLMPrefix: "groq", SkipPrefixes: []string{"groq/"},
+		ContextWindow: 131_072,
 	},
 }
 
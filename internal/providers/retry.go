@@ -0,0 +1,71 @@
+// Package providers — retry.go
+// Exponential backoff for transient LLM failures, honoring Retry-After.
+package providers
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy controls how transient provider errors are retried.
+// The zero value makes a single attempt.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts including the first
+	BaseDelay   time.Duration // delay before the first retry, doubled each time
+	MaxDelay    time.Duration // cap for backoff; a longer Retry-After gives up instead
+}
+
+// DefaultRetryPolicy is applied by NewProvider and NewAnthropicProvider.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   1 * time.Second,
+	MaxDelay:    30 * time.Second,
+}
+
+// backoff returns the wait before retry number attempt (1-based), and false
+// when the server asked for a longer wait than MaxDelay allows.
+func (rp RetryPolicy) backoff(attempt int, err error) (time.Duration, bool) {
+	var pe *Error
+	if errors.As(err, &pe) && pe.RetryAfter > 0 {
+		if rp.MaxDelay > 0 && pe.RetryAfter > rp.MaxDelay {
+			return 0, false
+		}
+		return pe.RetryAfter, true
+	}
+
+	d := rp.BaseDelay << (attempt - 1)
+	if d <= 0 || (rp.MaxDelay > 0 && d > rp.MaxDelay) {
+		d = rp.MaxDelay
+	}
+	if d <= 0 {
+		return 0, true
+	}
+	// Equal jitter: keep half, randomize the rest
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1)), true
+}
+
+// withRetry runs fn until it succeeds, returns a non-retryable error, or the
+// policy is exhausted. The last error is returned unchanged.
+func withRetry[T any](ctx context.Context, rp RetryPolicy, fn func() (T, error)) (T, error) {
+	for attempt := 1; ; attempt++ {
+		v, err := fn()
+		if err == nil || attempt >= rp.MaxAttempts || !IsRetryable(err) {
+			return v, err
+		}
+		wait, ok := rp.backoff(attempt, err)
+		if !ok {
+			return v, err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return v, err
+		case <-timer.C:
+		}
+	}
+}
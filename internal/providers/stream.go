@@ -0,0 +1,311 @@
+// Package providers — stream.go
+// Streaming chat completions: OpenAI SSE chunk parsing with incremental
+// content deltas and tool-call argument assembly.
+package providers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// StreamEventType identifies the kind of a StreamEvent.
+type StreamEventType string
+
+const (
+	StreamDelta     StreamEventType = "delta"     // Incremental content text
+	StreamReasoning StreamEventType = "reasoning" // Incremental reasoning text
+	StreamToolCall  StreamEventType = "tool_call" // A fully assembled tool call
+	StreamDone      StreamEventType = "done"      // Final assembled response
+	StreamError     StreamEventType = "error"     // Stream aborted
+)
+
+// StreamEvent is one incremental event from a streaming chat call.
+// The channel always ends with exactly one StreamDone or StreamError event.
+type StreamEvent struct {
+	Type     StreamEventType
+	Content  string           // delta / reasoning text
+	ToolCall *ToolCallRequest // set for StreamToolCall
+	Response *LLMResponse     // set for StreamDone
+	Err      error            // set for StreamError
+}
+
+// StreamProvider is implemented by providers that support incremental output.
+type StreamProvider interface {
+	// ChatStream starts a streaming chat completion. The returned channel is
+	// closed after the final StreamDone or StreamError event.
+	ChatStream(ctx context.Context, req ChatRequest) (<-chan StreamEvent, error)
+}
+
+// Stream calls ChatStream when p supports it, otherwise it emulates a stream
+// from a single blocking Chat call.
+func Stream(ctx context.Context, p LLMProvider, req ChatRequest) (<-chan StreamEvent, error) {
+	if sp, ok := p.(StreamProvider); ok {
+		return sp.ChatStream(ctx, req)
+	}
+	resp, err := p.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return replayResponse(resp), nil
+}
+
+// replayResponse turns a complete response into a finished event stream.
+func replayResponse(resp *LLMResponse) <-chan StreamEvent {
+	ch := make(chan StreamEvent, len(resp.ToolCalls)+3)
+	if resp.ReasoningContent != nil && *resp.ReasoningContent != "" {
+		ch <- StreamEvent{Type: StreamReasoning, Content: *resp.ReasoningContent}
+	}
+	if resp.Content != nil && *resp.Content != "" {
+		ch <- StreamEvent{Type: StreamDelta, Content: *resp.Content}
+	}
+	for i := range resp.ToolCalls {
+		tc := resp.ToolCalls[i]
+		ch <- StreamEvent{Type: StreamToolCall, ToolCall: &tc}
+	}
+	ch <- StreamEvent{Type: StreamDone, Response: resp}
+	close(ch)
+	return ch
+}
+
+// CollectStream drains a stream and returns the final response.
+func CollectStream(events <-chan StreamEvent) (*LLMResponse, error) {
+	var final *LLMResponse
+	for ev := range events {
+		switch ev.Type {
+		case StreamDone:
+			final = ev.Response
+		case StreamError:
+			return nil, ev.Err
+		}
+	}
+	if final == nil {
+		return nil, fmt.Errorf("stream ended without a response")
+	}
+	return final, nil
+}
+
+// ChatStream sends a streaming chat completion request and parses SSE chunks.
+func (p *Provider) ChatStream(ctx context.Context, req ChatRequest) (<-chan StreamEvent, error) {
+	if native := p.nativeProvider(req.Model); native != nil {
+		return Stream(ctx, native, req)
+	}
+	req.MaxTokens = ClampMaxTokens(req, p.modelFor(req))
+
+	// Only establishing the stream is retried; mid-stream failures are final.
+	resp, err := withRetry(ctx, p.Retry, func() (*http.Response, error) {
+		return p.openStream(ctx, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan StreamEvent, 64)
+	go func() {
+		defer close(ch)
+		defer resp.Body.Close()
+		parseSSEStream(ctx, resp.Body, ch)
+	}()
+	return ch, nil
+}
+
+// openStream sends a streaming request and returns the 200 response.
+func (p *Provider) openStream(ctx context.Context, req ChatRequest) (*http.Response, error) {
+	httpReq, err := p.newChatRequest(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, newNetworkError(p.name(), err)
+	}
+	if resp.StatusCode != 200 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newHTTPError(p.name(), resp, body)
+	}
+	return resp, nil
+}
+
+// openAIStreamChunk mirrors one chat.completion.chunk SSE payload.
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content          *string `json:"content"`
+			ReasoningContent *string `json:"reasoning_content"`
+			ToolCalls        []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// partialToolCall accumulates a tool call whose arguments arrive in pieces.
+type partialToolCall struct {
+	id   string
+	name string
+	args strings.Builder
+}
+
+// emitContent records and streams the reasoning and answer parts of a
+// content delta. It returns false when the consumer has gone away.
+func emitContent(send func(StreamEvent) bool, reasoning, content *strings.Builder, thought, text string) bool {
+	if thought != "" {
+		reasoning.WriteString(thought)
+		if !send(StreamEvent{Type: StreamReasoning, Content: thought}) {
+			return false
+		}
+	}
+	if text != "" {
+		content.WriteString(text)
+		if !send(StreamEvent{Type: StreamDelta, Content: text}) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseSSEStream reads "data:" lines until [DONE] or EOF and emits events.
+func parseSSEStream(ctx context.Context, body io.Reader, ch chan<- StreamEvent) {
+	send := func(ev StreamEvent) bool {
+		select {
+		case ch <- ev:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	var content, reasoning strings.Builder
+	var think thinkSplitter // separates inline <think> blocks from content
+	calls := map[int]*partialToolCall{}
+	finishReason := ""
+	usage := map[string]int{}
+	done := false // saw [DONE]
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			done = true
+			break
+		}
+
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			continue
+		}
+		if chunk.Usage != nil {
+			usage["prompt_tokens"] = chunk.Usage.PromptTokens
+			usage["completion_tokens"] = chunk.Usage.CompletionTokens
+			usage["total_tokens"] = chunk.Usage.TotalTokens
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		choice := chunk.Choices[0]
+		if choice.FinishReason != nil && *choice.FinishReason != "" {
+			finishReason = *choice.FinishReason
+		}
+		delta := choice.Delta
+		if delta.ReasoningContent != nil && *delta.ReasoningContent != "" {
+			reasoning.WriteString(*delta.ReasoningContent)
+			if !send(StreamEvent{Type: StreamReasoning, Content: *delta.ReasoningContent}) {
+				return
+			}
+		}
+		if delta.Content != nil && *delta.Content != "" {
+			thought, text := think.feed(*delta.Content)
+			if !emitContent(send, &reasoning, &content, thought, text) {
+				return
+			}
+		}
+		for _, tc := range delta.ToolCalls {
+			pc, ok := calls[tc.Index]
+			if !ok {
+				pc = &partialToolCall{}
+				calls[tc.Index] = pc
+			}
+			if tc.ID != "" {
+				pc.id = tc.ID
+			}
+			if tc.Function.Name != "" {
+				pc.name = tc.Function.Name
+			}
+			pc.args.WriteString(tc.Function.Arguments)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		send(StreamEvent{Type: StreamError, Err: newNetworkError("", fmt.Errorf("reading stream: %w", err))})
+		return
+	}
+	if ctx.Err() != nil {
+		send(StreamEvent{Type: StreamError, Err: ctx.Err()})
+		return
+	}
+	if !done && finishReason == "" {
+		// The connection dropped mid-answer; what arrived is truncated
+		send(StreamEvent{Type: StreamError, Err: newNetworkError("", fmt.Errorf("stream ended before it finished: %w", io.ErrUnexpectedEOF))})
+		return
+	}
+	if thought, text := think.flush(); !emitContent(send, &reasoning, &content, thought, text) {
+		return
+	}
+
+	// Emit assembled tool calls in index order
+	indexes := make([]int, 0, len(calls))
+	for idx := range calls {
+		indexes = append(indexes, idx)
+	}
+	sort.Ints(indexes)
+
+	var toolCalls []ToolCallRequest
+	for _, idx := range indexes {
+		pc := calls[idx]
+		args, argsErr := parseToolArguments(pc.args.String())
+		tc := ToolCallRequest{ID: pc.id, Name: pc.name, Arguments: args, ArgumentsError: argsErr}
+		toolCalls = append(toolCalls, tc)
+		if !send(StreamEvent{Type: StreamToolCall, ToolCall: &tc}) {
+			return
+		}
+	}
+
+	if finishReason == "" {
+		finishReason = "stop"
+	}
+	final := &LLMResponse{
+		ToolCalls:    toolCalls,
+		FinishReason: finishReason,
+		Usage:        usage,
+	}
+	if content.Len() > 0 || len(toolCalls) == 0 {
+		final.Content = strPtr(content.String())
+	}
+	if reasoning.Len() > 0 {
+		final.ReasoningContent = strPtr(reasoning.String())
+	}
+	send(StreamEvent{Type: StreamDone, Response: final})
+}
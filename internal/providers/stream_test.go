@@ -0,0 +1,157 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_ImplementsStreamProvider(t *testing.T) {
+	var _ StreamProvider = &Provider{}
+	var _ StreamProvider = &DynamicProvider{}
+}
+
+func sseServer(t *testing.T, chunks []string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, c := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", c)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+}
+
+func TestProvider_ChatStream_TextDeltas(t *testing.T) {
+	server := sseServer(t, []string{
+		`{"choices":[{"delta":{"role":"assistant","content":"Hel"}}]}`,
+		`{"choices":[{"delta":{"content":"lo"}}]}`,
+		`{"choices":[{"delta":{},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`,
+	})
+	defer server.Close()
+
+	p := NewProvider("key", server.URL, "gpt-4", "")
+	events, err := p.ChatStream(context.Background(), ChatRequest{
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	})
+	require.NoError(t, err)
+
+	var deltas []string
+	var final *LLMResponse
+	for ev := range events {
+		switch ev.Type {
+		case StreamDelta:
+			deltas = append(deltas, ev.Content)
+		case StreamDone:
+			final = ev.Response
+		case StreamError:
+			t.Fatalf("unexpected error: %v", ev.Err)
+		}
+	}
+	assert.Equal(t, []string{"Hel", "lo"}, deltas)
+	require.NotNil(t, final)
+	assert.Equal(t, "Hello", *final.Content)
+	assert.Equal(t, "stop", final.FinishReason)
+	assert.Equal(t, 5, final.Usage["total_tokens"])
+}
+
+func TestProvider_ChatStream_AssemblesToolCallArguments(t *testing.T) {
+	server := sseServer(t, []string{
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"exec","arguments":""}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"comm"}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":1,"id":"call_2","function":{"name":"read_file","arguments":"{\"path\":\"a\"}"}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"and\":\"ls\"}"}}]}}]}`,
+		`{"choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+	})
+	defer server.Close()
+
+	p := NewProvider("key", server.URL, "gpt-4", "")
+	events, err := p.ChatStream(context.Background(), ChatRequest{})
+	require.NoError(t, err)
+
+	var streamed []string
+	var final *LLMResponse
+	for ev := range events {
+		switch ev.Type {
+		case StreamToolCall:
+			streamed = append(streamed, ev.ToolCall.Name)
+		case StreamDone:
+			final = ev.Response
+		}
+	}
+	assert.Equal(t, []string{"exec", "read_file"}, streamed)
+	require.NotNil(t, final)
+	assert.Equal(t, "tool_calls", final.FinishReason)
+	require.Len(t, final.ToolCalls, 2)
+	assert.Equal(t, "call_1", final.ToolCalls[0].ID)
+	assert.Equal(t, "ls", final.ToolCalls[0].Arguments["command"])
+	assert.Equal(t, "a", final.ToolCalls[1].Arguments["path"])
+}
+
+func TestProvider_ChatStream_TruncatedStreamIsAnError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n")
+	}))
+	defer server.Close()
+
+	p := NewProvider("key", server.URL, "gpt-4", "")
+	events, err := p.ChatStream(context.Background(), ChatRequest{})
+	require.NoError(t, err)
+
+	var streamErr error
+	for ev := range events {
+		switch ev.Type {
+		case StreamDone:
+			t.Fatalf("truncated stream reported as done: %+v", ev.Response)
+		case StreamError:
+			streamErr = ev.Err
+		}
+	}
+	require.Error(t, streamErr)
+	assert.ErrorIs(t, streamErr, io.ErrUnexpectedEOF)
+	assert.True(t, IsRetryable(streamErr))
+}
+
+func TestProvider_ChatStream_HTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+	}))
+	defer server.Close()
+
+	p := NewProvider("key", server.URL, "gpt-4", "")
+	p.Retry = RetryPolicy{}
+	_, err := p.ChatStream(context.Background(), ChatRequest{})
+	var pe *Error
+	require.ErrorAs(t, err, &pe)
+	assert.Equal(t, ErrServer, pe.Kind)
+}
+
+func TestDynamicProvider_ChatStream_FallsBackToChat(t *testing.T) {
+	content := "whole answer"
+	dp := NewDynamicProvider(&mockDynamicProvider{
+		model:    "m",
+		response: &LLMResponse{Content: &content, FinishReason: "stop"},
+	})
+
+	events, err := dp.ChatStream(context.Background(), ChatRequest{})
+	require.NoError(t, err)
+
+	var types []StreamEventType
+	for ev := range events {
+		types = append(types, ev.Type)
+	}
+	assert.Equal(t, []StreamEventType{StreamDelta, StreamDone}, types)
+}
+
+func TestCollectStream(t *testing.T) {
+	content := "x"
+	resp, err := CollectStream(replayResponse(&LLMResponse{Content: &content}))
+	require.NoError(t, err)
+	assert.Equal(t, "x", *resp.Content)
+}
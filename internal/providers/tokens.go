@@ -0,0 +1,208 @@
+// Package providers — tokens.go
+// Token counting per model family and context-window lookup.
+// OpenAI-style models are counted with their real BPE vocabulary (embedded,
+// no network access); other families use a CJK-aware approximation.
+package providers
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktokenloader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// Tokenizer names for ProviderSpec.Tokenizer and ModelLimit.Tokenizer.
+// An empty tokenizer selects the approximation.
+const (
+	TokenizerCL100k = "cl100k_base"
+	TokenizerO200k  = "o200k_base"
+)
+
+const (
+	// DefaultContextWindow applies to models without registry metadata.
+	DefaultContextWindow = 64_000
+	// defaultMaxTokens is the completion budget when a request sets none.
+	defaultMaxTokens = 4096
+	// minMaxTokens is the floor for a clamped completion budget: a request
+	// that no longer fits is left for the backend to reject.
+	minMaxTokens = 256
+	// clampMargin absorbs tokenizer drift between our count and the backend's.
+	clampMargin = 256
+
+	// Chat framing overhead, per the OpenAI cookbook.
+	tokensPerMessage = 3
+	tokensPerName    = 1
+	tokensPerReply   = 3
+	tokensPerTool    = 8
+)
+
+// TokenCounter counts the tokens a model's tokenizer produces for text.
+type TokenCounter interface {
+	Count(text string) int
+}
+
+// ApproxCounter estimates tokens without a vocabulary: one token per CJK
+// character and one per 3.5 other characters, which overestimates slightly
+// for English and matches Claude/Gemini/Qwen-family tokenizers on mixed text.
+var ApproxCounter TokenCounter = approxCounter{}
+
+type approxCounter struct{}
+
+func (approxCounter) Count(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		if isCJK(r) {
+			cjk++
+		} else {
+			other++
+		}
+	}
+	return cjk + int(math.Ceil(float64(other)/3.5))
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(r >= 0x3000 && r <= 0x303F) || // CJK punctuation
+		(r >= 0xFF00 && r <= 0xFFEF) // full-width forms
+}
+
+// bpeCounter counts with a tiktoken vocabulary.
+type bpeCounter struct{ enc *tiktoken.Tiktoken }
+
+func (c bpeCounter) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+	return len(c.enc.EncodeOrdinary(text))
+}
+
+var (
+	encodingsMu sync.Mutex
+	encodings   = map[string]TokenCounter{}
+	loaderOnce  sync.Once
+)
+
+// NewTokenCounter returns the counter for a tokenizer name. Encodings are
+// loaded once and shared. Unknown names fall back to ApproxCounter.
+func NewTokenCounter(tokenizer string) TokenCounter {
+	if tokenizer != TokenizerCL100k && tokenizer != TokenizerO200k {
+		return ApproxCounter
+	}
+	loaderOnce.Do(func() { tiktoken.SetBpeLoader(tiktokenloader.NewOfflineLoader()) })
+
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+	if c, ok := encodings[tokenizer]; ok {
+		return c
+	}
+	var c TokenCounter = ApproxCounter
+	if enc, err := tiktoken.GetEncoding(tokenizer); err != nil {
+		log.Printf("[Tokens] ⚠️ load %s: %v, using approximation", tokenizer, err)
+	} else {
+		c = bpeCounter{enc: enc}
+	}
+	encodings[tokenizer] = c
+	return c
+}
+
+// ModelLimits resolves a model's context window and tokenizer from the
+// registry: the matching spec's defaults, refined by the first ModelLimit
+// whose pattern occurs in the model name.
+func ModelLimits(model string) (window int, tokenizer string) {
+	spec := FindByModel(model)
+	if spec == nil {
+		return DefaultContextWindow, ""
+	}
+	window, tokenizer = spec.ContextWindow, spec.Tokenizer
+	lower := strings.ToLower(model)
+	for _, ml := range spec.ModelLimits {
+		if strings.Contains(lower, ml.Pattern) {
+			if ml.ContextWindow > 0 {
+				window = ml.ContextWindow
+			}
+			if ml.Tokenizer != "" {
+				tokenizer = ml.Tokenizer
+			}
+			break
+		}
+	}
+	if window <= 0 {
+		window = DefaultContextWindow
+	}
+	return window, tokenizer
+}
+
+// ContextWindow returns the context window of model in tokens.
+func ContextWindow(model string) int {
+	window, _ := ModelLimits(model)
+	return window
+}
+
+// CounterForModel returns the token counter for model's family.
+func CounterForModel(model string) TokenCounter {
+	_, tokenizer := ModelLimits(model)
+	return NewTokenCounter(tokenizer)
+}
+
+// CountMessages counts messages including per-message framing and the
+// tokens that prime the assistant reply.
+func CountMessages(c TokenCounter, messages []Message) int {
+	total := tokensPerReply
+	for _, m := range messages {
+		total += tokensPerMessage + c.Count(m.Role) + c.Count(m.Content) + c.Count(m.ReasoningContent)
+		if m.Name != "" {
+			total += tokensPerName + c.Count(m.Name)
+		}
+		for _, tc := range m.ToolCalls {
+			total += tokensPerMessage + c.Count(tc.Function.Name) + c.Count(tc.Function.Arguments)
+		}
+	}
+	return total
+}
+
+// CountTools counts tool schemas as they are serialized into the request.
+func CountTools(c TokenCounter, tools []map[string]any) int {
+	total := 0
+	for _, t := range tools {
+		b, err := json.Marshal(t)
+		if err != nil {
+			continue
+		}
+		total += tokensPerTool + c.Count(string(b))
+	}
+	return total
+}
+
+// CountRequest counts everything a request sends: messages and tool schemas.
+func CountRequest(c TokenCounter, req ChatRequest) int {
+	return CountMessages(c, req.Messages) + CountTools(c, req.Tools)
+}
+
+// ClampMaxTokens returns the completion budget for req against model's
+// context window: req.MaxTokens (default 4096) lowered so that input plus
+// output fits, but never below a small floor.
+func ClampMaxTokens(req ChatRequest, model string) int {
+	want := req.MaxTokens
+	if want < 1 {
+		want = defaultMaxTokens
+	}
+	window, tokenizer := ModelLimits(model)
+	input := CountRequest(NewTokenCounter(tokenizer), req)
+	avail := window - input - clampMargin
+	if avail >= want {
+		return want
+	}
+	if avail < minMaxTokens {
+		avail = minMaxTokens
+	}
+	if avail < want {
+		log.Printf("[Tokens] ✂️ max_tokens %d → %d (input ≈%d of %d for %s)", want, avail, input, window, model)
+		return avail
+	}
+	return want
+}
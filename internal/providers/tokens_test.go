@@ -0,0 +1,99 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModelLimits_FromRegistry(t *testing.T) {
+	cases := []struct {
+		model     string
+		window    int
+		tokenizer string
+	}{
+		{"gpt-4o-mini", 128_000, TokenizerO200k},
+		{"openai/gpt-4.1", 1_047_576, TokenizerO200k},
+		{"gpt-4-turbo", 128_000, TokenizerCL100k},
+		{"gpt-4", 8_192, TokenizerCL100k},
+		{"deepseek/deepseek-chat", 64_000, TokenizerCL100k},
+		{"anthropic/claude-sonnet-4-5", 200_000, ""},
+		{"moonshot/kimi-k2.5", 262_144, ""},
+		{"some-local-model", DefaultContextWindow, ""},
+	}
+	for _, c := range cases {
+		window, tokenizer := ModelLimits(c.model)
+		assert.Equal(t, c.window, window, c.model)
+		assert.Equal(t, c.tokenizer, tokenizer, c.model)
+	}
+}
+
+func TestBPECounter_MatchesVocabulary(t *testing.T) {
+	// Reference counts from OpenAI's tiktoken.
+	assert.Equal(t, 2, NewTokenCounter(TokenizerCL100k).Count("hello world"))
+	assert.Equal(t, 2, NewTokenCounter(TokenizerO200k).Count("hello world"))
+	assert.Equal(t, 0, NewTokenCounter(TokenizerO200k).Count(""))
+	// Special-token text is counted as ordinary text, not rejected
+	assert.Positive(t, NewTokenCounter(TokenizerCL100k).Count("<|endoftext|>"))
+}
+
+func TestApproxCounter_CJKAware(t *testing.T) {
+	assert.Equal(t, 4, ApproxCounter.Count("你好世界"))
+	assert.Equal(t, 7, ApproxCounter.Count("こんにちは世界"))
+	assert.Equal(t, 4, ApproxCounter.Count("hello world!")) // 12 chars / 3.5, rounded up
+	assert.Equal(t, 4, ApproxCounter.Count("你好 world"))     // 2 + 6/3.5, rounded up
+	assert.Equal(t, 0, ApproxCounter.Count(""))
+}
+
+func TestCountRequest_IncludesToolSchemas(t *testing.T) {
+	c := NewTokenCounter(TokenizerCL100k)
+	req := ChatRequest{Messages: []Message{{Role: "user", Content: "hi"}}}
+	bare := CountRequest(c, req)
+
+	req.Tools = []map[string]any{{"type": "function", "function": map[string]any{
+		"name": "read_file", "description": "Read a file from the workspace",
+	}}}
+	assert.Greater(t, CountRequest(c, req), bare+10)
+}
+
+func TestClampMaxTokens(t *testing.T) {
+	small := ChatRequest{Messages: []Message{{Role: "user", Content: "hi"}}, MaxTokens: 1000}
+	assert.Equal(t, 1000, ClampMaxTokens(small, "gpt-4"))
+	small.MaxTokens = 0
+	assert.Equal(t, defaultMaxTokens, ClampMaxTokens(small, "gpt-4"))
+
+	// gpt-4 has an 8k window: a ~6k-token prompt leaves < 2k for the reply
+	big := ChatRequest{Messages: []Message{{Role: "user", Content: strings.Repeat("hello ", 6000)}}, MaxTokens: 4096}
+	got := ClampMaxTokens(big, "gpt-4")
+	assert.Less(t, got, 2048)
+	assert.GreaterOrEqual(t, got, minMaxTokens)
+
+	// Overflowing prompts keep the floor and are left for the backend to reject
+	big.Messages[0].Content = strings.Repeat("hello ", 9000)
+	assert.Equal(t, minMaxTokens, ClampMaxTokens(big, "gpt-4"))
+}
+
+func TestProvider_Chat_ClampsMaxTokens(t *testing.T) {
+	var sent map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(body, &sent))
+		w.Write([]byte(`{"choices":[{"message":{"content":"ok"},"finish_reason":"stop"}]}`))
+	}))
+	defer srv.Close()
+
+	p := NewProvider("k", srv.URL, "gpt-4", "")
+	_, err := p.Chat(context.Background(), ChatRequest{
+		Messages:  []Message{{Role: "user", Content: strings.Repeat("hello ", 6000)}},
+		MaxTokens: 4096,
+	})
+	require.NoError(t, err)
+	assert.Less(t, sent["max_tokens"].(float64), float64(2048))
+}
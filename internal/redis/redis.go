@@ -18,11 +18,12 @@ import (
 
 // Key prefixes — keep in sync with Backend (redis.ts REDIS_KEYS).
 const (
-	KeyMemory      = "mem:"     // User memory cache
-	KeyAgentPrompt = "ap:"      // Agent persona cache
-	KeySession     = "session:" // Session state
-	KeyLock        = "lock:"    // Distributed lock
-	KeyCache       = "cache:"   // General cache
+	KeyMemory      = "mem:"      // User memory cache
+	KeyAgentMemory = "agentmem:" // Agent long-term memory, per scope
+	KeyAgentPrompt = "ap:"       // Agent persona cache
+	KeySession     = "session:"  // Session state
+	KeyLock        = "lock:"     // Distributed lock
+	KeyCache       = "cache:"    // General cache
 )
 
 // Config holds Redis connection settings.
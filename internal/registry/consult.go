@@ -0,0 +1,122 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dayuer/nanobot-go/internal/agent"
+)
+
+// consultTimeout bounds one expert consultation.
+const consultTimeout = 90 * time.Second
+
+// errNestedConsult stops consulted agents from consulting in turn, which
+// could otherwise bounce a question around the registry indefinitely.
+var errNestedConsult = errors.New("a consulted agent cannot consult other agents")
+
+type consultingKey struct{}
+
+type consultLogKey struct{}
+
+// ConsultLog records which agents were consulted while serving a request.
+type ConsultLog struct {
+	mu     sync.Mutex
+	agents []string
+}
+
+// WithConsultLog returns a context that records consultations into the
+// returned log.
+func WithConsultLog(ctx context.Context) (context.Context, *ConsultLog) {
+	l := &ConsultLog{}
+	return context.WithValue(ctx, consultLogKey{}, l), l
+}
+
+func (l *ConsultLog) add(id string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, a := range l.agents {
+		if a == id {
+			return
+		}
+	}
+	l.agents = append(l.agents, id)
+}
+
+// Agents returns the consulted agent IDs in first-consulted order.
+func (l *ConsultLog) Agents() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.agents...)
+}
+
+// AskAgent has agent to answer question on behalf of agent from
+// (tools.AgentAsker).
+func (r *Registry) AskAgent(ctx context.Context, from, to, question string) (string, error) {
+	if ctx.Value(consultingKey{}) != nil {
+		return "", errNestedConsult
+	}
+	if to == from {
+		return "", fmt.Errorf("agent %q cannot consult itself", to)
+	}
+	loop := r.Get(to)
+	if loop == nil {
+		return "", fmt.Errorf("unknown agent %q", to)
+	}
+	if l, ok := ctx.Value(consultLogKey{}).(*ConsultLog); ok {
+		l.add(to)
+	}
+
+	log.Printf("[Registry] 🤝 %s consults %s", from, to)
+	ctx = context.WithValue(ctx, consultingKey{}, to)
+	ctx, cancel := context.WithTimeout(ctx, consultTimeout)
+	defer cancel()
+	return loop.Consult(ctx, question)
+}
+
+// Experts lists the agents from may consult (tools.AgentAsker).
+func (r *Registry) Experts(from string) map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	experts := make(map[string]string, len(r.agents))
+	for id, ra := range r.agents {
+		if id != from {
+			experts[id] = ra.spec.Description
+		}
+	}
+	return experts
+}
+
+// ConsultAll asks each agent in subTasks (agent ID → sub-question) its
+// question in parallel on behalf of agent from, and returns the answers
+// sorted by agent ID. from itself and unregistered agents are skipped.
+func (r *Registry) ConsultAll(ctx context.Context, from string, subTasks map[string]string) []agent.Consultation {
+	var out []agent.Consultation
+	for id, q := range subTasks {
+		if id != from && q != "" && r.Contains(id) {
+			out = append(out, agent.Consultation{AgentID: id, Question: q})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].AgentID < out[j].AgentID })
+
+	var wg sync.WaitGroup
+	for i := range out {
+		wg.Add(1)
+		go func(c *agent.Consultation) {
+			defer wg.Done()
+			answer, err := r.AskAgent(ctx, from, c.AgentID, c.Question)
+			if err != nil {
+				log.Printf("[Registry] ⚠️ Consulting %s failed: %v", c.AgentID, err)
+				c.Error = err.Error()
+				return
+			}
+			c.Answer = answer
+		}(&out[i])
+	}
+	wg.Wait()
+	return out
+}
@@ -0,0 +1,91 @@
+package registry
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dayuer/nanobot-go/internal/providers"
+	"github.com/dayuer/nanobot-go/internal/tools"
+)
+
+// echoProvider answers "<model>: <last user message>", so each agent (given
+// its own model) can be told apart.
+type echoProvider struct{}
+
+func (echoProvider) Chat(_ context.Context, req providers.ChatRequest) (*providers.LLMResponse, error) {
+	content := req.Model + ": " + req.Messages[len(req.Messages)-1].Content
+	return &providers.LLMResponse{Content: &content, FinishReason: "stop"}, nil
+}
+
+func (echoProvider) DefaultModel() string { return "m" }
+
+func newConsultRegistry(t *testing.T) *Registry {
+	reg := NewRegistry(RegistryConfig{DefaultProvider: echoProvider{}, Workspace: t.TempDir(), ToolFactory: tools.NewFactory()})
+	reg.Register(AgentSpec{ID: "general", Model: "general-m", IsDefault: true, Tools: []string{"*"}})
+	reg.Register(AgentSpec{ID: "legal", Model: "legal-m", Description: "叶律 — 法律纠纷"})
+	reg.Register(AgentSpec{ID: "mechanic", Model: "mechanic-m", Tools: []string{"read_file"}})
+	return reg
+}
+
+func TestRegistry_AskAgent(t *testing.T) {
+	reg := newConsultRegistry(t)
+	ctx, consults := WithConsultLog(context.Background())
+
+	answer, err := reg.AskAgent(ctx, "general", "legal", "is the contract valid?")
+	if err != nil || answer != "legal-m: is the contract valid?" {
+		t.Errorf("AskAgent = %q, %v", answer, err)
+	}
+	if got := consults.Agents(); len(got) != 1 || got[0] != "legal" {
+		t.Errorf("consulted = %v, want [legal]", got)
+	}
+
+	if _, err := reg.AskAgent(ctx, "general", "general", "q"); err == nil {
+		t.Error("an agent should not consult itself")
+	}
+	if _, err := reg.AskAgent(ctx, "general", "nobody", "q"); err == nil {
+		t.Error("unknown agents should be rejected")
+	}
+	nested := context.WithValue(ctx, consultingKey{}, "legal")
+	if _, err := reg.AskAgent(nested, "legal", "mechanic", "q"); err != errNestedConsult {
+		t.Errorf("nested consult err = %v, want errNestedConsult", err)
+	}
+}
+
+func TestRegistry_ConsultAll(t *testing.T) {
+	reg := newConsultRegistry(t)
+	ctx, consults := WithConsultLog(context.Background())
+
+	got := reg.ConsultAll(ctx, "general", map[string]string{
+		"mechanic": "what does the repair cost?",
+		"legal":    "who pays for the repair?",
+		"general":  "skipped: the primary itself",
+		"nobody":   "skipped: not registered",
+	})
+	if len(got) != 2 || got[0].AgentID != "legal" || got[1].AgentID != "mechanic" {
+		t.Fatalf("ConsultAll = %+v", got)
+	}
+	if got[0].Answer != "legal-m: who pays for the repair?" {
+		t.Errorf("legal answer = %q", got[0].Answer)
+	}
+	if n := len(consults.Agents()); n != 2 {
+		t.Errorf("consulted %d agents, want 2", n)
+	}
+}
+
+func TestRegistry_AskAgentTool(t *testing.T) {
+	reg := newConsultRegistry(t)
+	tool, ok := reg.Get("general").Tools.Get("ask_agent").(*tools.AskAgentTool)
+	if !ok {
+		t.Fatal("general should get ask_agent with a \"*\" whitelist")
+	}
+	if desc := tool.Description(); !strings.Contains(desc, "- legal: 叶律 — 法律纠纷") || strings.Contains(desc, "- general:") {
+		t.Errorf("description should list the other agents:\n%s", desc)
+	}
+	if reg.Get("mechanic").Tools.Get("ask_agent") != nil {
+		t.Error("mechanic's whitelist does not include ask_agent")
+	}
+	if n := len(reg.Get("legal").Tools.All()); n != 0 {
+		t.Errorf("legal has no tools list but got %d tools", n)
+	}
+}
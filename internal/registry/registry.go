@@ -19,7 +19,9 @@ import (
 
 	"github.com/dayuer/nanobot-go/internal/agent"
 	"github.com/dayuer/nanobot-go/internal/bus"
+	"github.com/dayuer/nanobot-go/internal/contextguard"
 	"github.com/dayuer/nanobot-go/internal/providers"
+	"github.com/dayuer/nanobot-go/internal/tools"
 )
 
 // AgentSpec defines a single agent's configuration (from agents.yaml).
@@ -30,13 +32,22 @@ type AgentSpec struct {
 	Temperature      float64  `yaml:"temperature,omitempty" json:"temperature,omitempty"`
 	MaxTokens        int      `yaml:"max_tokens,omitempty" json:"maxTokens,omitempty"`
 	MaxIterations    int      `yaml:"max_iterations,omitempty" json:"maxIterations,omitempty"`
+	ReasoningEffort  string   `yaml:"reasoning_effort,omitempty" json:"reasoningEffort,omitempty"`
+	ReasoningBudget  int      `yaml:"reasoning_budget,omitempty" json:"reasoningBudget,omitempty"`
 	SystemPromptFile string   `yaml:"system_prompt_file,omitempty" json:"systemPromptFile,omitempty"`
 	Tools            []string `yaml:"tools,omitempty" json:"tools,omitempty"`
 	Skills           []string `yaml:"skills,omitempty" json:"skills,omitempty"`
 	IsDefault        bool     `yaml:"is_default,omitempty" json:"isDefault,omitempty"`
 
+	// Subagent profiles the agent may spawn, and its running-subagent cap (optional)
+	Subagents    []agent.SubagentProfile `yaml:"subagents,omitempty" json:"subagents,omitempty"`
+	MaxSubagents int                     `yaml:"max_subagents,omitempty" json:"maxSubagents,omitempty"`
+
 	// Per-agent provider override (optional)
 	ProviderConfig *ProviderConfig `yaml:"provider,omitempty" json:"provider,omitempty"`
+
+	// Ordered fallback providers, tried when the primary keeps failing (optional)
+	Fallbacks []ProviderConfig `yaml:"fallbacks,omitempty" json:"fallbacks,omitempty"`
 }
 
 // ProviderConfig holds per-agent provider overrides.
@@ -44,6 +55,7 @@ type ProviderConfig struct {
 	APIKey       string `yaml:"api_key,omitempty" json:"apiKey,omitempty"`
 	APIBase      string `yaml:"api_base,omitempty" json:"apiBase,omitempty"`
 	ProviderName string `yaml:"provider_name,omitempty" json:"providerName,omitempty"`
+	Model        string `yaml:"model,omitempty" json:"model,omitempty"` // fallbacks only; defaults to the agent model
 }
 
 // agentsFile is the top-level structure of agents.yaml.
@@ -70,8 +82,8 @@ func LoadAgentSpecs(path string) ([]AgentSpec, error) {
 
 // registeredAgent holds a running agent and its spec.
 type registeredAgent struct {
-	spec  AgentSpec
-	loop  *agent.AgentLoop
+	spec   AgentSpec
+	loop   *agent.AgentLoop
 	prompt string // loaded system prompt content
 }
 
@@ -84,6 +96,10 @@ type Registry struct {
 	bus             *bus.MessageBus
 	workspace       string
 	defaultModel    string
+	toolFactory     *tools.Factory
+	knowledge       contextguard.KnowledgeStore
+	memory          agent.MemoryBackend
+	alwaysSkills    []string
 }
 
 // RegistryConfig holds shared settings for all agents.
@@ -92,6 +108,10 @@ type RegistryConfig struct {
 	Bus             *bus.MessageBus
 	Workspace       string
 	DefaultModel    string
+	ToolFactory     *tools.Factory              // builds each agent's AgentSpec.Tools; nil = no tools
+	Knowledge       contextguard.KnowledgeStore // receives sessions flushed on context reset
+	MemoryBackend   agent.MemoryBackend         // scoped long-term memory; nil = workspace files
+	AlwaysSkills    []string                    // skills inlined into every agent's prompt
 }
 
 // NewRegistry creates a new agent registry.
@@ -102,10 +122,16 @@ func NewRegistry(cfg RegistryConfig) *Registry {
 		bus:             cfg.Bus,
 		workspace:       cfg.Workspace,
 		defaultModel:    cfg.DefaultModel,
+		toolFactory:     cfg.ToolFactory,
+		knowledge:       cfg.Knowledge,
+		memory:          cfg.MemoryBackend,
+		alwaysSkills:    cfg.AlwaysSkills,
 	}
 }
 
-// Register creates and registers an AgentLoop from an AgentSpec.
+// Register creates and registers an AgentLoop from an AgentSpec. The agent
+// gets the tools named in spec.Tools: "*" grants every tool, and a spec
+// without tools gets none.
 func (r *Registry) Register(spec AgentSpec) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -137,15 +163,6 @@ func (r *Registry) Register(spec AgentSpec) error {
 		maxIter = 25
 	}
 
-	// Create AgentLoop
-	loop := agent.NewAgentLoop(r.bus, provider, agent.AgentConfig{
-		Workspace:     r.workspace,
-		Model:         model,
-		Temperature:   temp,
-		MaxTokens:     maxTokens,
-		MaxIterations: maxIter,
-	})
-
 	// Load system prompt
 	prompt := ""
 	if spec.SystemPromptFile != "" {
@@ -157,6 +174,29 @@ func (r *Registry) Register(spec AgentSpec) error {
 		}
 	}
 
+	// Create AgentLoop with the spec's tool whitelist, prompt and skills
+	loop := agent.NewAgentLoop(r.bus, provider, agent.AgentConfig{
+		AgentID:       spec.ID,
+		Workspace:     r.workspace,
+		Model:         model,
+		Temperature:   temp,
+		MaxTokens:     maxTokens,
+		MaxIterations: maxIter,
+		ToolFactory:   r.toolFactory,
+		ToolWhitelist: spec.Tools,
+		BoundTools:    []tools.Tool{&tools.AskAgentTool{Agents: r, From: spec.ID}},
+		SystemPrompt:  prompt,
+		Skills:        spec.Skills,
+		AlwaysSkills:  r.alwaysSkills,
+		Knowledge:     r.knowledge,
+		MemoryBackend: r.memory,
+
+		SubagentProfiles: spec.Subagents,
+		MaxSubagents:     spec.MaxSubagents,
+		ReasoningEffort:  spec.ReasoningEffort,
+		ReasoningBudget:  spec.ReasoningBudget,
+	})
+
 	r.agents[spec.ID] = &registeredAgent{
 		spec:   spec,
 		loop:   loop,
@@ -167,7 +207,7 @@ func (r *Registry) Register(spec AgentSpec) error {
 		r.defaultID = spec.ID
 	}
 
-	log.Printf("[Registry] ✅ Registered agent: %s (model=%s, temp=%.1f)", spec.ID, model, temp)
+	log.Printf("[Registry] ✅ Registered agent: %s (model=%s, temp=%.1f, tools=%d)", spec.ID, model, temp, len(loop.Tools.All()))
 	return nil
 }
 
@@ -220,17 +260,31 @@ func (r *Registry) RegisterOrUpdate(agentID string, config map[string]any) error
 	return r.Register(spec)
 }
 
-// resolveProvider returns per-agent or default provider.
+// resolveProvider returns per-agent or default provider, wrapped in a
+// FallbackProvider when the spec lists fallbacks.
 func (r *Registry) resolveProvider(spec AgentSpec) providers.LLMProvider {
+	primary := r.defaultProvider
 	if spec.ProviderConfig != nil && spec.ProviderConfig.APIKey != "" {
-		return providers.NewProvider(
+		primary = providers.NewLLMProvider(
 			spec.ProviderConfig.APIKey,
 			spec.ProviderConfig.APIBase,
 			spec.Model,
 			spec.ProviderConfig.ProviderName,
 		)
 	}
-	return r.defaultProvider
+	if len(spec.Fallbacks) == 0 {
+		return primary
+	}
+
+	chain := []providers.LLMProvider{primary}
+	for _, fb := range spec.Fallbacks {
+		model := fb.Model
+		if model == "" {
+			model = spec.Model
+		}
+		chain = append(chain, providers.NewLLMProvider(fb.APIKey, fb.APIBase, model, fb.ProviderName))
+	}
+	return providers.NewFallbackProvider(chain...)
 }
 
 // Get returns the AgentLoop for the given ID, or nil if not found.
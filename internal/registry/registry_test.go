@@ -1,9 +1,14 @@
 package registry
 
 import (
+	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/dayuer/nanobot-go/internal/providers"
+	"github.com/dayuer/nanobot-go/internal/tools"
 )
 
 func TestLoadAgentSpecs(t *testing.T) {
@@ -172,3 +177,120 @@ func TestRegistry_ListAgents(t *testing.T) {
 		t.Errorf("ListAgents() returned %d items, want 2", len(list))
 	}
 }
+
+func TestRegistry_Register_WithFallbacks(t *testing.T) {
+	reg := NewRegistry(RegistryConfig{
+		DefaultProvider: &mockProvider{model: "default-model"},
+		Bus:             bus_stub(),
+		Workspace:       t.TempDir(),
+		DefaultModel:    "default-model",
+	})
+
+	err := reg.Register(AgentSpec{
+		ID:    "general",
+		Model: "deepseek-chat",
+		Fallbacks: []ProviderConfig{
+			{APIKey: "sk-backup", Model: "gpt-4o"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Register() error: %v", err)
+	}
+
+	fp, ok := reg.Get("general").Provider.(*providers.FallbackProvider)
+	if !ok {
+		t.Fatalf("Provider = %T, want *providers.FallbackProvider", reg.Get("general").Provider)
+	}
+	if fp.DefaultModel() != "default-model" {
+		t.Errorf("DefaultModel() = %q, want primary's model", fp.DefaultModel())
+	}
+}
+
+type namedTool struct{ name string }
+
+func (n *namedTool) Name() string               { return n.name }
+func (n *namedTool) Description() string        { return n.name }
+func (n *namedTool) Parameters() map[string]any { return map[string]any{"type": "object"} }
+func (n *namedTool) Execute(_ context.Context, _ map[string]any) (string, error) {
+	return n.name, nil
+}
+
+func TestRegistry_Register_AppliesToolsPromptAndSkills(t *testing.T) {
+	root := t.TempDir()
+	workspace := filepath.Join(root, "workspace")
+	os.MkdirAll(filepath.Join(workspace, "skills", "contracts"), 0o755)
+	os.WriteFile(filepath.Join(workspace, "skills", "contracts", "SKILL.md"), []byte("Check every clause."), 0o644)
+	os.MkdirAll(filepath.Join(root, "team", "roles"), 0o755)
+	os.WriteFile(filepath.Join(root, "team", "roles", "legal.md"), []byte("You are 叶律."), 0o644)
+
+	factory := tools.NewFactory()
+	for _, name := range []string{"exec", "knowledge_search", "web_search"} {
+		name := name
+		factory.Register(name, func() tools.Tool { return &namedTool{name: name} })
+	}
+
+	reg := NewRegistry(RegistryConfig{
+		DefaultProvider: &mockProvider{model: "m"},
+		Bus:             bus_stub(),
+		Workspace:       workspace,
+		DefaultModel:    "m",
+		ToolFactory:     factory,
+	})
+	reg.Register(AgentSpec{ID: "general", Tools: []string{"*"}})
+	reg.Register(AgentSpec{
+		ID:               "legal",
+		SystemPromptFile: "team/roles/legal.md",
+		Tools:            []string{"knowledge_search", "web_search"},
+		Skills:           []string{"contracts"},
+	})
+
+	// "*" also grants spawn, subagent_status, subagent_cancel and ask_agent
+	if n := len(reg.Get("general").Tools.All()); n != 7 {
+		t.Errorf("general has %d tools, want 7", n)
+	}
+	legal := reg.Get("legal")
+	if n := len(legal.Tools.All()); n != 2 {
+		t.Errorf("legal has %d tools, want 2", n)
+	}
+	if legal.Tools.Get("exec") != nil {
+		t.Error("legal should not get exec")
+	}
+
+	prompt := legal.Context.BuildMessages(context.Background(), nil, "hi", "", "")[0]["content"].(string)
+	if !strings.Contains(prompt, "You are 叶律.") {
+		t.Error("system prompt should include the agent's prompt file")
+	}
+	if !strings.Contains(prompt, "Check every clause.") {
+		t.Error("system prompt should include the agent's skills")
+	}
+}
+
+func TestRegistry_Register_SubagentProfiles(t *testing.T) {
+	yaml := `agents:
+  - id: research
+    max_subagents: 2
+    subagents:
+      - name: scout
+        tools: [web_fetch]
+        max_iterations: 5
+        max_depth: 2
+        restrict_to_workspace: true
+`
+	path := filepath.Join(t.TempDir(), "agents.yaml")
+	os.WriteFile(path, []byte(yaml), 0644)
+	specs, err := LoadAgentSpecs(path)
+	if err != nil {
+		t.Fatalf("LoadAgentSpecs() error: %v", err)
+	}
+
+	reg := NewRegistry(RegistryConfig{DefaultProvider: &mockProvider{model: "m"}, Workspace: t.TempDir(), ToolFactory: tools.NewFactory()})
+	reg.Register(specs[0])
+	sm := reg.Get("research").Subagents
+	scout, ok := sm.Profiles["scout"]
+	if !ok || scout.MaxIterations != 5 || scout.MaxDepth != 2 || !scout.RestrictToWorkspace {
+		t.Errorf("scout profile = %+v", scout)
+	}
+	if sm.MaxConcurrent != 2 {
+		t.Errorf("MaxConcurrent = %d, want 2", sm.MaxConcurrent)
+	}
+}
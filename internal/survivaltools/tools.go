@@ -14,6 +14,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/dayuer/nanobot-go/internal/rag"
 	nanoredis "github.com/dayuer/nanobot-go/internal/redis"
 )
 
@@ -128,7 +129,7 @@ func NewDataTool(baseURL, apiKey string) *DataTool {
 	return &DataTool{client: newBackendClient(baseURL, apiKey)}
 }
 
-func (t *DataTool) Name() string        { return "survival_data" }
+func (t *DataTool) Name() string { return "survival_data" }
 func (t *DataTool) Description() string {
 	return "查询 Survival 运营数据。支持查询：今日收入、订单统计、用户增长、工具使用率、月度报表、环比数据等。"
 }
@@ -203,9 +204,9 @@ func (t *StockTool) Parameters() map[string]any {
 				"description": "操作类型",
 				"enum":        []string{"list_stocks", "get_ticks", "get_snapshots", "get_predictions", "get_stats", "get_sentiment", "get_risk", "get_performance", "get_portfolios", "get_positions", "get_strategies", "generate_prediction", "get_backtest", "run_backtest"},
 			},
-			"symbol":    map[string]any{"type": "string", "description": "股票代码 (如: 000001.SZ)"},
-			"period":    map[string]any{"type": "string", "description": "时间周期 (1min/5min/15min/30min/60min/daily)"},
-			"limit":     map[string]any{"type": "integer", "description": "返回条数，默认 50"},
+			"symbol":      map[string]any{"type": "string", "description": "股票代码 (如: 000001.SZ)"},
+			"period":      map[string]any{"type": "string", "description": "时间周期 (1min/5min/15min/30min/60min/daily)"},
+			"limit":       map[string]any{"type": "integer", "description": "返回条数，默认 50"},
 			"portfolioId": map[string]any{"type": "string", "description": "组合 ID"},
 		},
 		"required": []string{"action"},
@@ -303,6 +304,7 @@ func NewMemoryTool(baseURL, apiKey string) *MemoryTool {
 
 func (t *MemoryTool) SetPersonID(id string) { t.personID = id }
 func (t *MemoryTool) Name() string          { return "user_memory" }
+func (t *MemoryTool) Sequential()           {} // read/save/append order matters
 func (t *MemoryTool) Description() string {
 	return "管理每个用户的独立记忆 (长期偏好 + 每日笔记)。支持操作：read (读取记忆), save (保存/覆盖), append (追加), note (添加每日笔记)。记忆存储三级架构：Redis缓存 → Backend API → 本地文件。"
 }
@@ -417,7 +419,7 @@ func NewNotifyTool(baseURL, apiKey string) *NotifyTool {
 	return &NotifyTool{client: newBackendClient(baseURL, apiKey)}
 }
 
-func (t *NotifyTool) Name() string        { return "survival_notify" }
+func (t *NotifyTool) Name() string { return "survival_notify" }
 func (t *NotifyTool) Description() string {
 	return "通过 Survival 内置 IM 向用户发送通知消息。用于：工单状态更新、系统提醒、活动通知、AI 主动推送等。"
 }
@@ -551,9 +553,24 @@ type RAGQuerier interface {
 
 // SearchResult is imported from rag package (re-defined to avoid circular import).
 type SearchResult struct {
-	Text     string         `json:"text"`
-	Source   string         `json:"source"`
-	Distance float64       `json:"distance"`
+	Text     string  `json:"text"`
+	Source   string  `json:"source"`
+	Distance float64 `json:"distance"`
+}
+
+// RAGStore adapts a *rag.Store to RAGQuerier.
+type RAGStore struct{ Store *rag.Store }
+
+func (s RAGStore) Query(ctx context.Context, text string, topK int) ([]SearchResult, error) {
+	results, err := s.Store.Query(ctx, text, topK)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SearchResult, len(results))
+	for i, r := range results {
+		out[i] = SearchResult{Text: r.Text, Source: r.Source, Distance: r.Distance}
+	}
+	return out, nil
 }
 
 type KnowledgeSearchTool struct {
@@ -564,7 +581,7 @@ func NewKnowledgeSearchTool(rag RAGQuerier) *KnowledgeSearchTool {
 	return &KnowledgeSearchTool{rag: rag}
 }
 
-func (t *KnowledgeSearchTool) Name() string        { return "knowledge_search" }
+func (t *KnowledgeSearchTool) Name() string { return "knowledge_search" }
 func (t *KnowledgeSearchTool) Description() string {
 	return "在知识库中进行语义搜索，查找与查询最相关的文档片段。适用于查找 SOP、产品文档、FAQ、历史案例等。返回最相关的文档片段及其来源。"
 }
@@ -19,6 +19,20 @@ type Tool interface {
 	Execute(ctx context.Context, args map[string]any) (string, error)
 }
 
+// SequentialTool is an optional marker for tools whose calls must not overlap
+// with other calls from the same assistant turn (side effects, ordering).
+// The agent loop runs such a call alone, after every earlier call has finished.
+type SequentialTool interface {
+	Tool
+	Sequential()
+}
+
+// IsSequential reports whether t opted out of parallel execution.
+func IsSequential(t Tool) bool {
+	_, ok := t.(SequentialTool)
+	return ok
+}
+
 // ToSchema converts a tool to OpenAI function calling format.
 func ToSchema(t Tool) map[string]any {
 	return map[string]any{
@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AgentAsker lets an agent consult the other agents of a multi-agent registry.
+type AgentAsker interface {
+	AskAgent(ctx context.Context, from, to, question string) (string, error)
+	Experts(from string) map[string]string // agent ID → description, excluding from
+}
+
+// AskAgentTool asks another registered agent a focused question and returns
+// its answer. Independent calls run in parallel, so several experts can be
+// consulted at once.
+type AskAgentTool struct {
+	Agents AgentAsker
+	From   string // the asking agent's ID
+}
+
+func (t *AskAgentTool) Name() string { return "ask_agent" }
+func (t *AskAgentTool) Description() string {
+	desc := "Ask another expert agent a focused question and get its answer. Ask several experts in one turn to consult them in parallel."
+	if t.Agents == nil {
+		return desc
+	}
+	experts := t.Agents.Experts(t.From)
+	if len(experts) == 0 {
+		return desc
+	}
+	ids := make([]string, 0, len(experts))
+	for id := range experts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var b strings.Builder
+	b.WriteString(desc + "\n\nExperts:")
+	for _, id := range ids {
+		b.WriteString(fmt.Sprintf("\n- %s: %s", id, experts[id]))
+	}
+	return b.String()
+}
+func (t *AskAgentTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"agent_id": map[string]any{"type": "string", "description": "ID of the agent to ask"},
+			"question": map[string]any{"type": "string", "description": "A self-contained question for that agent"},
+		},
+		"required": []string{"agent_id", "question"},
+	}
+}
+
+func (t *AskAgentTool) Execute(ctx context.Context, args map[string]any) (string, error) {
+	to, _ := args["agent_id"].(string)
+	question, _ := args["question"].(string)
+	if to == "" || question == "" {
+		return "Error: agent_id and question are required", nil
+	}
+	if t.Agents == nil {
+		return "Error: Agent consultation not configured", nil
+	}
+	answer, err := t.Agents.AskAgent(ctx, t.From, to, question)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	return fmt.Sprintf("[%s] %s", to, answer), nil
+}
@@ -0,0 +1,47 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockAsker struct{ from, to, question string }
+
+func (m *mockAsker) AskAgent(_ context.Context, from, to, question string) (string, error) {
+	if to == "nobody" {
+		return "", errors.New(`unknown agent "nobody"`)
+	}
+	m.from, m.to, m.question = from, to, question
+	return "the contract is valid", nil
+}
+
+func (m *mockAsker) Experts(from string) map[string]string {
+	return map[string]string{"legal": "contracts and disputes"}
+}
+
+func TestAskAgentTool_Contract(t *testing.T) {
+	RunToolContractTests(t, &AskAgentTool{})
+}
+
+func TestAskAgentTool_Execute(t *testing.T) {
+	m := &mockAsker{}
+	tool := &AskAgentTool{Agents: m, From: "general"}
+	assert.Contains(t, tool.Description(), "- legal: contracts and disputes")
+
+	result, err := tool.Execute(context.Background(), map[string]any{"agent_id": "legal", "question": "valid?"})
+	require.NoError(t, err)
+	assert.Equal(t, "[legal] the contract is valid", result)
+	assert.Equal(t, "general", m.from)
+	assert.Equal(t, "valid?", m.question)
+
+	result, _ = tool.Execute(context.Background(), map[string]any{"agent_id": "nobody", "question": "q"})
+	assert.Contains(t, result, "Error: unknown agent")
+	result, _ = tool.Execute(context.Background(), map[string]any{"agent_id": "legal"})
+	assert.Contains(t, result, "required")
+	result, _ = (&AskAgentTool{}).Execute(context.Background(), map[string]any{"agent_id": "legal", "question": "q"})
+	assert.Contains(t, result, "not configured")
+}
@@ -0,0 +1,231 @@
+// Package tools — executor.go
+// Executor wraps a Registry with the runtime guarantees every tool call gets:
+// argument validation, a per-call deadline, panic isolation, consistent
+// output truncation, and duration/outcome accounting.
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Outcome classifies how a tool call ended.
+type Outcome string
+
+const (
+	OutcomeOK      Outcome = "ok"
+	OutcomeError   Outcome = "error"        // Execute returned an error
+	OutcomeTimeout Outcome = "timeout"      // deadline exceeded
+	OutcomePanic   Outcome = "panic"        // Execute panicked
+	OutcomeUnknown Outcome = "unknown_tool" // no such tool registered
+	OutcomeInvalid Outcome = "invalid_args" // arguments failed schema validation
+)
+
+// Executor defaults.
+const (
+	DefaultToolTimeout = 120 * time.Second
+	DefaultMaxOutput   = 16000 // bytes
+)
+
+// Result is the outcome of one tool call. Output is always suitable to hand
+// back to the LLM as the tool message content.
+type Result struct {
+	Tool      string
+	Output    string
+	Outcome   Outcome
+	Duration  time.Duration
+	Truncated bool
+}
+
+// ToolStats aggregates results per tool.
+type ToolStats struct {
+	Calls         int           `json:"calls"`
+	Errors        int           `json:"errors"`
+	Timeouts      int           `json:"timeouts"`
+	Panics        int           `json:"panics"`
+	TotalDuration time.Duration `json:"totalDuration"`
+	MaxDuration   time.Duration `json:"maxDuration"`
+}
+
+// Executor runs tools from a Registry.
+type Executor struct {
+	Registry  *Registry
+	Timeout   time.Duration            // default per-call deadline; 0 = DefaultToolTimeout, <0 disables
+	Timeouts  map[string]time.Duration // per-tool overrides, keyed by tool name
+	MaxOutput int                      // output cap in bytes; 0 = DefaultMaxOutput, <0 disables
+	OnResult  func(Result)             // optional hook, called after every call
+
+	mu    sync.Mutex
+	stats map[string]*ToolStats
+}
+
+// NewExecutor creates an Executor with default limits.
+func NewExecutor(reg *Registry) *Executor {
+	return &Executor{
+		Registry:  reg,
+		Timeout:   DefaultToolTimeout,
+		MaxOutput: DefaultMaxOutput,
+		stats:     make(map[string]*ToolStats),
+	}
+}
+
+// Execute validates args and runs the named tool. Failures never escape as
+// panics or errors: they are reported in Result.Output and Result.Outcome.
+func (e *Executor) Execute(ctx context.Context, name string, args map[string]any) Result {
+	start := time.Now()
+	res := Result{Tool: name}
+
+	tool := e.Registry.Get(name)
+	if tool == nil {
+		res.Outcome = OutcomeUnknown
+		res.Output = fmt.Sprintf("Error: unknown tool %q", name)
+	} else if issues := ValidateArgs(tool.Parameters(), args); len(issues) > 0 {
+		res.Outcome = OutcomeInvalid
+		res.Output = InvalidArgumentsOutput(name, issues)
+	} else {
+		res.Output, res.Outcome = e.run(ctx, tool, args)
+	}
+
+	res.Output, res.Truncated = e.truncate(res.Output)
+	res.Duration = time.Since(start)
+	e.record(res)
+	return res
+}
+
+// Reject records a call whose arguments could not even be decoded (e.g.
+// malformed JSON from the model) and returns the structured error for it.
+func (e *Executor) Reject(name, message string) Result {
+	res := Result{
+		Tool:    name,
+		Outcome: OutcomeInvalid,
+		Output:  InvalidArgumentsOutput(name, []ValidationIssue{{Path: "(root)", Message: message}}),
+	}
+	e.record(res)
+	return res
+}
+
+type toolReturn struct {
+	output  string
+	outcome Outcome
+}
+
+// run calls tool.Execute in its own goroutine so a hung or panicking tool
+// cannot take the caller down with it. A timed-out call keeps running in
+// the background until the tool honors ctx; its result is discarded.
+func (e *Executor) run(ctx context.Context, tool Tool, args map[string]any) (string, Outcome) {
+	timeout := e.timeoutFor(tool.Name())
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	done := make(chan toolReturn, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("[Tools] 💥 %s panicked: %v\n%s", tool.Name(), r, debug.Stack())
+				done <- toolReturn{fmt.Sprintf("Error: tool %q panicked: %v", tool.Name(), r), OutcomePanic}
+			}
+		}()
+		out, err := tool.Execute(ctx, args)
+		if err != nil {
+			done <- toolReturn{fmt.Sprintf("Error: %v", err), OutcomeError}
+			return
+		}
+		done <- toolReturn{out, OutcomeOK}
+	}()
+
+	select {
+	case r := <-done:
+		return r.output, r.outcome
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Sprintf("Error: tool %q timed out after %v", tool.Name(), timeout), OutcomeTimeout
+		}
+		return fmt.Sprintf("Error: tool %q cancelled: %v", tool.Name(), ctx.Err()), OutcomeError
+	}
+}
+
+func (e *Executor) timeoutFor(name string) time.Duration {
+	if d, ok := e.Timeouts[name]; ok {
+		return d
+	}
+	if e.Timeout == 0 {
+		return DefaultToolTimeout
+	}
+	return e.Timeout
+}
+
+func (e *Executor) truncate(s string) (string, bool) {
+	max := e.MaxOutput
+	if max == 0 {
+		max = DefaultMaxOutput
+	}
+	if max < 0 || len(s) <= max {
+		return s, false
+	}
+	return TruncateOutput(s, max), true
+}
+
+func (e *Executor) record(res Result) {
+	e.mu.Lock()
+	if e.stats == nil {
+		e.stats = make(map[string]*ToolStats)
+	}
+	st, ok := e.stats[res.Tool]
+	if !ok {
+		st = &ToolStats{}
+		e.stats[res.Tool] = st
+	}
+	st.Calls++
+	switch res.Outcome {
+	case OutcomeError, OutcomeUnknown, OutcomeInvalid:
+		st.Errors++
+	case OutcomeTimeout:
+		st.Timeouts++
+	case OutcomePanic:
+		st.Panics++
+	}
+	st.TotalDuration += res.Duration
+	if res.Duration > st.MaxDuration {
+		st.MaxDuration = res.Duration
+	}
+	e.mu.Unlock()
+
+	if res.Outcome == OutcomeTimeout || res.Outcome == OutcomePanic {
+		log.Printf("[Tools] ⚠️ %s %s after %v", res.Tool, res.Outcome, res.Duration.Round(time.Millisecond))
+	}
+	if e.OnResult != nil {
+		e.OnResult(res)
+	}
+}
+
+// Stats returns a snapshot of per-tool statistics.
+func (e *Executor) Stats() map[string]ToolStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	out := make(map[string]ToolStats, len(e.stats))
+	for name, st := range e.stats {
+		out[name] = *st
+	}
+	return out
+}
+
+// TruncateOutput cuts s to at most max bytes (on a UTF-8 boundary) and
+// appends a marker with the number of bytes dropped.
+func TruncateOutput(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + fmt.Sprintf("\n... (truncated, %d more bytes)", len(s)-cut)
+}
@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// funcTool adapts a function to the Tool interface.
+type funcTool struct {
+	name string
+	fn   func(ctx context.Context) (string, error)
+}
+
+func (f *funcTool) Name() string               { return f.name }
+func (f *funcTool) Description() string        { return "test tool" }
+func (f *funcTool) Parameters() map[string]any { return map[string]any{"type": "object"} }
+func (f *funcTool) Execute(ctx context.Context, _ map[string]any) (string, error) {
+	return f.fn(ctx)
+}
+
+func newTestExecutor(tools ...Tool) *Executor {
+	reg := NewRegistry()
+	for _, t := range tools {
+		reg.Register(t)
+	}
+	return NewExecutor(reg)
+}
+
+func TestExecutor_OK(t *testing.T) {
+	ex := newTestExecutor(&funcTool{name: "echo", fn: func(context.Context) (string, error) { return "hi", nil }})
+	res := ex.Execute(context.Background(), "echo", nil)
+	assert.Equal(t, OutcomeOK, res.Outcome)
+	assert.Equal(t, "hi", res.Output)
+	assert.False(t, res.Truncated)
+}
+
+func TestExecutor_Error(t *testing.T) {
+	ex := newTestExecutor(&funcTool{name: "fail", fn: func(context.Context) (string, error) { return "", errors.New("boom") }})
+	res := ex.Execute(context.Background(), "fail", nil)
+	assert.Equal(t, OutcomeError, res.Outcome)
+	assert.Equal(t, "Error: boom", res.Output)
+}
+
+func TestExecutor_UnknownTool(t *testing.T) {
+	ex := newTestExecutor()
+	res := ex.Execute(context.Background(), "missing", nil)
+	assert.Equal(t, OutcomeUnknown, res.Outcome)
+	assert.Contains(t, res.Output, `unknown tool "missing"`)
+}
+
+func TestExecutor_Timeout(t *testing.T) {
+	hang := &funcTool{name: "hang", fn: func(ctx context.Context) (string, error) {
+		time.Sleep(5 * time.Second) // ignores ctx on purpose
+		return "too late", nil
+	}}
+	ex := newTestExecutor(hang)
+	ex.Timeouts = map[string]time.Duration{"hang": 20 * time.Millisecond}
+
+	start := time.Now()
+	res := ex.Execute(context.Background(), "hang", nil)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, OutcomeTimeout, res.Outcome)
+	assert.Contains(t, res.Output, "timed out")
+}
+
+func TestExecutor_PanicRecovered(t *testing.T) {
+	ex := newTestExecutor(&funcTool{name: "crash", fn: func(context.Context) (string, error) {
+		var m map[string]int
+		m["x"] = 1 // nil map write
+		return "", nil
+	}})
+	res := ex.Execute(context.Background(), "crash", nil)
+	assert.Equal(t, OutcomePanic, res.Outcome)
+	assert.True(t, strings.HasPrefix(res.Output, "Error:"))
+}
+
+func TestExecutor_TruncatesOutput(t *testing.T) {
+	big := strings.Repeat("数据", 100) // 3-byte runes
+	ex := newTestExecutor(&funcTool{name: "big", fn: func(context.Context) (string, error) { return big, nil }})
+	ex.MaxOutput = 100
+
+	res := ex.Execute(context.Background(), "big", nil)
+	assert.True(t, res.Truncated)
+	assert.Contains(t, res.Output, "truncated")
+	assert.True(t, utf8.ValidString(res.Output), "never split a rune")
+}
+
+func TestExecutor_ZeroValueUsesDefaults(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register(&funcTool{name: "echo", fn: func(context.Context) (string, error) { return "hello", nil }})
+	ex := &Executor{Registry: reg}
+
+	res := ex.Execute(context.Background(), "echo", nil)
+	assert.Equal(t, "hello", res.Output)
+	assert.False(t, res.Truncated)
+	assert.Equal(t, DefaultToolTimeout, ex.timeoutFor("echo"))
+}
+
+func TestExecutor_StatsAndHook(t *testing.T) {
+	var seen []Outcome
+	ex := newTestExecutor(
+		&funcTool{name: "ok", fn: func(context.Context) (string, error) { return "", nil }},
+		&funcTool{name: "fail", fn: func(context.Context) (string, error) { return "", errors.New("x") }},
+	)
+	ex.OnResult = func(r Result) { seen = append(seen, r.Outcome) }
+
+	ex.Execute(context.Background(), "ok", nil)
+	ex.Execute(context.Background(), "ok", nil)
+	ex.Execute(context.Background(), "fail", nil)
+
+	stats := ex.Stats()
+	require.Contains(t, stats, "ok")
+	assert.Equal(t, 2, stats["ok"].Calls)
+	assert.Equal(t, 0, stats["ok"].Errors)
+	assert.Equal(t, 1, stats["fail"].Errors)
+	assert.Equal(t, []Outcome{OutcomeOK, OutcomeOK, OutcomeError}, seen)
+}
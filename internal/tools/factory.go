@@ -0,0 +1,133 @@
+// Package tools — factory.go
+// Factory builds per-agent tool sets from named constructors, so each agent
+// gets fresh tool instances limited to its AgentSpec.Tools whitelist.
+package tools
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// AllTools is the whitelist that admits every tool.
+var AllTools = []string{"*"}
+
+// Constructor builds one tool instance. It returns nil when the tool is not
+// available in this environment (e.g. missing credentials).
+type Constructor func() Tool
+
+// Factory maps tool names to constructors.
+type Factory struct {
+	mu           sync.RWMutex
+	constructors map[string]Constructor
+}
+
+// NewFactory creates an empty factory.
+func NewFactory() *Factory {
+	return &Factory{constructors: make(map[string]Constructor)}
+}
+
+// Register adds (or replaces) the constructor for name.
+func (f *Factory) Register(name string, c Constructor) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.constructors[name] = c
+}
+
+// Names returns the registered tool names, sorted.
+func (f *Factory) Names() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	names := make([]string, 0, len(f.constructors))
+	for name := range f.constructors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BuildInto constructs the whitelisted tools and registers them into reg.
+// A whitelist containing "*" means every tool; an empty one means none; a
+// name ending in "*" (e.g. "mcp_github_*") admits every tool with that
+// prefix. It returns the whitelisted names that have no constructor or
+// whose constructor declined to build; prefixes are never reported.
+func (f *Factory) BuildInto(reg *Registry, whitelist []string) (missing []string) {
+	explicit := !allowsAll(whitelist)
+	var names []string
+	if explicit {
+		for _, name := range whitelist {
+			if !isPrefix(name) {
+				names = append(names, name)
+			}
+		}
+	}
+	for _, name := range f.Names() {
+		if (!explicit || prefixWhitelisted(whitelist, name)) && !contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, name := range names {
+		c, ok := f.constructors[name]
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		tool := c()
+		if tool == nil {
+			if explicit && contains(whitelist, name) {
+				missing = append(missing, name)
+			}
+			continue
+		}
+		reg.Register(tool)
+	}
+	return missing
+}
+
+// Build is BuildInto on a fresh Registry.
+func (f *Factory) Build(whitelist []string) (*Registry, []string) {
+	reg := NewRegistry()
+	missing := f.BuildInto(reg, whitelist)
+	return reg, missing
+}
+
+// Whitelisted reports whether whitelist admits name. A whitelist containing
+// "*" admits every tool; an empty one admits none; "prefix*" admits the
+// tools whose names start with prefix.
+func Whitelisted(whitelist []string, name string) bool {
+	return allowsAll(whitelist) || contains(whitelist, name) || prefixWhitelisted(whitelist, name)
+}
+
+func prefixWhitelisted(whitelist []string, name string) bool {
+	for _, n := range whitelist {
+		if isPrefix(n) && strings.HasPrefix(name, strings.TrimSuffix(n, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+func isPrefix(entry string) bool {
+	return len(entry) > 1 && strings.HasSuffix(entry, "*")
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func allowsAll(whitelist []string) bool {
+	for _, name := range whitelist {
+		if name == "*" {
+			return true
+		}
+	}
+	return false
+}
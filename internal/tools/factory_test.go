@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestFactory() *Factory {
+	f := NewFactory()
+	for _, name := range []string{"alpha", "beta", "gamma"} {
+		name := name
+		f.Register(name, func() Tool {
+			return &funcTool{name: name, fn: func(context.Context) (string, error) { return name, nil }}
+		})
+	}
+	f.Register("offline", func() Tool { return nil })
+	return f
+}
+
+func toolNames(reg *Registry) []string {
+	var names []string
+	for _, t := range reg.All() {
+		names = append(names, t.Name())
+	}
+	return names
+}
+
+func TestFactory_BuildAll(t *testing.T) {
+	for _, whitelist := range [][]string{AllTools, {"beta", "*"}} {
+		reg, missing := newTestFactory().Build(whitelist)
+		assert.ElementsMatch(t, []string{"alpha", "beta", "gamma"}, toolNames(reg))
+		assert.Empty(t, missing, "unavailable tools are only reported when asked for")
+	}
+}
+
+func TestFactory_BuildEmptyWhitelist(t *testing.T) {
+	for _, whitelist := range [][]string{nil, {}} {
+		reg, missing := newTestFactory().Build(whitelist)
+		assert.Empty(t, reg.All())
+		assert.Empty(t, missing)
+	}
+}
+
+func TestFactory_BuildWhitelist(t *testing.T) {
+	reg, missing := newTestFactory().Build([]string{"beta", "offline", "nope"})
+	assert.Equal(t, []string{"beta"}, toolNames(reg))
+	assert.Equal(t, []string{"offline", "nope"}, missing)
+}
+
+func TestFactory_BuildPrefix(t *testing.T) {
+	reg, missing := newTestFactory().Build([]string{"gamma", "al*", "zz*"})
+	assert.ElementsMatch(t, []string{"alpha", "gamma"}, toolNames(reg))
+	assert.Empty(t, missing, "prefixes that match nothing are not missing tools")
+}
+
+func TestFactory_FreshInstances(t *testing.T) {
+	f := newTestFactory()
+	a, _ := f.Build([]string{"alpha"})
+	b, _ := f.Build([]string{"alpha"})
+	assert.NotSame(t, a.Get("alpha"), b.Get("alpha"))
+}
+
+func TestFactory_Names(t *testing.T) {
+	assert.Equal(t, []string{"alpha", "beta", "gamma", "offline"}, newTestFactory().Names())
+}
+
+func TestWhitelisted(t *testing.T) {
+	assert.False(t, Whitelisted(nil, "spawn"))
+	assert.True(t, Whitelisted(AllTools, "spawn"))
+	assert.True(t, Whitelisted([]string{"alpha", "*"}, "spawn"))
+	assert.True(t, Whitelisted([]string{"spawn"}, "spawn"))
+	assert.False(t, Whitelisted([]string{"alpha"}, "spawn"))
+	assert.True(t, Whitelisted([]string{"mcp_github_*"}, "mcp_github_search"))
+	assert.False(t, Whitelisted([]string{"mcp_github_*"}, "mcp_gitlab_search"))
+}
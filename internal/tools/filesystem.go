@@ -32,7 +32,7 @@ func resolvePath(path string, allowedDir string) (string, error) {
 type ReadFileTool struct{ AllowedDir string }
 
 func (t *ReadFileTool) Name() string        { return "read_file" }
-func (t *ReadFileTool) Description() string  { return "Read the contents of a file at the given path." }
+func (t *ReadFileTool) Description() string { return "Read the contents of a file at the given path." }
 func (t *ReadFileTool) Parameters() map[string]any {
 	return map[string]any{
 		"type": "object",
@@ -69,8 +69,11 @@ func (t *ReadFileTool) Execute(_ context.Context, args map[string]any) (string,
 // WriteFileTool writes content to a file.
 type WriteFileTool struct{ AllowedDir string }
 
-func (t *WriteFileTool) Name() string        { return "write_file" }
-func (t *WriteFileTool) Description() string  { return "Write content to a file. Creates parent directories." }
+func (t *WriteFileTool) Name() string { return "write_file" }
+func (t *WriteFileTool) Sequential()  {}
+func (t *WriteFileTool) Description() string {
+	return "Write content to a file. Creates parent directories."
+}
 func (t *WriteFileTool) Parameters() map[string]any {
 	return map[string]any{
 		"type": "object",
@@ -102,8 +105,11 @@ func (t *WriteFileTool) Execute(_ context.Context, args map[string]any) (string,
 // EditFileTool edits a file by replacing old text with new text.
 type EditFileTool struct{ AllowedDir string }
 
-func (t *EditFileTool) Name() string        { return "edit_file" }
-func (t *EditFileTool) Description() string  { return "Edit a file by replacing old_text with new_text." }
+func (t *EditFileTool) Name() string { return "edit_file" }
+func (t *EditFileTool) Sequential()  {}
+func (t *EditFileTool) Description() string {
+	return "Edit a file by replacing old_text with new_text."
+}
 func (t *EditFileTool) Parameters() map[string]any {
 	return map[string]any{
 		"type": "object",
@@ -153,7 +159,7 @@ func (t *EditFileTool) Execute(_ context.Context, args map[string]any) (string,
 type ListDirTool struct{ AllowedDir string }
 
 func (t *ListDirTool) Name() string        { return "list_dir" }
-func (t *ListDirTool) Description() string  { return "List the contents of a directory." }
+func (t *ListDirTool) Description() string { return "List the contents of a directory." }
 func (t *ListDirTool) Parameters() map[string]any {
 	return map[string]any{
 		"type": "object",
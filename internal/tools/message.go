@@ -5,12 +5,34 @@ import (
 	"fmt"
 
 	"github.com/dayuer/nanobot-go/internal/bus"
+	"github.com/dayuer/nanobot-go/internal/utils"
 )
 
 // SendFunc is the callback type for sending outbound messages.
 type SendFunc func(msg bus.OutboundMessage) error
 
+type chatTargetKey struct{}
+
+type chatTarget struct{ channel, chatID string }
+
+// WithChatTarget returns a context whose tool calls act for the chat
+// channel/chatID. The agent loop sets it per turn, so one set of tools can
+// serve concurrent sessions.
+func WithChatTarget(ctx context.Context, channel, chatID string) context.Context {
+	return context.WithValue(ctx, chatTargetKey{}, chatTarget{channel, chatID})
+}
+
+// ChatTarget returns the chat attached to ctx, or channel/chatID when
+// there is none.
+func ChatTarget(ctx context.Context, channel, chatID string) (string, string) {
+	if t, ok := ctx.Value(chatTargetKey{}).(chatTarget); ok {
+		return t.channel, t.chatID
+	}
+	return channel, chatID
+}
+
 // MessageTool sends messages to users on chat channels.
+// DefaultChannel/DefaultChatID apply when the call has no chat target.
 type MessageTool struct {
 	SendCallback   SendFunc
 	DefaultChannel string
@@ -18,7 +40,8 @@ type MessageTool struct {
 }
 
 func (t *MessageTool) Name() string        { return "message" }
-func (t *MessageTool) Description() string  { return "Send a message to the user." }
+func (t *MessageTool) Sequential()         {}
+func (t *MessageTool) Description() string { return "Send a message to the user." }
 func (t *MessageTool) Parameters() map[string]any {
 	return map[string]any{
 		"type": "object",
@@ -31,22 +54,17 @@ func (t *MessageTool) Parameters() map[string]any {
 	}
 }
 
-// SetContext sets the current message context.
-func (t *MessageTool) SetContext(channel, chatID string) {
-	t.DefaultChannel = channel
-	t.DefaultChatID = chatID
-}
-
-func (t *MessageTool) Execute(_ context.Context, args map[string]any) (string, error) {
+func (t *MessageTool) Execute(ctx context.Context, args map[string]any) (string, error) {
 	content, _ := args["content"].(string)
 	channel, _ := args["channel"].(string)
 	chatID, _ := args["chat_id"].(string)
 
+	defChannel, defChatID := ChatTarget(ctx, t.DefaultChannel, t.DefaultChatID)
 	if channel == "" {
-		channel = t.DefaultChannel
+		channel = defChannel
 	}
 	if chatID == "" {
-		chatID = t.DefaultChatID
+		chatID = defChatID
 	}
 	if channel == "" || chatID == "" {
 		return "Error: No target channel/chat specified", nil
@@ -66,43 +84,144 @@ func (t *MessageTool) Execute(_ context.Context, args map[string]any) (string, e
 	return fmt.Sprintf("Message sent to %s:%s", channel, chatID), nil
 }
 
+// SpawnRequest is one spawn call. Tools, Model and MaxIterations adjust the
+// chosen profile for this subagent only.
+type SpawnRequest struct {
+	Task          string
+	Label         string
+	Profile       string
+	Tools         []string
+	Model         string
+	MaxIterations int
+	Channel       string
+	ChatID        string
+}
+
 // SpawnFunc is the callback for spawning subagents.
-type SpawnFunc func(task, label, channel, chatID string) (string, error)
+type SpawnFunc func(req SpawnRequest) (string, error)
 
-// SpawnTool spawns a subagent for background task execution.
+// SpawnTool spawns a subagent for background task execution. Results are
+// announced to the call's chat target, or OriginChannel/OriginChatID.
 type SpawnTool struct {
-	SpawnCallback  SpawnFunc
-	OriginChannel  string
-	OriginChatID   string
+	SpawnCallback SpawnFunc
+	OriginChannel string
+	OriginChatID  string
+	// Profiles are the subagent profile names the caller may pick from
+	Profiles []string
 }
 
-func (t *SpawnTool) Name() string        { return "spawn" }
-func (t *SpawnTool) Description() string  { return "Spawn a subagent to handle a task in the background." }
+func (t *SpawnTool) Name() string { return "spawn" }
+func (t *SpawnTool) Description() string {
+	return "Spawn a subagent to handle a task in the background."
+}
 func (t *SpawnTool) Parameters() map[string]any {
+	props := map[string]any{
+		"task":           map[string]any{"type": "string", "description": "The task for the subagent"},
+		"label":          map[string]any{"type": "string", "description": "Optional short label"},
+		"tools":          map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "Optional: limit the subagent to these of its profile's tools"},
+		"model":          map[string]any{"type": "string", "description": "Optional: model override"},
+		"max_iterations": map[string]any{"type": "integer", "description": "Optional: lower iteration budget"},
+	}
+	if len(t.Profiles) > 0 {
+		props["profile"] = map[string]any{"type": "string", "enum": t.Profiles, "description": "Optional: subagent profile"}
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": props,
+		"required":   []string{"task"},
+	}
+}
+
+func (t *SpawnTool) Execute(ctx context.Context, args map[string]any) (string, error) {
+	req := SpawnRequest{}
+	req.Channel, req.ChatID = ChatTarget(ctx, t.OriginChannel, t.OriginChatID)
+	req.Task, _ = args["task"].(string)
+	req.Label, _ = args["label"].(string)
+	req.Profile, _ = args["profile"].(string)
+	req.Model, _ = args["model"].(string)
+	if v, ok := args["max_iterations"].(float64); ok {
+		req.MaxIterations = int(v)
+	}
+	if list, ok := args["tools"].([]any); ok {
+		for _, v := range list {
+			if name, ok := v.(string); ok {
+				req.Tools = append(req.Tools, name)
+			}
+		}
+	}
+
+	if t.SpawnCallback == nil {
+		return "Error: Subagent spawning not configured", nil
+	}
+	return t.SpawnCallback(req)
+}
+
+// SubagentCallback is the interface for inspecting and cancelling subagents.
+type SubagentCallback interface {
+	SubagentStatus(id string) (string, error) // id "" lists all subagents
+	CancelSubagent(id string) (string, error)
+}
+
+// SubagentStatusTool reports the status of spawned subagents.
+type SubagentStatusTool struct {
+	Subagents SubagentCallback
+}
+
+func (t *SubagentStatusTool) Name() string { return "subagent_status" }
+func (t *SubagentStatusTool) Description() string {
+	return "Show the status of spawned subagents. Omit id to list all of them."
+}
+func (t *SubagentStatusTool) Parameters() map[string]any {
 	return map[string]any{
 		"type": "object",
 		"properties": map[string]any{
-			"task":  map[string]any{"type": "string", "description": "The task for the subagent"},
-			"label": map[string]any{"type": "string", "description": "Optional short label"},
+			"id": map[string]any{"type": "string", "description": "Optional: subagent ID returned by spawn"},
 		},
-		"required": []string{"task"},
 	}
 }
 
-// SetContext sets the origin context for subagent announcements.
-func (t *SpawnTool) SetContext(channel, chatID string) {
-	t.OriginChannel = channel
-	t.OriginChatID = chatID
+func (t *SubagentStatusTool) Execute(_ context.Context, args map[string]any) (string, error) {
+	id, _ := args["id"].(string)
+	if t.Subagents == nil {
+		return "Error: Subagents not configured", nil
+	}
+	out, err := t.Subagents.SubagentStatus(id)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	return out, nil
 }
 
-func (t *SpawnTool) Execute(_ context.Context, args map[string]any) (string, error) {
-	task, _ := args["task"].(string)
-	label, _ := args["label"].(string)
+// SubagentCancelTool cancels a running subagent.
+type SubagentCancelTool struct {
+	Subagents SubagentCallback
+}
 
-	if t.SpawnCallback == nil {
-		return "Error: Subagent spawning not configured", nil
+func (t *SubagentCancelTool) Name() string        { return "subagent_cancel" }
+func (t *SubagentCancelTool) Description() string { return "Cancel a running subagent." }
+func (t *SubagentCancelTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"id": map[string]any{"type": "string", "description": "Subagent ID returned by spawn"},
+		},
+		"required": []string{"id"},
+	}
+}
+
+func (t *SubagentCancelTool) Execute(_ context.Context, args map[string]any) (string, error) {
+	id, _ := args["id"].(string)
+	if id == "" {
+		return "Error: id is required", nil
 	}
-	return t.SpawnCallback(task, label, t.OriginChannel, t.OriginChatID)
+	if t.Subagents == nil {
+		return "Error: Subagents not configured", nil
+	}
+	out, err := t.Subagents.CancelSubagent(id)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	return out, nil
 }
 
 // CronAction describes a scheduled task action.
@@ -121,15 +240,18 @@ type CronCallback interface {
 	RemoveJob(jobID string) (string, error)
 }
 
-// CronTool manages scheduled reminders and recurring tasks.
+// CronTool manages scheduled reminders and recurring tasks. Jobs deliver to
+// the call's chat target, or Channel/ChatID.
 type CronTool struct {
 	Cron    CronCallback
 	Channel string
 	ChatID  string
 }
 
-func (t *CronTool) Name() string        { return "cron" }
-func (t *CronTool) Description() string  { return "Schedule reminders and recurring tasks. Actions: add, list, remove." }
+func (t *CronTool) Name() string { return "cron" }
+func (t *CronTool) Description() string {
+	return "Schedule reminders and recurring tasks. Actions: add, list, remove."
+}
 func (t *CronTool) Parameters() map[string]any {
 	return map[string]any{
 		"type": "object",
@@ -137,21 +259,15 @@ func (t *CronTool) Parameters() map[string]any {
 			"action":        map[string]any{"type": "string", "enum": []string{"add", "list", "remove"}},
 			"message":       map[string]any{"type": "string", "description": "Reminder message (for add)"},
 			"every_seconds": map[string]any{"type": "integer", "description": "Interval in seconds"},
-			"cron_expr":     map[string]any{"type": "string", "description": "Cron expression"},
-			"at":            map[string]any{"type": "string", "description": "ISO datetime for one-time"},
+			"cron_expr":     map[string]any{"type": "string", "description": "Five-field cron expression, e.g. '0 9 * * 1-5'; prefix 'CRON_TZ=Asia/Shanghai ' for a time zone"},
+			"at":            map[string]any{"type": "string", "description": "ISO datetime for one-time, e.g. 2025-01-31T09:00:00 (local unless it has an offset)"},
 			"job_id":        map[string]any{"type": "string", "description": "Job ID (for remove)"},
 		},
 		"required": []string{"action"},
 	}
 }
 
-// SetContext sets the delivery target for scheduled messages.
-func (t *CronTool) SetContext(channel, chatID string) {
-	t.Channel = channel
-	t.ChatID = chatID
-}
-
-func (t *CronTool) Execute(_ context.Context, args map[string]any) (string, error) {
+func (t *CronTool) Execute(ctx context.Context, args map[string]any) (string, error) {
 	action, _ := args["action"].(string)
 
 	if t.Cron == nil {
@@ -164,7 +280,8 @@ func (t *CronTool) Execute(_ context.Context, args map[string]any) (string, erro
 		if message == "" {
 			return "Error: message is required for add", nil
 		}
-		if t.Channel == "" || t.ChatID == "" {
+		channel, chatID := ChatTarget(ctx, t.Channel, t.ChatID)
+		if channel == "" || chatID == "" {
 			return "Error: no session context (channel/chat_id)", nil
 		}
 		everySeconds := 0
@@ -174,11 +291,8 @@ func (t *CronTool) Execute(_ context.Context, args map[string]any) (string, erro
 		cronExpr, _ := args["cron_expr"].(string)
 		at, _ := args["at"].(string)
 
-		name := message
-		if len(name) > 30 {
-			name = name[:30]
-		}
-		return t.Cron.AddJob(name, message, t.Channel, t.ChatID, everySeconds, cronExpr, at)
+		name := utils.TruncateRunes(message, 30)
+		return t.Cron.AddJob(name, message, channel, chatID, everySeconds, cronExpr, at)
 
 	case CronList:
 		return t.Cron.ListJobs()
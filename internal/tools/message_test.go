@@ -50,6 +50,23 @@ func TestMessageTool_OverrideChannel(t *testing.T) {
 	assert.Equal(t, "456", sent.ChatID)
 }
 
+func TestMessageTool_ChatTargetFromContext(t *testing.T) {
+	var sent bus.OutboundMessage
+	tool := &MessageTool{
+		SendCallback:   func(msg bus.OutboundMessage) error { sent = msg; return nil },
+		DefaultChannel: "telegram",
+		DefaultChatID:  "123",
+	}
+	ctx := WithChatTarget(context.Background(), "slack", "C42")
+	tool.Execute(ctx, map[string]any{"content": "hi"})
+	assert.Equal(t, "slack", sent.Channel)
+	assert.Equal(t, "C42", sent.ChatID)
+
+	// An explicit target still wins
+	tool.Execute(ctx, map[string]any{"content": "hi", "chat_id": "C7"})
+	assert.Equal(t, "C7", sent.ChatID)
+}
+
 func TestMessageTool_NoTarget(t *testing.T) {
 	tool := &MessageTool{}
 	result, _ := tool.Execute(context.Background(), map[string]any{"content": "hi"})
@@ -78,13 +95,10 @@ func TestSpawnTool_Contract(t *testing.T) {
 }
 
 func TestSpawnTool_Execute(t *testing.T) {
-	var capturedTask, capturedLabel, capturedCh, capturedID string
+	var captured SpawnRequest
 	tool := &SpawnTool{
-		SpawnCallback: func(task, label, ch, id string) (string, error) {
-			capturedTask = task
-			capturedLabel = label
-			capturedCh = ch
-			capturedID = id
+		SpawnCallback: func(req SpawnRequest) (string, error) {
+			captured = req
 			return "Spawned subagent 'abc'", nil
 		},
 		OriginChannel: "cli",
@@ -96,10 +110,27 @@ func TestSpawnTool_Execute(t *testing.T) {
 	})
 	require.NoError(t, err)
 	assert.Contains(t, result, "Spawned")
-	assert.Equal(t, "research topic", capturedTask)
-	assert.Equal(t, "Research", capturedLabel)
-	assert.Equal(t, "cli", capturedCh)
-	assert.Equal(t, "direct", capturedID)
+	assert.Equal(t, "research topic", captured.Task)
+	assert.Equal(t, "Research", captured.Label)
+	assert.Equal(t, "cli", captured.Channel)
+	assert.Equal(t, "direct", captured.ChatID)
+}
+
+func TestSpawnTool_ProfileAndOverrides(t *testing.T) {
+	var captured SpawnRequest
+	tool := &SpawnTool{
+		SpawnCallback: func(req SpawnRequest) (string, error) { captured = req; return "ok", nil },
+		Profiles:      []string{"researcher"},
+	}
+	props := tool.Parameters()["properties"].(map[string]any)
+	assert.Equal(t, []string{"researcher"}, props["profile"].(map[string]any)["enum"])
+	_, hasProfile := (&SpawnTool{}).Parameters()["properties"].(map[string]any)["profile"]
+	assert.False(t, hasProfile, "no profile parameter without profiles")
+
+	tool.Execute(context.Background(), map[string]any{
+		"task": "dig", "profile": "researcher", "tools": []any{"web_fetch"}, "model": "m2", "max_iterations": float64(3),
+	})
+	assert.Equal(t, SpawnRequest{Task: "dig", Profile: "researcher", Tools: []string{"web_fetch"}, Model: "m2", MaxIterations: 3}, captured)
 }
 
 func TestSpawnTool_NoCallback(t *testing.T) {
@@ -108,6 +139,52 @@ func TestSpawnTool_NoCallback(t *testing.T) {
 	assert.Contains(t, result, "not configured")
 }
 
+// --- Subagent tool Tests ---
+
+type mockSubagents struct{ cancelled string }
+
+func (m *mockSubagents) SubagentStatus(id string) (string, error) {
+	if id == "" {
+		return "- sub-1 [running] research", nil
+	}
+	return "", fmt.Errorf("subagent not found: %s", id)
+}
+func (m *mockSubagents) CancelSubagent(id string) (string, error) {
+	m.cancelled = id
+	return "Cancellation requested for subagent " + id + ".", nil
+}
+
+func TestSubagentTools_Contract(t *testing.T) {
+	RunToolContractTests(t, &SubagentStatusTool{})
+	RunToolContractTests(t, &SubagentCancelTool{})
+}
+
+func TestSubagentStatusTool_Execute(t *testing.T) {
+	tool := &SubagentStatusTool{Subagents: &mockSubagents{}}
+	result, err := tool.Execute(context.Background(), map[string]any{})
+	require.NoError(t, err)
+	assert.Contains(t, result, "sub-1 [running]")
+
+	result, err = tool.Execute(context.Background(), map[string]any{"id": "sub-2"})
+	require.NoError(t, err)
+	assert.Contains(t, result, "Error: subagent not found")
+}
+
+func TestSubagentCancelTool_Execute(t *testing.T) {
+	m := &mockSubagents{}
+	tool := &SubagentCancelTool{Subagents: m}
+	result, _ := tool.Execute(context.Background(), map[string]any{})
+	assert.Contains(t, result, "id is required")
+
+	result, err := tool.Execute(context.Background(), map[string]any{"id": "sub-1"})
+	require.NoError(t, err)
+	assert.Contains(t, result, "Cancellation requested")
+	assert.Equal(t, "sub-1", m.cancelled)
+
+	result, _ = (&SubagentCancelTool{}).Execute(context.Background(), map[string]any{"id": "sub-1"})
+	assert.Contains(t, result, "not configured")
+}
+
 // --- CronTool Tests ---
 
 func TestCronTool_Contract(t *testing.T) {
@@ -115,11 +192,13 @@ func TestCronTool_Contract(t *testing.T) {
 }
 
 type mockCron struct {
-	jobs []string
+	jobs    []string
+	targets []string
 }
 
 func (m *mockCron) AddJob(name, message, channel, chatID string, every int, expr string, at string) (string, error) {
 	m.jobs = append(m.jobs, name)
+	m.targets = append(m.targets, channel+":"+chatID)
 	return fmt.Sprintf("Created job '%s' (id: mock-1)", name), nil
 }
 func (m *mockCron) ListJobs() (string, error) {
@@ -144,6 +223,17 @@ func TestCronTool_Add(t *testing.T) {
 	assert.Len(t, mc.jobs, 1)
 }
 
+func TestCronTool_AddUsesChatTarget(t *testing.T) {
+	mc := &mockCron{}
+	tool := &CronTool{Cron: mc}
+	ctx := WithChatTarget(context.Background(), "telegram", "42")
+	_, err := tool.Execute(ctx, map[string]any{
+		"action": "add", "message": "Drink water", "every_seconds": float64(3600),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"telegram:42"}, mc.targets)
+}
+
 func TestCronTool_AddNoMessage(t *testing.T) {
 	tool := &CronTool{Cron: &mockCron{}, Channel: "t", ChatID: "1"}
 	result, _ := tool.Execute(context.Background(), map[string]any{"action": "add"})
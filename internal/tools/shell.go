@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
@@ -22,13 +24,23 @@ var DefaultDenyPatterns = []string{
 	`:\(\)\s*\{.*\};\s*:`,
 }
 
-// ExecTool executes shell commands with safety guards.
+// SkillDirs resolves a skill name to the directory holding its SKILL.md and
+// bundled scripts (*agent.SkillsLoader).
+type SkillDirs interface {
+	SkillDir(name string) (string, bool)
+}
+
+// ExecTool executes shell commands with safety guards. RestrictToWorkspace
+// keeps working_dir and skill directories under WorkingDir.
 type ExecTool struct {
 	Timeout             time.Duration
 	WorkingDir          string
 	DenyPatterns        []string
 	AllowPatterns       []string
 	RestrictToWorkspace bool
+	// Skills lets a command run inside a skill's directory (the skill
+	// argument); nil disables it
+	Skills SkillDirs
 }
 
 // NewExecTool creates an ExecTool with default safety patterns.
@@ -40,15 +52,20 @@ func NewExecTool() *ExecTool {
 }
 
 func (t *ExecTool) Name() string        { return "exec" }
-func (t *ExecTool) Description() string  { return "Execute a shell command and return its output." }
+func (t *ExecTool) Sequential()         {}
+func (t *ExecTool) Description() string { return "Execute a shell command and return its output." }
 func (t *ExecTool) Parameters() map[string]any {
+	props := map[string]any{
+		"command":     map[string]any{"type": "string", "description": "The shell command to execute"},
+		"working_dir": map[string]any{"type": "string", "description": "Optional working directory"},
+	}
+	if t.Skills != nil {
+		props["skill"] = map[string]any{"type": "string", "description": "Optional skill name: run inside that skill's directory (e.g. scripts/run.sh), with $SKILL_DIR set"}
+	}
 	return map[string]any{
-		"type": "object",
-		"properties": map[string]any{
-			"command":     map[string]any{"type": "string", "description": "The shell command to execute"},
-			"working_dir": map[string]any{"type": "string", "description": "Optional working directory"},
-		},
-		"required": []string{"command"},
+		"type":       "object",
+		"properties": props,
+		"required":   []string{"command"},
 	}
 }
 
@@ -62,6 +79,25 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]any) (string, er
 	if cwd == "" {
 		cwd = t.WorkingDir
 	}
+	var env []string
+	if skill, _ := args["skill"].(string); skill != "" {
+		if t.Skills == nil {
+			return "Error: Skill scripts not configured", nil
+		}
+		dir, ok := t.Skills.SkillDir(skill)
+		if !ok {
+			return fmt.Sprintf("Error: Skill not found: %s", skill), nil
+		}
+		cwd = dir
+		env = append(os.Environ(), "SKILL_DIR="+dir)
+	}
+	if t.RestrictToWorkspace {
+		confined, err := t.confine(cwd)
+		if err != nil {
+			return "Error: " + err.Error(), nil
+		}
+		cwd = confined
+	}
 
 	if err := t.guardCommand(command); err != "" {
 		return err, nil
@@ -78,6 +114,7 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]any) (string, er
 	if cwd != "" {
 		cmd.Dir = cwd
 	}
+	cmd.Env = env
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -111,10 +148,43 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]any) (string, er
 
 	// Truncate long output
 	const maxLen = 10000
-	if len(result) > maxLen {
-		result = result[:maxLen] + fmt.Sprintf("\n... (truncated, %d more chars)", len(result)-maxLen)
+	return TruncateOutput(result, maxLen), nil
+}
+
+// confine resolves dir (relative to WorkingDir, symlinks followed) and
+// rejects it unless it is WorkingDir or below it.
+func (t *ExecTool) confine(dir string) (string, error) {
+	root, err := realPath(t.WorkingDir)
+	if err != nil {
+		return "", err
+	}
+	if dir == "" {
+		return root, nil
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	resolved, err := realPath(dir)
+	if err != nil {
+		return "", err
+	}
+	if rel, err := filepath.Rel(root, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("working directory %s is outside the workspace %s", dir, t.WorkingDir)
+	}
+	return resolved, nil
+}
+
+// realPath returns the absolute path of dir with symlinks resolved, or just
+// the absolute path when dir does not exist (yet).
+func realPath(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	if real, err := filepath.EvalSymlinks(abs); err == nil {
+		return real, nil
 	}
-	return result, nil
+	return abs, nil
 }
 
 func (t *ExecTool) guardCommand(command string) string {
@@ -2,6 +2,8 @@ package tools
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -107,9 +109,69 @@ func TestExecTool_PathTraversal(t *testing.T) {
 	assert.Contains(t, result, "path traversal")
 }
 
+func TestExecTool_RestrictedWorkingDir(t *testing.T) {
+	workspace := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(workspace, "sub"), 0o755))
+	outside := t.TempDir()
+	require.NoError(t, os.Symlink(outside, filepath.Join(workspace, "link")))
+
+	tool := NewExecTool()
+	tool.WorkingDir = workspace
+	tool.RestrictToWorkspace = true
+	tool.Skills = skillDirs{"outside": outside}
+
+	for _, args := range []map[string]any{
+		{"command": "pwd", "working_dir": outside},
+		{"command": "pwd", "working_dir": "/"},
+		{"command": "pwd", "working_dir": ".."},
+		{"command": "pwd", "working_dir": "link"},
+		{"command": "pwd", "skill": "outside"},
+	} {
+		result, err := tool.Execute(context.Background(), args)
+		require.NoError(t, err)
+		assert.Contains(t, result, "outside the workspace", "args %v", args)
+	}
+
+	result, err := tool.Execute(context.Background(), map[string]any{"command": "pwd", "working_dir": "sub"})
+	require.NoError(t, err)
+	real, _ := filepath.EvalSymlinks(filepath.Join(workspace, "sub"))
+	assert.Equal(t, real+"\n", result)
+}
+
 func TestExecTool_EmptyCommand(t *testing.T) {
 	tool := NewExecTool()
 	result, err := tool.Execute(context.Background(), map[string]any{})
 	require.NoError(t, err)
 	assert.Contains(t, result, "command is required")
 }
+
+type skillDirs map[string]string
+
+func (s skillDirs) SkillDir(name string) (string, bool) {
+	dir, ok := s[name]
+	return dir, ok
+}
+
+func TestExecTool_Skill(t *testing.T) {
+	dir := t.TempDir()
+	tool := NewExecTool()
+	tool.Skills = skillDirs{"deploy": dir}
+	assert.Contains(t, tool.Parameters()["properties"], "skill")
+
+	result, err := tool.Execute(context.Background(), map[string]any{
+		"command": "pwd; echo $SKILL_DIR",
+		"skill":   "deploy",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, dir+"\n"+dir+"\n", result)
+
+	result, _ = tool.Execute(context.Background(), map[string]any{"command": "ls", "skill": "nope"})
+	assert.Contains(t, result, "Skill not found")
+}
+
+func TestExecTool_Skill_NotConfigured(t *testing.T) {
+	tool := NewExecTool()
+	assert.NotContains(t, tool.Parameters()["properties"], "skill")
+	result, _ := tool.Execute(context.Background(), map[string]any{"command": "ls", "skill": "deploy"})
+	assert.Contains(t, result, "not configured")
+}
@@ -0,0 +1,284 @@
+// Package tools — validate.go
+// Minimal JSON Schema validation of model-produced tool arguments.
+// Supports the subset tools actually declare: type (single or list), required,
+// properties, enum, minimum/maximum (+ exclusive), minLength/maxLength,
+// pattern, items, minItems/maxItems.
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationIssue describes one argument that does not match the schema.
+type ValidationIssue struct {
+	Path    string `json:"path"` // dotted path, e.g. "dateRange.from" or "items[2]"
+	Message string `json:"message"`
+}
+
+// ValidateArgs checks args against a tool's Parameters() schema.
+// A nil or empty schema accepts anything.
+func ValidateArgs(schema map[string]any, args map[string]any) []ValidationIssue {
+	if len(schema) == 0 {
+		return nil
+	}
+	var value any = args
+	if args == nil {
+		value = map[string]any{}
+	}
+	var issues []ValidationIssue
+	validateValue("", schema, value, &issues)
+	return issues
+}
+
+// InvalidArgumentsOutput formats validation issues as the structured tool
+// result the LLM receives, so it can fix the call and retry.
+func InvalidArgumentsOutput(tool string, issues []ValidationIssue) string {
+	b, _ := json.Marshal(map[string]any{
+		"error":  "invalid_arguments",
+		"tool":   tool,
+		"issues": issues,
+		"hint":   "Fix the arguments to match the tool's parameter schema and call the tool again.",
+	})
+	return string(b)
+}
+
+func validateValue(path string, schema map[string]any, v any, issues *[]ValidationIssue) {
+	add := func(format string, a ...any) {
+		p := path
+		if p == "" {
+			p = "(root)"
+		}
+		*issues = append(*issues, ValidationIssue{Path: p, Message: fmt.Sprintf(format, a...)})
+	}
+
+	actual := jsonType(v)
+	if types := schemaTypes(schema["type"]); len(types) > 0 && !typeMatches(types, actual) {
+		add("expected %s, got %s", strings.Join(types, " or "), actual)
+		return
+	}
+
+	if enum, ok := schema["enum"]; ok {
+		options := toSlice(enum)
+		found := false
+		for _, opt := range options {
+			if jsonEqual(opt, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			add("must be one of %s", formatOptions(options))
+			return
+		}
+	}
+
+	switch actual {
+	case "string":
+		s := v.(string)
+		n := utf8.RuneCountInString(s)
+		if min, ok := toFloat(schema["minLength"]); ok && float64(n) < min {
+			add("must be at least %v characters", min)
+		}
+		if max, ok := toFloat(schema["maxLength"]); ok && float64(n) > max {
+			add("must be at most %v characters", max)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(s) {
+				add("must match pattern %q", pattern)
+			}
+		}
+
+	case "integer", "number":
+		f, _ := toFloat(v)
+		if min, ok := toFloat(schema["minimum"]); ok && f < min {
+			add("must be >= %v", min)
+		}
+		if max, ok := toFloat(schema["maximum"]); ok && f > max {
+			add("must be <= %v", max)
+		}
+		if min, ok := toFloat(schema["exclusiveMinimum"]); ok && f <= min {
+			add("must be > %v", min)
+		}
+		if max, ok := toFloat(schema["exclusiveMaximum"]); ok && f >= max {
+			add("must be < %v", max)
+		}
+
+	case "array":
+		items := toSlice(v)
+		if min, ok := toFloat(schema["minItems"]); ok && float64(len(items)) < min {
+			add("must have at least %v items", min)
+		}
+		if max, ok := toFloat(schema["maxItems"]); ok && float64(len(items)) > max {
+			add("must have at most %v items", max)
+		}
+		if itemSchema, ok := schema["items"].(map[string]any); ok {
+			for i, item := range items {
+				validateValue(fmt.Sprintf("%s[%d]", path, i), itemSchema, item, issues)
+			}
+		}
+
+	case "object":
+		obj := toObject(v)
+		for _, name := range toStrings(schema["required"]) {
+			if _, ok := obj[name]; !ok {
+				*issues = append(*issues, ValidationIssue{Path: joinPath(path, name), Message: "is required"})
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		names := make([]string, 0, len(props))
+		for name := range props {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			val, present := obj[name]
+			propSchema, ok := props[name].(map[string]any)
+			if !present || !ok {
+				continue
+			}
+			validateValue(joinPath(path, name), propSchema, val, issues)
+		}
+	}
+}
+
+func joinPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// jsonType returns the JSON Schema type name of a decoded value.
+// Integral numbers report "integer" (which also satisfies "number").
+func jsonType(v any) string {
+	if v == nil {
+		return "null"
+	}
+	switch x := v.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := x.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	}
+	if f, ok := toFloat(v); ok {
+		if f == math.Trunc(f) && !math.IsInf(f, 0) {
+			return "integer"
+		}
+		return "number"
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func typeMatches(types []string, actual string) bool {
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func schemaTypes(t any) []string {
+	switch x := t.(type) {
+	case string:
+		return []string{x}
+	default:
+		return toStrings(t)
+	}
+}
+
+func toFloat(v any) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case float32:
+		return float64(x), true
+	case int:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case int32:
+		return float64(x), true
+	case json.Number:
+		f, err := x.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func toSlice(v any) []any {
+	if s, ok := v.([]any); ok {
+		return s
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil
+	}
+	out := make([]any, rv.Len())
+	for i := range out {
+		out[i] = rv.Index(i).Interface()
+	}
+	return out
+}
+
+func toStrings(v any) []string {
+	var out []string
+	for _, x := range toSlice(v) {
+		if s, ok := x.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func toObject(v any) map[string]any {
+	if m, ok := v.(map[string]any); ok {
+		return m
+	}
+	out := map[string]any{}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Map {
+		for _, k := range rv.MapKeys() {
+			out[fmt.Sprint(k.Interface())] = rv.MapIndex(k).Interface()
+		}
+	}
+	return out
+}
+
+// jsonEqual compares an enum option with a value, treating all numeric
+// types as equal when their values match.
+func jsonEqual(a, b any) bool {
+	fa, okA := toFloat(a)
+	fb, okB := toFloat(b)
+	if okA && okB {
+		return fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func formatOptions(options []any) string {
+	parts := make([]string, len(options))
+	for i, o := range options {
+		b, _ := json.Marshal(o)
+		parts[i] = string(b)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
@@ -0,0 +1,113 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var weatherSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"city":  map[string]any{"type": "string", "minLength": 1},
+		"unit":  map[string]any{"type": "string", "enum": []string{"c", "f"}},
+		"days":  map[string]any{"type": "integer", "minimum": 1, "maximum": 7},
+		"tags":  map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "maxItems": 2},
+		"range": map[string]any{"type": "object", "required": []string{"from"}, "properties": map[string]any{"from": map[string]any{"type": "string"}}},
+	},
+	"required": []string{"city"},
+}
+
+func issuePaths(issues []ValidationIssue) []string {
+	var paths []string
+	for _, i := range issues {
+		paths = append(paths, i.Path)
+	}
+	return paths
+}
+
+func TestValidateArgs_Valid(t *testing.T) {
+	issues := ValidateArgs(weatherSchema, map[string]any{
+		"city":  "Paris",
+		"unit":  "c",
+		"days":  float64(3), // JSON numbers decode as float64
+		"tags":  []any{"a"},
+		"range": map[string]any{"from": "today"},
+	})
+	assert.Empty(t, issues)
+}
+
+func TestValidateArgs_Required(t *testing.T) {
+	issues := ValidateArgs(weatherSchema, nil)
+	require.Len(t, issues, 1)
+	assert.Equal(t, "city", issues[0].Path)
+	assert.Equal(t, "is required", issues[0].Message)
+}
+
+func TestValidateArgs_TypeMismatch(t *testing.T) {
+	issues := ValidateArgs(weatherSchema, map[string]any{"city": 42.0, "days": "3"})
+	assert.ElementsMatch(t, []string{"city", "days"}, issuePaths(issues))
+	assert.Contains(t, issues[0].Message, "expected string")
+}
+
+func TestValidateArgs_IntegerRejectsFraction(t *testing.T) {
+	issues := ValidateArgs(weatherSchema, map[string]any{"city": "x", "days": 2.5})
+	require.Len(t, issues, 1)
+	assert.Contains(t, issues[0].Message, "expected integer")
+}
+
+func TestValidateArgs_EnumAndBounds(t *testing.T) {
+	issues := ValidateArgs(weatherSchema, map[string]any{
+		"city": "",
+		"unit": "k",
+		"days": 10.0,
+		"tags": []any{"a", "b", 3.0},
+	})
+	assert.ElementsMatch(t, []string{"city", "unit", "days", "tags", "tags[2]"}, issuePaths(issues))
+}
+
+func TestValidateArgs_NestedRequired(t *testing.T) {
+	issues := ValidateArgs(weatherSchema, map[string]any{"city": "x", "range": map[string]any{}})
+	require.Len(t, issues, 1)
+	assert.Equal(t, "range.from", issues[0].Path)
+}
+
+func TestValidateArgs_EmptySchemaAcceptsAnything(t *testing.T) {
+	assert.Empty(t, ValidateArgs(nil, map[string]any{"x": 1}))
+	assert.Empty(t, ValidateArgs(map[string]any{}, map[string]any{"x": 1}))
+}
+
+func TestExecutor_InvalidArgsSkipExecute(t *testing.T) {
+	called := false
+	tool := &schemaTool{funcTool: funcTool{name: "weather", fn: func(context.Context) (string, error) {
+		called = true
+		return "sunny", nil
+	}}}
+	ex := newTestExecutor(tool)
+
+	res := ex.Execute(context.Background(), "weather", map[string]any{"unit": "k"})
+	assert.False(t, called)
+	assert.Equal(t, OutcomeInvalid, res.Outcome)
+
+	var out map[string]any
+	require.NoError(t, json.Unmarshal([]byte(res.Output), &out))
+	assert.Equal(t, "invalid_arguments", out["error"])
+	assert.Equal(t, "weather", out["tool"])
+	assert.Len(t, out["issues"], 2)
+}
+
+func TestExecutor_Reject(t *testing.T) {
+	ex := newTestExecutor()
+	res := ex.Reject("weather", "arguments are not a valid JSON object")
+	assert.Equal(t, OutcomeInvalid, res.Outcome)
+	assert.Contains(t, res.Output, "not a valid JSON object")
+	assert.Equal(t, 1, ex.Stats()["weather"].Errors)
+}
+
+// schemaTool is a funcTool that declares weatherSchema.
+type schemaTool struct{ funcTool }
+
+func (s *schemaTool) Parameters() map[string]any { return weatherSchema }
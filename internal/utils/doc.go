@@ -46,6 +46,31 @@ func GetWorkspacePath(workspace string) string {
 	return p
 }
 
+// WriteFileAtomic writes data to a temporary file next to path and renames
+// it into place, so readers never observe a partially written file.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
 // Timestamp returns the current time as an ISO 8601 string.
 func Timestamp() string {
 	return time.Now().Format(time.RFC3339)
@@ -66,6 +91,18 @@ func TruncateString(s string, maxLen int, suffix string) string {
 	return s[:cutoff] + suffix
 }
 
+// TruncateRunes returns the first n runes of s, never splitting a
+// multi-byte character.
+func TruncateRunes(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
 // SafeFilename converts a string to a safe filename by replacing unsafe characters.
 func SafeFilename(name string) string {
 	unsafe := `<>:"/\|?*`
@@ -56,6 +56,13 @@ func TestTruncateString_EmptySuffix(t *testing.T) {
 	assert.Equal(t, "he...", TruncateString("hello world", 5, ""))
 }
 
+func TestTruncateRunes(t *testing.T) {
+	assert.Equal(t, "hello", TruncateRunes("hello", 10))
+	assert.Equal(t, "he", TruncateRunes("hello", 2))
+	assert.Equal(t, "提醒我", TruncateRunes("提醒我明天开会", 3))
+	assert.Equal(t, "", TruncateRunes("提醒", 0))
+}
+
 func TestParseSessionKey_Valid(t *testing.T) {
 	ch, id, err := ParseSessionKey("telegram:12345")
 	require.NoError(t, err)
@@ -81,3 +88,17 @@ func TestTimestamp(t *testing.T) {
 	assert.NotEmpty(t, ts)
 	assert.Contains(t, ts, "T") // ISO 8601 has T separator
 }
+
+func TestWriteFileAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "MEMORY.md")
+	require.NoError(t, WriteFileAtomic(path, []byte("first"), 0o644))
+	require.NoError(t, WriteFileAtomic(path, []byte("second"), 0o644))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(data))
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temp files left behind")
+}